@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/audit"
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool/service/hash"
+)
+
+// runAudit handles `iav audit`, printing the recorded tool calls that match the flags.
+func runAudit(args []string) error {
+	if len(args) > 0 && args[0] == "verify" {
+		return runAuditVerify()
+	}
+
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	session := flags.String("session", "", "only calls from this session ID")
+	toolName := flags.String("tool", "", "only calls to this tool")
+	since := flags.String("since", "", "only calls started at or after this time (RFC 3339, or a duration such as 24h meaning that long ago)")
+	until := flags.String("until", "", "only calls started before this time (same formats as --since)")
+	asJSON := flags.Bool("json", false, "print matching records as JSON lines")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	filter := audit.Filter{SessionID: *session, Tool: *toolName}
+	var err error
+	if filter.Since, err = parseTimeFlag(*since, now); err != nil {
+		return fmt.Errorf("--since: %w", err)
+	}
+	if filter.Until, err = parseTimeFlag(*until, now); err != nil {
+		return fmt.Errorf("--until: %w", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	records, err := audit.Read(cfg.Audit.Path, filter)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, rec := range records {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return printAuditTable(os.Stdout, records)
+}
+
+// parseTimeFlag accepts an RFC 3339 timestamp or a duration before now. Empty means unset.
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 time or duration, got %q", value)
+	}
+	return t, nil
+}
+
+func printAuditTable(w io.Writer, records []audit.Record) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tSESSION\tTOOL\tCALL ID\tSUCCESS\tDURATION\tBYTES")
+	for _, rec := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\t%d\n",
+			rec.StartedAt.Local().Format(time.RFC3339),
+			rec.SessionID,
+			rec.Tool,
+			rec.ToolCallID,
+			rec.Success,
+			rec.EndedAt.Sub(rec.StartedAt).Round(time.Millisecond),
+			rec.ResultBytes,
+		)
+	}
+	return tw.Flush()
+}
+
+// runAuditVerify handles `iav audit verify`, failing if the trail shows signs of tampering.
+func runAuditVerify() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	res, err := audit.Verify(cfg, hash.NewChecksumManager())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d records (seq %d-%d), %d checkpoints verified\n", res.Records, res.FirstSeq, res.LastSeq, res.Checkpoints)
+	if res.FirstSeq > 1 {
+		fmt.Printf("records before seq %d have been rotated away\n", res.FirstSeq)
+	}
+	if res.OK() {
+		fmt.Println("audit trail intact")
+		return nil
+	}
+	for _, p := range res.Problems {
+		fmt.Println("  " + p)
+	}
+	return fmt.Errorf("audit trail verification failed: %d problems", len(res.Problems))
+}
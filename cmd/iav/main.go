@@ -1 +1,38 @@
 package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `usage: iav <command> [arguments]
+
+commands:
+  mcp serve [--workspace dir] [--profile name] [--session id]
+                                expose workspace tools to MCP clients over stdio
+  audit [flags]                 list recorded tool calls (see iav audit -h)
+  audit verify                  check the audit trail's hash chain and signed checkpoints`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "iav:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches to the subcommand named by args.
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n\n%s", usage)
+	}
+	switch args[0] {
+	case "mcp":
+		return runMCP(args[1:])
+	case "audit":
+		return runAudit(args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+}
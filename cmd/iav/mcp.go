@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/Cyclone1070/iav/internal/audit"
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/mcp/client"
+	"github.com/Cyclone1070/iav/internal/mcp/server"
+	"github.com/Cyclone1070/iav/internal/session"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/file"
+	"github.com/Cyclone1070/iav/internal/tool/helper/diffview"
+	"github.com/Cyclone1070/iav/internal/tool/search"
+	"github.com/Cyclone1070/iav/internal/tool/service/diagnostics"
+	"github.com/Cyclone1070/iav/internal/tool/service/executor"
+	"github.com/Cyclone1070/iav/internal/tool/service/fs"
+	"github.com/Cyclone1070/iav/internal/tool/service/git"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+	"github.com/Cyclone1070/iav/internal/tool/shell"
+	"github.com/Cyclone1070/iav/internal/workflow"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// runMCP handles `iav mcp <subcommand>`.
+func runMCP(args []string) error {
+	if len(args) == 0 || args[0] != "serve" {
+		return fmt.Errorf("usage: iav mcp serve [--workspace dir] [--profile name] [--session id]")
+	}
+
+	flags := flag.NewFlagSet("mcp serve", flag.ContinueOnError)
+	workspace := flags.String("workspace", ".", "workspace root the tools operate in")
+	profile := flags.String("profile", "", "tool profile from config to expose (default: all tools, or the resumed session's)")
+	sessionID := flags.String("session", "", "session to resume, keeping the file checksums its tools cached (default: a new session)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	root, err := path.CanonicaliseRoot(*workspace)
+	if err != nil {
+		return err
+	}
+
+	// Stdout carries the protocol, so diagnostics go to stderr.
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	resolver := path.NewResolver(root)
+	fileSystem := fs.NewOSFileSystem(cfg)
+	// Finish any multi-file edit a previous run was killed in the middle of.
+	if n, err := fileSystem.Recover(); err != nil {
+		logger.Warn("transaction recovery failed", "error", err)
+	} else if n > 0 {
+		logger.Info("recovered interrupted transactions", "count", n)
+	}
+	// The session's checksums let the edit tools detect files changed since they
+	// were read, even when that read happened in an earlier run.
+	sess, err := openSession(session.NewStore(cfg, logger), *sessionID, *profile)
+	if err != nil {
+		return err
+	}
+	logger.Info("serving session", "id", sess.ID())
+	checksums := sess.Checksums()
+	ignore, err := git.NewIgnoreMatcher(root, fileSystem)
+	if err != nil {
+		return err
+	}
+	commandExecutor := executor.NewOSCommandExecutor(cfg)
+	checker := diagnostics.NewRunner(commandExecutor, cfg, root)
+
+	// Each tool resolves paths through a guard for the access it needs, so
+	// protected paths are refused the same way everywhere.
+	protection := path.NewProtection(cfg.Tools.ProtectedPaths)
+	reader := path.NewGuard(resolver, protection, path.AccessRead)
+	editor := path.NewGuard(resolver, protection, path.AccessRead|path.AccessWrite)
+	writer := path.NewGuard(resolver, protection, path.AccessWrite)
+	searcher := path.NewGuard(resolver, protection, path.AccessRead|path.AccessList)
+
+	tools := toolmanager.NewToolManager(cfg, logger, audit.NewLog(cfg, checksums),
+		file.NewReadFileTool(fileSystem, checksums, reader, ignore, cfg),
+		file.NewEditFileTool(fileSystem, checksums, editor, checker, cfg),
+		file.NewApplyPatchTool(fileSystem, checksums, editor, checker, cfg),
+		file.NewBatchEditTool(fileSystem, checksums, editor, checker, cfg),
+		file.NewEditStructuredTool(fileSystem, checksums, editor, checker, cfg),
+		file.NewGoRefactorTool(fileSystem, checksums, editor, checker, cfg),
+		file.NewMoveFileTool(fileSystem, checksums, editor, cfg),
+		file.NewCopyFileTool(fileSystem, checksums, editor, cfg),
+		file.NewDeleteFileTool(fileSystem, checksums, editor, cfg),
+		file.NewCreateDirectoryTool(fileSystem, checksums, writer, cfg),
+		search.NewSearchContentTool(fileSystem, commandExecutor, cfg, searcher),
+	)
+	for _, spec := range cfg.CustomTools {
+		ct, err := shell.NewCustomTool(spec, commandExecutor, cfg, resolver)
+		if err != nil {
+			return err
+		}
+		tools.Register(ct)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The tools of the configured MCP servers sit alongside the built-in ones.
+	// A server that fails to start is logged and left out.
+	servers := client.NewManager(cfg, logger)
+	defer servers.Close()
+	for _, t := range servers.Start(ctx) {
+		tools.Register(t)
+	}
+	if err := tools.UseProfile(sess.Profile()); err != nil {
+		return err
+	}
+
+	ctx = workflow.WithSessionID(ctx, "mcp-"+sess.ID())
+
+	srv := server.NewServer(tools, root)
+	// The operator sees the edits on stderr, highlighted when it is a terminal.
+	srv.OnToolEnd(printDiffs(os.Stderr, diffview.Options{Color: diffview.ColorEnabled(os.Stderr)}, logger))
+	return srv.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// printDiffs returns a handler that writes the diffs of tool calls that changed
+// files to w. Calls end concurrently, so writes are serialised to keep each
+// diff in one piece.
+func printDiffs(w io.Writer, opts diffview.Options, logger *slog.Logger) func(workflow.ToolEndEvent) {
+	var mu sync.Mutex
+	return func(ev workflow.ToolEndEvent) {
+		var diffs []tool.DiffDisplay
+		switch d := ev.Display.(type) {
+		case tool.DiffDisplay:
+			diffs = []tool.DiffDisplay{d}
+		case tool.MultiDiffDisplay:
+			diffs = d
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, d := range diffs {
+			if err := diffview.Render(w, d, opts); err != nil {
+				logger.Warn("render diff failed", "tool", ev.ToolName, "error", err)
+				return
+			}
+		}
+	}
+}
+
+// openSession resumes session id, or starts a new one if id is "". A profile given
+// on the command line replaces the session's; otherwise a resumed session keeps its own.
+func openSession(store *session.Store, id, profile string) (*session.Session, error) {
+	var sess *session.Session
+	var err error
+	if id == "" {
+		sess, err = store.NewSession()
+	} else {
+		sess, err = store.LoadSession(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open session: %w", err)
+	}
+	if profile != "" && profile != sess.Profile() {
+		sess.SetProfile(profile)
+		if err := sess.Save(); err != nil {
+			return nil, fmt.Errorf("save session: %w", err)
+		}
+	}
+	return sess, nil
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Checkpoint is a signed statement that the record with Seq had hash RecordHash.
+// Checkpoints live in <path>.checkpoints and let verification detect a truncated log.
+type Checkpoint struct {
+	Seq        uint64    `json:"seq"`
+	RecordHash string    `json:"record_hash"`
+	CreatedAt  time.Time `json:"created_at"`
+	Signature  []byte    `json:"signature"` // ed25519 over signedMessage()
+}
+
+func (c Checkpoint) signedMessage() []byte {
+	return fmt.Appendf(nil, "iav-audit-checkpoint\n%d\n%s\n%s", c.Seq, c.RecordHash, c.CreatedAt.UTC().Format(time.RFC3339Nano))
+}
+
+// Verify reports whether the checkpoint was signed by the key pair of pub.
+func (c Checkpoint) Verify(pub ed25519.PublicKey) bool {
+	return ed25519.Verify(pub, c.signedMessage(), c.Signature)
+}
+
+func checkpointPath(path string) string {
+	return path + ".checkpoints"
+}
+
+// writeCheckpoint signs and appends a checkpoint for the record with seq and hash.
+func writeCheckpoint(path string, key ed25519.PrivateKey, seq uint64, hash string) error {
+	c := Checkpoint{Seq: seq, RecordHash: hash, CreatedAt: time.Now().UTC()}
+	c.Signature = ed25519.Sign(key, c.signedMessage())
+
+	line, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("encode audit checkpoint: %w", err)
+	}
+	f, err := os.OpenFile(checkpointPath(path), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit checkpoints: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit checkpoint: %w", err)
+	}
+	return f.Sync()
+}
@@ -0,0 +1,6 @@
+package audit
+
+// checksumComputer hashes the LLM content of each recorded call.
+type checksumComputer interface {
+	Compute(data []byte) string
+}
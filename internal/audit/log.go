@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+)
+
+// Record is one tool call in the audit log.
+// Records form a hash chain: each one holds the hash of the line before it.
+type Record struct {
+	Seq         uint64          `json:"seq"`       // 1 for the first record, then consecutive
+	PrevHash    string          `json:"prev_hash"` // SHA-256 of the previous record's line, "" for the first
+	SessionID   string          `json:"session_id"`
+	ToolCallID  string          `json:"tool_call_id"`
+	Tool        string          `json:"tool"`
+	Arguments   json.RawMessage `json:"arguments"` // As sent by the LLM; a JSON string if it was not valid JSON
+	StartedAt   time.Time       `json:"started_at"`
+	EndedAt     time.Time       `json:"ended_at"`
+	Success     bool            `json:"success"`
+	ResultBytes int             `json:"result_bytes"`    // Size of the LLM content
+	ResultHash  string          `json:"result_hash"`     // SHA-256 of the LLM content, hex encoded
+	Error       string          `json:"error,omitempty"` // Infrastructure error, e.g. cancellation
+}
+
+// Log appends records to a JSONL file, rotating it when it grows past the configured size.
+// Rotated files are named <path>.1 (newest) to <path>.N (oldest). Every checkpointInterval
+// records a checkpoint signed with the key at keyPath is appended to <path>.checkpoints.
+//
+// The chain position is read back from the file on each append, under an advisory lock on
+// <path>.lock, so processes that share a log continue the same chain instead of forking it.
+type Log struct {
+	enabled            bool
+	path               string
+	maxFileSize        int64
+	maxFiles           int
+	keyPath            string
+	checkpointInterval int
+	checksums          checksumComputer
+
+	mu  sync.Mutex
+	key ed25519.PrivateKey // Loaded on the first checkpoint
+}
+
+// NewLog creates an audit log at cfg.Audit.Path.
+func NewLog(cfg *config.Config, checksums checksumComputer) *Log {
+	if cfg == nil {
+		panic("cfg is required")
+	}
+	if checksums == nil {
+		panic("checksums is required")
+	}
+	return &Log{
+		enabled:            cfg.Audit.Enabled,
+		path:               cfg.Audit.Path,
+		maxFileSize:        cfg.Audit.MaxFileSize,
+		maxFiles:           cfg.Audit.MaxFiles,
+		keyPath:            cfg.Audit.KeyPath,
+		checkpointInterval: cfg.Audit.CheckpointInterval,
+		checksums:          checksums,
+	}
+}
+
+// Append completes rec with the size and hash of llmContent and writes it to the log.
+// The record is synced to disk before Append returns. Append does nothing when auditing is disabled.
+func (l *Log) Append(rec Record, llmContent string) error {
+	if !l.enabled {
+		return nil
+	}
+	rec.ResultBytes = len(llmContent)
+	rec.ResultHash = l.checksums.Compute([]byte(llmContent))
+	if !json.Valid(rec.Arguments) {
+		rec.Arguments, _ = json.Marshal(string(rec.Arguments))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("create audit dir: %w", err)
+	}
+
+	// Held from reading the tail to writing the record and its checkpoint.
+	lock, err := os.OpenFile(lockPath(l.path), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit lock: %w", err)
+	}
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("lock audit log: %w", err)
+	}
+
+	prev, err := lastLine(l.path)
+	if err != nil {
+		return err
+	}
+	rec.Seq, rec.PrevHash = 1, ""
+	if prev != nil {
+		var p Record
+		if err := json.Unmarshal(prev, &p); err != nil {
+			return fmt.Errorf("audit log tail is malformed: %w", err)
+		}
+		rec.Seq, rec.PrevHash = p.Seq+1, l.checksums.Compute(prev)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode audit record: %w", err)
+	}
+
+	if err := l.rotateIfNeeded(int64(len(line) + 1)); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync audit log: %w", err)
+	}
+
+	if rec.Seq%uint64(l.checkpointInterval) == 0 {
+		return l.checkpoint(rec.Seq, l.checksums.Compute(line))
+	}
+	return nil
+}
+
+func (l *Log) checkpoint(seq uint64, hash string) error {
+	if l.key == nil {
+		key, err := loadOrCreateKey(l.keyPath)
+		if err != nil {
+			return err
+		}
+		l.key = key
+	}
+	return writeCheckpoint(l.path, l.key, seq, hash)
+}
+
+// lastLine returns the last non-empty line of the file at path, or nil if there is none.
+// It reads backwards from the end so large logs are not scanned.
+func lastLine(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat audit log: %w", err)
+	}
+
+	const chunkSize = 4096
+	var tail []byte
+	for end := info.Size(); end > 0; {
+		start := max(end-chunkSize, 0)
+		chunk := make([]byte, end-start)
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return nil, fmt.Errorf("read audit log: %w", err)
+		}
+		tail = append(chunk, tail...)
+		end = start
+
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	trimmed := bytes.TrimRight(tail, "\n")
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	return trimmed, nil
+}
+
+// rotateIfNeeded shifts the log files when writing n more bytes would exceed the size limit.
+// A single record larger than the limit is still written to a fresh file.
+func (l *Log) rotateIfNeeded(n int64) error {
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat audit log: %w", err)
+	}
+	if info.Size() == 0 || info.Size()+n <= l.maxFileSize {
+		return nil
+	}
+
+	if l.maxFiles == 0 {
+		if err := os.Remove(l.path); err != nil {
+			return fmt.Errorf("rotate audit log: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.Remove(rotatedPath(l.path, l.maxFiles)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate audit log: %w", err)
+	}
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedPath(l.path, i), rotatedPath(l.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("rotate audit log: %w", err)
+		}
+	}
+	if err := os.Rename(l.path, rotatedPath(l.path, 1)); err != nil {
+		return fmt.Errorf("rotate audit log: %w", err)
+	}
+	return nil
+}
+
+func rotatedPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+func lockPath(path string) string {
+	return path + ".lock"
+}
@@ -0,0 +1,218 @@
+package audit
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool/service/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockChecksums struct{}
+
+func (mockChecksums) Compute(data []byte) string {
+	return "hash-of-" + string(data)
+}
+
+func newTestLog(t *testing.T) (*Log, string) {
+	t.Helper()
+	cfg := newTestConfig(t)
+	return NewLog(cfg, mockChecksums{}), cfg.Audit.Path
+}
+
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Audit.Path = filepath.Join(dir, "audit", "audit.jsonl")
+	cfg.Audit.KeyPath = filepath.Join(dir, "config", "audit_ed25519.pem")
+	return cfg
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestLog_Append_WritesCompletedRecord(t *testing.T) {
+	l, path := newTestLog(t)
+
+	require.NoError(t, l.Append(Record{
+		SessionID:  "s1",
+		ToolCallID: "c1",
+		Tool:       "read_file",
+		Arguments:  json.RawMessage(`{"path":"a.go"}`),
+		Success:    true,
+	}, "body"))
+
+	lines := readLines(t, path)
+	require.Len(t, lines, 1)
+	var rec Record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	assert.Equal(t, "read_file", rec.Tool)
+	assert.Equal(t, 4, rec.ResultBytes)
+	assert.Equal(t, "hash-of-body", rec.ResultHash)
+	assert.JSONEq(t, `{"path":"a.go"}`, string(rec.Arguments))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestLog_Append_ChainsRecordsAcrossInstances(t *testing.T) {
+	cfg := newTestConfig(t)
+	first := NewLog(cfg, mockChecksums{})
+	require.NoError(t, first.Append(Record{Tool: "a", Arguments: json.RawMessage(`{}`)}, ""))
+	// A second process appending to the same log continues the chain.
+	second := NewLog(cfg, mockChecksums{})
+	require.NoError(t, second.Append(Record{Tool: "b", Arguments: json.RawMessage(`{}`)}, ""))
+
+	lines := readLines(t, cfg.Audit.Path)
+	require.Len(t, lines, 2)
+	var r1, r2 Record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &r1))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &r2))
+	assert.Equal(t, uint64(1), r1.Seq)
+	assert.Empty(t, r1.PrevHash)
+	assert.Equal(t, uint64(2), r2.Seq)
+	assert.Equal(t, "hash-of-"+lines[0], r2.PrevHash)
+}
+
+func TestLog_Append_ConcurrentInstances_KeepOneChain(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Audit.CheckpointInterval = 1000
+	var wg sync.WaitGroup
+	for range 2 {
+		// Each instance has its own mutex, as two processes would.
+		l := NewLog(cfg, hash.NewChecksumManager())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				assert.NoError(t, l.Append(Record{Tool: "tool", Arguments: json.RawMessage(`{}`)}, ""))
+			}
+		}()
+	}
+	wg.Wait()
+
+	res, err := Verify(cfg, hash.NewChecksumManager())
+	require.NoError(t, err)
+	assert.True(t, res.OK(), res.Problems)
+	assert.Equal(t, 40, res.Records)
+}
+
+func TestLog_Append_WritesSignedCheckpoints(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Audit.CheckpointInterval = 2
+	l := NewLog(cfg, mockChecksums{})
+
+	for range 5 {
+		require.NoError(t, l.Append(Record{Tool: "a", Arguments: json.RawMessage(`{}`)}, ""))
+	}
+
+	lines := readLines(t, cfg.Audit.Path+".checkpoints")
+	require.Len(t, lines, 2)
+	key, err := LoadKey(cfg.Audit.KeyPath)
+	require.NoError(t, err)
+	var c Checkpoint
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &c))
+	assert.Equal(t, uint64(4), c.Seq)
+	assert.True(t, c.Verify(key.Public().(ed25519.PublicKey)))
+
+	info, err := os.Stat(cfg.Audit.KeyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestLog_Append_InvalidArguments_StoredAsString(t *testing.T) {
+	l, path := newTestLog(t)
+
+	require.NoError(t, l.Append(Record{Tool: "x", Arguments: json.RawMessage(`{broken`)}, ""))
+
+	var rec Record
+	require.NoError(t, json.Unmarshal([]byte(readLines(t, path)[0]), &rec))
+	assert.Equal(t, `"{broken"`, string(rec.Arguments))
+}
+
+func TestLog_Append_RotatesAndKeepsMaxFiles(t *testing.T) {
+	l, path := newTestLog(t)
+	l.maxFileSize = 1 // every record gets its own file
+	l.maxFiles = 2
+
+	for _, tool := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, l.Append(Record{Tool: tool, Arguments: json.RawMessage(`{}`)}, ""))
+	}
+
+	assert.Contains(t, readLines(t, path)[0], `"tool":"d"`)
+	assert.Contains(t, readLines(t, path+".1")[0], `"tool":"c"`)
+	assert.Contains(t, readLines(t, path+".2")[0], `"tool":"b"`)
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestLog_Append_Disabled_WritesNothing(t *testing.T) {
+	l, path := newTestLog(t)
+	l.enabled = false
+
+	require.NoError(t, l.Append(Record{Tool: "x"}, ""))
+	assert.NoFileExists(t, path)
+}
+
+func TestRead_FiltersAcrossRotatedFiles(t *testing.T) {
+	l, path := newTestLog(t)
+	l.maxFileSize = 300
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []Record{
+		{SessionID: "s1", Tool: "read_file", StartedAt: base},
+		{SessionID: "s2", Tool: "read_file", StartedAt: base.Add(time.Hour)},
+		{SessionID: "s1", Tool: "edit_file", StartedAt: base.Add(2 * time.Hour)},
+		{SessionID: "s1", Tool: "read_file", StartedAt: base.Add(3 * time.Hour)},
+	}
+	for _, rec := range records {
+		rec.Arguments = json.RawMessage(`{}`)
+		require.NoError(t, l.Append(rec, ""))
+	}
+	require.FileExists(t, path+".1")
+
+	all, err := Read(path, Filter{})
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	for i := range records {
+		assert.True(t, all[i].StartedAt.Equal(records[i].StartedAt), "records must be oldest first")
+	}
+
+	got, err := Read(path, Filter{SessionID: "s1", Tool: "read_file"})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	got, err = Read(path, Filter{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "s2", got[0].SessionID)
+	assert.Equal(t, "edit_file", got[1].Tool)
+}
+
+func TestRead_MissingLog_ReturnsNothing(t *testing.T) {
+	got, err := Read(filepath.Join(t.TempDir(), "audit.jsonl"), Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestRead_MalformedLine_ReportsLocation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{}\nnot json\n"), 0600))
+
+	_, err := Read(path, Filter{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "audit.jsonl:2")
+}
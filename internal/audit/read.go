@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects records when reading the log. Zero fields match everything.
+type Filter struct {
+	SessionID string
+	Tool      string
+	Since     time.Time // Calls started at or after
+	Until     time.Time // Calls started before
+}
+
+// Match reports whether rec passes the filter.
+func (f Filter) Match(rec Record) bool {
+	if f.SessionID != "" && rec.SessionID != f.SessionID {
+		return false
+	}
+	if f.Tool != "" && rec.Tool != f.Tool {
+		return false
+	}
+	if !f.Since.IsZero() && rec.StartedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !rec.StartedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Read returns the records in the log at path and its rotated files that match f,
+// oldest first.
+func Read(path string, f Filter) ([]Record, error) {
+	files, err := logFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for _, file := range files {
+		recs, err := readFile(file, f)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, recs...)
+	}
+	return records, nil
+}
+
+// logFiles returns the active log and every rotated file that exists, oldest first.
+func logFiles(path string) ([]string, error) {
+	indexes, err := rotatedIndexes(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for i := len(indexes) - 1; i >= 0; i-- {
+		files = append(files, rotatedPath(path, indexes[i]))
+	}
+	return append(files, path), nil
+}
+
+// rotatedIndexes returns the numbers of the rotated files of the log at path
+// that exist, in ascending order. A number may be missing if its file was removed.
+func rotatedIndexes(path string) ([]int, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list audit logs: %w", err)
+	}
+	prefix := filepath.Base(path) + "."
+	var indexes []int
+	for _, e := range entries {
+		suffix, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		if n, err := strconv.Atoi(suffix); err == nil && n > 0 && strconv.Itoa(n) == suffix {
+			indexes = append(indexes, n)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+func readFile(path string, f Filter) ([]Record, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	sc := bufio.NewScanner(file)
+	// Arguments can be large (whole file contents for write tools).
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: malformed audit record: %w", path, line, err)
+		}
+		if f.Match(rec) {
+			records = append(records, rec)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	return records, nil
+}
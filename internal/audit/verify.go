@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Cyclone1070/iav/internal/config"
+)
+
+// VerifyResult summarises a check of the audit trail.
+type VerifyResult struct {
+	Records     int
+	FirstSeq    uint64 // Greater than 1 once the oldest files have been rotated away
+	LastSeq     uint64
+	Checkpoints int      // Checkpoints matched against records
+	Problems    []string // Evidence of gaps, reordering or modification
+}
+
+// OK reports whether the trail verified without problems.
+func (r *VerifyResult) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify checks the hash chain across the log and its rotated files, then checks the
+// signed checkpoints against the records. A checkpoint is expected for every multiple
+// of cfg.Audit.CheckpointInterval within the retained records.
+func Verify(cfg *config.Config, checksums checksumComputer) (*VerifyResult, error) {
+	res := &VerifyResult{}
+	hashes := make(map[uint64]string)
+	if err := verifyChain(cfg.Audit.Path, checksums, res, hashes); err != nil {
+		return nil, err
+	}
+	if err := verifyCheckpoints(cfg, res, hashes); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func verifyChain(path string, checksums checksumComputer, res *VerifyResult, hashes map[uint64]string) error {
+	var prevLine []byte
+	var prevSeq uint64
+
+	// Rotation only ever drops the oldest file, so a hole in the numbering is a removed file.
+	indexes, err := rotatedIndexes(path)
+	if err != nil {
+		return err
+	}
+	for i, n := 0, 1; i < len(indexes); n++ {
+		if indexes[i] == n {
+			i++
+			continue
+		}
+		res.Problems = append(res.Problems, fmt.Sprintf("%s is missing (a rotated file was removed)", rotatedPath(path, n)))
+	}
+
+	files, err := logFiles(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("open audit log: %w", err)
+		}
+
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+		for n := 1; sc.Scan(); n++ {
+			line := sc.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			var rec Record
+			if err := json.Unmarshal(line, &rec); err != nil {
+				res.Problems = append(res.Problems, fmt.Sprintf("%s:%d: malformed record", file, n))
+				prevLine = nil
+				continue
+			}
+			res.Records++
+
+			switch {
+			case res.Records == 1:
+				res.FirstSeq = rec.Seq
+				if rec.Seq == 1 && rec.PrevHash != "" {
+					res.Problems = append(res.Problems, fmt.Sprintf("%s:%d: first record has a previous hash", file, n))
+				}
+			case prevLine == nil:
+				// The previous line was malformed and already reported.
+			default:
+				if rec.Seq != prevSeq+1 {
+					res.Problems = append(res.Problems, fmt.Sprintf("%s:%d: seq %d follows seq %d (records missing or reordered)", file, n, rec.Seq, prevSeq))
+				}
+				if rec.PrevHash != checksums.Compute(prevLine) {
+					res.Problems = append(res.Problems, fmt.Sprintf("%s:%d: seq %d: previous record hash mismatch (a record was modified, removed or reordered)", file, n, rec.Seq))
+				}
+			}
+
+			hashes[rec.Seq] = checksums.Compute(line)
+			prevLine = append(prevLine[:0], line...)
+			prevSeq = rec.Seq
+			res.LastSeq = rec.Seq
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("read audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+func verifyCheckpoints(cfg *config.Config, res *VerifyResult, hashes map[uint64]string) error {
+	path := checkpointPath(cfg.Audit.Path)
+	f, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("open audit checkpoints: %w", err)
+	}
+
+	seen := make(map[uint64]bool)
+	if f != nil {
+		defer f.Close()
+		// The key is created with the first checkpoint, so it is only needed once one exists.
+		key, err := LoadKey(cfg.Audit.KeyPath)
+		if err != nil {
+			return err
+		}
+		pub := key.Public().(ed25519.PublicKey)
+
+		sc := bufio.NewScanner(f)
+		for n := 1; sc.Scan(); n++ {
+			if len(sc.Bytes()) == 0 {
+				continue
+			}
+			var c Checkpoint
+			if err := json.Unmarshal(sc.Bytes(), &c); err != nil {
+				res.Problems = append(res.Problems, fmt.Sprintf("%s:%d: malformed checkpoint", path, n))
+				continue
+			}
+			if !c.Verify(pub) {
+				res.Problems = append(res.Problems, fmt.Sprintf("checkpoint at seq %d: invalid signature", c.Seq))
+				continue
+			}
+			seen[c.Seq] = true
+
+			switch {
+			case c.Seq < res.FirstSeq:
+				// Its record has been rotated away.
+			case c.Seq > res.LastSeq:
+				res.Problems = append(res.Problems, fmt.Sprintf("checkpoint at seq %d: log ends at seq %d (records removed from the end)", c.Seq, res.LastSeq))
+			case hashes[c.Seq] != c.RecordHash:
+				res.Problems = append(res.Problems, fmt.Sprintf("checkpoint at seq %d: record hash does not match (record modified)", c.Seq))
+			default:
+				res.Checkpoints++
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return fmt.Errorf("read audit checkpoints: %w", err)
+		}
+	}
+
+	interval := uint64(cfg.Audit.CheckpointInterval)
+	if res.Records == 0 {
+		return nil
+	}
+	for seq := (res.FirstSeq + interval - 1) / interval * interval; seq <= res.LastSeq; seq += interval {
+		if seq > 0 && !seen[seq] {
+			res.Problems = append(res.Problems, fmt.Sprintf("checkpoint at seq %d is missing", seq))
+		}
+	}
+	return nil
+}
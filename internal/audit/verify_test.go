@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool/service/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTrail appends n records with a checkpoint every 2 and returns the config.
+func writeTrail(t *testing.T, n int) *config.Config {
+	t.Helper()
+	cfg := newTestConfig(t)
+	cfg.Audit.CheckpointInterval = 2
+	l := NewLog(cfg, hash.NewChecksumManager())
+	for i := range n {
+		require.NoError(t, l.Append(Record{Tool: "tool", Arguments: json.RawMessage(`{}`), ResultBytes: i}, "out"))
+	}
+	return cfg
+}
+
+func editLines(t *testing.T, path string, edit func(lines []string) []string) {
+	t.Helper()
+	lines := readLines(t, path)
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(edit(lines), "\n")+"\n"), 0600))
+}
+
+func verify(t *testing.T, cfg *config.Config) *VerifyResult {
+	t.Helper()
+	res, err := Verify(cfg, hash.NewChecksumManager())
+	require.NoError(t, err)
+	return res
+}
+
+func TestVerify_IntactTrail_OK(t *testing.T) {
+	cfg := writeTrail(t, 5)
+
+	res := verify(t, cfg)
+	assert.True(t, res.OK(), res.Problems)
+	assert.Equal(t, 5, res.Records)
+	assert.Equal(t, 2, res.Checkpoints)
+}
+
+func TestVerify_AcrossRotatedFiles_OK(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Audit.CheckpointInterval = 2
+	cfg.Audit.MaxFileSize = 400
+	l := NewLog(cfg, hash.NewChecksumManager())
+	for range 6 {
+		require.NoError(t, l.Append(Record{Tool: "tool", Arguments: json.RawMessage(`{}`)}, ""))
+	}
+	require.FileExists(t, cfg.Audit.Path+".1")
+
+	res := verify(t, cfg)
+	assert.True(t, res.OK(), res.Problems)
+	assert.Equal(t, 6, res.Records)
+}
+
+func TestVerify_RemovedRotatedFile_Reported(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Audit.CheckpointInterval = 100
+	cfg.Audit.MaxFileSize = 400
+	cfg.Audit.MaxFiles = 5
+	l := NewLog(cfg, hash.NewChecksumManager())
+	for range 8 {
+		require.NoError(t, l.Append(Record{Tool: "tool", Arguments: json.RawMessage(`{}`)}, ""))
+	}
+	require.FileExists(t, cfg.Audit.Path+".2")
+	require.NoError(t, os.Remove(cfg.Audit.Path+".1"))
+
+	res := verify(t, cfg)
+	assert.False(t, res.OK())
+	problems := strings.Join(res.Problems, "\n")
+	assert.Contains(t, problems, "audit.jsonl.1 is missing")
+	assert.Contains(t, problems, "records missing or reordered")
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(lines []string) []string
+		want string
+	}{
+		{"ModifiedRecord", func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], `"tool":"tool"`, `"tool":"shell"`, 1)
+			return lines
+		}, "seq 3: previous record hash mismatch"},
+		{"RemovedRecord", func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		}, "seq 3 follows seq 1"},
+		{"ReorderedRecords", func(lines []string) []string {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, "records missing or reordered"},
+		{"TruncatedTail", func(lines []string) []string {
+			return lines[:3]
+		}, "checkpoint at seq 4: log ends at seq 3"},
+		{"ModifiedLastCheckpointedRecord", func(lines []string) []string {
+			lines[3] = strings.Replace(lines[3], `"tool":"tool"`, `"tool":"shell"`, 1)
+			return lines
+		}, "checkpoint at seq 4: record hash does not match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := writeTrail(t, 5)
+			editLines(t, cfg.Audit.Path, tt.edit)
+
+			res := verify(t, cfg)
+			assert.False(t, res.OK())
+			assert.Contains(t, strings.Join(res.Problems, "\n"), tt.want)
+		})
+	}
+}
+
+func TestVerify_ForgedOrMissingCheckpoints(t *testing.T) {
+	t.Run("Deleted", func(t *testing.T) {
+		cfg := writeTrail(t, 5)
+		require.NoError(t, os.Remove(cfg.Audit.Path+".checkpoints"))
+
+		res := verify(t, cfg)
+		assert.Contains(t, res.Problems, "checkpoint at seq 2 is missing")
+	})
+
+	t.Run("Forged", func(t *testing.T) {
+		cfg := writeTrail(t, 5)
+		editLines(t, cfg.Audit.Path+".checkpoints", func(lines []string) []string {
+			lines[0] = strings.Replace(lines[0], `"seq":2`, `"seq":1`, 1)
+			return lines
+		})
+
+		res := verify(t, cfg)
+		assert.Contains(t, res.Problems, "checkpoint at seq 1: invalid signature")
+	})
+}
+
+func TestVerify_BeforeFirstCheckpoint_OK(t *testing.T) {
+	cfg := writeTrail(t, 1)
+
+	res := verify(t, cfg)
+	assert.True(t, res.OK(), res.Problems)
+	assert.NoFileExists(t, cfg.Audit.KeyPath)
+}
+
+func TestVerify_CheckpointsWithoutKey_ReturnsError(t *testing.T) {
+	cfg := writeTrail(t, 2)
+	require.NoError(t, os.Remove(cfg.Audit.KeyPath))
+
+	_, err := Verify(cfg, hash.NewChecksumManager())
+	assert.Error(t, err)
+}
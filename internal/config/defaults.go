@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"path"
 	"path/filepath"
+
+	"github.com/Cyclone1070/iav/internal/tool"
 )
 
 // Config holds all application configuration values.
@@ -10,8 +13,105 @@ import (
 // NOTE: Values in config files override defaults, including explicit zero values.
 // Missing keys are left at their default values.
 type Config struct {
-	Tools   ToolsConfig   `json:"tools"`
-	Session SessionConfig `json:"session"`
+	Tools       ToolsConfig                `json:"tools"`
+	Session     SessionConfig              `json:"session"`
+	MCP         MCPConfig                  `json:"mcp"`
+	Audit       AuditConfig                `json:"audit"`
+	MCPServers  map[string]MCPServerConfig `json:"mcp_servers"` // Keyed by server name, used as tool name prefix
+	CustomTools []CustomToolConfig         `json:"custom_tools"`
+	Profiles    map[string]ProfileConfig   `json:"profiles"` // Keyed by profile name
+}
+
+// ProfileConfig selects the tools offered to the LLM in a session.
+type ProfileConfig struct {
+	Tools        []string          `json:"tools"`                  // Tool names; glob patterns such as "github__*" are allowed
+	Descriptions map[string]string `json:"descriptions,omitempty"` // Per-tool description overrides, keyed by tool name
+	ReadOnly     bool              `json:"read_only,omitempty"`    // Only tools declared not to modify the workspace, such as read_file and read_only custom tools
+}
+
+// MCPConfig holds settings shared by all MCP server connections.
+type MCPConfig struct {
+	StartupTimeoutMs  int `json:"startup_timeout_ms"`  // Default: 10000
+	ShutdownTimeoutMs int `json:"shutdown_timeout_ms"` // Default: 2000
+	MaxRestarts       int `json:"max_restarts"`        // Default: 3 (restarts after a crash, per server)
+}
+
+// MCPServerConfig describes an external MCP server launched over stdio.
+type MCPServerConfig struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"` // Added to the inherited environment
+}
+
+// CustomToolConfig declares a tool that runs a fixed command.
+// Placeholders of the form {{param}} in Command are replaced by argument values.
+type CustomToolConfig struct {
+	Name           string       `json:"name"`
+	Description    string       `json:"description"`
+	Parameters     *tool.Schema `json:"parameters,omitempty"`      // Object schema for the arguments; nil means none
+	Command        []string     `json:"command"`                   // Argv template, executed without a shell
+	WorkingDir     string       `json:"working_dir,omitempty"`     // Relative to the workspace root
+	TimeoutSeconds int          `json:"timeout_seconds,omitempty"` // Default: tools.default_shell_timeout
+	ReadOnly       bool         `json:"read_only,omitempty"`       // Command does not modify the workspace; only such tools are offered by read_only profiles
+}
+
+// AuditConfig controls the append-only log of tool calls.
+type AuditConfig struct {
+	Enabled     bool   `json:"enabled"`       // Default: true
+	Path        string `json:"path"`          // Default: ~/.iav/audit/audit.jsonl
+	MaxFileSize int64  `json:"max_file_size"` // Default: 10 * 1024 * 1024 (10MB), rotated when exceeded
+	MaxFiles    int    `json:"max_files"`     // Default: 10 (rotated files kept besides the active one)
+
+	// Tamper evidence
+	KeyPath            string `json:"key_path"`            // Default: ~/.config/iav/audit_ed25519.pem, created on first use
+	CheckpointInterval int    `json:"checkpoint_interval"` // Default: 100 (records between signed checkpoints)
+}
+
+// ProtectedPathConfig keeps the file tools away from paths matching a glob.
+// A rule that matches a directory covers everything below it.
+type ProtectedPathConfig struct {
+	Glob    string `json:"glob"`               // Workspace-relative, / separated; ** matches any number of directories
+	NoRead  bool   `json:"no_read,omitempty"`  // Content may not be read, searched or edited
+	NoWrite bool   `json:"no_write,omitempty"` // May not be created, changed, moved or deleted
+	NoList  bool   `json:"no_list,omitempty"`  // Left out of directory listings and file search
+}
+
+// DefaultProtectedPaths returns the rules shipped by default: secrets can be
+// neither read nor written, and Git internals are read-only and not listed.
+func DefaultProtectedPaths() []ProtectedPathConfig {
+	secret := func(glob string) ProtectedPathConfig {
+		return ProtectedPathConfig{Glob: glob, NoRead: true, NoWrite: true}
+	}
+	return []ProtectedPathConfig{
+		secret("**/.env"),
+		secret("**/.env.*"),
+		secret("**/*.pem"),
+		secret("**/*.key"),
+		secret("**/id_rsa"),
+		secret("**/id_ecdsa"),
+		secret("**/id_ed25519"),
+		secret("**/*.tfstate"),
+		secret("**/*.tfstate.backup"),
+		{Glob: ".git", NoWrite: true, NoList: true},
+	}
+}
+
+// DiagnosticConfig declares a formatter or linter to run on files with the given
+// extensions after the file tools change them. The command runs in the workspace
+// root without a shell; {{file}} in it is replaced by the workspace-relative path
+// of the file, which is appended if there is no placeholder.
+//
+// A linter's output lines are reported to the LLM, parsed as file:line[:column]:
+// message where they have that form, so it should be quiet when all is well, as
+// gofmt -l, terraform fmt -check and yamllint are. A formatter (Format) prints the
+// formatted file instead, e.g. gofmt or terraform fmt -; a file it would change is
+// reported as unformatted, or rewritten with its output if Apply is set.
+type DiagnosticConfig struct {
+	Extensions     []string `json:"extensions"`                // With the dot, e.g. [".go"]
+	Command        []string `json:"command"`                   // Argv template
+	Format         bool     `json:"format,omitempty"`          // Command prints the formatted file
+	Apply          bool     `json:"apply,omitempty"`           // Replace the file with the formatter's output
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"` // Default: 30
 }
 
 type SessionConfig struct {
@@ -20,8 +120,25 @@ type SessionConfig struct {
 
 type ToolsConfig struct {
 	// File Operations
-	MaxFileSize          int64 `json:"max_file_size"`           // Default: 20 * 1024 * 1024 (20MB)
-	DefaultReadFileLimit int   `json:"default_read_file_limit"` // Default: 2000
+	MaxFileSize           int64 `json:"max_file_size"`             // Default: 20 * 1024 * 1024 (20MB)
+	DefaultReadFileLimit  int   `json:"default_read_file_limit"`   // Default: 2000
+	MaxReadFileBatchSize  int64 `json:"max_read_file_batch_size"`  // Default: 256 * 1024 (content bytes across one multi-file read)
+	MaxReadFileBatchFiles int   `json:"max_read_file_batch_files"` // Default: 50 (files one multi-file read may match)
+	DefaultFollowTimeout  int   `json:"default_follow_timeout"`    // Default: 30 (seconds read_file waits for new lines with follow)
+	MaxFollowTimeout      int   `json:"max_follow_timeout"`        // Default: 110 (seconds; keep below the read_file tool timeout)
+	FollowPollIntervalMs  int   `json:"follow_poll_interval_ms"`   // Default: 250
+
+	// Transactions
+	TransactionJournalDir string `json:"transaction_journal_dir"` // Default: ~/.iav/journal (recovery journals for multi-file edits)
+
+	// Protected Paths
+	ProtectedPaths []ProtectedPathConfig `json:"protected_paths"` // Default: DefaultProtectedPaths(); a configured list replaces it
+
+	// Diagnostics
+	Diagnostics []DiagnosticConfig `json:"diagnostics"` // Default: none; run on files after edit_file, edit_files, edit_structured, go_refactor and apply_patch change them
+
+	// Edit Matching
+	EditFuzzyThreshold float64 `json:"edit_fuzzy_threshold"` // Default: 0.9 (similarity needed for a fuzzy edit match)
 
 	// Directory Listing
 	DefaultListDirectoryLimit int `json:"default_list_directory_limit"` // Default: 1000
@@ -34,7 +151,7 @@ type ToolsConfig struct {
 
 	// Search
 	MaxSearchContentResults   int `json:"max_search_content_results"`   // Default: 10000
-	MaxLineLength             int `json:"max_line_length"`              // Default: 10000
+	MaxLineLength             int `json:"max_line_length"`              // Default: 10000 (bytes; longer lines are cut in search results and read_file)
 	DefaultSearchContentLimit int `json:"default_search_content_limit"` // Default: 100
 	MaxSearchContentLimit     int `json:"max_search_content_limit"`     // Default: 1000
 	MaxFindFileResults        int `json:"max_find_file_results"`        // Default: 10000
@@ -47,7 +164,9 @@ type ToolsConfig struct {
 	DockerGracefulShutdownMs int `json:"docker_graceful_shutdown_ms"` // Default: 2000
 
 	// Workflow
-	MaxIterations int `json:"max_iterations"` // Default: 20
+	MaxIterations      int            `json:"max_iterations"`       // Default: 20
+	DefaultToolTimeout int            `json:"default_tool_timeout"` // Default: 120 (2 minutes, in seconds)
+	ToolTimeouts       map[string]int `json:"tool_timeouts"`        // Per-tool overrides in seconds, keyed by tool name
 }
 
 // DefaultConfig returns the default configuration.
@@ -56,6 +175,15 @@ func DefaultConfig() *Config {
 		Tools: ToolsConfig{
 			MaxFileSize:                 20 * 1024 * 1024,
 			DefaultReadFileLimit:        2000,
+			MaxReadFileBatchSize:        256 * 1024,
+			MaxReadFileBatchFiles:       50,
+			DefaultFollowTimeout:        30,
+			MaxFollowTimeout:            110,
+			FollowPollIntervalMs:        250,
+			ProtectedPaths:              DefaultProtectedPaths(),
+			Diagnostics:                 []DiagnosticConfig{},
+			EditFuzzyThreshold:          0.9,
+			TransactionJournalDir:       filepath.Join(os.Getenv("HOME"), ".iav", "journal"),
 			DefaultListDirectoryLimit:   1000,
 			MaxListDirectoryLimit:       10000,
 			MaxListDirectoryResults:     50000,
@@ -72,9 +200,38 @@ func DefaultConfig() *Config {
 			DockerRetryIntervalMs:       1000,
 			DockerGracefulShutdownMs:    2000,
 			MaxIterations:               20,
+			DefaultToolTimeout:          120,
+			ToolTimeouts:                map[string]int{},
 		},
 		Session: SessionConfig{
 			StorageDir: filepath.Join(os.Getenv("HOME"), ".iav", "sessions"),
 		},
+		MCP: MCPConfig{
+			StartupTimeoutMs:  10000,
+			ShutdownTimeoutMs: 2000,
+			MaxRestarts:       3,
+		},
+		Audit: AuditConfig{
+			Enabled:     true,
+			Path:        filepath.Join(os.Getenv("HOME"), ".iav", "audit", "audit.jsonl"),
+			MaxFileSize: 10 * 1024 * 1024,
+			MaxFiles:    10,
+
+			KeyPath:            filepath.Join(os.Getenv("HOME"), ".config", "iav", "audit_ed25519.pem"),
+			CheckpointInterval: 100,
+		},
+		MCPServers:  map[string]MCPServerConfig{},
+		CustomTools: []CustomToolConfig{},
+		Profiles:    map[string]ProfileConfig{},
+	}
+}
+
+// Includes reports whether the profile lists the tool, directly or through a pattern.
+func (p ProfileConfig) Includes(tool string) bool {
+	for _, pattern := range p.Tools {
+		if ok, _ := path.Match(pattern, tool); ok {
+			return true
+		}
 	}
+	return false
 }
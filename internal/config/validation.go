@@ -2,8 +2,16 @@ package config
 
 import (
 	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/tool"
 )
 
+// namePattern restricts MCP server and custom tool names to characters valid in tool names.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
 // Validate checks config values for life correctness.
 // Returns an error if any values are invalid.
 func (c *Config) Validate() error {
@@ -16,6 +24,56 @@ func (c *Config) Validate() error {
 	if c.Tools.DefaultReadFileLimit < 1 {
 		errs = append(errs, "tools.default_read_file_limit must be >= 1")
 	}
+	if c.Tools.MaxReadFileBatchSize < 1 {
+		errs = append(errs, "tools.max_read_file_batch_size must be >= 1")
+	}
+	if c.Tools.MaxReadFileBatchFiles < 1 {
+		errs = append(errs, "tools.max_read_file_batch_files must be >= 1")
+	}
+	if c.Tools.DefaultFollowTimeout < 1 {
+		errs = append(errs, "tools.default_follow_timeout must be >= 1")
+	}
+	if c.Tools.MaxFollowTimeout < 1 {
+		errs = append(errs, "tools.max_follow_timeout must be >= 1")
+	}
+	if c.Tools.FollowPollIntervalMs < 1 {
+		errs = append(errs, "tools.follow_poll_interval_ms must be >= 1")
+	}
+	if c.Tools.EditFuzzyThreshold <= 0 || c.Tools.EditFuzzyThreshold > 1 {
+		errs = append(errs, "tools.edit_fuzzy_threshold must be > 0 and <= 1")
+	}
+	if c.Tools.TransactionJournalDir == "" {
+		errs = append(errs, "tools.transaction_journal_dir must not be empty")
+	}
+	for i, pp := range c.Tools.ProtectedPaths {
+		if pp.Glob == "" {
+			errs = append(errs, fmt.Sprintf("tools.protected_paths[%d].glob must not be empty", i))
+		} else if _, err := path.Match(pp.Glob, ""); err != nil {
+			errs = append(errs, fmt.Sprintf("tools.protected_paths[%d].glob is invalid: %v", i, err))
+		}
+		if !pp.NoRead && !pp.NoWrite && !pp.NoList {
+			errs = append(errs, fmt.Sprintf("tools.protected_paths[%d] must set no_read, no_write or no_list", i))
+		}
+	}
+	for i, d := range c.Tools.Diagnostics {
+		if len(d.Extensions) == 0 {
+			errs = append(errs, fmt.Sprintf("tools.diagnostics[%d].extensions must not be empty", i))
+		}
+		for _, ext := range d.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				errs = append(errs, fmt.Sprintf("tools.diagnostics[%d].extensions: %q must start with a dot", i, ext))
+			}
+		}
+		if len(d.Command) == 0 || d.Command[0] == "" {
+			errs = append(errs, fmt.Sprintf("tools.diagnostics[%d].command must not be empty", i))
+		}
+		if d.Apply && !d.Format {
+			errs = append(errs, fmt.Sprintf("tools.diagnostics[%d].apply requires format", i))
+		}
+		if d.TimeoutSeconds < 0 {
+			errs = append(errs, fmt.Sprintf("tools.diagnostics[%d].timeout_seconds must be >= 0", i))
+		}
+	}
 	if c.Tools.DefaultListDirectoryLimit < 1 {
 		errs = append(errs, "tools.default_list_directory_limit must be >= 1")
 	}
@@ -65,6 +123,9 @@ func (c *Config) Validate() error {
 	if c.Tools.DefaultFindFileLimit > c.Tools.MaxFindFileLimit {
 		errs = append(errs, "tools.default_find_file_limit must be <= tools.max_find_file_limit")
 	}
+	if c.Tools.DefaultFollowTimeout > c.Tools.MaxFollowTimeout {
+		errs = append(errs, "tools.default_follow_timeout must be <= tools.max_follow_timeout")
+	}
 
 	// Tools validation - Docker
 	if c.Tools.DockerRetryAttempts < 1 {
@@ -79,12 +140,106 @@ func (c *Config) Validate() error {
 	if c.Tools.MaxIterations < 1 {
 		errs = append(errs, "tools.max_iterations must be >= 1")
 	}
+	if c.Tools.DefaultToolTimeout < 1 {
+		errs = append(errs, "tools.default_tool_timeout must be >= 1")
+	}
+	for name, timeout := range c.Tools.ToolTimeouts {
+		if timeout < 1 {
+			errs = append(errs, fmt.Sprintf("tools.tool_timeouts.%s must be >= 1", name))
+		}
+	}
+	// A follow that outlasts read_file's own timeout is cut off before it can report.
+	readTimeout, key := c.Tools.DefaultToolTimeout, "tools.default_tool_timeout"
+	if timeout, ok := c.Tools.ToolTimeouts["read_file"]; ok {
+		readTimeout, key = timeout, "tools.tool_timeouts.read_file"
+	}
+	if c.Tools.MaxFollowTimeout >= readTimeout {
+		errs = append(errs, fmt.Sprintf("tools.max_follow_timeout must be < %s", key))
+	}
 
 	// Session validation
 	if c.Session.StorageDir == "" {
 		errs = append(errs, "session.storage_dir must not be empty")
 	}
 
+	// MCP validation
+	if c.MCP.StartupTimeoutMs < 1 {
+		errs = append(errs, "mcp.startup_timeout_ms must be >= 1")
+	}
+	if c.MCP.ShutdownTimeoutMs < 1 {
+		errs = append(errs, "mcp.shutdown_timeout_ms must be >= 1")
+	}
+	if c.MCP.MaxRestarts < 0 {
+		errs = append(errs, "mcp.max_restarts must be >= 0")
+	}
+	for name, server := range c.MCPServers {
+		if !namePattern.MatchString(name) {
+			errs = append(errs, fmt.Sprintf("mcp_servers.%s: name must match %s", name, namePattern))
+		}
+		if server.Command == "" {
+			errs = append(errs, fmt.Sprintf("mcp_servers.%s.command must not be empty", name))
+		}
+	}
+
+	// Audit validation
+	if c.Audit.Enabled {
+		if c.Audit.Path == "" {
+			errs = append(errs, "audit.path must not be empty")
+		}
+		if c.Audit.MaxFileSize < 1 {
+			errs = append(errs, "audit.max_file_size must be >= 1")
+		}
+		if c.Audit.MaxFiles < 0 {
+			errs = append(errs, "audit.max_files must be >= 0")
+		}
+		if c.Audit.KeyPath == "" {
+			errs = append(errs, "audit.key_path must not be empty")
+		}
+		if c.Audit.CheckpointInterval < 1 {
+			errs = append(errs, "audit.checkpoint_interval must be >= 1")
+		}
+	}
+
+	// Custom tools validation
+	seen := make(map[string]bool)
+	for i, ct := range c.CustomTools {
+		if !namePattern.MatchString(ct.Name) {
+			errs = append(errs, fmt.Sprintf("custom_tools[%d].name must match %s", i, namePattern))
+		} else if seen[ct.Name] {
+			errs = append(errs, fmt.Sprintf("custom_tools[%d].name %q is declared more than once", i, ct.Name))
+		}
+		seen[ct.Name] = true
+		if len(ct.Command) == 0 || ct.Command[0] == "" {
+			errs = append(errs, fmt.Sprintf("custom_tools[%d].command must not be empty", i))
+		}
+		if ct.Parameters != nil && ct.Parameters.Type != tool.TypeObject {
+			errs = append(errs, fmt.Sprintf("custom_tools[%d].parameters must have type object", i))
+		}
+		if ct.TimeoutSeconds < 0 {
+			errs = append(errs, fmt.Sprintf("custom_tools[%d].timeout_seconds must be >= 0", i))
+		}
+	}
+
+	// Profiles validation
+	for name, profile := range c.Profiles {
+		if !namePattern.MatchString(name) {
+			errs = append(errs, fmt.Sprintf("profiles.%s: name must match %s", name, namePattern))
+		}
+		if len(profile.Tools) == 0 {
+			errs = append(errs, fmt.Sprintf("profiles.%s.tools must not be empty", name))
+		}
+		for _, pattern := range profile.Tools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Sprintf("profiles.%s.tools: invalid pattern %q", name, pattern))
+			}
+		}
+		for tool := range profile.Descriptions {
+			if !profile.Includes(tool) {
+				errs = append(errs, fmt.Sprintf("profiles.%s.descriptions.%s: tool is not in the profile", name, tool))
+			}
+		}
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("config validation failed: %v", errs)
 	}
@@ -3,6 +3,7 @@ package config
 import (
 	"testing"
 
+	"github.com/Cyclone1070/iav/internal/tool"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -21,6 +22,16 @@ func TestValidate_Tools(t *testing.T) {
 		assert.Contains(t, err.Error(), "max_file_size")
 	})
 
+	t.Run("Edit Fuzzy Threshold Out Of Range Fails", func(t *testing.T) {
+		for _, v := range []float64{0, 1.5} {
+			cfg := DefaultConfig()
+			cfg.Tools.EditFuzzyThreshold = v
+			err := cfg.Validate()
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "edit_fuzzy_threshold")
+		}
+	})
+
 	t.Run("Zero Docker Retry Fails", func(t *testing.T) {
 		cfg := DefaultConfig()
 		cfg.Tools.DockerRetryAttempts = 0
@@ -84,6 +95,10 @@ func TestValidate_SemanticConstraints(t *testing.T) {
 			c.Tools.DefaultFindFileLimit = 2000
 			c.Tools.MaxFindFileLimit = 1000
 		}},
+		{"DefaultFollowTimeout_ExceedsMax_Fails", func(c *Config) {
+			c.Tools.DefaultFollowTimeout = 60
+			c.Tools.MaxFollowTimeout = 30
+		}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -104,3 +119,197 @@ func TestValidate_SemanticConstraints(t *testing.T) {
 		}
 	})
 }
+
+func TestValidate_ToolTimeouts(t *testing.T) {
+	t.Run("Zero Default Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tools.DefaultToolTimeout = 0
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "default_tool_timeout")
+	})
+
+	t.Run("Zero Override Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tools.ToolTimeouts = map[string]int{"read_file": 0}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "tool_timeouts.read_file")
+	})
+
+	t.Run("Follow Outlasting Read File Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tools.DefaultToolTimeout = cfg.Tools.MaxFollowTimeout
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "max_follow_timeout must be < tools.default_tool_timeout")
+
+		// A read_file override is what counts.
+		cfg.Tools.ToolTimeouts = map[string]int{"read_file": cfg.Tools.MaxFollowTimeout + 10}
+		assert.NoError(t, cfg.Validate())
+		cfg.Tools.ToolTimeouts["read_file"] = 30
+		err = cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "max_follow_timeout must be < tools.tool_timeouts.read_file")
+	})
+}
+
+func TestValidate_ProtectedPaths(t *testing.T) {
+	t.Run("Invalid Glob Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tools.ProtectedPaths = []ProtectedPathConfig{{Glob: "secrets/[", NoRead: true}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "protected_paths[0].glob is invalid")
+	})
+
+	t.Run("Rule Without Restriction Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tools.ProtectedPaths = []ProtectedPathConfig{{Glob: "vendor"}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "must set no_read, no_write or no_list")
+	})
+}
+
+func TestValidate_Diagnostics(t *testing.T) {
+	t.Run("Valid Formatter Passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tools.Diagnostics = []DiagnosticConfig{{Extensions: []string{".go"}, Command: []string{"gofmt"}, Format: true, Apply: true}}
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("Extension Without Dot Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tools.Diagnostics = []DiagnosticConfig{{Extensions: []string{"go"}, Command: []string{"gofmt", "-l"}}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "must start with a dot")
+	})
+
+	t.Run("Apply Without Format Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Tools.Diagnostics = []DiagnosticConfig{{Extensions: []string{".yaml"}, Command: []string{"yamllint"}, Apply: true}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "apply requires format")
+	})
+}
+
+func TestValidate_MCPServers(t *testing.T) {
+	t.Run("Empty Command Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.MCPServers = map[string]MCPServerConfig{"github": {}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "mcp_servers.github.command")
+	})
+
+	t.Run("Invalid Name Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.MCPServers = map[string]MCPServerConfig{"my server": {Command: "srv"}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "name must match")
+	})
+
+	t.Run("Valid Server Passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.MCPServers = map[string]MCPServerConfig{"github": {Command: "github-mcp", Args: []string{"stdio"}}}
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestValidate_CustomTools(t *testing.T) {
+	t.Run("Empty Command Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.CustomTools = []CustomToolConfig{{Name: "lint"}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "custom_tools[0].command")
+	})
+
+	t.Run("Duplicate Name Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.CustomTools = []CustomToolConfig{
+			{Name: "lint", Command: []string{"make", "lint"}},
+			{Name: "lint", Command: []string{"golangci-lint", "run"}},
+		}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "declared more than once")
+	})
+
+	t.Run("Non-Object Parameters Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.CustomTools = []CustomToolConfig{{Name: "lint", Command: []string{"make"}, Parameters: &tool.Schema{Type: tool.TypeString}}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "custom_tools[0].parameters")
+	})
+
+	t.Run("Valid Tool Passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.CustomTools = []CustomToolConfig{{
+			Name:       "validate",
+			Command:    []string{"terraform", "validate", "{{dir}}"},
+			Parameters: &tool.Schema{Type: tool.TypeObject, Properties: map[string]*tool.Schema{"dir": {Type: tool.TypeString}}},
+			ReadOnly:   true,
+		}}
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestValidate_Audit(t *testing.T) {
+	t.Run("Empty Path Fails When Enabled", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Audit.Path = ""
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "audit.path")
+	})
+
+	t.Run("Disabled Skips Checks", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Audit = AuditConfig{Enabled: false}
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestValidate_Profiles(t *testing.T) {
+	t.Run("Empty Tools Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Profiles = map[string]ProfileConfig{"review": {}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "profiles.review.tools")
+	})
+
+	t.Run("Description For Excluded Tool Fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Profiles = map[string]ProfileConfig{"review": {
+			Tools:        []string{"read_file"},
+			Descriptions: map[string]string{"shell": "Run commands"},
+		}}
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "profiles.review.descriptions.shell")
+	})
+
+	t.Run("Valid Profile Passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Profiles = map[string]ProfileConfig{"review": {
+			Tools:        []string{"read_file", "search_content", "github__*"},
+			Descriptions: map[string]string{"github__get_pr": "Fetch the PR under review"},
+		}}
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestProfileConfig_Includes(t *testing.T) {
+	p := ProfileConfig{Tools: []string{"read_file", "github__*"}}
+
+	assert.True(t, p.Includes("read_file"))
+	assert.True(t, p.Includes("github__list_issues"))
+	assert.False(t, p.Includes("shell"))
+}
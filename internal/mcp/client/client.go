@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/mcp"
+)
+
+// clientVersion is reported to servers during initialize.
+const clientVersion = "0.1.0"
+
+// Client is a connection to one MCP server launched over stdio.
+// If the server crashes, the next call restarts it, up to mcp.max_restarts times.
+type Client struct {
+	name   string
+	server config.MCPServerConfig
+	config *config.Config
+
+	mu       sync.Mutex
+	conn     *conn
+	tools    []mcp.Tool
+	restarts int
+	closed   bool
+}
+
+// NewClient creates a client for the named server. The server is not started until Start.
+func NewClient(name string, server config.MCPServerConfig, cfg *config.Config) *Client {
+	if name == "" {
+		panic("name is required")
+	}
+	if cfg == nil {
+		panic("cfg is required")
+	}
+	return &Client{
+		name:   name,
+		server: server,
+		config: cfg,
+	}
+}
+
+// Name returns the configured server name.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Start launches the server, performs the initialize handshake and lists its tools.
+func (c *Client) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return fmt.Errorf("mcp server %s: client is closed", c.name)
+	}
+	return c.connectLocked(ctx)
+}
+
+// Tools returns the tool definitions listed by the server at the last (re)start.
+func (c *Client) Tools() []mcp.Tool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tools
+}
+
+// CallTool invokes a tool on the server, restarting the server first if it has crashed.
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	cn, err := c.liveConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result mcp.CallToolResult
+	if err := cn.request(ctx, mcp.MethodToolsCall, mcp.CallToolParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, fmt.Errorf("mcp server %s: call %s: %w", c.name, name, err)
+	}
+	return &result, nil
+}
+
+// Close shuts the server down. It is safe to call more than once.
+func (c *Client) Close() {
+	c.mu.Lock()
+	cn := c.conn
+	c.conn = nil
+	c.closed = true
+	c.mu.Unlock()
+
+	if cn != nil {
+		cn.close(time.Duration(c.config.MCP.ShutdownTimeoutMs) * time.Millisecond)
+	}
+}
+
+// liveConn returns the running connection, restarting a crashed server if allowed.
+func (c *Client) liveConn(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, fmt.Errorf("mcp server %s: client is closed", c.name)
+	}
+	if c.conn != nil && c.conn.alive() {
+		return c.conn, nil
+	}
+
+	crashErr := fmt.Errorf("server not started")
+	if c.conn != nil {
+		crashErr = c.conn.exitError()
+	}
+	if c.restarts >= c.config.MCP.MaxRestarts {
+		return nil, fmt.Errorf("mcp server %s is unavailable after %d restarts: %w", c.name, c.restarts, crashErr)
+	}
+	c.restarts++
+	if err := c.connectLocked(ctx); err != nil {
+		return nil, fmt.Errorf("mcp server %s: restart after crash (%v): %w", c.name, crashErr, err)
+	}
+	return c.conn, nil
+}
+
+// connectLocked starts a new server process and runs the handshake. c.mu must be held.
+func (c *Client) connectLocked(ctx context.Context) error {
+	if c.conn != nil {
+		c.conn.close(time.Duration(c.config.MCP.ShutdownTimeoutMs) * time.Millisecond)
+		c.conn = nil
+	}
+
+	env := os.Environ()
+	for k, v := range c.server.Env {
+		env = append(env, k+"="+v)
+	}
+
+	cn, err := startConn(c.server.Command, c.server.Args, env)
+	if err != nil {
+		return fmt.Errorf("mcp server %s: %w", c.name, err)
+	}
+
+	startupCtx, cancel := context.WithTimeout(ctx, time.Duration(c.config.MCP.StartupTimeoutMs)*time.Millisecond)
+	defer cancel()
+
+	tools, err := handshake(startupCtx, cn)
+	if err != nil {
+		cn.close(time.Duration(c.config.MCP.ShutdownTimeoutMs) * time.Millisecond)
+		return fmt.Errorf("mcp server %s: %w", c.name, err)
+	}
+
+	c.conn = cn
+	c.tools = tools
+	return nil
+}
+
+// handshake runs initialize, notifications/initialized and a paginated tools/list.
+func handshake(ctx context.Context, cn *conn) ([]mcp.Tool, error) {
+	params := mcp.InitializeParams{
+		ProtocolVersion: mcp.ProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo:      mcp.Implementation{Name: "iav", Version: clientVersion},
+	}
+	var initResult mcp.InitializeResult
+	if err := cn.request(ctx, mcp.MethodInitialize, params, &initResult); err != nil {
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	if _, ok := initResult.Capabilities["tools"]; !ok {
+		return nil, fmt.Errorf("initialize: server does not offer tools")
+	}
+	if err := cn.notify(mcp.MethodInitialized, nil); err != nil {
+		return nil, fmt.Errorf("initialized: %w", err)
+	}
+
+	var tools []mcp.Tool
+	cursor := ""
+	for {
+		var page mcp.ListToolsResult
+		if err := cn.request(ctx, mcp.MethodToolsList, mcp.ListToolsParams{Cursor: cursor}, &page); err != nil {
+			return nil, fmt.Errorf("list tools: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
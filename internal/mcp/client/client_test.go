@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServerBin is the fake MCP server built once for the package tests.
+var fakeServerBin string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mcp-fakeserver")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fakeServerBin = filepath.Join(dir, "fakeserver")
+	build := exec.Command("go", "build", "-o", fakeServerBin, "./testdata/fakeserver")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "build fake server:", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+type mockLogger struct {
+	entries []string
+}
+
+func (m *mockLogger) Error(msg string, args ...any) {
+	m.entries = append(m.entries, msg)
+}
+
+func newTestConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.MCP.StartupTimeoutMs = 5000
+	cfg.MCP.ShutdownTimeoutMs = 200
+	return cfg
+}
+
+func startFakeClient(t *testing.T, cfg *config.Config, env map[string]string) *Client {
+	t.Helper()
+	c := NewClient("fake", config.MCPServerConfig{Command: fakeServerBin, Env: env}, cfg)
+	require.NoError(t, c.Start(context.Background()))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestClient_Start_ListsAllPages(t *testing.T) {
+	c := startFakeClient(t, newTestConfig(), nil)
+
+	names := []string{}
+	for _, tl := range c.Tools() {
+		names = append(names, tl.Name)
+	}
+	assert.Equal(t, []string{"echo", "fail", "crash", "sleep"}, names)
+}
+
+func TestClient_Start_ServerWithoutTools_Fails(t *testing.T) {
+	c := NewClient("fake", config.MCPServerConfig{
+		Command: fakeServerBin,
+		Env:     map[string]string{"FAKE_MCP_NO_TOOLS": "1"},
+	}, newTestConfig())
+	defer c.Close()
+
+	err := c.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not offer tools")
+}
+
+func TestClient_Start_BadCommand_Fails(t *testing.T) {
+	c := NewClient("missing", config.MCPServerConfig{Command: "/nonexistent/mcp-server"}, newTestConfig())
+	defer c.Close()
+
+	assert.Error(t, c.Start(context.Background()))
+}
+
+func TestClient_CallTool_ReturnsContent(t *testing.T) {
+	c := startFakeClient(t, newTestConfig(), nil)
+
+	res, err := c.CallTool(context.Background(), "echo", json.RawMessage(`{"text": "hello"}`))
+	require.NoError(t, err)
+	require.Len(t, res.Content, 1)
+	assert.Equal(t, "hello", res.Content[0].Text)
+	assert.False(t, res.IsError)
+}
+
+func TestClient_CallTool_ContextCancelled(t *testing.T) {
+	c := startFakeClient(t, newTestConfig(), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := c.CallTool(ctx, "sleep", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClient_Crash_RestartsOnNextCall(t *testing.T) {
+	crashFile := filepath.Join(t.TempDir(), "crash")
+	require.NoError(t, os.WriteFile(crashFile, nil, 0o644))
+	c := startFakeClient(t, newTestConfig(), map[string]string{"FAKE_MCP_CRASH_FILE": crashFile})
+
+	_, err := c.CallTool(context.Background(), "crash", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fakeserver crashing")
+
+	require.NoError(t, os.Remove(crashFile))
+	res, err := c.CallTool(context.Background(), "crash", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "survived", res.Content[0].Text)
+}
+
+func TestClient_Crash_GivesUpAfterMaxRestarts(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MCP.MaxRestarts = 1
+	crashFile := filepath.Join(t.TempDir(), "crash")
+	require.NoError(t, os.WriteFile(crashFile, nil, 0o644))
+	c := startFakeClient(t, cfg, map[string]string{"FAKE_MCP_CRASH_FILE": crashFile})
+
+	_, err := c.CallTool(context.Background(), "crash", nil) // crash 1
+	require.Error(t, err)
+	_, err = c.CallTool(context.Background(), "crash", nil) // restart 1, crash 2
+	require.Error(t, err)
+
+	_, err = c.CallTool(context.Background(), "echo", json.RawMessage(`{"text": "x"}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unavailable after 1 restarts")
+}
+
+func TestClient_Close_StopsServer(t *testing.T) {
+	c := NewClient("fake", config.MCPServerConfig{Command: fakeServerBin}, newTestConfig())
+	require.NoError(t, c.Start(context.Background()))
+	cn := c.conn
+
+	c.Close()
+
+	select {
+	case <-cn.exited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server process still running after Close")
+	}
+	_, err := c.CallTool(context.Background(), "echo", nil)
+	assert.Error(t, err)
+}
+
+func TestManager_Start_RegistersToolsAndSkipsBrokenServers(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MCPServers = map[string]config.MCPServerConfig{
+		"good":   {Command: fakeServerBin},
+		"broken": {Command: "/nonexistent/mcp-server"},
+	}
+	log := &mockLogger{}
+	m := NewManager(cfg, log)
+	defer m.Close()
+
+	tools := m.Start(context.Background())
+
+	names := []string{}
+	for _, tl := range tools {
+		names = append(names, tl.Name())
+	}
+	assert.Equal(t, []string{"good__echo", "good__fail", "good__crash", "good__sleep"}, names)
+	assert.Equal(t, []string{"mcp server failed to start"}, log.entries)
+}
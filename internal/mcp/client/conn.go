@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/mcp"
+)
+
+// stderrTailSize bounds how much server stderr is kept for error messages.
+const stderrTailSize = 4096
+
+// conn is one running server process and its JSON-RPC session.
+// A conn is never restarted; the Client replaces it with a new one.
+type conn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	enc    *mcp.Encoder
+	stderr *tailBuffer
+
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[int64]chan *mcp.Message
+
+	done    chan struct{} // closed when stdout reaches EOF; readErr is set before
+	exited  chan struct{} // closed when the process has been reaped; waitErr is set before
+	readErr error
+	waitErr error
+	closing atomic.Bool
+}
+
+// startConn launches the server process and starts reading its stdout.
+func startConn(command string, args []string, env []string) (*conn, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = env
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	stderr := &tailBuffer{max: stderrTailSize}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", command, err)
+	}
+
+	c := &conn{
+		cmd:     cmd,
+		stdin:   stdin,
+		enc:     mcp.NewEncoder(stdin),
+		stderr:  stderr,
+		pending: make(map[int64]chan *mcp.Message),
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+	return c, nil
+}
+
+// alive reports whether the server is still connected.
+func (c *conn) alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// request sends a request and waits for its response.
+// If ctx ends first, the server is told to cancel the request.
+func (c *conn) request(ctx context.Context, method string, params any, result any) error {
+	id := c.nextID.Add(1)
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+
+	ch := make(chan *mcp.Message, 1)
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(&mcp.Message{ID: rawID, Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("decode %s result: %w", method, err)
+		}
+		return nil
+	case <-c.done:
+		return c.exitError()
+	case <-ctx.Done():
+		_ = c.notify(mcp.MethodCancelled, map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification.
+func (c *conn) notify(method string, params any) error {
+	return c.send(&mcp.Message{Method: method}, params)
+}
+
+func (c *conn) send(msg *mcp.Message, params any) error {
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("marshal %s params: %w", msg.Method, err)
+		}
+		msg.Params = data
+	}
+	if !c.alive() {
+		return c.exitError()
+	}
+	return c.enc.Encode(msg)
+}
+
+// readLoop dispatches responses to waiting requests and answers server requests.
+func (c *conn) readLoop(stdout io.Reader) {
+	dec := mcp.NewDecoder(stdout)
+	for {
+		msg, err := dec.Decode()
+		if err != nil {
+			var rpcErr *mcp.RPCError
+			if errors.As(err, &rpcErr) {
+				continue // Skip garbage lines, e.g. stray prints to stdout
+			}
+			if !errors.Is(err, io.EOF) {
+				c.readErr = err
+			}
+			break
+		}
+
+		switch {
+		case msg.IsRequest():
+			c.answerServerRequest(msg)
+		case msg.IsNotification():
+			// Notifications such as logging or progress are not used.
+		default:
+			id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		}
+	}
+
+	close(c.done)
+	c.waitErr = c.cmd.Wait()
+	close(c.exited)
+}
+
+// answerServerRequest replies to requests initiated by the server.
+// Only ping is supported since iav advertises no client capabilities.
+func (c *conn) answerServerRequest(msg *mcp.Message) {
+	reply := &mcp.Message{ID: msg.ID}
+	if msg.Method == mcp.MethodPing {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &mcp.RPCError{Code: mcp.CodeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+	_ = c.enc.Encode(reply)
+}
+
+// exitError describes why the server is no longer reachable.
+func (c *conn) exitError() error {
+	if c.closing.Load() {
+		return fmt.Errorf("server is shutting down")
+	}
+	var waitErr error
+	select {
+	case <-c.exited:
+		waitErr = c.waitErr
+	case <-time.After(100 * time.Millisecond):
+		// The process closed stdout but has not exited yet
+	}
+	msg := "server exited"
+	if waitErr != nil {
+		msg = fmt.Sprintf("server exited: %v", waitErr)
+	} else if c.readErr != nil {
+		msg = fmt.Sprintf("server connection lost: %v", c.readErr)
+	}
+	if tail := c.stderr.String(); tail != "" {
+		msg += "\nserver stderr:\n" + tail
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// close shuts the server down: stdin is closed so a well-behaved server exits,
+// then the process is killed if it has not exited within grace.
+func (c *conn) close(grace time.Duration) {
+	c.closing.Store(true)
+	_ = c.stdin.Close()
+
+	select {
+	case <-c.exited:
+		return
+	case <-time.After(grace):
+	}
+
+	_ = c.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-c.exited:
+		return
+	case <-time.After(grace):
+	}
+
+	_ = c.cmd.Process.Kill()
+	<-c.exited
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = b.buf[len(b.buf)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// logger records server start failures that should not stop iav.
+type logger interface {
+	Error(msg string, args ...any)
+}
+
+// Manager owns the lifecycle of every configured MCP server.
+type Manager struct {
+	config  *config.Config
+	logger  logger
+	clients []*Client
+}
+
+// NewManager creates a manager for the servers in cfg.MCPServers.
+func NewManager(cfg *config.Config, logger logger) *Manager {
+	if cfg == nil {
+		panic("cfg is required")
+	}
+	if logger == nil {
+		panic("logger is required")
+	}
+	return &Manager{config: cfg, logger: logger}
+}
+
+// Start launches all configured servers and returns their tools as adapters.
+// A server that fails to start is logged and skipped so the remaining tools stay usable.
+func (m *Manager) Start(ctx context.Context) []toolmanager.Tool {
+	names := make([]string, 0, len(m.config.MCPServers))
+	for name := range m.config.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tools []toolmanager.Tool
+	for _, name := range names {
+		c := NewClient(name, m.config.MCPServers[name], m.config)
+		if err := c.Start(ctx); err != nil {
+			m.logger.Error("mcp server failed to start", "server", name, "error", err)
+			continue
+		}
+		m.clients = append(m.clients, c)
+
+		for _, def := range c.Tools() {
+			t, err := NewRemoteTool(name, def, c)
+			if err != nil {
+				m.logger.Error("mcp tool skipped", "server", name, "tool", def.Name, "error", err)
+				continue
+			}
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
+// Close shuts down every started server.
+func (m *Manager) Close() {
+	for _, c := range m.clients {
+		c.Close()
+	}
+	m.clients = nil
+}
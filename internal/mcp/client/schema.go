@@ -0,0 +1,106 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Cyclone1070/iav/internal/tool"
+)
+
+// jsonSchema is the subset of JSON Schema that maps onto tool.Schema.
+// Fields whose JSON Schema form is wider than tool.Schema (type lists,
+// non-string enums, schema-valued additionalProperties) are kept raw.
+type jsonSchema struct {
+	Type                 json.RawMessage        `json:"type"`
+	Description          string                 `json:"description"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []any                  `json:"enum"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	Pattern              string                 `json:"pattern"`
+	Default              any                    `json:"default"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+}
+
+// translateSchema converts an MCP tool inputSchema into a tool.Schema.
+// An empty schema becomes an object schema without properties.
+func translateSchema(raw json.RawMessage) (*tool.Schema, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return &tool.Schema{Type: tool.TypeObject}, nil
+	}
+	var js jsonSchema
+	if err := json.Unmarshal(raw, &js); err != nil {
+		return nil, fmt.Errorf("parse input schema: %w", err)
+	}
+	s := convertSchema(&js)
+	if s.Type == "" {
+		s.Type = tool.TypeObject
+	}
+	return s, nil
+}
+
+func convertSchema(js *jsonSchema) *tool.Schema {
+	s := &tool.Schema{
+		Type:        schemaType(js),
+		Description: js.Description,
+		Required:    js.Required,
+		Minimum:     js.Minimum,
+		Maximum:     js.Maximum,
+		Pattern:     js.Pattern,
+		Default:     js.Default,
+	}
+
+	if len(js.Properties) > 0 {
+		s.Properties = make(map[string]*tool.Schema, len(js.Properties))
+		for name, prop := range js.Properties {
+			if prop == nil {
+				prop = &jsonSchema{}
+			}
+			s.Properties[name] = convertSchema(prop)
+		}
+	}
+	if js.Items != nil {
+		s.Items = convertSchema(js.Items)
+	}
+	for _, e := range js.Enum {
+		if str, ok := e.(string); ok {
+			s.Enum = append(s.Enum, str)
+		}
+	}
+	// A non-string enum cannot be represented, drop it rather than reject valid values
+	if len(s.Enum) != len(js.Enum) {
+		s.Enum = nil
+	}
+
+	var additional bool
+	if err := json.Unmarshal(js.AdditionalProperties, &additional); err == nil {
+		s.AdditionalProperties = &additional
+	}
+	return s
+}
+
+// schemaType picks the tool.Type for a schema. For a list of types the first
+// non-null entry wins; a missing type is inferred from object or array keywords.
+func schemaType(js *jsonSchema) tool.Type {
+	var single string
+	if err := json.Unmarshal(js.Type, &single); err == nil {
+		return tool.Type(single)
+	}
+	var list []string
+	if err := json.Unmarshal(js.Type, &list); err == nil {
+		for _, t := range list {
+			if t != "null" {
+				return tool.Type(t)
+			}
+		}
+	}
+	switch {
+	case js.Properties != nil:
+		return tool.TypeObject
+	case js.Items != nil:
+		return tool.TypeArray
+	}
+	return ""
+}
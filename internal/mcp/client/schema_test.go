@@ -0,0 +1,66 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateSchema_FullSchema(t *testing.T) {
+	raw := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name":  {"type": "string", "description": "Name", "pattern": "^a", "default": "abc"},
+			"count": {"type": ["integer", "null"], "minimum": 1, "maximum": 10},
+			"mode":  {"type": "string", "enum": ["a", "b"]},
+			"tags":  {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["name"],
+		"additionalProperties": false
+	}`)
+
+	s, err := translateSchema(raw)
+	require.NoError(t, err)
+
+	assert.Equal(t, tool.TypeObject, s.Type)
+	assert.Equal(t, []string{"name"}, s.Required)
+	require.NotNil(t, s.AdditionalProperties)
+	assert.False(t, *s.AdditionalProperties)
+
+	assert.Equal(t, "Name", s.Properties["name"].Description)
+	assert.Equal(t, "^a", s.Properties["name"].Pattern)
+	assert.Equal(t, "abc", s.Properties["name"].Default)
+	assert.Equal(t, tool.TypeInteger, s.Properties["count"].Type)
+	assert.Equal(t, 1.0, *s.Properties["count"].Minimum)
+	assert.Equal(t, 10.0, *s.Properties["count"].Maximum)
+	assert.Equal(t, []string{"a", "b"}, s.Properties["mode"].Enum)
+	assert.Equal(t, tool.TypeString, s.Properties["tags"].Items.Type)
+}
+
+func TestTranslateSchema_EmptySchema_IsObject(t *testing.T) {
+	for _, raw := range []string{"", "null", "{}"} {
+		s, err := translateSchema(json.RawMessage(raw))
+		require.NoError(t, err)
+		assert.Equal(t, tool.TypeObject, s.Type)
+	}
+}
+
+func TestTranslateSchema_NonStringEnum_Dropped(t *testing.T) {
+	s, err := translateSchema(json.RawMessage(`{"type": "object", "properties": {"n": {"type": "integer", "enum": [1, 2]}}}`))
+	require.NoError(t, err)
+	assert.Nil(t, s.Properties["n"].Enum)
+}
+
+func TestTranslateSchema_SchemaValuedAdditionalProperties_Ignored(t *testing.T) {
+	s, err := translateSchema(json.RawMessage(`{"type": "object", "additionalProperties": {"type": "string"}}`))
+	require.NoError(t, err)
+	assert.Nil(t, s.AdditionalProperties)
+}
+
+func TestTranslateSchema_Malformed_ReturnsError(t *testing.T) {
+	_, err := translateSchema(json.RawMessage(`{"type": `))
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+// Command fakeserver is a minimal MCP server used by the client tests.
+// Behaviour is tuned through environment variables:
+//
+//	FAKE_MCP_NO_TOOLS=1   omit the tools capability
+//	FAKE_MCP_CRASH_FILE=p crash on "crash" calls only while file p exists
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   any             `json:"error,omitempty"`
+}
+
+func main() {
+	fmt.Fprintln(os.Stderr, "fakeserver starting")
+	scanner := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		if len(msg.ID) == 0 {
+			continue // notification
+		}
+
+		reply := message{JSONRPC: "2.0", ID: msg.ID}
+		switch msg.Method {
+		case "initialize":
+			caps := map[string]any{"tools": map[string]any{}}
+			if os.Getenv("FAKE_MCP_NO_TOOLS") == "1" {
+				caps = map[string]any{}
+			}
+			reply.Result = map[string]any{
+				"protocolVersion": "2024-11-05",
+				"capabilities":    caps,
+				"serverInfo":      map[string]any{"name": "fake", "version": "1"},
+			}
+		case "tools/list":
+			var p struct {
+				Cursor string `json:"cursor"`
+			}
+			_ = json.Unmarshal(msg.Params, &p)
+			if p.Cursor == "" {
+				reply.Result = map[string]any{
+					"tools": []any{map[string]any{
+						"name":        "echo",
+						"description": "Echo the text back",
+						"inputSchema": map[string]any{
+							"type":       "object",
+							"properties": map[string]any{"text": map[string]any{"type": "string"}},
+							"required":   []string{"text"},
+						},
+					}},
+					"nextCursor": "page2",
+				}
+			} else {
+				reply.Result = map[string]any{"tools": []any{
+					map[string]any{"name": "fail", "inputSchema": map[string]any{"type": "object"}},
+					map[string]any{"name": "crash", "inputSchema": map[string]any{"type": "object"}},
+					map[string]any{"name": "sleep", "inputSchema": map[string]any{"type": "object"}},
+				}}
+			}
+		case "tools/call":
+			var p struct {
+				Name      string         `json:"name"`
+				Arguments map[string]any `json:"arguments"`
+			}
+			_ = json.Unmarshal(msg.Params, &p)
+			switch p.Name {
+			case "echo":
+				reply.Result = map[string]any{"content": []any{map[string]any{"type": "text", "text": fmt.Sprint(p.Arguments["text"])}}}
+			case "fail":
+				reply.Result = map[string]any{"content": []any{map[string]any{"type": "text", "text": "it broke"}}, "isError": true}
+			case "crash":
+				if _, err := os.Stat(os.Getenv("FAKE_MCP_CRASH_FILE")); err == nil {
+					fmt.Fprintln(os.Stderr, "fakeserver crashing")
+					os.Exit(3)
+				}
+				reply.Result = map[string]any{"content": []any{map[string]any{"type": "text", "text": "survived"}}}
+			case "sleep":
+				time.Sleep(10 * time.Second)
+				reply.Result = map[string]any{"content": []any{}}
+			default:
+				reply.Error = map[string]any{"code": -32602, "message": "unknown tool"}
+			}
+		default:
+			reply.Error = map[string]any{"code": -32601, "message": "method not found"}
+		}
+		_ = out.Encode(reply)
+	}
+}
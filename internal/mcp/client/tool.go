@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/mcp"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// invalidToolNameChars matches characters not allowed in LLM function names.
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// toolCaller invokes tools on an MCP server.
+type toolCaller interface {
+	CallTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error)
+}
+
+// RemoteTool adapts a tool served by an MCP server to toolmanager.Tool.
+type RemoteTool struct {
+	name       string // name exposed to the LLM: <server>__<tool>
+	remoteName string // name known to the server
+	decl       tool.Declaration
+	caller     toolCaller
+}
+
+// NewRemoteTool creates an adapter for def served by the named server.
+func NewRemoteTool(server string, def mcp.Tool, caller toolCaller) (*RemoteTool, error) {
+	if caller == nil {
+		panic("caller is required")
+	}
+	schema, err := translateSchema(def.InputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", def.Name, err)
+	}
+
+	name := invalidToolNameChars.ReplaceAllString(server+"__"+def.Name, "_")
+	return &RemoteTool{
+		name:       name,
+		remoteName: def.Name,
+		decl: tool.Declaration{
+			Name:        name,
+			Description: def.Description,
+			Parameters:  schema,
+		},
+		caller: caller,
+	}, nil
+}
+
+func (t *RemoteTool) Name() string {
+	return t.name
+}
+
+func (t *RemoteTool) Declaration() tool.Declaration {
+	return t.decl
+}
+
+func (t *RemoteTool) Request() toolmanager.ToolRequest {
+	return &RemoteToolRequest{toolName: t.remoteName}
+}
+
+// Execute forwards the arguments to the server via tools/call.
+// Transport failures and server crashes are reported to the LLM as tool failures;
+// only context errors are returned.
+func (t *RemoteTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*RemoteToolRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+
+	result, err := t.caller.CallTool(ctx, t.remoteName, r.Arguments)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &RemoteToolResponse{Error: err.Error()}, nil
+	}
+
+	return &RemoteToolResponse{
+		Content: formatContent(result.Content),
+		IsError: result.IsError,
+	}, nil
+}
+
+// formatContent flattens tool output blocks into text for the LLM.
+// Binary blocks are summarised since they cannot be forwarded as text.
+func formatContent(blocks []mcp.Content) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			parts = append(parts, b.Text)
+		case "resource":
+			if b.Resource == nil {
+				continue
+			}
+			if b.Resource.Text != "" {
+				parts = append(parts, fmt.Sprintf("[resource %s]\n%s", b.Resource.URI, b.Resource.Text))
+			} else {
+				parts = append(parts, fmt.Sprintf("[binary resource %s (%s)]", b.Resource.URI, b.Resource.MimeType))
+			}
+		default:
+			parts = append(parts, fmt.Sprintf("[%s content (%s) omitted]", b.Type, b.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// RemoteToolRequest carries the raw arguments of a remote tool call.
+// Arguments are forwarded untouched, the server owns their validation.
+type RemoteToolRequest struct {
+	toolName  string
+	Arguments json.RawMessage
+}
+
+// UnmarshalJSON captures the whole argument object.
+func (r *RemoteToolRequest) UnmarshalJSON(data []byte) error {
+	r.Arguments = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+func (r *RemoteToolRequest) Display() string {
+	return r.toolName
+}
+
+// RemoteToolResponse is the outcome of a remote tool call.
+type RemoteToolResponse struct {
+	Content string
+	IsError bool   // Tool reported failure
+	Error   string // Call could not be completed
+}
+
+func (r *RemoteToolResponse) LLMContent() string {
+	if r.Error != "" {
+		return fmt.Sprintf("Error: %s", r.Error)
+	}
+	if r.IsError {
+		return fmt.Sprintf("Error: %s", r.Content)
+	}
+	if r.Content == "" {
+		return "(no output)"
+	}
+	return r.Content
+}
+
+func (r *RemoteToolResponse) Display() tool.ToolDisplay {
+	if r.Error != "" {
+		return tool.StringDisplay("Server unavailable")
+	}
+	return tool.StringDisplay(r.Content)
+}
+
+func (r *RemoteToolResponse) Success() bool {
+	return r.Error == "" && !r.IsError
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCaller struct {
+	callFunc func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error)
+}
+
+func (m *mockCaller) CallTool(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+	return m.callFunc(ctx, name, args)
+}
+
+func newEchoTool(t *testing.T, caller toolCaller) *RemoteTool {
+	t.Helper()
+	rt, err := NewRemoteTool("my.server", mcp.Tool{
+		Name:        "echo",
+		Description: "Echo",
+		InputSchema: json.RawMessage(`{"type": "object", "properties": {"text": {"type": "string"}}}`),
+	}, caller)
+	require.NoError(t, err)
+	return rt
+}
+
+func TestRemoteTool_NameAndDeclaration(t *testing.T) {
+	rt := newEchoTool(t, &mockCaller{})
+
+	assert.Equal(t, "my_server__echo", rt.Name())
+	decl := rt.Declaration()
+	assert.Equal(t, "my_server__echo", decl.Name)
+	assert.Equal(t, "Echo", decl.Description)
+	assert.Contains(t, decl.Parameters.Properties, "text")
+}
+
+func TestRemoteTool_Execute_ForwardsRawArguments(t *testing.T) {
+	var gotName string
+	var gotArgs json.RawMessage
+	rt := newEchoTool(t, &mockCaller{callFunc: func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		gotName, gotArgs = name, args
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent("hi")}}, nil
+	}})
+
+	req := rt.Request()
+	require.NoError(t, json.Unmarshal([]byte(`{"text":"hi"}`), req))
+
+	res, err := rt.Execute(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "echo", gotName)
+	assert.JSONEq(t, `{"text":"hi"}`, string(gotArgs))
+	assert.Equal(t, "hi", res.LLMContent())
+	assert.True(t, res.Success())
+}
+
+func TestRemoteTool_Execute_IsErrorIsToolFailure(t *testing.T) {
+	rt := newEchoTool(t, &mockCaller{callFunc: func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{mcp.TextContent("bad input")}, IsError: true}, nil
+	}})
+
+	res, err := rt.Execute(context.Background(), rt.Request())
+	require.NoError(t, err)
+	assert.False(t, res.Success())
+	assert.Equal(t, "Error: bad input", res.LLMContent())
+}
+
+func TestRemoteTool_Execute_TransportErrorIsToolFailure(t *testing.T) {
+	rt := newEchoTool(t, &mockCaller{callFunc: func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		return nil, fmt.Errorf("server exited")
+	}})
+
+	res, err := rt.Execute(context.Background(), rt.Request())
+	require.NoError(t, err)
+	assert.False(t, res.Success())
+	assert.Contains(t, res.LLMContent(), "server exited")
+}
+
+func TestRemoteTool_Execute_ContextCancelledIsInfraError(t *testing.T) {
+	rt := newEchoTool(t, &mockCaller{callFunc: func(ctx context.Context, name string, args json.RawMessage) (*mcp.CallToolResult, error) {
+		return nil, ctx.Err()
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := rt.Execute(ctx, rt.Request())
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestFormatContent(t *testing.T) {
+	out := formatContent([]mcp.Content{
+		mcp.TextContent("line"),
+		{Type: "image", MimeType: "image/png", Data: "AAAA"},
+		{Type: "resource", Resource: &mcp.EmbeddedResource{URI: "file:///a.txt", Text: "body"}},
+		{Type: "resource", Resource: &mcp.EmbeddedResource{URI: "file:///b.bin", MimeType: "application/octet-stream", Blob: "AA"}},
+	})
+	assert.Equal(t, "line\n[image content (image/png) omitted]\n[resource file:///a.txt]\nbody\n[binary resource file:///b.bin (application/octet-stream)]", out)
+}
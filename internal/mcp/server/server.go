@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Cyclone1070/iav/internal/mcp"
+	"github.com/Cyclone1070/iav/internal/provider"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/workflow"
+)
+
+// serverVersion is reported to clients during initialize.
+const serverVersion = "0.1.0"
+
+// toolManager provides the tools exposed over MCP.
+type toolManager interface {
+	Declarations() []tool.Declaration
+	Execute(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error)
+}
+
+// Server exposes a tool manager's tools to MCP clients over a stdio-style stream.
+// Calls go through the tool manager, so argument validation, deadlines and
+// panic isolation apply exactly as they do for the agent loop.
+type Server struct {
+	tools         toolManager
+	workspaceRoot string
+	onToolEnd     func(workflow.ToolEndEvent)
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc // keyed by raw request ID
+}
+
+// NewServer creates a server for tools operating inside workspaceRoot.
+func NewServer(tools toolManager, workspaceRoot string) *Server {
+	if tools == nil {
+		panic("tools is required")
+	}
+	if workspaceRoot == "" {
+		panic("workspaceRoot is required")
+	}
+	return &Server{
+		tools:         tools,
+		workspaceRoot: workspaceRoot,
+		inflight:      make(map[string]context.CancelFunc),
+	}
+}
+
+// OnToolEnd sets fn to be called with the end event of every tool call, so the
+// operator can see what the tools did, such as the diffs of edits. Calls run
+// concurrently, so fn may be called from several goroutines at once.
+func (s *Server) OnToolEnd(fn func(workflow.ToolEndEvent)) {
+	s.onToolEnd = fn
+}
+
+// Serve reads requests from r and writes responses to w until r reaches EOF.
+// Tool calls run concurrently; Serve waits for in-flight calls before returning.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dec := mcp.NewDecoder(r)
+	enc := mcp.NewEncoder(w)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		msg, err := dec.Decode()
+		if err != nil {
+			var rpcErr *mcp.RPCError
+			if errors.As(err, &rpcErr) {
+				_ = enc.Encode(&mcp.Message{ID: json.RawMessage("null"), Error: rpcErr})
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read request: %w", err)
+		}
+
+		switch {
+		case msg.IsNotification():
+			s.handleNotification(msg)
+		case msg.IsRequest():
+			if msg.Method == mcp.MethodToolsCall {
+				callCtx := s.track(ctx, msg.ID)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer s.untrack(msg.ID)
+					s.reply(enc, msg, s.callTool(callCtx, msg))
+				}()
+				continue
+			}
+			s.reply(enc, msg, s.handleRequest(msg))
+		default:
+			// Responses are unexpected since the server never sends requests.
+		}
+	}
+}
+
+// response is the outcome of a request: result, error, or nothing for a cancelled call.
+type response struct {
+	result any
+	err    *mcp.RPCError
+	skip   bool
+}
+
+func (s *Server) reply(enc *mcp.Encoder, req *mcp.Message, resp response) {
+	if resp.skip {
+		return
+	}
+	out := &mcp.Message{ID: req.ID, Error: resp.err}
+	if resp.err == nil {
+		data, err := json.Marshal(resp.result)
+		if err != nil {
+			out.Error = &mcp.RPCError{Code: mcp.CodeInternalError, Message: fmt.Sprintf("encode result: %v", err)}
+		} else {
+			out.Result = data
+		}
+	}
+	_ = enc.Encode(out)
+}
+
+func (s *Server) handleRequest(msg *mcp.Message) response {
+	switch msg.Method {
+	case mcp.MethodInitialize:
+		return response{result: s.initialize()}
+	case mcp.MethodPing:
+		return response{result: struct{}{}}
+	case mcp.MethodToolsList:
+		return s.listTools()
+	}
+	return response{err: &mcp.RPCError{Code: mcp.CodeMethodNotFound, Message: "method not found: " + msg.Method}}
+}
+
+func (s *Server) handleNotification(msg *mcp.Message) {
+	if msg.Method != mcp.MethodCancelled {
+		return
+	}
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return
+	}
+	s.mu.Lock()
+	cancel, ok := s.inflight[string(params.RequestID)]
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (s *Server) initialize() mcp.InitializeResult {
+	return mcp.InitializeResult{
+		// Only one revision is supported; clients decide whether they can proceed.
+		ProtocolVersion: mcp.ProtocolVersion,
+		Capabilities:    map[string]any{"tools": map[string]any{"listChanged": false}},
+		ServerInfo:      mcp.Implementation{Name: "iav", Version: serverVersion},
+		Instructions: fmt.Sprintf("Tools operate inside the workspace %s. Relative paths are resolved against it and paths outside it are rejected. "+
+			"Call read_file before edit_file: edits are refused if the file changed since it was last read.", s.workspaceRoot),
+	}
+}
+
+func (s *Server) listTools() response {
+	decls := s.tools.Declarations()
+	tools := make([]mcp.Tool, 0, len(decls))
+	for _, d := range decls {
+		params := d.Parameters
+		if params == nil {
+			params = &tool.Schema{Type: tool.TypeObject}
+		}
+		schema, err := json.Marshal(params)
+		if err != nil {
+			return response{err: &mcp.RPCError{Code: mcp.CodeInternalError, Message: fmt.Sprintf("encode schema of %s: %v", d.Name, err)}}
+		}
+		tools = append(tools, mcp.Tool{Name: d.Name, Description: d.Description, InputSchema: schema})
+	}
+	return response{result: mcp.ListToolsResult{Tools: tools}}
+}
+
+// callTool runs a tools/call request through the tool manager.
+// The tool's success flag is taken from its ToolEndEvent.
+func (s *Server) callTool(ctx context.Context, msg *mcp.Message) response {
+	var params mcp.CallToolParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+		return response{err: &mcp.RPCError{Code: mcp.CodeInvalidParams, Message: "tools/call requires a tool name"}}
+	}
+	args := params.Arguments
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+
+	events := make(chan workflow.Event, 16)
+	success := make(chan bool, 1)
+	go func() {
+		ok := false
+		for ev := range events {
+			if end, isEnd := ev.(workflow.ToolEndEvent); isEnd {
+				ok = end.Success
+				if s.onToolEnd != nil {
+					s.onToolEnd(end)
+				}
+			}
+		}
+		success <- ok
+	}()
+
+	result, err := s.tools.Execute(ctx, provider.ToolCall{
+		ID:       "mcp-" + string(msg.ID),
+		Type:     "function",
+		Function: provider.FunctionCall{Name: params.Name, Arguments: args},
+	}, events)
+	close(events)
+	ok := <-success
+
+	if err != nil {
+		if ctx.Err() != nil {
+			// The client cancelled the request; the spec asks for no response.
+			return response{skip: true}
+		}
+		return response{err: &mcp.RPCError{Code: mcp.CodeInternalError, Message: err.Error()}}
+	}
+
+	return response{result: mcp.CallToolResult{
+		Content: []mcp.Content{mcp.TextContent(result.Content)},
+		IsError: !ok,
+	}}
+}
+
+func (s *Server) track(ctx context.Context, id json.RawMessage) context.Context {
+	callCtx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.inflight[string(id)] = cancel
+	s.mu.Unlock()
+	return callCtx
+}
+
+func (s *Server) untrack(id json.RawMessage) {
+	s.mu.Lock()
+	cancel, ok := s.inflight[string(id)]
+	delete(s.inflight, string(id))
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
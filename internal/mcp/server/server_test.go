@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/mcp"
+	"github.com/Cyclone1070/iav/internal/provider"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/workflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockToolManager struct {
+	decls       []tool.Declaration
+	executeFunc func(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error)
+}
+
+func (m *mockToolManager) Declarations() []tool.Declaration {
+	return m.decls
+}
+
+func (m *mockToolManager) Execute(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error) {
+	return m.executeFunc(ctx, tc, events)
+}
+
+// serve runs the server over the given newline-delimited input and returns the responses.
+func serve(t *testing.T, tm toolManager, input string) []mcp.Message {
+	t.Helper()
+	var out strings.Builder
+	require.NoError(t, NewServer(tm, "/work").Serve(context.Background(), strings.NewReader(input), &out))
+	return decodeAll(t, out.String())
+}
+
+func decodeAll(t *testing.T, s string) []mcp.Message {
+	t.Helper()
+	var msgs []mcp.Message
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		if line == "" {
+			continue
+		}
+		var m mcp.Message
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+func succeed(content string, success bool) func(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error) {
+	return func(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error) {
+		events <- workflow.ToolEndEvent{ToolName: tc.Function.Name, Success: success}
+		return provider.Message{Role: provider.RoleTool, ToolCallID: tc.ID, Content: content}, nil
+	}
+}
+
+func TestServer_Initialize(t *testing.T) {
+	msgs := serve(t, &mockToolManager{}, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"c","version":"1"}}}`+"\n")
+
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "1", string(msgs[0].ID))
+	var res mcp.InitializeResult
+	require.NoError(t, json.Unmarshal(msgs[0].Result, &res))
+	assert.Equal(t, mcp.ProtocolVersion, res.ProtocolVersion)
+	assert.Contains(t, res.Capabilities, "tools")
+	assert.Equal(t, "iav", res.ServerInfo.Name)
+	assert.Contains(t, res.Instructions, "/work")
+}
+
+func TestServer_ListTools_TranslatesDeclarations(t *testing.T) {
+	tm := &mockToolManager{decls: []tool.Declaration{
+		{Name: "read_file", Description: "Read", Parameters: &tool.Schema{
+			Type:       tool.TypeObject,
+			Properties: map[string]*tool.Schema{"path": {Type: tool.TypeString}},
+			Required:   []string{"path"},
+		}},
+		{Name: "noargs", Description: "No args"},
+	}}
+
+	msgs := serve(t, tm, `{"jsonrpc":"2.0","id":"a","method":"tools/list"}`+"\n")
+
+	require.Len(t, msgs, 1)
+	var res mcp.ListToolsResult
+	require.NoError(t, json.Unmarshal(msgs[0].Result, &res))
+	require.Len(t, res.Tools, 2)
+	assert.Equal(t, "read_file", res.Tools[0].Name)
+	assert.JSONEq(t, `{"type":"object","properties":{"path":{"type":"string"}},"required":["path"]}`, string(res.Tools[0].InputSchema))
+	assert.JSONEq(t, `{"type":"object"}`, string(res.Tools[1].InputSchema))
+}
+
+func TestServer_CallTool_ForwardsToToolManager(t *testing.T) {
+	var got provider.ToolCall
+	tm := &mockToolManager{executeFunc: func(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error) {
+		got = tc
+		return succeed("file contents", true)(ctx, tc, events)
+	}}
+
+	msgs := serve(t, tm, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"read_file","arguments":{"path":"a.go"}}}`+"\n")
+
+	assert.Equal(t, "read_file", got.Function.Name)
+	assert.JSONEq(t, `{"path":"a.go"}`, string(got.Function.Arguments))
+	require.Len(t, msgs, 1)
+	var res mcp.CallToolResult
+	require.NoError(t, json.Unmarshal(msgs[0].Result, &res))
+	assert.False(t, res.IsError)
+	assert.Equal(t, "file contents", res.Content[0].Text)
+}
+
+func TestServer_CallTool_ToolFailureIsError(t *testing.T) {
+	tm := &mockToolManager{executeFunc: succeed("Error: file not found", false)}
+
+	msgs := serve(t, tm, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"read_file"}}`+"\n")
+
+	require.Len(t, msgs, 1)
+	require.Nil(t, msgs[0].Error)
+	var res mcp.CallToolResult
+	require.NoError(t, json.Unmarshal(msgs[0].Result, &res))
+	assert.True(t, res.IsError)
+	assert.Equal(t, "Error: file not found", res.Content[0].Text)
+}
+
+func TestServer_CallTool_ReportsToolEnd(t *testing.T) {
+	display := tool.DiffDisplay{Path: "a.go", Diff: "@@ -1 +1 @@\n-a\n+b\n"}
+	tm := &mockToolManager{executeFunc: func(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error) {
+		events <- workflow.ToolEndEvent{ToolName: tc.Function.Name, Display: display, Success: true}
+		return provider.Message{Role: provider.RoleTool, ToolCallID: tc.ID, Content: "edited"}, nil
+	}}
+	var ended []workflow.ToolEndEvent
+	srv := NewServer(tm, "/work")
+	srv.OnToolEnd(func(ev workflow.ToolEndEvent) { ended = append(ended, ev) })
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"edit_file"}}` + "\n"
+	require.NoError(t, srv.Serve(context.Background(), strings.NewReader(input), io.Discard))
+
+	require.Len(t, ended, 1)
+	assert.Equal(t, "edit_file", ended[0].ToolName)
+	assert.Equal(t, display, ended[0].Display)
+}
+
+func TestServer_CallTool_MissingName_InvalidParams(t *testing.T) {
+	msgs := serve(t, &mockToolManager{}, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{}}`+"\n")
+
+	require.Len(t, msgs, 1)
+	require.NotNil(t, msgs[0].Error)
+	assert.Equal(t, mcp.CodeInvalidParams, msgs[0].Error.Code)
+}
+
+func TestServer_UnknownMethod_MethodNotFound(t *testing.T) {
+	msgs := serve(t, &mockToolManager{}, `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`+"\n")
+
+	require.Len(t, msgs, 1)
+	require.NotNil(t, msgs[0].Error)
+	assert.Equal(t, mcp.CodeMethodNotFound, msgs[0].Error.Code)
+}
+
+func TestServer_MalformedLine_ParseErrorAndContinues(t *testing.T) {
+	msgs := serve(t, &mockToolManager{}, "{not json\n"+`{"jsonrpc":"2.0","id":2,"method":"ping"}`+"\n")
+
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "null", string(msgs[0].ID))
+	assert.Equal(t, mcp.CodeParseError, msgs[0].Error.Code)
+	assert.Equal(t, "2", string(msgs[1].ID))
+	assert.Nil(t, msgs[1].Error)
+}
+
+func TestServer_CancelledNotification_CancelsCallWithoutResponse(t *testing.T) {
+	started := make(chan struct{})
+	tm := &mockToolManager{executeFunc: func(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error) {
+		close(started)
+		<-ctx.Done()
+		return provider.Message{}, ctx.Err()
+	}}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(tm, "/work").Serve(context.Background(), inR, outW)
+		_ = outW.Close()
+	}()
+
+	_, err := io.WriteString(inW, `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"slow"}}`+"\n")
+	require.NoError(t, err)
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("tool call never started")
+	}
+	_, err = io.WriteString(inW, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":5}}`+"\n")
+	require.NoError(t, err)
+	_, err = io.WriteString(inW, `{"jsonrpc":"2.0","id":6,"method":"ping"}`+"\n")
+	require.NoError(t, err)
+	require.NoError(t, inW.Close())
+
+	var lines []string
+	sc := bufio.NewScanner(outR)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	require.NoError(t, <-done)
+
+	msgs := decodeAll(t, strings.Join(lines, "\n"))
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "6", string(msgs[0].ID))
+}
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxMessageSize bounds a single newline-delimited message on the stdio transport.
+const maxMessageSize = 64 * 1024 * 1024
+
+// Decoder reads newline-delimited JSON-RPC messages, as used by the MCP stdio transport.
+type Decoder struct {
+	scanner *bufio.Scanner
+}
+
+// NewDecoder creates a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	return &Decoder{scanner: scanner}
+}
+
+// Decode reads the next message. Blank lines are skipped.
+// It returns io.EOF when the stream ends cleanly.
+// A line that is not valid JSON-RPC yields a *RPCError with CodeParseError;
+// the stream remains usable after such an error.
+func (d *Decoder) Decode() (*Message, error) {
+	for d.scanner.Scan() {
+		line := d.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var msg Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, &RPCError{Code: CodeParseError, Message: fmt.Sprintf("parse error: %v", err)}
+		}
+		return &msg, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Encoder writes newline-delimited JSON-RPC messages. It is safe for concurrent use.
+type Encoder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewEncoder creates an Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes msg followed by a newline, filling in the JSON-RPC version.
+func (e *Encoder) Encode(msg *Message) error {
+	msg.JSONRPC = JSONRPCVersion
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+	data = append(data, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(data); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	return nil
+}
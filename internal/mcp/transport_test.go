@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder_ReadsMessagesAndSkipsBlankLines(t *testing.T) {
+	input := `{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","id":1,"result":{}}` + "\n"
+	dec := NewDecoder(strings.NewReader(input))
+
+	msg, err := dec.Decode()
+	require.NoError(t, err)
+	assert.True(t, msg.IsRequest())
+
+	msg, err = dec.Decode()
+	require.NoError(t, err)
+	assert.True(t, msg.IsNotification())
+
+	msg, err = dec.Decode()
+	require.NoError(t, err)
+	assert.False(t, msg.IsRequest())
+	assert.False(t, msg.IsNotification())
+
+	_, err = dec.Decode()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestDecoder_MalformedLine_ParseErrorThenContinues(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("not json\n" + `{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"))
+
+	_, err := dec.Decode()
+	var rpcErr *RPCError
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, CodeParseError, rpcErr.Code)
+
+	msg, err := dec.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, "ping", msg.Method)
+}
+
+func TestEncoder_WritesNewlineDelimitedJSON(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	require.NoError(t, enc.Encode(&Message{ID: []byte("7"), Method: "ping"}))
+
+	assert.Equal(t, `{"jsonrpc":"2.0","id":7,"method":"ping"}`+"\n", buf.String())
+}
@@ -7,18 +7,22 @@ import (
 	"path/filepath"
 
 	"github.com/Cyclone1070/iav/internal/provider"
+	"github.com/Cyclone1070/iav/internal/tool/service/hash"
 )
 
 // sessionDTO is used for JSON serialization.
 type sessionDTO struct {
 	ID       string             `json:"id"`
+	Profile  string             `json:"profile,omitempty"`
 	Messages []provider.Message `json:"messages"`
 }
 
 // Session represents a conversation session with message history.
 type Session struct {
 	id         string
+	profile    string
 	messages   []provider.Message
+	checksums  *hash.PersistentChecksumManager
 	storageDir string
 }
 
@@ -27,6 +31,23 @@ func (s *Session) ID() string {
 	return s.id
 }
 
+// Profile returns the name of the tool profile selected for the session, or "" for all tools.
+func (s *Session) Profile() string {
+	return s.profile
+}
+
+// SetProfile selects the tool profile used by the session.
+func (s *Session) SetProfile(name string) {
+	s.profile = name
+}
+
+// Checksums returns the file checksums cached by the session's tools. They are
+// saved next to the session file as they change, so edit-conflict detection
+// carries over when the session is resumed.
+func (s *Session) Checksums() *hash.PersistentChecksumManager {
+	return s.checksums
+}
+
 // Messages returns the slice of messages in the session.
 func (s *Session) Messages() []provider.Message {
 	return s.messages
@@ -42,6 +63,7 @@ func (s *Session) Save() error {
 	path := filepath.Join(s.storageDir, s.id+".json")
 	dto := sessionDTO{
 		ID:       s.id,
+		Profile:  s.profile,
 		Messages: s.messages,
 	}
 	data, err := json.MarshalIndent(dto, "", "  ")
@@ -51,12 +73,23 @@ func (s *Session) Save() error {
 	return os.WriteFile(path, data, 0644)
 }
 
-// Delete removes the session from disk.
+// Delete removes the session and its checksums from disk.
 func (s *Session) Delete() error {
+	if s.checksums != nil {
+		if err := s.checksums.Delete(); err != nil {
+			return err
+		}
+	}
 	path := filepath.Join(s.storageDir, s.id+".json")
 	return os.Remove(path)
 }
 
+// checksumPath returns where the checksums of session id are stored. The extension
+// is not .json so ListSessions does not mistake the file for a session.
+func checksumPath(storageDir, id string) string {
+	return filepath.Join(storageDir, id+".checksums")
+}
+
 // Clear removes all messages from the session but keeps the ID.
 func (s *Session) Clear() {
 	s.messages = []provider.Message{}
@@ -1,12 +1,22 @@
 package session
 
 import (
+	"os"
 	"testing"
 
+	"github.com/Cyclone1070/iav/internal/config"
 	"github.com/Cyclone1070/iav/internal/provider"
 	"github.com/stretchr/testify/assert"
 )
 
+type mockLogger struct {
+	warnings []string
+}
+
+func (l *mockLogger) Warn(msg string, args ...any) {
+	l.warnings = append(l.warnings, msg)
+}
+
 func TestSession_Add(t *testing.T) {
 	s := &Session{
 		id:       "test-id",
@@ -53,3 +63,69 @@ func TestSession_Clear(t *testing.T) {
 
 	assert.Empty(t, s.Messages())
 }
+
+func TestSession_Profile_PersistsAcrossLoad(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Session.StorageDir = t.TempDir()
+	store := NewStore(cfg, &mockLogger{})
+
+	s, err := store.NewSession()
+	assert.NoError(t, err)
+	s.SetProfile("review")
+	assert.NoError(t, s.Save())
+
+	loaded, err := store.LoadSession(s.ID())
+	assert.NoError(t, err)
+	assert.Equal(t, "review", loaded.Profile())
+}
+
+func TestSession_Checksums_PersistAcrossLoad(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Session.StorageDir = t.TempDir()
+	store := NewStore(cfg, &mockLogger{})
+
+	s, err := store.NewSession()
+	assert.NoError(t, err)
+	s.Checksums().Update("/workspace/main.go", "abc")
+
+	loaded, err := store.LoadSession(s.ID())
+	assert.NoError(t, err)
+	checksum, ok := loaded.Checksums().Get("/workspace/main.go")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", checksum)
+
+	ids, err := store.ListSessions()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{s.ID()}, ids)
+
+	assert.NoError(t, loaded.Delete())
+	_, err = store.LoadSession(s.ID())
+	assert.Error(t, err)
+}
+
+func TestSession_CorruptChecksums_LoadEmpty(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Session.StorageDir = t.TempDir()
+	log := &mockLogger{}
+	store := NewStore(cfg, log)
+
+	s, err := store.NewSession()
+	assert.NoError(t, err)
+	s.Checksums().Update("/workspace/main.go", "abc")
+	path := checksumPath(cfg.Session.StorageDir, s.ID())
+	assert.NoError(t, os.WriteFile(path, []byte(`{"/workspace/main.go": {"checks`), 0o644))
+
+	loaded, err := store.LoadSession(s.ID())
+	assert.NoError(t, err)
+	_, ok := loaded.Checksums().Get("/workspace/main.go")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"discarding unreadable checksum cache"}, log.warnings)
+
+	// The next update replaces the damaged file.
+	loaded.Checksums().Update("/workspace/main.go", "def")
+	reloaded, err := store.LoadSession(s.ID())
+	assert.NoError(t, err)
+	checksum, _ := reloaded.Checksums().Get("/workspace/main.go")
+	assert.Equal(t, "def", checksum)
+	assert.Len(t, log.warnings, 1)
+}
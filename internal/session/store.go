@@ -10,17 +10,30 @@ import (
 
 	"github.com/Cyclone1070/iav/internal/config"
 	"github.com/Cyclone1070/iav/internal/provider"
+	"github.com/Cyclone1070/iav/internal/tool/service/hash"
 	"github.com/google/uuid"
 )
 
+// logger reports damaged session files that are worked around rather than failing.
+type logger interface {
+	Warn(msg string, args ...any)
+}
+
 // Store manages session creation, loading, and listing.
 type Store struct {
 	storageDir string
+	logger     logger
 }
 
 // NewStore creates a new session store.
-func NewStore(cfg *config.Config) *Store {
-	return &Store{storageDir: cfg.Session.StorageDir}
+func NewStore(cfg *config.Config, logger logger) *Store {
+	if cfg == nil {
+		panic("cfg is required")
+	}
+	if logger == nil {
+		panic("logger is required")
+	}
+	return &Store{storageDir: cfg.Session.StorageDir, logger: logger}
 }
 
 // NewSession creates a new session with a unique ID.
@@ -28,9 +41,11 @@ func (st *Store) NewSession() (*Session, error) {
 	if err := os.MkdirAll(st.storageDir, 0755); err != nil {
 		return nil, fmt.Errorf("create storage dir: %w", err)
 	}
+	id := uuid.New().String()
 	s := &Session{
-		id:         uuid.New().String(),
+		id:         id,
 		messages:   []provider.Message{},
+		checksums:  st.loadChecksums(id),
 		storageDir: st.storageDir,
 	}
 	if err := s.Save(); err != nil {
@@ -39,7 +54,7 @@ func (st *Store) NewSession() (*Session, error) {
 	return s, nil
 }
 
-// LoadSession loads a session from disk by ID.
+// LoadSession loads a session and its cached file checksums from disk by ID.
 func (st *Store) LoadSession(id string) (*Session, error) {
 	path := filepath.Join(st.storageDir, id+".json")
 	data, err := os.ReadFile(path)
@@ -53,11 +68,24 @@ func (st *Store) LoadSession(id string) (*Session, error) {
 	}
 	return &Session{
 		id:         dto.ID,
+		profile:    dto.Profile,
 		messages:   dto.Messages,
+		checksums:  st.loadChecksums(dto.ID),
 		storageDir: st.storageDir,
 	}, nil
 }
 
+// loadChecksums opens the checksum cache of session id. A damaged cache is
+// logged and started empty rather than failing the session, since it only saves
+// re-reading files.
+func (st *Store) loadChecksums(id string) *hash.PersistentChecksumManager {
+	checksums, err := hash.LoadChecksumManager(checksumPath(st.storageDir, id))
+	if err != nil {
+		st.logger.Warn("discarding unreadable checksum cache", "session", id, "error", err)
+	}
+	return checksums
+}
+
 // ListSessions returns all session IDs sorted by modification time (newest first).
 func (st *Store) ListSessions() ([]string, error) {
 	entries, err := os.ReadDir(st.storageDir)
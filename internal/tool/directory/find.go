@@ -2,6 +2,7 @@ package directory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,7 @@ import (
 	"github.com/Cyclone1070/iav/internal/config"
 	"github.com/Cyclone1070/iav/internal/tool/helper/pagination"
 	"github.com/Cyclone1070/iav/internal/tool/service/executor"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
 )
 
 // dirFinder defines the filesystem operations needed for finding files.
@@ -135,6 +137,9 @@ func (t *FindFileTool) Run(ctx context.Context, req *FindFileRequest) (*FindFile
 		}
 
 		relPath, err := t.pathResolver.Rel(line)
+		if errors.Is(err, path.ErrProtected) {
+			continue
+		}
 		if err != nil {
 			relPath = line
 		}
@@ -199,6 +199,9 @@ func (t *ListDirectoryTool) listRecursive(ctx context.Context, abs string, curre
 		// Calculate relative path for this entry
 		entryAbs := filepath.Join(abs, entry.Name())
 		entryRel, err := t.pathResolver.Rel(entryAbs)
+		if errors.Is(err, path.ErrSymlinkEscape) || errors.Is(err, path.ErrProtected) {
+			continue // Symlinks leading out of the workspace and protected paths are not listed
+		}
 		if err != nil {
 			// This indicates a bug in path resolution or directory structure - don't mask it
 			return nil, false, fmt.Errorf("relative path for %s: %w", entryAbs, err)
@@ -508,6 +508,40 @@ func TestListDirectory_WithSymlinks(t *testing.T) {
 	})
 }
 
+// escapingResolver reports one path as a symlink leading out of the workspace.
+type escapingResolver struct {
+	*path.Resolver
+	escape string
+}
+
+func (r escapingResolver) Rel(p string) (string, error) {
+	if p == r.escape {
+		return "", path.ErrSymlinkEscape
+	}
+	return r.Resolver.Rel(p)
+}
+
+func TestListDirectory_SymlinkOutOfWorkspace(t *testing.T) {
+	t.Run("link out is skipped", func(t *testing.T) {
+		fs := newMockFileSystemForList()
+		fs.createDir("/workspace")
+		fs.createFile("/workspace/file.txt", []byte("content"), 0o644)
+		fs.createSymlink("/workspace/ssh", "/home/user/.ssh")
+
+		cfg := config.DefaultConfig()
+		resolver := escapingResolver{Resolver: path.NewResolver("/workspace"), escape: "/workspace/ssh"}
+		listTool := NewListDirectoryTool(fs, nil, cfg, resolver)
+
+		resp, err := listTool.Run(context.Background(), &ListDirectoryRequest{Path: ".", MaxDepth: -1, Limit: 1000})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.FormattedEntries != "file.txt\n" {
+			t.Errorf("expected only file.txt, got:\n%q", resp.FormattedEntries)
+		}
+	})
+}
+
 func TestListDirectory_UnicodeFilenames(t *testing.T) {
 	workspaceRoot := "/workspace"
 
@@ -0,0 +1,214 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/fs"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// changeCommitter applies several file changes all-or-nothing.
+type changeCommitter interface {
+	ApplyChanges(changes []fs.Change) error
+}
+
+// batchFileSystem defines the filesystem operations needed for batch edits.
+type batchFileSystem interface {
+	fileEditor
+	EnsureDirs(path string) error
+	changeCommitter
+}
+
+// commitError describes a failed ApplyChanges for the LLM.
+func commitError(err error) string {
+	if errors.Is(err, fs.ErrIncompleteRollback) {
+		return fmt.Sprintf("failed to apply changes: %v (some files may stay changed until recovery runs on the next start)", err)
+	}
+	return fmt.Sprintf("failed to apply changes: %v (no files were changed)", err)
+}
+
+// BatchEditTool applies edit_file and write_file requests to several files as one transaction.
+type BatchEditTool struct {
+	edit            *EditFileTool
+	write           *WriteFileTool
+	fileOps         batchFileSystem
+	checksumManager checksumManager
+	config          *config.Config
+	pathResolver    pathResolver
+	diagnoser       diagnoser
+}
+
+// NewBatchEditTool creates a new BatchEditTool with injected dependencies.
+// diagnoser may be nil, in which case changed files are not checked.
+func NewBatchEditTool(
+	fileOps batchFileSystem,
+	checksumManager checksumManager,
+	pathResolver pathResolver,
+	diagnoser diagnoser,
+	cfg *config.Config,
+) *BatchEditTool {
+	if fileOps == nil {
+		panic("fileOps is required")
+	}
+	if checksumManager == nil {
+		panic("checksumManager is required")
+	}
+	if pathResolver == nil {
+		panic("pathResolver is required")
+	}
+	if cfg == nil {
+		panic("config is required")
+	}
+	return &BatchEditTool{
+		edit:            NewEditFileTool(fileOps, checksumManager, pathResolver, nil, cfg),
+		write:           NewWriteFileTool(fileOps, checksumManager, cfg, pathResolver, nil),
+		fileOps:         fileOps,
+		checksumManager: checksumManager,
+		config:          cfg,
+		pathResolver:    pathResolver,
+		diagnoser:       diagnoser,
+	}
+}
+
+func (t *BatchEditTool) Name() string {
+	return "edit_files"
+}
+
+func (t *BatchEditTool) Declaration() tool.Declaration {
+	editSchema := t.edit.Declaration().Parameters
+	return tool.Declaration{
+		Name: "edit_files",
+		Description: "Edit existing files and write whole files in a single all-or-nothing change. " +
+			"Every edit is checked before anything is written; if any fails, no file is changed.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"edits": {
+					Type:        tool.TypeArray,
+					Description: "Edits to existing files, as for edit_file",
+					Items:       editSchema,
+				},
+				"writes": {
+					Type:        tool.TypeArray,
+					Description: "Whole files to write",
+					Items: &tool.Schema{
+						Type:                 tool.TypeObject,
+						AdditionalProperties: tool.Ptr(false),
+						Properties: map[string]*tool.Schema{
+							"path":      {Type: tool.TypeString, Description: "Path to the file"},
+							"content":   {Type: tool.TypeString, Description: "File content"},
+							"overwrite": {Type: tool.TypeBoolean, Description: "Replace an existing file; it must have been read first and be unchanged since"},
+						},
+						Required: []string{"path", "content"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (t *BatchEditTool) Request() toolmanager.ToolRequest {
+	return &BatchEditRequest{}
+}
+
+// Execute plans every edit and write in memory, with the same checks as edit_file
+// and write_file, then commits them in one filesystem transaction. A failed check
+// or commit leaves every file as it was. Once committed, each file is checked
+// with the formatters and linters configured for its extension.
+func (t *BatchEditTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*BatchEditRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+
+	if err := r.Validate(t.config); err != nil {
+		return &BatchEditResponse{Error: err.Error()}, nil
+	}
+
+	var (
+		changes   []fs.Change
+		files     []ChangedFile
+		checksums = make(map[string]string) // Keyed by absolute path; also catches duplicates
+	)
+	fail := func(path string, err error) (toolmanager.ToolResult, error) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &BatchEditResponse{Error: fmt.Sprintf("%s: %v (no files were changed)", path, err)}, nil
+	}
+	claim := func(abs, checksum string) error {
+		if _, ok := checksums[abs]; ok {
+			return fmt.Errorf("file is changed more than once in the batch")
+		}
+		checksums[abs] = checksum
+		return nil
+	}
+
+	for i := range r.Edits {
+		e := &r.Edits[i]
+		p, err := t.edit.plan(e)
+		if err != nil {
+			return fail(e.Path, err)
+		}
+		rel, err := t.pathResolver.Rel(p.abs)
+		if err != nil {
+			return fail(e.Path, err)
+		}
+		if err := claim(p.abs, p.checksum); err != nil {
+			return fail(e.Path, err)
+		}
+		changes = append(changes, fs.Change{Path: p.abs, Content: p.output, Perm: p.perm})
+
+		diff, added, removed := unifiedDiff("a/"+rel, "b/"+rel, p.oldContent, p.newContent)
+		files = append(files, ChangedFile{
+			Op: "modify", Path: rel, Diff: diff, AddedLines: added, RemovedLines: removed, Notes: p.notes,
+		})
+	}
+
+	for i := range r.Writes {
+		w := &r.Writes[i]
+		p, err := t.write.plan(w)
+		if err != nil {
+			return fail(w.Path, err)
+		}
+		if err := claim(p.abs, fileChecksum(t.checksumManager, p.content)); err != nil {
+			return fail(w.Path, err)
+		}
+		changes = append(changes, fs.Change{Path: p.abs, Content: p.content, Perm: p.perm})
+
+		op := "add"
+		if p.exists {
+			op = "modify"
+		}
+		diff, added, removed := p.diff()
+		files = append(files, ChangedFile{
+			Op: op, Path: p.rel, Diff: diff, AddedLines: added, RemovedLines: removed,
+		})
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := t.fileOps.ApplyChanges(changes); err != nil {
+		return &BatchEditResponse{Error: commitError(err)}, nil
+	}
+
+	for abs, sum := range checksums {
+		t.checksumManager.Update(abs, sum)
+	}
+	// changes and files are in the same order, one per file.
+	for i, c := range changes {
+		report, err := checkWritten(ctx, t.diagnoser, t.fileOps, t.checksumManager, c.Path, files[i].Path, c.Content, c.Perm)
+		if err != nil {
+			return nil, err
+		}
+		files[i].Diagnostics = report
+	}
+	return &BatchEditResponse{Files: files}, nil
+}
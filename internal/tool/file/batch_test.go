@@ -0,0 +1,158 @@
+package file
+
+// Mocks are defined in write_test.go and shared across all test files in this package.
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/fs"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+)
+
+func executeBatch(t *testing.T, btool *BatchEditTool, req *BatchEditRequest) *BatchEditResponse {
+	t.Helper()
+	result, err := btool.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	resp, ok := result.(*BatchEditResponse)
+	if !ok {
+		t.Fatalf("Execute returned wrong type: %T", result)
+	}
+	return resp
+}
+
+func TestBatchEdit(t *testing.T) {
+	setup := func() (*mockFileSystemForWrite, *mockChecksumManagerForWrite, *BatchEditTool) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createFile("/workspace/main.tf", []byte("module \"net\" {\n  source = \"./net\"\n}\n"), 0o600)
+		fs.createFile("/workspace/app/main.tf", []byte("cidr = var.cidr\r\n"), 0o644)
+		return fs, checksumManager, NewBatchEditTool(fs, checksumManager, path.NewResolver("/workspace"), nil, cfg)
+	}
+
+	twoEditsAndAWrite := func() *BatchEditRequest {
+		return &BatchEditRequest{
+			Edits: []EditFileRequest{
+				{Path: "main.tf", Operations: []EditOperation{{Before: "./net", After: "./network"}}},
+				{Path: "app/main.tf", Operations: []EditOperation{{Before: "var.cidr", After: "var.network_cidr"}}},
+			},
+			Writes: []WriteFileRequest{
+				{Path: "network/variables.tf", Content: "variable \"network_cidr\" {}\n"},
+			},
+		}
+	}
+
+	t.Run("applies edits and writes together", func(t *testing.T) {
+		fs, checksumManager, btool := setup()
+
+		resp := executeBatch(t, btool, twoEditsAndAWrite())
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+
+		assertFile(t, fs, "/workspace/main.tf", "module \"net\" {\n  source = \"./network\"\n}\n")
+		assertFile(t, fs, "/workspace/app/main.tf", "cidr = var.network_cidr\r\n")
+		assertFile(t, fs, "/workspace/network/variables.tf", "variable \"network_cidr\" {}\n")
+		if fs.files["/workspace/main.tf"].mode != 0o600 {
+			t.Errorf("expected permissions to be preserved")
+		}
+
+		// Edits are checksummed on \n-normalised content, like edit_file.
+		if sum, _ := checksumManager.Get("/workspace/app/main.tf"); sum != checksumManager.Compute([]byte("cidr = var.network_cidr\n")) {
+			t.Errorf("unexpected checksum for app/main.tf: %s", sum)
+		}
+		if _, ok := checksumManager.Get("/workspace/network/variables.tf"); !ok {
+			t.Errorf("expected checksum of new file to be cached")
+		}
+
+		llm := resp.LLMContent()
+		for _, want := range []string{"3 file(s)", "modify main.tf (+1 -1)", "modify app/main.tf (+1 -1)", "add network/variables.tf (+1 -0)"} {
+			if !strings.Contains(llm, want) {
+				t.Errorf("LLMContent missing %q:\n%s", want, llm)
+			}
+		}
+		if display, ok := resp.Display().(tool.MultiDiffDisplay); !ok || len(display) != 3 {
+			t.Errorf("unexpected display: %#v", resp.Display())
+		}
+	})
+
+	t.Run("failing edit changes nothing", func(t *testing.T) {
+		fs, _, btool := setup()
+		req := twoEditsAndAWrite()
+		req.Edits[1].Operations[0].Before = "var.missing"
+
+		resp := executeBatch(t, btool, req)
+
+		if !strings.Contains(resp.Error, "app/main.tf: snippet not found") || !strings.Contains(resp.Error, "no files were changed") {
+			t.Errorf("expected snippet error for app/main.tf, got: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/main.tf", "module \"net\" {\n  source = \"./net\"\n}\n")
+		if _, err := fs.ReadFile("/workspace/network/variables.tf"); err == nil {
+			t.Errorf("expected new file not to be created")
+		}
+	})
+
+	t.Run("write over existing file changes nothing", func(t *testing.T) {
+		fs, _, btool := setup()
+		req := twoEditsAndAWrite()
+		req.Writes[0].Path = "app/main.tf"
+		req.Edits = req.Edits[:1]
+
+		resp := executeBatch(t, btool, req)
+
+		if !strings.Contains(resp.Error, "file already exists") {
+			t.Errorf("expected exists error, got: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/main.tf", "module \"net\" {\n  source = \"./net\"\n}\n")
+	})
+
+	t.Run("same file twice fails", func(t *testing.T) {
+		_, _, btool := setup()
+		req := twoEditsAndAWrite()
+		req.Edits[1].Path = "main.tf"
+		req.Edits[1].Operations[0] = EditOperation{Before: "module", After: "mod"}
+
+		resp := executeBatch(t, btool, req)
+
+		if !strings.Contains(resp.Error, "more than once") {
+			t.Errorf("expected duplicate error, got: %s", resp.Error)
+		}
+	})
+
+	t.Run("commit failure reports whether files changed", func(t *testing.T) {
+		files, checksumManager, btool := setup()
+		files.setOperationError("ApplyChanges", errors.New("disk on fire"))
+
+		resp := executeBatch(t, btool, twoEditsAndAWrite())
+
+		if !strings.Contains(resp.Error, "disk on fire") || !strings.Contains(resp.Error, "no files were changed") {
+			t.Errorf("expected commit error, got: %s", resp.Error)
+		}
+		if _, ok := checksumManager.Get("/workspace/main.tf"); ok {
+			t.Errorf("expected checksums not to be updated")
+		}
+
+		files.setOperationError("ApplyChanges", errors.Join(errors.New("disk on fire"), fs.ErrIncompleteRollback))
+		resp = executeBatch(t, btool, twoEditsAndAWrite())
+		if strings.Contains(resp.Error, "no files were changed") {
+			t.Errorf("incomplete rollback must not claim nothing changed: %s", resp.Error)
+		}
+	})
+
+	t.Run("empty request fails validation", func(t *testing.T) {
+		_, _, btool := setup()
+
+		resp := executeBatch(t, btool, &BatchEditRequest{})
+
+		if resp.Error == "" {
+			t.Errorf("expected validation error")
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/tool/service/diagnostics"
+)
+
+// diagnoser runs the formatters and linters configured for a file.
+type diagnoser interface {
+	Check(ctx context.Context, rel string, data []byte) (*diagnostics.Report, error)
+}
+
+// atomicWriter writes one file atomically.
+type atomicWriter interface {
+	WriteFileAtomic(path string, content []byte, perm os.FileMode) error
+}
+
+// checkWritten runs the diagnoser on a file that has just been written with data.
+// If a formatter rewrote the content, it is written back and its checksum cached,
+// so the next edit is not taken for a conflict. It returns nil if d is nil or no
+// command is configured for the file; only a cancelled ctx is an error.
+func checkWritten(
+	ctx context.Context,
+	d diagnoser,
+	fileOps atomicWriter,
+	checksumManager checksumManager,
+	abs, rel string,
+	data []byte,
+	perm os.FileMode,
+) (*diagnostics.Report, error) {
+	if d == nil {
+		return nil, nil
+	}
+	report, err := d.Check(ctx, rel, data)
+	if err != nil || report == nil || report.Formatted == nil {
+		return report, err
+	}
+
+	if err := fileOps.WriteFileAtomic(abs, report.Formatted, perm); err != nil {
+		report.Diagnostics = append(report.Diagnostics, diagnostics.Diagnostic{
+			File:    rel,
+			Message: fmt.Sprintf("could not apply formatting: %v", err),
+			Source:  report.FormattedBy,
+		})
+		report.Formatted, report.FormattedBy = nil, ""
+		return report, nil
+	}
+	checksumManager.Update(abs, fileChecksum(checksumManager, report.Formatted))
+	return report, nil
+}
+
+// diagnosticsNote describes a diagnostics report for the LLM, or returns "" for nil.
+func diagnosticsNote(r *diagnostics.Report) string {
+	if r == nil {
+		return ""
+	}
+	var sb strings.Builder
+	if r.FormattedBy != "" {
+		fmt.Fprintf(&sb, "Formatted with %s after the change; read the file again before editing it.\n", r.FormattedBy)
+	}
+	commands := strings.Join(r.Commands, ", ")
+	if len(r.Diagnostics) == 0 {
+		fmt.Fprintf(&sb, "Checked with %s: no problems found.", commands)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "Diagnostics from %s:", commands)
+	for _, d := range r.Diagnostics {
+		fmt.Fprintf(&sb, "\n  %s", d)
+	}
+	if r.Omitted > 0 {
+		fmt.Fprintf(&sb, "\n  (%d more not shown)", r.Omitted)
+	}
+	return sb.String()
+}
@@ -33,13 +33,16 @@ type EditFileTool struct {
 	checksumManager checksumManager
 	config          *config.Config
 	pathResolver    pathResolver
+	diagnoser       diagnoser
 }
 
 // NewEditFileTool creates a new EditFileTool with injected dependencies.
+// diagnoser may be nil, in which case edited files are not checked.
 func NewEditFileTool(
 	fileOps fileEditor,
 	checksumManager checksumManager,
 	pathResolver pathResolver,
+	diagnoser diagnoser,
 	cfg *config.Config,
 ) *EditFileTool {
 	if fileOps == nil {
@@ -59,6 +62,7 @@ func NewEditFileTool(
 		checksumManager: checksumManager,
 		config:          cfg,
 		pathResolver:    pathResolver,
+		diagnoser:       diagnoser,
 	}
 }
 
@@ -71,16 +75,18 @@ func (t *EditFileTool) Declaration() tool.Declaration {
 		Name:        "edit_file",
 		Description: "Edit an existing file by replacing text. Supports multiple operations.",
 		Parameters: &tool.Schema{
-			Type: tool.TypeObject,
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
 			Properties: map[string]*tool.Schema{
 				"path": {Type: tool.TypeString, Description: "Path to file"},
 				"operations": {
 					Type:        tool.TypeArray,
 					Description: "List of edit operations",
 					Items: &tool.Schema{
-						Type: tool.TypeObject,
+						Type:                 tool.TypeObject,
+						AdditionalProperties: tool.Ptr(false),
 						Properties: map[string]*tool.Schema{
-							"before":                {Type: tool.TypeString, Description: "Text to find"},
+							"before":                {Type: tool.TypeString, Description: "Text to find. Whole-line snippets tolerate whitespace and indentation drift"},
 							"after":                 {Type: tool.TypeString, Description: "Replacement text"},
 							"expected_replacements": {Type: tool.TypeInteger, Description: "Expected match count"},
 						},
@@ -101,10 +107,18 @@ func (t *EditFileTool) Request() toolmanager.ToolRequest {
 // It detects concurrent modifications by comparing file checksums and validates
 // operations before applying them. The file is written atomically.
 //
+// Each before snippet is located with the cascade in findMatches, so indentation or
+// trailing-space drift does not fail the edit. When nothing matches, the error shows
+// the closest region of the file and how it differs from the snippet.
+//
+// Files in UTF-16, UTF-32 or ISO-8859-1 are matched as UTF-8 text and written back
+// in their own encoding, with their BOM and line endings.
+//
+// Once written, the file is checked with the formatters and linters configured
+// for its extension, and what they report is added to the response.
+//
 // Note: There is a narrow race condition window between checksum validation and write.
 // For guaranteed conflict-free edits, external file locking would be required.
-//
-// Note: ctx is accepted for API consistency but not used - file I/O is synchronous.
 func (t *EditFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
 	r, ok := req.(*EditFileRequest)
 	if !ok {
@@ -115,22 +129,7 @@ func (t *EditFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest)
 		return &EditFileResponse{Error: err.Error()}, nil
 	}
 
-	abs, err := t.pathResolver.Abs(r.Path)
-	if err != nil {
-		return &EditFileResponse{Error: err.Error()}, nil
-	}
-
-	// Check if file exists
-	info, err := t.fileOps.Stat(abs)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return &EditFileResponse{Error: fmt.Sprintf("file does not exist: %s", abs)}, nil
-		}
-		return &EditFileResponse{Error: fmt.Sprintf("failed to stat %s: %v", abs, err)}, nil
-	}
-
-	// Read full file content
-	data, err := t.fileOps.ReadFile(abs)
+	p, err := t.plan(r)
 	if err != nil {
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
@@ -138,29 +137,67 @@ func (t *EditFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest)
 		return &EditFileResponse{Error: err.Error()}, nil
 	}
 
-	rawContent := string(data)
+	return p.commit(ctx, t.fileOps, t.checksumManager, t.pathResolver, t.diagnoser)
+}
 
-	// Detect original line endings for restoration
-	hasCRLF := strings.Contains(rawContent, "\r\n")
+// editPlan is an edit computed in memory and ready to write.
+type editPlan struct {
+	abs        string
+	perm       os.FileMode
+	oldContent string // Normalised to \n
+	newContent string // Normalised to \n
+	output     []byte // newContent with the file's original line endings and encoding
+	checksum   string // Of output, to cache once it is written
+	notes      []string
+}
 
-	// Normalize to \n for consistent matching
-	oldContent := strings.ReplaceAll(rawContent, "\r\n", "\n")
+// commit writes the planned content atomically, caches its checksum and runs
+// the diagnoser, which may be nil, on the written file.
+func (p *editPlan) commit(
+	ctx context.Context,
+	fileOps fileEditor,
+	checksumManager checksumManager,
+	pathResolver pathResolver,
+	diagnoser diagnoser,
+) (*EditFileResponse, error) {
+	if err := fileOps.WriteFileAtomic(p.abs, p.output, p.perm); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &EditFileResponse{Error: fmt.Sprintf("failed to write file %s: %v", p.abs, err)}, nil
+	}
 
-	// Compute current checksum (on normalized content for consistency)
-	currentChecksum := t.checksumManager.Compute([]byte(oldContent))
+	// Update cache with the checksum of what was written
+	checksumManager.Update(p.abs, p.checksum)
 
-	// Check for conflicts with cached version
-	priorChecksum, checksumOk := t.checksumManager.Get(abs)
-	if checksumOk && priorChecksum != currentChecksum {
-		return &EditFileResponse{Error: fmt.Sprintf("edit conflict: file changed since last read: %s", abs)}, nil
+	resp := p.response()
+	rel, err := pathResolver.Rel(p.abs)
+	if err != nil {
+		return resp, nil // Written; there is just no workspace path to check it under
 	}
+	if resp.Diagnostics, err = checkWritten(ctx, diagnoser, fileOps, checksumManager, p.abs, rel, p.output, p.perm); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
 
-	// Preserve original permissions
-	originalPerm := info.Mode()
+// plan reads the file, checks it for conflicts and applies the operations in memory.
+// The returned error is meant for the LLM.
+func (t *EditFileTool) plan(r *EditFileRequest) (*editPlan, error) {
+	abs, err := t.pathResolver.Abs(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	file, perm, err := loadForEdit(t.fileOps, t.checksumManager, abs)
+	if err != nil {
+		return nil, err
+	}
+	oldContent := file.text
 
 	// Apply operations sequentially (on normalized content)
 	content := oldContent
-	for _, op := range r.Operations {
+	var notes []string
+	for i, op := range r.Operations {
 		// Normalize operation strings for matching
 		before := strings.ReplaceAll(op.Before, "\r\n", "\n")
 		after := strings.ReplaceAll(op.After, "\r\n", "\n")
@@ -170,68 +207,115 @@ func (t *EditFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest)
 			// Append has exactly 1 logical "target" (end of file).
 			// If count > 1 is specified, it's a mismatch since there's only 1 place to append.
 			if op.ExpectedReplacements > 1 {
-				return &EditFileResponse{Error: fmt.Sprintf("replacement count mismatch: append has 1 target, got %d", op.ExpectedReplacements)}, nil
+				return nil, fmt.Errorf("replacement count mismatch: append has 1 target, got %d", op.ExpectedReplacements)
 			}
 			content += after
 			continue
 		}
 
-		count := strings.Count(content, before)
+		m := findMatches(content, before, after, t.config.Tools.EditFuzzyThreshold)
+		count := len(m.spans)
 		if count == 0 {
-			return &EditFileResponse{Error: fmt.Sprintf("snippet not found: %q in %s", op.Before, abs)}, nil
+			return nil, fmt.Errorf("snippet not found: %q in %s%s", op.Before, abs, notFoundDiagnostic(content, before, m.closest, m.refused))
 		}
 
 		expected := op.ExpectedReplacements
 
 		if count != expected {
-			return &EditFileResponse{Error: fmt.Sprintf("replacement count mismatch in %s: expected %d, found %d", abs, expected, count)}, nil
+			return nil, fmt.Errorf("replacement count mismatch in %s: expected %d, found %d (%s match)", abs, expected, count, m.strategy)
 		}
 
-		content = strings.Replace(content, before, after, expected)
+		content = m.apply(content, after)
+		if m.strategy != matchExact {
+			notes = append(notes, fmt.Sprintf("operation %d %s", i+1, m.describe()))
+		}
 	}
 
-	// Restore original line endings if file had CRLF
-	finalContent := content
-	if hasCRLF {
-		finalContent = strings.ReplaceAll(content, "\n", "\r\n")
+	return newEditPlan(t.config, t.checksumManager, abs, perm, file, content, notes)
+}
+
+// loadForEdit reads and decodes an existing file to edit in memory. It fails if
+// the file changed since it was last read, going by the checksum cache.
+func loadForEdit(fileOps fileEditor, checksumManager checksumManager, abs string) (*textFile, os.FileMode, error) {
+	// Check if file exists
+	info, err := fileOps.Stat(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, fmt.Errorf("file does not exist: %s", abs)
+		}
+		return nil, 0, fmt.Errorf("failed to stat %s: %v", abs, err)
 	}
 
-	newContentBytes := []byte(finalContent)
+	// Read full file content
+	data, err := fileOps.ReadFile(abs)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	// Check size limit
-	maxFileSize := t.config.Tools.MaxFileSize
-	if int64(len(newContentBytes)) > maxFileSize {
-		return &EditFileResponse{Error: fmt.Sprintf("file too large after edit: %s (size %d, limit %d)", abs, len(newContentBytes), maxFileSize)}, nil
+	// Compute current checksum (on normalized content for consistency)
+	currentChecksum := fileChecksum(checksumManager, data)
+
+	// Check for conflicts with cached version
+	priorChecksum, checksumOk := checksumManager.Get(abs)
+	if checksumOk && priorChecksum != currentChecksum {
+		return nil, 0, fmt.Errorf("edit conflict: file changed since last read: %s", abs)
 	}
 
-	// Write the modified content atomically
-	if err := t.fileOps.WriteFileAtomic(abs, newContentBytes, originalPerm); err != nil {
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
-		}
-		return &EditFileResponse{Error: fmt.Sprintf("failed to write file %s: %v", abs, err)}, nil
+	// Transcode to UTF-8 and normalize to \n for consistent matching
+	file, err := decodeText(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot decode %s: %v", abs, err)
 	}
+	return file, info.Mode(), nil // Preserve original permissions
+}
 
-	// Compute new checksum and update cache (on normalized content)
-	newChecksum := t.checksumManager.Compute([]byte(content))
-	t.checksumManager.Update(abs, newChecksum)
+// newEditPlan encodes the edited text of file back to its line endings, encoding
+// and BOM, and checks the result against the size limit.
+func newEditPlan(cfg *config.Config, checksumManager checksumManager, abs string, perm os.FileMode, file *textFile, content string, notes []string) (*editPlan, error) {
+	// Restore original line endings, encoding and BOM
+	newContentBytes, err := file.encode(content)
+	if err != nil {
+		return nil, fmt.Errorf("cannot save %s as %s: %v", abs, file.enc, err)
+	}
 
-	diff, added, removed := computeUnifiedDiff(filepath.Base(abs), oldContent, content)
+	// Check size limit
+	maxFileSize := cfg.Tools.MaxFileSize
+	if int64(len(newContentBytes)) > maxFileSize {
+		return nil, fmt.Errorf("file too large after edit: %s (size %d, limit %d)", abs, len(newContentBytes), maxFileSize)
+	}
+
+	return &editPlan{
+		abs:        abs,
+		perm:       perm,
+		oldContent: file.text,
+		newContent: content,
+		output:     newContentBytes,
+		checksum:   fileChecksum(checksumManager, newContentBytes),
+		notes:      notes,
+	}, nil
+}
 
+func (p *editPlan) response() *EditFileResponse {
+	diff, added, removed := computeUnifiedDiff(filepath.Base(p.abs), p.oldContent, p.newContent)
 	return &EditFileResponse{
-		Path:         abs,
+		Path:         p.abs,
 		Diff:         diff,
 		AddedLines:   added,
 		RemovedLines: removed,
-	}, nil
+		MatchNotes:   p.notes,
+	}
 }
 
 func computeUnifiedDiff(filename, oldContent, newContent string) (diff string, added, removed int) {
+	return unifiedDiff("a/"+filename, "b/"+filename, oldContent, newContent)
+}
+
+func unifiedDiff(fromFile, toFile, oldContent, newContent string) (diff string, added, removed int) {
 	ud := difflib.UnifiedDiff{
 		A:        difflib.SplitLines(oldContent),
 		B:        difflib.SplitLines(newContent),
-		FromFile: "a/" + filename,
-		ToFile:   "b/" + filename,
+		FromFile: fromFile,
+		ToFile:   toFile,
 		Context:  3,
 	}
 	diff, _ = difflib.GetUnifiedDiffString(ud)
@@ -4,12 +4,14 @@ package file
 // Mocks are defined in write_test.go and shared across all test files in this package.
 
 import (
+	"bytes"
 	"context"
 	"strings"
 	"testing"
 
 	"github.com/Cyclone1070/iav/internal/config"
 	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/diagnostics"
 	"github.com/Cyclone1070/iav/internal/tool/service/path"
 )
 
@@ -68,8 +70,8 @@ func TestEditFile(t *testing.T) {
 		originalContent := []byte("original content")
 		fs.createFile("/workspace/test.txt", originalContent, 0o644)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		// Read file to populate cache
 		readReq := &ReadFileRequest{Path: "test.txt"}
@@ -103,7 +105,7 @@ func TestEditFile(t *testing.T) {
 		fs.createFile("/workspace/test.txt", content, 0o644)
 
 		// Skip reading first, so no cache
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -128,8 +130,8 @@ func TestEditFile(t *testing.T) {
 		content := []byte("line1\nline2\nline3")
 		fs.createFile("/workspace/test.txt", content, 0o644)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		// Read first to populate cache
 		readReq := &ReadFileRequest{Path: "test.txt"}
@@ -170,8 +172,8 @@ func TestEditFile(t *testing.T) {
 		content := []byte("line1\nline1\nline3")
 		fs.createFile("/workspace/test.txt", content, 0o644)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		// Read first to populate cache
 		readReq := &ReadFileRequest{Path: "test.txt"}
@@ -199,8 +201,8 @@ func TestEditFile(t *testing.T) {
 		content := []byte("foo\nfoo\nbar")
 		fs.createFile("/workspace/test.txt", content, 0o644)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		// Read first to populate cache
 		readReq := &ReadFileRequest{Path: "test.txt"}
@@ -234,8 +236,8 @@ func TestEditFile(t *testing.T) {
 		content := []byte("foo\nfoo\nbar")
 		fs.createFile("/workspace/test.txt", content, 0o644)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		// Read first to populate cache
 		readReq := &ReadFileRequest{Path: "test.txt"}
@@ -262,8 +264,8 @@ func TestEditFile(t *testing.T) {
 		checksumManager := newMockChecksumManagerForWrite()
 		fs.createFile("/workspace/test.txt", []byte("content"), 0o644)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		readReq := &ReadFileRequest{Path: "test.txt"}
 		executeReadForEdit(t, readTool, readReq)
@@ -288,7 +290,7 @@ func TestEditFile(t *testing.T) {
 		checksumManager := newMockChecksumManagerForWrite()
 		fs.createFile("/workspace/test.txt", []byte("existing"), 0o644)
 
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -316,7 +318,7 @@ func TestEditFile(t *testing.T) {
 		checksumManager := newMockChecksumManagerForWrite()
 		fs.createFile("/workspace/test.txt", []byte(""), 0o644)
 
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -344,7 +346,7 @@ func TestEditFile(t *testing.T) {
 		checksumManager := newMockChecksumManagerForWrite()
 		fs.createFile("/workspace/test.txt", []byte("start"), 0o644)
 
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -376,7 +378,7 @@ func TestEditFile(t *testing.T) {
 		checksumManager := newMockChecksumManagerForWrite()
 		fs.createFile("/workspace/test.txt", []byte("foo\nbar"), 0o644)
 
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -409,7 +411,7 @@ func TestEditFile(t *testing.T) {
 		checksumManager := newMockChecksumManagerForWrite()
 		fs.createFile("/workspace/test.txt", []byte("start"), 0o644)
 
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -478,7 +480,7 @@ func TestEditFile(t *testing.T) {
 		// File with CRLF line endings
 		fs.createFile("/workspace/test.txt", []byte("line1\r\nline2\r\nline3"), 0o644)
 
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -511,7 +513,7 @@ func TestEditFile(t *testing.T) {
 		// File with CRLF line endings
 		fs.createFile("/workspace/test.txt", []byte("hello\r\nworld"), 0o644)
 
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -542,7 +544,7 @@ func TestEditFile(t *testing.T) {
 		// File with LF line endings
 		fs.createFile("/workspace/test.txt", []byte("hello\nworld"), 0o644)
 
-		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		ops := []EditOperation{
 			{
@@ -565,4 +567,147 @@ func TestEditFile(t *testing.T) {
 			t.Errorf("expected content %q, got %q", expected, string(data))
 		}
 	})
+	t.Run("indentation drift reports strategy", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createFile("/workspace/main.go", []byte("func main() {\n\tif ok {\n\t\trun()\n\t}\n}\n"), 0o644)
+
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		ops := []EditOperation{{Before: "if ok {\n\trun()\n}", After: "if ok {\n\trun(ctx)\n}"}}
+		resp := executeEdit(t, editTool, &EditFileRequest{Path: "main.go", Operations: ops})
+
+		data, _ := fs.ReadFile("/workspace/main.go")
+		expected := "func main() {\n\tif ok {\n\t\trun(ctx)\n\t}\n}\n"
+		if string(data) != expected {
+			t.Errorf("expected content %q, got %q", expected, string(data))
+		}
+		if !strings.Contains(resp.LLMContent(), "operation 1 matched after normalising indentation") {
+			t.Errorf("expected strategy note, got: %s", resp.LLMContent())
+		}
+	})
+
+	t.Run("snippet not found shows closest region", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createFile("/workspace/test.txt", []byte("first\nsecond line\nthird\n"), 0o644)
+
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		ops := []EditOperation{{Before: "second lines", After: "x"}}
+		cfg.Tools.EditFuzzyThreshold = 0.99
+		resp := executeEditExpectError(t, editTool, &EditFileRequest{Path: "test.txt", Operations: ops})
+
+		for _, want := range []string{"snippet not found", "Closest match (lines 2-2", "00002| second line", "-second lines"} {
+			if !strings.Contains(resp.Error, want) {
+				t.Errorf("expected %q in error, got: %s", want, resp.Error)
+			}
+		}
+	})
+
+	t.Run("UTF-16 file keeps its encoding, BOM and line endings", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		utf16 := func(s string) []byte {
+			out := []byte{0xFF, 0xFE}
+			for _, r := range s {
+				out = append(out, byte(r), byte(r>>8))
+			}
+			return out
+		}
+		fs.createFile("/workspace/app.ini", utf16("name=café\r\nport=80\r\n"), 0o644)
+
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		resp := executeEdit(t, editTool, &EditFileRequest{
+			Path:       "app.ini",
+			Operations: []EditOperation{{Before: "port=80\n", After: "port=8080\n"}},
+		})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+
+		want := utf16("name=café\r\nport=8080\r\n")
+		data, _ := fs.ReadFile("/workspace/app.ini")
+		if string(data) != string(want) {
+			t.Errorf("expected %x, got %x", want, data)
+		}
+		if sum, _ := checksumManager.Get("/workspace/app.ini"); sum != checksumManager.Compute(bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n"))) {
+			t.Errorf("unexpected checksum %s", sum)
+		}
+		assertContains(t, resp.Diff, "+port=8080")
+	})
+
+	t.Run("Latin-1 file rejects characters it cannot hold", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createFile("/workspace/price.txt", []byte("caf\xE9 5 EUR\n"), 0o644)
+
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		resp := executeEditExpectError(t, editTool, &EditFileRequest{
+			Path:       "price.txt",
+			Operations: []EditOperation{{Before: "café 5 EUR", After: "café 5 €"}},
+		})
+		assertContains(t, resp.Error, "cannot save /workspace/price.txt as ISO-8859-1")
+
+		resp = executeEdit(t, editTool, &EditFileRequest{
+			Path:       "price.txt",
+			Operations: []EditOperation{{Before: "café 5 EUR", After: "café 6 EUR"}},
+		})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		data, _ := fs.ReadFile("/workspace/price.txt")
+		if string(data) != "caf\xE9 6 EUR\n" {
+			t.Errorf("expected Latin-1 bytes to be kept, got %q", data)
+		}
+	})
+	t.Run("diagnostics are reported and formatting is applied", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createFile("/workspace/pkg/main.go", []byte("package main\nfunc main() {}\n"), 0o644)
+
+		diag := &mockDiagnoser{report: &diagnostics.Report{
+			Commands:    []string{"gofmt", "go"},
+			Diagnostics: []diagnostics.Diagnostic{{File: "pkg/main.go", Line: 2, Column: 14, Message: "undefined: x", Source: "go"}},
+			Formatted:   []byte("package main\n\nfunc main() { x }\n"),
+			FormattedBy: "gofmt",
+		}}
+		editTool := NewEditFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), diag, cfg)
+
+		resp := executeEdit(t, editTool, &EditFileRequest{
+			Path:       "pkg/main.go",
+			Operations: []EditOperation{{Before: "{}", After: "{ x }"}},
+		})
+		if diag.rel != "pkg/main.go" || string(diag.data) != "package main\nfunc main() { x }\n" {
+			t.Errorf("diagnoser got %q with %q", diag.rel, diag.data)
+		}
+		assertContains(t, resp.LLMContent(), "Formatted with gofmt")
+		assertContains(t, resp.LLMContent(), "Diagnostics from gofmt, go:\n  pkg/main.go:2:14: undefined: x (go)")
+
+		data, _ := fs.ReadFile("/workspace/pkg/main.go")
+		if string(data) != "package main\n\nfunc main() { x }\n" {
+			t.Errorf("expected formatted content to be written, got %q", data)
+		}
+		if sum, _ := checksumManager.Get("/workspace/pkg/main.go"); sum != checksumManager.Compute(data) {
+			t.Errorf("expected checksum of the formatted content to be cached")
+		}
+	})
+}
+
+type mockDiagnoser struct {
+	report *diagnostics.Report
+	rel    string
+	data   []byte
+}
+
+func (m *mockDiagnoser) Check(ctx context.Context, rel string, data []byte) (*diagnostics.Report, error) {
+	m.rel, m.data = rel, data
+	return m.report, nil
 }
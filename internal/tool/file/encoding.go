@@ -0,0 +1,76 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/tool/helper/content"
+)
+
+// textFile is a file's content as the LLM sees it, with what is needed to write
+// changed text back in the file's own encoding and line endings.
+type textFile struct {
+	text string // UTF-8 without BOM, normalised to \n
+	enc  content.Encoding
+	crlf bool
+}
+
+// decodeText transcodes file data to UTF-8 with \n line endings.
+func decodeText(data []byte) (*textFile, error) {
+	text, enc, err := content.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return &textFile{
+		text: strings.ReplaceAll(text, "\r\n", "\n"),
+		enc:  enc,
+		crlf: strings.Contains(text, "\r\n"),
+	}, nil
+}
+
+// encode converts \n-normalised text back to the file's line endings, encoding and BOM.
+func (f *textFile) encode(text string) ([]byte, error) {
+	if f.crlf {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	return content.Encode(text, f.enc)
+}
+
+// fileChecksum returns the checksum the file tools cache for file data. It is
+// taken over the bytes on disk with \r\n normalised to \n, so it does not depend
+// on decoding and matches what the watcher and the persistent cache compute.
+func fileChecksum(m interface{ Compute(data []byte) string }, data []byte) string {
+	return m.Compute(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")))
+}
+
+// crlfReader reads r with \r\n normalised to \n, for checksumming a file as
+// fileChecksum does without reading it into memory.
+type crlfReader struct {
+	r *bufio.Reader
+}
+
+func newCRLFReader(r io.Reader) *crlfReader {
+	return &crlfReader{r: bufio.NewReader(r)}
+}
+
+func (c *crlfReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	out := p[:0]
+	for i := 0; i < n; i++ {
+		if p[i] == '\r' {
+			if i+1 < n && p[i+1] == '\n' {
+				continue
+			}
+			if i+1 == n {
+				// The \n may be the first byte of the next read.
+				if next, perr := c.r.Peek(1); perr == nil && next[0] == '\n' {
+					continue
+				}
+			}
+		}
+		out = append(out, p[i])
+	}
+	return len(out), err
+}
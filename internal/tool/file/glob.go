@@ -0,0 +1,84 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+)
+
+// glob returns up to max workspace files matching pattern, in path order. The
+// pattern is relative to the workspace root (or absolute inside it) and uses /
+// separators; ** matches any number of directories. Ignored paths are skipped,
+// directories included.
+func (t *ReadFileTool) glob(ctx context.Context, pattern string, max int) ([]readTarget, error) {
+	pat, err := t.pathResolver.Rel(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if pat == "" {
+		return nil, fmt.Errorf("glob %q matches the workspace root, not files", pattern)
+	}
+	segments := strings.Split(pat, "/")
+
+	// Walk from the longest prefix without wildcards.
+	base := 0
+	for base < len(segments)-1 && !hasMeta(segments[base]) {
+		base++
+	}
+	root, err := t.pathResolver.Abs(filepath.Join(".", filepath.Join(segments[:base]...)))
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []readTarget
+	var walk func(dir string, depth int) error
+	walk = func(dir string, depth int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entries, err := t.fileOps.ListDir(dir)
+		if err != nil {
+			if depth == 0 {
+				return fmt.Errorf("failed to list %s: %w", dir, err)
+			}
+			return nil // Unreadable subdirectories are skipped
+		}
+		for _, entry := range entries {
+			if len(matches) >= max {
+				return nil
+			}
+			abs := filepath.Join(dir, entry.Name())
+			rel, err := t.pathResolver.Rel(abs)
+			if err != nil {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			if t.ignoreMatcher != nil && t.ignoreMatcher.ShouldIgnore(rel) {
+				continue
+			}
+			if entry.IsDir() {
+				if path.CouldMatch(pat, rel) {
+					if err := walk(abs, depth+1); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if entry.Mode().IsRegular() && path.Match(pat, rel) {
+				matches = append(matches, readTarget{abs: abs, rel: rel})
+			}
+		}
+		return nil
+	}
+	if err := walk(root, 0); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+func hasMeta(segment string) bool {
+	return strings.ContainsAny(segment, "*?[\\")
+}
@@ -0,0 +1,272 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/helper/gosource"
+	"github.com/Cyclone1070/iav/internal/tool/service/fs"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// goFileSystem defines the filesystem operations needed for Go refactoring.
+type goFileSystem interface {
+	fileEditor
+	ListDir(path string) ([]os.FileInfo, error) // For the other files of a package
+	changeCommitter
+}
+
+// GoRefactorTool edits Go source through its syntax tree: renaming identifiers
+// across a package, replacing function bodies, adding and removing imports,
+// and listing declarations.
+type GoRefactorTool struct {
+	fileOps         goFileSystem
+	checksumManager checksumManager
+	config          *config.Config
+	pathResolver    pathResolver
+	diagnoser       diagnoser
+}
+
+// NewGoRefactorTool creates a new GoRefactorTool with injected dependencies.
+// diagnoser may be nil, in which case edited files are not checked.
+func NewGoRefactorTool(
+	fileOps goFileSystem,
+	checksumManager checksumManager,
+	pathResolver pathResolver,
+	diagnoser diagnoser,
+	cfg *config.Config,
+) *GoRefactorTool {
+	if fileOps == nil {
+		panic("fileOps is required")
+	}
+	if checksumManager == nil {
+		panic("checksumManager is required")
+	}
+	if pathResolver == nil {
+		panic("pathResolver is required")
+	}
+	if cfg == nil {
+		panic("config is required")
+	}
+	return &GoRefactorTool{
+		fileOps:         fileOps,
+		checksumManager: checksumManager,
+		config:          cfg,
+		pathResolver:    pathResolver,
+		diagnoser:       diagnoser,
+	}
+}
+
+func (t *GoRefactorTool) Name() string {
+	return "go_refactor"
+}
+
+func (t *GoRefactorTool) Declaration() tool.Declaration {
+	return tool.Declaration{
+		Name: "go_refactor",
+		Description: "Edit Go source through its syntax tree instead of by text. " +
+			"list shows a file's declarations with their lines; rename renames an identifier and every reference to it across the package, type-checked; " +
+			"replace_body replaces a function or method body; add_import and remove_import edit the import block. " +
+			"Results are gofmt-ed, and an edit that would not parse is refused.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"op": {
+					Type: tool.TypeString,
+					Enum: []string{GoOpList, GoOpRename, GoOpReplaceBody, GoOpAddImport, GoOpRemoveImport},
+				},
+				"path": {Type: tool.TypeString, Description: "Path to a .go file; for rename, any file of the package"},
+				"name": {
+					Type: tool.TypeString,
+					Description: "rename: a package-level Name, Type.Method or Type.Field, or a local identifier together with line; " +
+						"replace_body: Func or Type.Method",
+				},
+				"line":        {Type: tool.TypeInteger, Description: "rename: the line of path where the local identifier name is declared or used", Minimum: tool.Ptr(1.0)},
+				"new_name":    {Type: tool.TypeString, Description: "rename: the new identifier"},
+				"body":        {Type: tool.TypeString, Description: "replace_body: the new statements, with or without the enclosing braces"},
+				"import_path": {Type: tool.TypeString, Description: "add_import and remove_import: the package path, e.g. net/http"},
+				"import_name": {Type: tool.TypeString, Description: "add_import: a local name for the import, if needed"},
+			},
+			Required: []string{"op", "path"},
+		},
+	}
+}
+
+func (t *GoRefactorTool) Request() toolmanager.ToolRequest {
+	return &GoRefactorRequest{}
+}
+
+// Execute applies the operation in memory to every file it touches and writes
+// them in one filesystem transaction, with the same conflict detection as
+// edit_file. Written files keep their line endings and are checked with the
+// formatters and linters configured for .go files.
+func (t *GoRefactorTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*GoRefactorRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+
+	if err := r.Validate(); err != nil {
+		return &GoRefactorResponse{Op: r.Op, Error: err.Error()}, nil
+	}
+
+	abs, err := t.pathResolver.Abs(r.Path)
+	if err != nil {
+		return &GoRefactorResponse{Op: r.Op, Error: err.Error()}, nil
+	}
+	if r.Op == GoOpList {
+		return t.list(abs), nil
+	}
+
+	var plans []*editPlan
+	var notes []string
+	if r.Op == GoOpRename {
+		plans, notes, err = t.planRename(abs, r)
+	} else {
+		var p *editPlan
+		p, err = t.planFile(abs, r)
+		plans = []*editPlan{p}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &GoRefactorResponse{Op: r.Op, Error: err.Error()}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	changes := make([]fs.Change, len(plans))
+	for i, p := range plans {
+		changes[i] = fs.Change{Path: p.abs, Content: p.output, Perm: p.perm}
+	}
+	if err := t.fileOps.ApplyChanges(changes); err != nil {
+		return &GoRefactorResponse{Op: r.Op, Error: commitError(err)}, nil
+	}
+
+	files := make([]ChangedFile, len(plans))
+	for i, p := range plans {
+		t.checksumManager.Update(p.abs, p.checksum)
+		rel, relErr := t.pathResolver.Rel(p.abs)
+		if relErr != nil {
+			rel = p.abs
+		}
+		diff, added, removed := unifiedDiff("a/"+rel, "b/"+rel, p.oldContent, p.newContent)
+		files[i] = ChangedFile{Op: "modify", Path: rel, Diff: diff, AddedLines: added, RemovedLines: removed, Notes: p.notes}
+		if relErr != nil {
+			continue // Written; there is just no workspace path to check it under
+		}
+		var err error
+		if files[i].Diagnostics, err = checkWritten(ctx, t.diagnoser, t.fileOps, t.checksumManager, p.abs, rel, p.output, p.perm); err != nil {
+			return nil, err
+		}
+	}
+	return &GoRefactorResponse{Op: r.Op, Files: files, Notes: notes}, nil
+}
+
+func (t *GoRefactorTool) list(abs string) *GoRefactorResponse {
+	data, err := t.fileOps.ReadFile(abs)
+	if err != nil {
+		return &GoRefactorResponse{Op: GoOpList, Error: err.Error()}
+	}
+	file, err := decodeText(data)
+	if err != nil {
+		return &GoRefactorResponse{Op: GoOpList, Error: fmt.Sprintf("cannot decode %s: %v", abs, err)}
+	}
+	pkg, decls, err := gosource.Declarations(filepath.Base(abs), []byte(file.text))
+	if err != nil {
+		return &GoRefactorResponse{Op: GoOpList, Error: err.Error()}
+	}
+	rel, err := t.pathResolver.Rel(abs)
+	if err != nil {
+		rel = abs
+	}
+	return &GoRefactorResponse{Op: GoOpList, Path: rel, Package: pkg, Declarations: decls}
+}
+
+// planFile applies an operation on a single file in memory.
+func (t *GoRefactorTool) planFile(abs string, r *GoRefactorRequest) (*editPlan, error) {
+	file, perm, err := loadForEdit(t.fileOps, t.checksumManager, abs)
+	if err != nil {
+		return nil, err
+	}
+
+	name, src := filepath.Base(abs), []byte(file.text)
+	var res *gosource.Result
+	switch r.Op {
+	case GoOpReplaceBody:
+		res, err = gosource.ReplaceBody(name, src, r.Name, r.Body)
+	case GoOpAddImport:
+		res, err = gosource.AddImport(name, src, r.ImportPath, r.ImportName)
+	case GoOpRemoveImport:
+		res, err = gosource.RemoveImport(name, src, r.ImportPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if string(res.Src) == file.text {
+		res.Notes = append(res.Notes, "the operation left the file as it was")
+	}
+	return newEditPlan(t.config, t.checksumManager, abs, perm, file, string(res.Src), res.Notes)
+}
+
+// planRename renames across the package of abs, which is every .go file in its
+// directory. Every file is loaded with conflict detection, since the rename
+// depends on all of them.
+func (t *GoRefactorTool) planRename(abs string, r *GoRefactorRequest) ([]*editPlan, []string, error) {
+	dir := filepath.Dir(abs)
+	entries, err := t.fileOps.ListDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list %s: %v", dir, err)
+	}
+
+	type loaded struct {
+		abs  string
+		file *textFile
+		perm os.FileMode
+	}
+	byName := make(map[string]loaded)
+	var files []gosource.File
+	for _, e := range entries {
+		if !e.Mode().IsRegular() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		path, err := t.pathResolver.Abs(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+		file, perm, err := loadForEdit(t.fileOps, t.checksumManager, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		byName[e.Name()] = loaded{abs: path, file: file, perm: perm}
+		files = append(files, gosource.File{Name: e.Name(), Src: []byte(file.text)})
+	}
+
+	renamed, err := gosource.Rename(dir, files, gosource.Target{File: filepath.Base(abs), Name: r.Name, Line: r.Line}, r.NewName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(renamed.Files))
+	for name := range renamed.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	plans := make([]*editPlan, len(names))
+	for i, name := range names {
+		l, res := byName[name], renamed.Files[name]
+		if plans[i], err = newEditPlan(t.config, t.checksumManager, l.abs, l.perm, l.file, string(res.Src), res.Notes); err != nil {
+			return nil, nil, err
+		}
+	}
+	return plans, renamed.Notes, nil
+}
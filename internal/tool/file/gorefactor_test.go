@@ -0,0 +1,119 @@
+package file
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+)
+
+func TestGoRefactor(t *testing.T) {
+	const store = "package store\n\ntype Store struct{ n int }\n\nfunc (s *Store) Len() int {\n\treturn s.n\n}\n"
+	const cache = "package store\r\n\r\nfunc size(s *Store) int {\r\n\treturn s.Len()\r\n}\r\n"
+
+	newTool := func() (*mockFileSystemForWrite, *mockChecksumManagerForWrite, *GoRefactorTool) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		fs.createFile("/workspace/store/store.go", []byte(store), 0o644)
+		fs.createFile("/workspace/store/cache.go", []byte(cache), 0o600)
+		fs.createFile("/workspace/store/README.md", []byte("Store.Len\n"), 0o644)
+		checksumManager := newMockChecksumManagerForWrite()
+		return fs, checksumManager, NewGoRefactorTool(fs, checksumManager, path.NewResolver("/workspace"), nil, cfg)
+	}
+	execute := func(t *testing.T, gt *GoRefactorTool, req *GoRefactorRequest) *GoRefactorResponse {
+		t.Helper()
+		result, err := gt.Execute(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Execute returned error: %v", err)
+		}
+		return result.(*GoRefactorResponse)
+	}
+
+	t.Run("rename across the package", func(t *testing.T) {
+		fs, checksumManager, gt := newTool()
+		resp := execute(t, gt, &GoRefactorRequest{Op: GoOpRename, Path: "store/store.go", Name: "Store.Len", NewName: "Size"})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		if len(resp.Files) != 2 || resp.Files[0].Path != "store/cache.go" || resp.Files[1].Path != "store/store.go" {
+			t.Fatalf("expected cache.go and store.go to change, got %+v", resp.Files)
+		}
+		data, _ := fs.ReadFile("/workspace/store/cache.go")
+		if want := strings.Replace(cache, "s.Len()", "s.Size()", 1); string(data) != want {
+			t.Errorf("expected %q, got %q", want, data)
+		}
+		if fs.files["/workspace/store/cache.go"].mode != 0o600 {
+			t.Errorf("expected permissions to be kept")
+		}
+		if sum, _ := checksumManager.Get("/workspace/store/store.go"); sum != fileChecksum(checksumManager, []byte(strings.Replace(store, "Len()", "Size()", 1))) {
+			t.Errorf("expected checksum of the written file to be cached")
+		}
+		assertContains(t, resp.LLMContent(), "Note: Len is exported; code in other packages")
+		if _, ok := resp.Display().(tool.MultiDiffDisplay); !ok {
+			t.Errorf("expected MultiDiffDisplay, got %T", resp.Display())
+		}
+	})
+
+	t.Run("a conflict in any file of the package refuses the rename", func(t *testing.T) {
+		fs, checksumManager, gt := newTool()
+		checksumManager.Update("/workspace/store/cache.go", "stale")
+		resp := execute(t, gt, &GoRefactorRequest{Op: GoOpRename, Path: "store/store.go", Name: "Store", NewName: "Cache"})
+		assertContains(t, resp.Error, "edit conflict")
+		if data, _ := fs.ReadFile("/workspace/store/store.go"); string(data) != store {
+			t.Errorf("expected store.go to be unchanged")
+		}
+	})
+
+	t.Run("replace body", func(t *testing.T) {
+		fs, _, gt := newTool()
+		resp := execute(t, gt, &GoRefactorRequest{Op: GoOpReplaceBody, Path: "store/cache.go", Name: "size", Body: "if s == nil {\n\treturn 0\n}\nreturn s.Len()"})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		data, _ := fs.ReadFile("/workspace/store/cache.go")
+		if !strings.Contains(string(data), "\tif s == nil {\r\n\t\treturn 0\r\n\t}\r\n") {
+			t.Errorf("expected the body with CRLF line endings, got %q", data)
+		}
+		if _, ok := resp.Display().(tool.DiffDisplay); !ok {
+			t.Errorf("expected DiffDisplay, got %T", resp.Display())
+		}
+	})
+
+	t.Run("an edit that does not parse is refused", func(t *testing.T) {
+		_, _, gt := newTool()
+		resp := execute(t, gt, &GoRefactorRequest{Op: GoOpReplaceBody, Path: "store/cache.go", Name: "size", Body: "return s.Len("})
+		assertContains(t, resp.Error, "body does not parse as Go statements")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		_, _, gt := newTool()
+		resp := execute(t, gt, &GoRefactorRequest{Op: GoOpList, Path: "store/store.go"})
+		want := "store/store.go: package store, 2 declaration(s)\n3-3 type Store struct\n5-7 func (s *Store) Len() int"
+		if resp.LLMContent() != want {
+			t.Errorf("expected %q, got %q", want, resp.LLMContent())
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			req  GoRefactorRequest
+			want string
+		}{
+			{GoRefactorRequest{Op: GoOpList, Path: "a.py"}, "path must be a .go file"},
+			{GoRefactorRequest{Op: GoOpRename, Path: "a.go", Name: "A"}, "name and new_name are required"},
+			{GoRefactorRequest{Op: GoOpRename, Path: "a.go", Name: "T.M", NewName: "N", Line: 3}, "line names a local identifier"},
+			{GoRefactorRequest{Op: GoOpReplaceBody, Path: "a.go", Name: "f"}, "body is required"},
+			{GoRefactorRequest{Op: GoOpRemoveImport, Path: "a.go", ImportPath: "os", ImportName: "o"}, "import_name is only used by add_import"},
+			{GoRefactorRequest{Op: "extract", Path: "a.go"}, "op must be list, rename"},
+		}
+		for _, tt := range tests {
+			err := tt.req.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,346 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Cyclone1070/iav/internal/tool/helper/content"
+)
+
+// lineIndexStride is the number of lines between two offsets kept in a line index.
+// A read seeks to the nearest kept offset and scans at most this many lines.
+const lineIndexStride = 1000
+
+// maxLineIndexes caps the number of files a ReadFileTool keeps a line index for.
+const maxLineIndexes = 64
+
+// headSize is how much of a file is sampled to detect binary content and the
+// encoding, matching the sample IsBinaryContent looks at.
+const headSize = 8000
+
+// lineIndex is a sparse map from line numbers to byte offsets in one version of
+// a file, identified by its size and modification time. It is built in a single
+// streaming pass, which also takes the file's checksum and settles its encoding.
+type lineIndex struct {
+	info      os.FileInfo // Identifies the file, to tell rotation from appends
+	size      int64
+	modTime   time.Time
+	offsets   []int64 // offsets[i] is where line i*lineIndexStride starts
+	lines     int
+	lineStart int64 // Where the line after the last \n starts
+	enc       content.Encoding
+	checksum  string // Empty for an index extended by extendIndex
+}
+
+// lineIndexCache holds the line indexes of recently read files by absolute path.
+type lineIndexCache struct {
+	mu      sync.Mutex
+	indexes map[string]*lineIndex
+}
+
+func newLineIndexCache() *lineIndexCache {
+	return &lineIndexCache{indexes: make(map[string]*lineIndex)}
+}
+
+// peek returns the index of the file at abs, current or not, or nil.
+func (c *lineIndexCache) peek(abs string) *lineIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.indexes[abs]
+}
+
+// get returns the index of the file at abs if it is still current.
+func (c *lineIndexCache) get(abs string, size int64, modTime time.Time) *lineIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idx, ok := c.indexes[abs]
+	if !ok || idx.size != size || !idx.modTime.Equal(modTime) {
+		return nil
+	}
+	return idx
+}
+
+func (c *lineIndexCache) put(abs string, idx *lineIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.indexes[abs]; !ok && len(c.indexes) >= maxLineIndexes {
+		for p := range c.indexes {
+			delete(c.indexes, p) // Any one will do
+			break
+		}
+	}
+	c.indexes[abs] = idx
+}
+
+// errWideEncoding means a file is UTF-16 or UTF-32, whose lines cannot be found
+// by scanning for \n bytes, so it has to be decoded whole.
+var errWideEncoding = errors.New("file must be decoded whole")
+
+// lineIndex returns the line index of the file at abs, building it if there is
+// none for the file's current size and modification time. It fails for binary
+// files, and with errWideEncoding for UTF-16 and UTF-32 files.
+func (t *ReadFileTool) lineIndex(abs string) (*lineIndex, error) {
+	info, err := t.fileOps.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("read %s: is a directory", abs)
+	}
+	if idx := t.lines.get(abs, info.Size(), info.ModTime()); idx != nil {
+		return idx, nil
+	}
+
+	f, err := t.fileOps.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, min(info.Size(), headSize))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if content.IsBinaryContent(head) {
+		return nil, fmt.Errorf("binary file: %s", abs)
+	}
+	enc := content.DetectEncoding(head)
+	switch enc.Charset {
+	case content.CharsetUTF16LE, content.CharsetUTF16BE, content.CharsetUTF32LE, content.CharsetUTF32BE:
+		return nil, errWideEncoding
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	idx := &lineIndex{info: info, size: info.Size(), modTime: info.ModTime()}
+	ix := &lineIndexer{idx: idx, lineStart: int64(enc.BOMSize())}
+	idx.offsets = []int64{ix.lineStart}
+	if idx.checksum, err = t.checksumManager.ComputeReader(newCRLFReader(io.TeeReader(f, ix))); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	idx.lineStart = ix.lineStart
+	if ix.pos > ix.lineStart {
+		idx.lines++ // A last line without \n
+	}
+
+	// The sample cannot tell UTF-8 from ISO-8859-1 that happens to look like it.
+	switch {
+	case enc.BOM:
+		idx.enc = enc
+	case ix.invalid || len(ix.carry) > 0:
+		idx.enc = content.Encoding{Charset: content.CharsetLatin1}
+	default:
+		idx.enc = content.UTF8
+	}
+
+	t.lines.put(abs, idx)
+	return idx, nil
+}
+
+// extendIndex returns the index of the file at abs as prev with the bytes
+// appended since it was built, reading only those. It returns nil if the file
+// did not just grow, or if the appended bytes may change how the file decodes;
+// the index then has to be built again. The returned index has no checksum and
+// is not cached.
+func (t *ReadFileTool) extendIndex(abs string, prev *lineIndex, info os.FileInfo) (*lineIndex, error) {
+	if info.Size() <= prev.size || !sameFile(prev.info, info) {
+		return nil, nil
+	}
+	if prev.enc.Charset != content.UTF8.Charset {
+		return nil, nil
+	}
+
+	f, err := t.fileOps.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(prev.size, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	idx := &lineIndex{
+		info:    info,
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		offsets: append([]int64(nil), prev.offsets...),
+		lines:   prev.lines,
+		enc:     prev.enc,
+	}
+	if prev.lineStart < prev.size {
+		idx.lines-- // The last line is counted again once its end is known
+	}
+	ix := &lineIndexer{idx: idx, pos: prev.size, lineStart: prev.lineStart}
+	if _, err := io.Copy(ix, io.LimitReader(f, idx.size-prev.size)); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if ix.invalid || len(ix.carry) > 0 {
+		return nil, nil // No longer UTF-8, or a character is still being written
+	}
+	idx.lineStart = ix.lineStart
+	if ix.pos > ix.lineStart {
+		idx.lines++
+	}
+	return idx, nil
+}
+
+// sameFile reports whether prev and next describe the same file. Without the
+// platform's file identity they are taken to be.
+func sameFile(prev, next os.FileInfo) bool {
+	return prev.Sys() == nil || next.Sys() == nil || os.SameFile(prev, next)
+}
+
+// lineIndexer receives a file's bytes in order, counting lines, keeping every
+// lineIndexStride-th line offset and checking whether the file is valid UTF-8.
+type lineIndexer struct {
+	idx       *lineIndex
+	pos       int64 // Bytes seen
+	lineStart int64 // Where the current line starts
+	invalid   bool  // Not valid UTF-8
+	carry     []byte
+}
+
+func (x *lineIndexer) Write(p []byte) (int, error) {
+	x.checkUTF8(p)
+	for rest, base := p, x.pos; ; {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		x.lineStart = base + int64(i) + 1
+		x.idx.lines++
+		if x.idx.lines%lineIndexStride == 0 {
+			x.idx.offsets = append(x.idx.offsets, x.lineStart)
+		}
+		base += int64(i) + 1
+		rest = rest[i+1:]
+	}
+	x.pos += int64(len(p))
+	return len(p), nil
+}
+
+// checkUTF8 validates p, carrying a rune cut off at its end over to the next write.
+func (x *lineIndexer) checkUTF8(p []byte) {
+	if x.invalid {
+		return
+	}
+	buf := append(x.carry, p...)
+	cut := len(buf)
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	if !utf8.Valid(buf[:cut]) {
+		x.invalid = true
+		return
+	}
+	x.carry = append(x.carry[:0], buf[cut:]...)
+}
+
+// truncatedMarker ends a line that was cut at MaxLineLength, as in search results.
+const truncatedMarker = "...[truncated]"
+
+// trimPartialRune drops a UTF-8 sequence cut off at the end of a line.
+func trimPartialRune(line []byte, enc content.Encoding) []byte {
+	if enc.Charset == content.CharsetLatin1 {
+		return line
+	}
+	for i := len(line) - 1; i >= 0 && i >= len(line)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(line[i]) {
+			if !utf8.FullRune(line[i:]) {
+				return line[:i]
+			}
+			break
+		}
+	}
+	return line
+}
+
+// readLines returns up to limit lines of the indexed file from the 0-based line
+// offset on, decoded and without line endings. It seeks to the nearest indexed
+// line and scans from there, so it reads little more than the lines it returns.
+// Lines longer than MaxLineLength end in truncatedMarker, and fewer than limit
+// lines are returned if they would add up to more than MaxFileSize bytes.
+func (t *ReadFileTool) readLines(abs string, idx *lineIndex, offset, limit int) ([]string, error) {
+	if offset >= idx.lines || limit <= 0 {
+		return nil, nil
+	}
+	f, err := t.fileOps.Open(abs)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	from := idx.offsets[offset/lineIndexStride]
+	if _, err := f.Seek(from, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	// Stop where the index does, even if lines have been appended since.
+	r := bufio.NewReader(io.LimitReader(f, idx.size-from))
+	for skip := offset % lineIndexStride; skip > 0; {
+		_, err := r.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue // Still in the same long line
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w (changed while reading?)", err)
+		}
+		skip--
+	}
+
+	// A line is cut at MaxLineLength bytes and the lines stop once MaxFileSize
+	// bytes are collected, so a huge or single-line file is not read whole.
+	maxLine := min(int64(t.config.Tools.MaxLineLength), t.config.Tools.MaxFileSize)
+	var data, line []byte
+	var cut bool
+	for n := 0; n < limit; {
+		chunk, err := r.ReadSlice('\n')
+		body := bytes.TrimSuffix(bytes.TrimSuffix(chunk, []byte("\n")), []byte("\r"))
+		if room := maxLine - int64(len(line)); int64(len(body)) > room {
+			line, cut = append(line, body[:max(room, 0)]...), true
+		} else if !cut {
+			line = append(line, chunk...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		if len(line) == 0 {
+			break
+		}
+		if cut {
+			line = append(trimPartialRune(line, idx.enc), truncatedMarker...)
+			if err == nil {
+				line = append(line, '\n')
+			}
+		}
+		if len(data) > 0 && int64(len(data)+len(line)) > t.config.Tools.MaxFileSize {
+			break
+		}
+		data, line, cut = append(data, line...), line[:0], false
+		n++
+		if err != nil {
+			break
+		}
+	}
+
+	text, err := content.DecodeAs(data, idx.enc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode %s: %v", abs, err)
+	}
+	return content.SplitLines(text), nil
+}
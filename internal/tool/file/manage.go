@@ -0,0 +1,624 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// fileManager defines the filesystem operations needed to move, copy and delete files.
+type fileManager interface {
+	Stat(path string) (os.FileInfo, error)
+	ReadFile(path string) ([]byte, error) // For conflict checks
+	ListDir(path string) ([]os.FileInfo, error)
+	EnsureDirs(path string) error
+	Rename(oldPath, newPath string) error
+	CopyFile(src, dst string) error
+	Readlink(path string) (string, error)
+	Symlink(target, link string) error
+	Remove(path string) error
+	RemoveAll(path string) error
+}
+
+// checksumTracker defines the checksum cache operations needed to keep cached
+// checksums in step with files that are moved, copied or deleted.
+type checksumTracker interface {
+	Compute(data []byte) string
+	Get(path string) (checksum string, ok bool)
+	Update(path string, checksum string)
+	Remove(path string)
+	Paths() []string
+}
+
+// fileManagement holds what move_file, copy_file, delete_file and create_directory share.
+type fileManagement struct {
+	fileOps         fileManager
+	checksumManager checksumTracker
+	pathResolver    pathResolver
+	config          *config.Config
+}
+
+func newFileManagement(
+	fileOps fileManager,
+	checksumManager checksumTracker,
+	pathResolver pathResolver,
+	cfg *config.Config,
+) fileManagement {
+	if fileOps == nil {
+		panic("fileOps is required")
+	}
+	if checksumManager == nil {
+		panic("checksumManager is required")
+	}
+	if pathResolver == nil {
+		panic("pathResolver is required")
+	}
+	if cfg == nil {
+		panic("config is required")
+	}
+	return fileManagement{
+		fileOps:         fileOps,
+		checksumManager: checksumManager,
+		pathResolver:    pathResolver,
+		config:          cfg,
+	}
+}
+
+// resolve maps a request path into the workspace. The workspace root itself is refused.
+func (m *fileManagement) resolve(p, verb string) (abs, rel string, err error) {
+	if abs, err = m.pathResolver.Abs(p); err != nil {
+		return "", "", err
+	}
+	if rel, err = m.pathResolver.Rel(abs); err != nil {
+		return "", "", err
+	}
+	if rel == "" {
+		return "", "", fmt.Errorf("cannot %s the workspace root", verb)
+	}
+	return abs, rel, nil
+}
+
+// source stats the path an operation acts on. Directories need recursive set.
+func (m *fileManagement) source(abs, rel string, recursive bool) (os.FileInfo, error) {
+	info, err := m.fileOps.Stat(abs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file does not exist: %s", abs)
+		}
+		return nil, fmt.Errorf("failed to stat %s: %v", abs, err)
+	}
+	if info.IsDir() && !recursive {
+		return nil, fmt.Errorf("%s is a directory; set recursive to act on it and everything in it", rel)
+	}
+	return info, nil
+}
+
+// tracked returns the paths at or below abs that have a cached checksum.
+func (m *fileManagement) tracked(abs string) []string {
+	var paths []string
+	for _, p := range m.checksumManager.Paths() {
+		if p == abs || strings.HasPrefix(p, abs+"/") {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// checkUnchanged fails if a file at or below abs changed since it was last read,
+// as edit_file does. Files that were never read, or are gone, are not checked.
+func (m *fileManagement) checkUnchanged(abs string) error {
+	for _, p := range m.tracked(abs) {
+		prior, ok := m.checksumManager.Get(p)
+		if !ok {
+			continue
+		}
+		data, err := m.fileOps.ReadFile(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot check %s for changes: %v", p, err)
+		}
+		if fileChecksum(m.checksumManager, data) != prior {
+			return fmt.Errorf("edit conflict: file changed since last read: %s", p)
+		}
+	}
+	return nil
+}
+
+// checkDestination checks that a move or copy from src may write dst, and reports
+// whether it replaces an existing file. Only a file may be replaced, with overwrite
+// set, and only if it was read and is unchanged since, as for write_file.
+func (m *fileManagement) checkDestination(src, dst string, srcIsDir, overwrite bool, verb string) (bool, error) {
+	if dst == src {
+		return false, fmt.Errorf("source and destination are the same: %s", src)
+	}
+	if srcIsDir && strings.HasPrefix(dst, src+"/") {
+		return false, fmt.Errorf("cannot %s a directory into itself: %s", verb, dst)
+	}
+
+	info, err := m.fileOps.Stat(dst)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s: %v", dst, err)
+	}
+	if !overwrite {
+		return false, fmt.Errorf("destination already exists: %s (set overwrite to replace it)", dst)
+	}
+	if info.IsDir() {
+		return false, fmt.Errorf("destination is a directory: %s", dst)
+	}
+	if srcIsDir {
+		return false, fmt.Errorf("cannot replace file %s with a directory", dst)
+	}
+	if _, ok := m.checksumManager.Get(dst); !ok {
+		return false, fmt.Errorf("cannot overwrite %s: read the file first", dst)
+	}
+	if err := m.checkUnchanged(dst); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// carryChecksums gives the files now at dst the cached checksums of the files at
+// src they came from. Entries for whatever dst replaced are dropped; src keeps its
+// entries only if keepSource is set.
+func (m *fileManagement) carryChecksums(src, dst string, keepSource bool) {
+	for _, p := range m.tracked(dst) {
+		m.checksumManager.Remove(p)
+	}
+	for _, p := range m.tracked(src) {
+		sum, ok := m.checksumManager.Get(p)
+		if !ok {
+			continue
+		}
+		m.checksumManager.Update(dst+strings.TrimPrefix(p, src), sum)
+		if !keepSource {
+			m.checksumManager.Remove(p)
+		}
+	}
+}
+
+// checkProtected fails if abs, an entry below a directory being moved, copied
+// or deleted, is a protected path the directory would take along with it.
+func (m *fileManagement) checkProtected(abs string) error {
+	if _, err := m.pathResolver.Rel(abs); errors.Is(err, path.ErrProtected) {
+		return err
+	}
+	return nil
+}
+
+// countFiles returns the number of non-directory entries below dir. It fails if
+// one of them is protected.
+func (m *fileManagement) countFiles(ctx context.Context, dir string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	entries, err := m.fileOps.ListDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %v", dir, err)
+	}
+	n := 0
+	for _, e := range entries {
+		if err := m.checkProtected(filepath.Join(dir, e.Name())); err != nil {
+			return 0, err
+		}
+		if !e.IsDir() {
+			n++
+			continue
+		}
+		c, err := m.countFiles(ctx, filepath.Join(dir, e.Name()))
+		if err != nil {
+			return 0, err
+		}
+		n += c
+	}
+	return n, nil
+}
+
+// fail turns an error into a response for the LLM, unless ctx was cancelled.
+func fail(ctx context.Context, err error) (toolmanager.ToolResult, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return &FileOperationResponse{Error: err.Error()}, nil
+}
+
+// -- Move File --
+
+// MoveFileTool moves or renames a file or directory within the workspace.
+type MoveFileTool struct {
+	fileManagement
+}
+
+// NewMoveFileTool creates a new MoveFileTool with injected dependencies.
+func NewMoveFileTool(fileOps fileManager, checksumManager checksumTracker, pathResolver pathResolver, cfg *config.Config) *MoveFileTool {
+	return &MoveFileTool{newFileManagement(fileOps, checksumManager, pathResolver, cfg)}
+}
+
+func (t *MoveFileTool) Name() string {
+	return "move_file"
+}
+
+func (t *MoveFileTool) Declaration() tool.Declaration {
+	return tool.Declaration{
+		Name:        "move_file",
+		Description: "Move or rename a file or directory. Parent directories of the destination are created.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"source":      {Type: tool.TypeString, Description: "Path to move"},
+				"destination": {Type: tool.TypeString, Description: "New path"},
+				"recursive":   {Type: tool.TypeBoolean, Description: "Required to move a directory with everything in it"},
+				"overwrite":   {Type: tool.TypeBoolean, Description: "Replace an existing destination file; it must have been read first and be unchanged since"},
+			},
+			Required: []string{"source", "destination"},
+		},
+	}
+}
+
+func (t *MoveFileTool) Request() toolmanager.ToolRequest {
+	return &MoveFileRequest{}
+}
+
+// Execute renames the source to the destination. Files the agent has read must be
+// unchanged since, as for edit_file, and their cached checksums follow them.
+//
+// Note: The rename is atomic, but only within one filesystem.
+func (t *MoveFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*MoveFileRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+	if err := r.Validate(t.config); err != nil {
+		return &FileOperationResponse{Error: err.Error()}, nil
+	}
+
+	src, srcRel, err := t.resolve(r.Source, "move")
+	if err != nil {
+		return fail(ctx, err)
+	}
+	dst, dstRel, err := t.resolve(r.Destination, "move")
+	if err != nil {
+		return fail(ctx, err)
+	}
+	info, err := t.source(src, srcRel, r.Recursive)
+	if err != nil {
+		return fail(ctx, err)
+	}
+	replaced, err := t.checkDestination(src, dst, info.IsDir(), r.Overwrite, "move")
+	if err != nil {
+		return fail(ctx, err)
+	}
+	if err := t.checkUnchanged(src); err != nil {
+		return fail(ctx, err)
+	}
+
+	resp := &FileOperationResponse{Action: "Moved", Path: dstRel, From: srcRel, IsDir: info.IsDir(), Replaced: replaced}
+	if info.IsDir() {
+		if resp.Files, err = t.countFiles(ctx, src); err != nil {
+			return fail(ctx, err)
+		}
+	}
+
+	if err := t.fileOps.EnsureDirs(filepath.Dir(dst)); err != nil {
+		return fail(ctx, fmt.Errorf("failed to create directories for %s: %v", dst, err))
+	}
+	if err := t.fileOps.Rename(src, dst); err != nil {
+		return fail(ctx, fmt.Errorf("failed to move %s to %s: %v", src, dst, err))
+	}
+
+	t.carryChecksums(src, dst, false)
+	return resp, nil
+}
+
+// -- Copy File --
+
+// CopyFileTool copies a file or directory within the workspace.
+type CopyFileTool struct {
+	fileManagement
+}
+
+// NewCopyFileTool creates a new CopyFileTool with injected dependencies.
+func NewCopyFileTool(fileOps fileManager, checksumManager checksumTracker, pathResolver pathResolver, cfg *config.Config) *CopyFileTool {
+	return &CopyFileTool{newFileManagement(fileOps, checksumManager, pathResolver, cfg)}
+}
+
+func (t *CopyFileTool) Name() string {
+	return "copy_file"
+}
+
+func (t *CopyFileTool) Declaration() tool.Declaration {
+	return tool.Declaration{
+		Name:        "copy_file",
+		Description: "Copy a file or directory. Parent directories of the destination are created.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"source":      {Type: tool.TypeString, Description: "Path to copy"},
+				"destination": {Type: tool.TypeString, Description: "Path of the copy"},
+				"recursive":   {Type: tool.TypeBoolean, Description: "Required to copy a directory with everything in it"},
+				"overwrite":   {Type: tool.TypeBoolean, Description: "Replace an existing destination file; it must have been read first and be unchanged since"},
+			},
+			Required: []string{"source", "destination"},
+		},
+	}
+}
+
+func (t *CopyFileTool) Request() toolmanager.ToolRequest {
+	return &CopyFileRequest{}
+}
+
+// Execute copies the source to the destination. Files are copied byte for byte
+// with their permissions, whatever their size or content; symlinks inside a
+// copied directory are recreated, not followed, and the copy is refused if one
+// of them leads outside the workspace from where it lands. A directory copy
+// that fails part-way is removed again.
+func (t *CopyFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*CopyFileRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+	if err := r.Validate(t.config); err != nil {
+		return &FileOperationResponse{Error: err.Error()}, nil
+	}
+
+	src, srcRel, err := t.resolve(r.Source, "copy")
+	if err != nil {
+		return fail(ctx, err)
+	}
+	dst, dstRel, err := t.resolve(r.Destination, "copy")
+	if err != nil {
+		return fail(ctx, err)
+	}
+	info, err := t.source(src, srcRel, r.Recursive)
+	if err != nil {
+		return fail(ctx, err)
+	}
+	replaced, err := t.checkDestination(src, dst, info.IsDir(), r.Overwrite, "copy")
+	if err != nil {
+		return fail(ctx, err)
+	}
+
+	if err := t.fileOps.EnsureDirs(filepath.Dir(dst)); err != nil {
+		return fail(ctx, fmt.Errorf("failed to create directories for %s: %v", dst, err))
+	}
+
+	resp := &FileOperationResponse{Action: "Copied", Path: dstRel, From: srcRel, IsDir: info.IsDir(), Replaced: replaced}
+	if info.IsDir() {
+		var links []string
+		resp.Files, err = t.copyTree(ctx, src, dst, &links)
+		if err == nil {
+			err = t.checkLinks(links)
+		}
+		if err != nil {
+			_ = t.fileOps.RemoveAll(dst) // The destination did not exist before
+			return fail(ctx, err)
+		}
+	} else if err := t.fileOps.CopyFile(src, dst); err != nil {
+		return fail(ctx, fmt.Errorf("failed to copy %s to %s: %v", src, dst, err))
+	}
+
+	t.carryChecksums(src, dst, true)
+	return resp, nil
+}
+
+// copyTree copies the directory src to dst and returns the number of files copied.
+// The symlinks it creates are appended to links.
+func (t *CopyFileTool) copyTree(ctx context.Context, src, dst string, links *[]string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := t.fileOps.EnsureDirs(dst); err != nil {
+		return 0, fmt.Errorf("failed to create %s: %v", dst, err)
+	}
+	entries, err := t.fileOps.ListDir(src)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %v", src, err)
+	}
+
+	n := 0
+	for _, e := range entries {
+		from, to := filepath.Join(src, e.Name()), filepath.Join(dst, e.Name())
+		if err := t.checkProtected(from); err != nil {
+			return 0, err
+		}
+		switch {
+		case e.IsDir():
+			c, err := t.copyTree(ctx, from, to, links)
+			if err != nil {
+				return 0, err
+			}
+			n += c
+		case e.Mode()&os.ModeSymlink != 0:
+			target, err := t.fileOps.Readlink(from)
+			if err != nil {
+				return 0, fmt.Errorf("failed to read link %s: %v", from, err)
+			}
+			if err := t.fileOps.Symlink(target, to); err != nil {
+				return 0, fmt.Errorf("failed to create link %s: %v", to, err)
+			}
+			*links = append(*links, to)
+			n++
+		case e.Mode().IsRegular():
+			if err := t.fileOps.CopyFile(from, to); err != nil {
+				return 0, fmt.Errorf("failed to copy %s: %v", from, err)
+			}
+			n++
+		default:
+			return 0, fmt.Errorf("cannot copy special file: %s", from)
+		}
+	}
+	return n, nil
+}
+
+// checkLinks refuses copied symlinks that lead outside the workspace. A relative
+// target that stays inside from the source can escape from the destination, and
+// a link can lead through another, so they are resolved once the whole tree is
+// in place.
+func (t *CopyFileTool) checkLinks(links []string) error {
+	for _, link := range links {
+		if _, err := t.pathResolver.Abs(link); err != nil {
+			return fmt.Errorf("cannot copy link %s: %w", link, err)
+		}
+	}
+	return nil
+}
+
+// -- Delete File --
+
+// DeleteFileTool deletes a file or directory in the workspace.
+type DeleteFileTool struct {
+	fileManagement
+}
+
+// NewDeleteFileTool creates a new DeleteFileTool with injected dependencies.
+func NewDeleteFileTool(fileOps fileManager, checksumManager checksumTracker, pathResolver pathResolver, cfg *config.Config) *DeleteFileTool {
+	return &DeleteFileTool{newFileManagement(fileOps, checksumManager, pathResolver, cfg)}
+}
+
+func (t *DeleteFileTool) Name() string {
+	return "delete_file"
+}
+
+func (t *DeleteFileTool) Declaration() tool.Declaration {
+	return tool.Declaration{
+		Name:        "delete_file",
+		Description: "Delete a file, or a directory with everything in it.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"path":      {Type: tool.TypeString, Description: "Path to delete"},
+				"recursive": {Type: tool.TypeBoolean, Description: "Required to delete a directory with everything in it"},
+			},
+			Required: []string{"path"},
+		},
+	}
+}
+
+func (t *DeleteFileTool) Request() toolmanager.ToolRequest {
+	return &DeleteFileRequest{}
+}
+
+// Execute deletes the path. Files the agent has read must be unchanged since, as
+// for edit_file; their cached checksums are dropped.
+func (t *DeleteFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*DeleteFileRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+	if err := r.Validate(t.config); err != nil {
+		return &FileOperationResponse{Error: err.Error()}, nil
+	}
+
+	abs, rel, err := t.resolve(r.Path, "delete")
+	if err != nil {
+		return fail(ctx, err)
+	}
+	info, err := t.source(abs, rel, r.Recursive)
+	if err != nil {
+		return fail(ctx, err)
+	}
+	if err := t.checkUnchanged(abs); err != nil {
+		return fail(ctx, err)
+	}
+
+	resp := &FileOperationResponse{Action: "Deleted", Path: rel, IsDir: info.IsDir()}
+	if info.IsDir() {
+		if resp.Files, err = t.countFiles(ctx, abs); err != nil {
+			return fail(ctx, err)
+		}
+		err = t.fileOps.RemoveAll(abs)
+	} else {
+		err = t.fileOps.Remove(abs)
+	}
+	if err != nil {
+		return fail(ctx, fmt.Errorf("failed to delete %s: %v", abs, err))
+	}
+
+	for _, p := range t.tracked(abs) {
+		t.checksumManager.Remove(p)
+	}
+	return resp, nil
+}
+
+// -- Create Directory --
+
+// CreateDirectoryTool creates a directory, with its parents, in the workspace.
+type CreateDirectoryTool struct {
+	fileManagement
+}
+
+// NewCreateDirectoryTool creates a new CreateDirectoryTool with injected dependencies.
+func NewCreateDirectoryTool(fileOps fileManager, checksumManager checksumTracker, pathResolver pathResolver, cfg *config.Config) *CreateDirectoryTool {
+	return &CreateDirectoryTool{newFileManagement(fileOps, checksumManager, pathResolver, cfg)}
+}
+
+func (t *CreateDirectoryTool) Name() string {
+	return "create_directory"
+}
+
+func (t *CreateDirectoryTool) Declaration() tool.Declaration {
+	return tool.Declaration{
+		Name:        "create_directory",
+		Description: "Create a directory and any missing parent directories. An existing directory is left as it is.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"path": {Type: tool.TypeString, Description: "Path of the directory"},
+			},
+			Required: []string{"path"},
+		},
+	}
+}
+
+func (t *CreateDirectoryTool) Request() toolmanager.ToolRequest {
+	return &CreateDirectoryRequest{}
+}
+
+// Execute creates the directory. It fails if a file is in the way.
+func (t *CreateDirectoryTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*CreateDirectoryRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+	if err := r.Validate(t.config); err != nil {
+		return &FileOperationResponse{Error: err.Error()}, nil
+	}
+
+	abs, rel, err := t.resolve(r.Path, "create")
+	if err != nil {
+		return fail(ctx, err)
+	}
+
+	resp := &FileOperationResponse{Action: "Created", Path: rel, IsDir: true}
+	info, err := t.fileOps.Stat(abs)
+	switch {
+	case err == nil && info.IsDir():
+		resp.Existed = true
+		return resp, nil
+	case err == nil:
+		return fail(ctx, fmt.Errorf("a file already exists at %s", abs))
+	case !os.IsNotExist(err):
+		return fail(ctx, fmt.Errorf("failed to stat %s: %v", abs, err))
+	}
+
+	if err := t.fileOps.EnsureDirs(abs); err != nil {
+		return fail(ctx, fmt.Errorf("failed to create %s: %v", abs, err))
+	}
+	return resp, nil
+}
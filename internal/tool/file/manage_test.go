@@ -0,0 +1,290 @@
+package file
+
+// Mocks are defined in write_test.go and shared across all test files in this package.
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+func executeFileOp(t *testing.T, ftool toolmanager.Tool, req toolmanager.ToolRequest) *FileOperationResponse {
+	t.Helper()
+	result, err := ftool.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	resp, ok := result.(*FileOperationResponse)
+	if !ok {
+		t.Fatalf("Execute returned wrong type: %T", result)
+	}
+	return resp
+}
+
+// escapingResolver reports link as leading outside the workspace, as the real
+// resolver does for a symlink whose target escapes.
+type escapingResolver struct {
+	*path.Resolver
+	link string
+}
+
+func (r *escapingResolver) Abs(p string) (string, error) {
+	if p == r.link {
+		return "", path.ErrSymlinkEscape
+	}
+	return r.Resolver.Abs(p)
+}
+
+func TestFileManagement(t *testing.T) {
+	setup := func() (*mockFileSystemForWrite, *mockChecksumManagerForWrite, *config.Config) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createDir("/workspace")
+		fs.createDir("/workspace/pkg")
+		fs.createDir("/workspace/pkg/util")
+		fs.createFile("/workspace/pkg/a.go", []byte("package pkg\n"), 0o644)
+		fs.createFile("/workspace/pkg/util/b.go", []byte("package util\n"), 0o600)
+		fs.createSymlink("/workspace/pkg/link", "a.go")
+		fs.createFile("/workspace/README.md", []byte("# readme\n"), 0o644)
+		// The agent has read a.go and README.md.
+		checksumManager.Update("/workspace/pkg/a.go", checksumManager.Compute([]byte("package pkg\n")))
+		checksumManager.Update("/workspace/README.md", checksumManager.Compute([]byte("# readme\n")))
+		return fs, checksumManager, cfg
+	}
+	resolver := path.NewResolver("/workspace")
+
+	t.Run("move file carries its checksum", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		mtool := NewMoveFileTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, mtool, &MoveFileRequest{Source: "README.md", Destination: "docs/README.md"})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/docs/README.md", "# readme\n")
+		if _, err := fs.Stat("/workspace/README.md"); err == nil {
+			t.Errorf("expected source to be gone")
+		}
+		if _, ok := checksumManager.Get("/workspace/README.md"); ok {
+			t.Errorf("expected checksum of source to be removed")
+		}
+		if _, ok := checksumManager.Get("/workspace/docs/README.md"); !ok {
+			t.Errorf("expected checksum to follow the file")
+		}
+		if got := resp.LLMContent(); got != "Moved README.md to docs/README.md" {
+			t.Errorf("unexpected LLMContent: %q", got)
+		}
+	})
+
+	t.Run("directories need recursive", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		mtool := NewMoveFileTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, mtool, &MoveFileRequest{Source: "pkg", Destination: "lib"})
+		if !strings.Contains(resp.Error, "set recursive") {
+			t.Errorf("expected recursive error, got: %q", resp.Error)
+		}
+
+		resp = executeFileOp(t, mtool, &MoveFileRequest{Source: "pkg", Destination: "lib", Recursive: true})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/lib/util/b.go", "package util\n")
+		if _, ok := checksumManager.Get("/workspace/lib/a.go"); !ok {
+			t.Errorf("expected checksum to follow a.go")
+		}
+		if _, ok := checksumManager.Get("/workspace/pkg/a.go"); ok {
+			t.Errorf("expected old checksum to be removed")
+		}
+		if got := resp.LLMContent(); got != "Moved directory pkg to lib (3 files)" {
+			t.Errorf("unexpected LLMContent: %q", got)
+		}
+	})
+
+	t.Run("move refuses a file changed since it was read", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		fs.createFile("/workspace/pkg/a.go", []byte("package pkg // changed\n"), 0o644)
+		mtool := NewMoveFileTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, mtool, &MoveFileRequest{Source: "pkg", Destination: "lib", Recursive: true})
+		if !strings.Contains(resp.Error, "edit conflict") {
+			t.Errorf("expected conflict error, got: %q", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/pkg/a.go", "package pkg // changed\n")
+	})
+
+	t.Run("overwrite needs the destination read", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		mtool := NewMoveFileTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, mtool, &MoveFileRequest{Source: "pkg/util/b.go", Destination: "pkg/a.go"})
+		if !strings.Contains(resp.Error, "destination already exists") {
+			t.Errorf("expected exists error, got: %q", resp.Error)
+		}
+
+		resp = executeFileOp(t, mtool, &MoveFileRequest{Source: "README.md", Destination: "pkg/util/b.go", Overwrite: true})
+		if !strings.Contains(resp.Error, "read the file first") {
+			t.Errorf("expected read-first error, got: %q", resp.Error)
+		}
+
+		resp = executeFileOp(t, mtool, &MoveFileRequest{Source: "pkg/util/b.go", Destination: "pkg/a.go", Overwrite: true})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/pkg/a.go", "package util\n")
+		if _, ok := checksumManager.Get("/workspace/pkg/a.go"); ok {
+			t.Errorf("expected checksum of replaced file to be dropped, since b.go was never read")
+		}
+		assertContains(t, resp.LLMContent(), "replacing the existing file")
+	})
+
+	t.Run("copy directory keeps source checksums and recreates links", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		ctool := NewCopyFileTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, ctool, &CopyFileRequest{Source: "pkg", Destination: "vendor/pkg", Recursive: true})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/pkg/a.go", "package pkg\n")
+		assertFile(t, fs, "/workspace/vendor/pkg/a.go", "package pkg\n")
+		if fs.files["/workspace/vendor/pkg/util/b.go"].mode != 0o600 {
+			t.Errorf("expected permissions to be copied")
+		}
+		if link, ok := fs.symlinks["/workspace/vendor/pkg/link"]; !ok || link.target != "a.go" {
+			t.Errorf("expected symlink to be recreated, got %+v", link)
+		}
+		for _, p := range []string{"/workspace/pkg/a.go", "/workspace/vendor/pkg/a.go"} {
+			if _, ok := checksumManager.Get(p); !ok {
+				t.Errorf("expected checksum for %s", p)
+			}
+		}
+		if resp.Files != 3 {
+			t.Errorf("expected 3 files copied, got %d", resp.Files)
+		}
+	})
+
+	t.Run("copy refuses a link that escapes from the destination", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		fs.createSymlink("/workspace/pkg/up", "../../secret") // Inside from pkg, outside from vendor/pkg
+		escapes := &escapingResolver{Resolver: resolver, link: "/workspace/vendor/pkg/up"}
+		ctool := NewCopyFileTool(fs, checksumManager, escapes, cfg)
+
+		resp := executeFileOp(t, ctool, &CopyFileRequest{Source: "pkg", Destination: "vendor/pkg", Recursive: true})
+		if !strings.Contains(resp.Error, "cannot copy link /workspace/vendor/pkg/up") {
+			t.Errorf("expected link error, got: %q", resp.Error)
+		}
+		if _, err := fs.Stat("/workspace/vendor/pkg"); err == nil {
+			t.Errorf("expected the partial copy to be removed")
+		}
+	})
+
+	t.Run("copy into itself fails", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		ctool := NewCopyFileTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, ctool, &CopyFileRequest{Source: "pkg", Destination: "pkg/util/pkg", Recursive: true})
+		if !strings.Contains(resp.Error, "into itself") {
+			t.Errorf("expected error, got: %q", resp.Error)
+		}
+	})
+
+	t.Run("delete drops checksums and checks for conflicts", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		dtool := NewDeleteFileTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, dtool, &DeleteFileRequest{Path: "pkg"})
+		if !strings.Contains(resp.Error, "set recursive") {
+			t.Errorf("expected recursive error, got: %q", resp.Error)
+		}
+
+		fs.createFile("/workspace/README.md", []byte("# edited elsewhere\n"), 0o644)
+		resp = executeFileOp(t, dtool, &DeleteFileRequest{Path: "README.md"})
+		if !strings.Contains(resp.Error, "edit conflict") {
+			t.Errorf("expected conflict error, got: %q", resp.Error)
+		}
+
+		resp = executeFileOp(t, dtool, &DeleteFileRequest{Path: "pkg", Recursive: true})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		if _, err := fs.Stat("/workspace/pkg/util/b.go"); err == nil {
+			t.Errorf("expected directory contents to be deleted")
+		}
+		if _, ok := checksumManager.Get("/workspace/pkg/a.go"); ok {
+			t.Errorf("expected checksum to be removed")
+		}
+	})
+
+	t.Run("create directory", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		mkdir := NewCreateDirectoryTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, mkdir, &CreateDirectoryRequest{Path: "a/b/c"})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		if !fs.dirs["/workspace/a/b/c"] {
+			t.Errorf("expected directory to be created")
+		}
+
+		resp = executeFileOp(t, mkdir, &CreateDirectoryRequest{Path: "pkg"})
+		if resp.Error != "" || resp.LLMContent() != "Directory pkg already exists" {
+			t.Errorf("unexpected response: %q", resp.LLMContent())
+		}
+
+		resp = executeFileOp(t, mkdir, &CreateDirectoryRequest{Path: "README.md"})
+		if !strings.Contains(resp.Error, "a file already exists") {
+			t.Errorf("expected error, got: %q", resp.Error)
+		}
+	})
+
+	t.Run("workspace boundaries", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		dtool := NewDeleteFileTool(fs, checksumManager, resolver, cfg)
+		mtool := NewMoveFileTool(fs, checksumManager, resolver, cfg)
+
+		resp := executeFileOp(t, dtool, &DeleteFileRequest{Path: ".", Recursive: true})
+		if !strings.Contains(resp.Error, "workspace root") {
+			t.Errorf("expected root error, got: %q", resp.Error)
+		}
+		resp = executeFileOp(t, mtool, &MoveFileRequest{Source: "README.md", Destination: "../README.md"})
+		if resp.Error == "" {
+			t.Errorf("expected error moving outside the workspace")
+		}
+		if _, err := fs.Stat("/workspace/README.md"); err != nil {
+			t.Errorf("expected file to stay")
+		}
+	})
+
+	t.Run("protected paths", func(t *testing.T) {
+		fs, checksumManager, cfg := setup()
+		fs.createFile("/workspace/pkg/.env", []byte("TOKEN=secret\n"), 0o600)
+		guard := path.NewGuard(resolver, path.NewProtection(config.DefaultProtectedPaths()), path.AccessRead|path.AccessWrite)
+		dtool := NewDeleteFileTool(fs, checksumManager, guard, cfg)
+		ctool := NewCopyFileTool(fs, checksumManager, guard, cfg)
+
+		resp := executeFileOp(t, dtool, &DeleteFileRequest{Path: "pkg/.env"})
+		if !strings.Contains(resp.Error, "path is protected") {
+			t.Errorf("expected protected error, got: %q", resp.Error)
+		}
+		// A directory cannot take a protected file along with it.
+		resp = executeFileOp(t, dtool, &DeleteFileRequest{Path: "pkg", Recursive: true})
+		if !strings.Contains(resp.Error, "path is protected") {
+			t.Errorf("expected protected error, got: %q", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/pkg/.env", "TOKEN=secret\n")
+		resp = executeFileOp(t, ctool, &CopyFileRequest{Source: "pkg", Destination: "lib", Recursive: true})
+		if !strings.Contains(resp.Error, "path is protected") {
+			t.Errorf("expected protected error, got: %q", resp.Error)
+		}
+		if _, err := fs.Stat("/workspace/lib"); err == nil {
+			t.Errorf("expected partial copy to be removed")
+		}
+	})
+}
@@ -0,0 +1,412 @@
+package file
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// matchStrategy names how an edit's before text was located in the file.
+// Strategies are tried in declaration order; the first that finds anything wins.
+type matchStrategy string
+
+const (
+	matchExact              matchStrategy = "exact"
+	matchTrailingWhitespace matchStrategy = "trailing-whitespace"
+	matchIndentation        matchStrategy = "indentation"
+	matchFuzzy              matchStrategy = "fuzzy"
+)
+
+// span is a byte range [start, end) of the content being edited.
+type span struct {
+	start, end int
+}
+
+// lineSpan is one line of the content without its newline.
+type lineSpan struct {
+	text       string
+	start, end int
+}
+
+// window is a run of lines [first, first+n) and its similarity to the before text.
+type window struct {
+	first, n int
+	score    float64
+}
+
+// matchResult is the outcome of locating before in the content.
+type matchResult struct {
+	strategy matchStrategy
+	spans    []span
+	windows  []window // Line windows behind spans; nil for exact matches
+
+	// Replacement text for each span of an indentation or fuzzy match: after
+	// re-indented to that span's own indentation and, for fuzzy matches, applied
+	// to the matched lines so context lines keep the file's text. Nil otherwise.
+	replacements []string
+
+	closest *window // Most similar region when nothing matched
+	refused string  // Why a fuzzy candidate scoring above the threshold was not used
+}
+
+// findMatches locates before in content using the cascade: exact, ignoring trailing
+// whitespace, normalising indentation, then line windows at least threshold similar.
+// Line-based strategies match whole lines only.
+//
+// A fuzzy match is only used for a snippet of several lines whose lines that the
+// edit removes or changes, going by after, match the file exactly; only the context
+// lines may differ. Otherwise a one-token difference such as "replicas: 3" against
+// "replicas: 5" would edit a different value than the one the LLM meant.
+func findMatches(content, before, after string, threshold float64) matchResult {
+	if n := strings.Count(content, before); n > 0 {
+		res := matchResult{strategy: matchExact}
+		for i, from := 0, 0; i < n; i++ {
+			idx := strings.Index(content[from:], before) + from
+			res.spans = append(res.spans, span{idx, idx + len(before)})
+			from = idx + len(before)
+		}
+		return res
+	}
+
+	lines := splitLineSpans(content)
+	beforeLines := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	keepNewline := strings.HasSuffix(before, "\n")
+
+	trimmed := trimRightAll(beforeLines)
+	if res := matchLines(lines, len(beforeLines), func(w []string) bool {
+		return equalLines(trimRightAll(w), trimmed)
+	}); len(res) > 0 {
+		return matchResult{strategy: matchTrailingWhitespace, spans: toSpans(content, lines, res, keepNewline), windows: res}
+	}
+
+	beforeIndent, dedented := dedent(beforeLines)
+	if res := matchLines(lines, len(beforeLines), func(w []string) bool {
+		_, d := dedent(w)
+		return equalLines(d, dedented)
+	}); len(res) > 0 {
+		m := matchResult{strategy: matchIndentation, spans: toSpans(content, lines, res, keepNewline), windows: res}
+		// Each occurrence may sit at its own depth.
+		for _, w := range res {
+			replacement := after
+			if fileIndent, _ := dedent(windowTexts(lines, w)); fileIndent != beforeIndent {
+				replacement = reindentText(after, beforeIndent, fileIndent)
+			}
+			m.replacements = append(m.replacements, replacement)
+		}
+		return m
+	}
+
+	candidates, closest := similarWindows(lines, dedented, threshold)
+	if len(candidates) == 0 {
+		return matchResult{closest: closest}
+	}
+	if len(beforeLines) < 2 {
+		return matchResult{closest: closest, refused: "a fuzzy match is not used for a one-line snippet"}
+	}
+	ops := lineOpCodes(trimRightAll(beforeLines), afterLines(after))
+	for _, w := range candidates {
+		_, d := dedent(windowTexts(lines, w))
+		if !changedLinesMatch(ops, dedented, d) {
+			return matchResult{closest: &w, refused: "a fuzzy match is only used when the lines being changed match the file exactly and only context lines differ"}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].first < candidates[j].first })
+	res := matchResult{strategy: matchFuzzy, spans: toSpans(content, lines, candidates, keepNewline), windows: candidates}
+	for _, w := range candidates {
+		res.replacements = append(res.replacements, mergeWindow(lines, w, ops, after, beforeIndent))
+	}
+	return res
+}
+
+// afterLines splits after into lines like before, with none for an empty after.
+func afterLines(after string) []string {
+	after = strings.TrimSuffix(after, "\n")
+	if after == "" {
+		return nil
+	}
+	return trimRightAll(strings.Split(after, "\n"))
+}
+
+// lineOpCodes diffs the lines of before against those of after.
+func lineOpCodes(before, after []string) []difflib.OpCode {
+	return difflib.NewMatcherWithJunk(before, after, false, nil).GetOpCodes()
+}
+
+// changedLinesMatch reports whether every line of before that ops removes or
+// replaces equals the line at the same position of the window.
+func changedLinesMatch(ops []difflib.OpCode, before, window []string) bool {
+	for _, op := range ops {
+		if op.Tag == 'e' {
+			continue
+		}
+		for i := op.I1; i < op.I2; i++ {
+			if before[i] != window[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mergeWindow applies ops to the lines of w: context lines are kept as they are
+// in the file and the lines of after replace the rest, re-indented to the window.
+func mergeWindow(lines []lineSpan, w window, ops []difflib.OpCode, after, beforeIndent string) string {
+	texts := windowTexts(lines, w)
+	fileIndent, _ := dedent(texts)
+	raw := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+	var out []string
+	for _, op := range ops {
+		if op.Tag == 'e' {
+			out = append(out, texts[op.I1:op.I2]...)
+			continue
+		}
+		for _, l := range raw[op.J1:op.J2] {
+			out = append(out, reindentText(l, beforeIndent, fileIndent))
+		}
+	}
+	text := strings.Join(out, "\n")
+	if strings.HasSuffix(after, "\n") {
+		text += "\n"
+	}
+	return text
+}
+
+// apply replaces every matched span with after, or with the span's own
+// replacement when the match was found at a different indentation or fuzzily.
+func (m matchResult) apply(content, after string) string {
+	var sb strings.Builder
+	prev := 0
+	for i, s := range m.spans {
+		sb.WriteString(content[prev:s.start])
+		if m.replacements != nil {
+			sb.WriteString(m.replacements[i])
+		} else {
+			sb.WriteString(after)
+		}
+		prev = s.end
+	}
+	sb.WriteString(content[prev:])
+	return sb.String()
+}
+
+// describe explains a non-exact match to the LLM.
+func (m matchResult) describe() string {
+	switch m.strategy {
+	case matchTrailingWhitespace:
+		return "matched ignoring trailing whitespace"
+	case matchIndentation:
+		return "matched after normalising indentation; the replacement was re-indented to fit"
+	case matchFuzzy:
+		w := m.windows[0]
+		return fmt.Sprintf("fuzzy match (%.0f%% similar) at lines %d-%d; only context lines differed and they were kept as in the file", w.score*100, w.first+1, w.first+w.n)
+	default:
+		return "exact match"
+	}
+}
+
+// notFoundDiagnostic shows the region of content most similar to before, with line
+// numbers and a diff, so the LLM can correct its snippet.
+func notFoundDiagnostic(content, before string, closest *window, refused string) string {
+	if closest == nil {
+		return ""
+	}
+	lines := splitLineSpans(content)
+	region := windowTexts(lines, *closest)
+
+	var sb strings.Builder
+	if refused != "" {
+		fmt.Fprintf(&sb, "\nThe closest region was not edited: %s. Copy the exact text from the file.", refused)
+	}
+	fmt.Fprintf(&sb, "\nClosest match (lines %d-%d, %.0f%% similar):\n", closest.first+1, closest.first+closest.n, closest.score*100)
+	for i, line := range region {
+		fmt.Fprintf(&sb, "%05d| %s\n", closest.first+i+1, line)
+	}
+
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSuffix(before, "\n") + "\n"),
+		B:        difflib.SplitLines(strings.Join(region, "\n") + "\n"),
+		FromFile: "before",
+		ToFile:   fmt.Sprintf("file lines %d-%d", closest.first+1, closest.first+closest.n),
+		Context:  3,
+	})
+	sb.WriteString("Diff from before to the closest match:\n")
+	sb.WriteString(diff)
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func splitLineSpans(content string) []lineSpan {
+	var lines []lineSpan
+	start := 0
+	for {
+		i := strings.IndexByte(content[start:], '\n')
+		if i < 0 {
+			return append(lines, lineSpan{content[start:], start, len(content)})
+		}
+		lines = append(lines, lineSpan{content[start : start+i], start, start + i})
+		start += i + 1
+	}
+}
+
+// matchLines returns non-overlapping windows of n lines accepted by eq, in file order.
+func matchLines(lines []lineSpan, n int, eq func(window []string) bool) []window {
+	var res []window
+	for i := 0; i+n <= len(lines); {
+		if eq(windowTexts(lines, window{first: i, n: n})) {
+			res = append(res, window{first: i, n: n, score: 1})
+			i += n
+			continue
+		}
+		i++
+	}
+	return res
+}
+
+// fuzzyTieMargin is how close to the best score another window must be for the
+// fuzzy match to count it as a further occurrence rather than a worse candidate.
+const fuzzyTieMargin = 0.02
+
+// similarWindows scores every window of len(dedented) lines against the dedented
+// before text. It returns the non-overlapping windows scoring at least threshold and
+// within fuzzyTieMargin of the best, best first, and the best window regardless of threshold.
+func similarWindows(lines []lineSpan, dedented []string, threshold float64) ([]window, *window) {
+	n := min(len(dedented), len(lines))
+	target := strings.Split(strings.Join(dedented, "\n"), "")
+
+	// The target is the matcher's second sequence so its index is built once.
+	m := difflib.NewMatcherWithJunk(nil, target, false, nil)
+
+	var scored []window
+	var best *window
+	for i := 0; i+n <= len(lines); i++ {
+		_, d := dedent(windowTexts(lines, window{first: i, n: n}))
+		m.SetSeq1(strings.Split(strings.Join(d, "\n"), ""))
+
+		// Only windows that could be a match or beat the closest so far need a full score.
+		if best != nil {
+			floor := min(threshold, best.score)
+			if m.RealQuickRatio() < floor || m.QuickRatio() < floor {
+				continue
+			}
+		}
+		w := window{first: i, n: n, score: m.Ratio()}
+		if best == nil || w.score > best.score {
+			best = &w
+		}
+		if w.score >= threshold {
+			scored = append(scored, w)
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	var picked []window
+	for _, w := range scored {
+		if w.score < best.score-fuzzyTieMargin {
+			break
+		}
+		overlaps := false
+		for _, p := range picked {
+			if w.first < p.first+p.n && p.first < w.first+w.n {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			picked = append(picked, w)
+		}
+	}
+	return picked, best
+}
+
+// toSpans converts line windows to byte spans. When keepNewline is set the span
+// includes the newline after the last line, mirroring a before text that ends in one.
+func toSpans(content string, lines []lineSpan, windows []window, keepNewline bool) []span {
+	spans := make([]span, len(windows))
+	for i, w := range windows {
+		end := lines[w.first+w.n-1].end
+		if keepNewline && end < len(content) {
+			end++
+		}
+		spans[i] = span{lines[w.first].start, end}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans
+}
+
+func windowTexts(lines []lineSpan, w window) []string {
+	texts := make([]string, w.n)
+	for i := range texts {
+		texts[i] = lines[w.first+i].text
+	}
+	return texts
+}
+
+func trimRightAll(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.TrimRight(l, " \t")
+	}
+	return out
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// dedent removes the longest leading whitespace shared by all non-blank lines and
+// trailing whitespace from every line. It returns the removed prefix and the lines.
+func dedent(lines []string) (indent string, out []string) {
+	out = trimRightAll(lines)
+	first := true
+	for _, l := range out {
+		if l == "" {
+			continue
+		}
+		lead := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			indent, first = lead, false
+			continue
+		}
+		indent = commonPrefix(indent, lead)
+	}
+	for i, l := range out {
+		out[i] = strings.TrimPrefix(l, indent)
+	}
+	return indent, out
+}
+
+func commonPrefix(a, b string) string {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return a[:i]
+		}
+	}
+	return a[:n]
+}
+
+// reindentText moves every non-blank line of text from indentation from to to.
+// Lines not starting with from keep their own indentation under to.
+func reindentText(text, from, to string) string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		lines[i] = to + strings.TrimPrefix(l, from)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,176 @@
+package file
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindMatches_Cascade(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		before   string
+		after    string
+		strategy matchStrategy
+		want     string
+	}{
+		{
+			name:     "exact",
+			content:  "a\nb\nc\n",
+			before:   "b\n",
+			after:    "B\n",
+			strategy: matchExact,
+			want:     "a\nB\nc\n",
+		},
+		{
+			name:     "trailing whitespace in file",
+			content:  "func f() {  \n\treturn 1\t\n}\n",
+			before:   "func f() {\n\treturn 1\n}",
+			after:    "func f() {\n\treturn 2\n}",
+			strategy: matchTrailingWhitespace,
+			want:     "func f() {\n\treturn 2\n}\n",
+		},
+		{
+			name:     "indentation drift is re-indented",
+			content:  "class A:\n    def f(self):\n        return 1\n",
+			before:   "def f(self):\n    return 1\n",
+			after:    "def f(self):\n    return 2\n",
+			strategy: matchIndentation,
+			want:     "class A:\n    def f(self):\n        return 2\n",
+		},
+		{
+			name:     "fuzzy match keeps the file's context lines",
+			content:  "one\nresult := compute(alpha, beta)\nreturn result\nthree\n",
+			before:   "result := compute(alpha, betta)\nreturn result",
+			after:    "result := compute(alpha, betta)\nreturn result * 2",
+			strategy: matchFuzzy,
+			want:     "one\nresult := compute(alpha, beta)\nreturn result * 2\nthree\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := findMatches(tt.content, tt.before, tt.after, 0.9)
+			if m.strategy != tt.strategy {
+				t.Fatalf("strategy = %q, want %q", m.strategy, tt.strategy)
+			}
+			if len(m.spans) != 1 {
+				t.Fatalf("got %d matches, want 1", len(m.spans))
+			}
+			if got := m.apply(tt.content, tt.after); got != tt.want {
+				t.Errorf("apply = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMatches_NonExactCountsEveryOccurrence(t *testing.T) {
+	content := "x := 1\ny := 2\nx := 1\t\n"
+
+	m := findMatches(content, "x := 1 ", "x := 3", 0.9)
+
+	if m.strategy != matchTrailingWhitespace {
+		t.Fatalf("strategy = %q, want %q", m.strategy, matchTrailingWhitespace)
+	}
+	if len(m.spans) != 2 {
+		t.Errorf("got %d matches, want 2", len(m.spans))
+	}
+}
+
+func TestFindMatches_IndentationReindentsEachOccurrence(t *testing.T) {
+	content := "func a() {\n\tif ok {\n\t\treturn 1\n\t}\n}\n\nfunc b() {\n\tfor {\n\t\tif ok {\n\t\t\treturn 1\n\t\t}\n\t}\n}\n"
+	before := "if ok {\n\treturn 1\n}\n"
+	after := "if ok {\n\treturn 2\n}\n"
+
+	m := findMatches(content, before, after, 0.9)
+
+	if m.strategy != matchIndentation || len(m.spans) != 2 {
+		t.Fatalf("got %q with %d matches, want two indentation matches", m.strategy, len(m.spans))
+	}
+	want := "func a() {\n\tif ok {\n\t\treturn 2\n\t}\n}\n\nfunc b() {\n\tfor {\n\t\tif ok {\n\t\t\treturn 2\n\t\t}\n\t}\n}\n"
+	if got := m.apply(content, after); got != want {
+		t.Errorf("apply = %q, want %q", got, want)
+	}
+}
+
+func TestFindMatches_FuzzyPrefersClearlyBestWindow(t *testing.T) {
+	content := "// sum a, b and c\ntotal := sum(a, b, c)\n// sum a, b and d\ntotal := sum(a, b, d)\n"
+
+	m := findMatches(content, "// sum a, b and dd\ntotal := sum(a, b, d)", "// sum a, b and dd\ntotal := sum(a, b, e)", 0.8)
+
+	if m.strategy != matchFuzzy || len(m.spans) != 1 {
+		t.Fatalf("got %q with %d matches, want one fuzzy match", m.strategy, len(m.spans))
+	}
+	if m.windows[0].first != 2 {
+		t.Errorf("matched line %d, want 3", m.windows[0].first+1)
+	}
+}
+
+func TestFindMatches_FuzzyNearTies_AreAllCounted(t *testing.T) {
+	content := "// call\ncall(alpha)\nother()\n// call\ncall(alpha)\n"
+
+	m := findMatches(content, "// cal\ncall(alpha)", "// cal\ncall(beta)", 0.8)
+
+	if m.strategy != matchFuzzy || len(m.spans) != 2 {
+		t.Fatalf("got %q with %d matches, want two fuzzy matches", m.strategy, len(m.spans))
+	}
+	if got := m.apply(content, "// cal\ncall(beta)"); got != "// call\ncall(beta)\nother()\n// call\ncall(beta)\n" {
+		t.Errorf("apply = %q", got)
+	}
+}
+
+func TestFindMatches_FuzzyNeverChangesADifferentValue(t *testing.T) {
+	tests := []struct {
+		name, content, before, after string
+	}{
+		{"one line, one token", "spec:\n  replicas: 5\n  paused: false\n", "  replicas: 3\n", "  replicas: 4\n"},
+		{"one line image tag", "image: nginx:1.25\n", "image: nginx:1.24", "image: nginx:1.26"},
+		{"changed line differs", "spec:\n  replicas: 5\n  paused: false\n", "spec:\n  replicas: 3\n  paused: false\n", "spec:\n  replicas: 4\n  paused: false\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := findMatches(tt.content, tt.before, tt.after, 0.5)
+			if len(m.spans) != 0 {
+				t.Fatalf("got %q match at %v, want none", m.strategy, m.windows)
+			}
+			if m.closest == nil || m.refused == "" {
+				t.Fatalf("expected the closest region with a reason it was not used, got %+v", m)
+			}
+			if diag := notFoundDiagnostic(tt.content, tt.before, m.closest, m.refused); !strings.Contains(diag, "The closest region was not edited") {
+				t.Errorf("diagnostic missing the refusal:\n%s", diag)
+			}
+		})
+	}
+}
+
+func TestFindMatches_BelowThreshold_ReportsClosest(t *testing.T) {
+	content := "alpha\nbeta\ngamma\ndelta\n"
+
+	m := findMatches(content, "gamma ray\ndelta", "", 0.99)
+
+	if len(m.spans) != 0 {
+		t.Fatalf("expected no match, got %d", len(m.spans))
+	}
+	if m.closest == nil || m.closest.first != 2 || m.closest.n != 2 {
+		t.Fatalf("closest = %+v, want lines 3-4", m.closest)
+	}
+
+	diag := notFoundDiagnostic(content, "gamma ray\ndelta", m.closest, m.refused)
+	for _, want := range []string{"Closest match (lines 3-4", "00003| gamma", "-gamma ray", "+gamma"} {
+		if !strings.Contains(diag, want) {
+			t.Errorf("diagnostic missing %q:\n%s", want, diag)
+		}
+	}
+}
+
+func TestDedent(t *testing.T) {
+	indent, lines := dedent([]string{"    if x {", "", "        y()  ", "    }"})
+
+	if indent != "    " {
+		t.Errorf("indent = %q, want 4 spaces", indent)
+	}
+	want := []string{"if x {", "", "    y()", "}"}
+	if !equalLines(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
@@ -0,0 +1,327 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/helper/patch"
+	"github.com/Cyclone1070/iav/internal/tool/service/fs"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// patchFileSystem defines the filesystem operations needed to apply a patch.
+type patchFileSystem interface {
+	Stat(path string) (os.FileInfo, error)
+	ReadFile(path string) ([]byte, error)
+	atomicWriter
+	changeCommitter
+}
+
+// patchChecksums extends checksumManager with forgetting files the patch removes.
+type patchChecksums interface {
+	checksumManager
+	Remove(path string)
+}
+
+// ApplyPatchTool applies multi-file unified diffs.
+type ApplyPatchTool struct {
+	fileOps         patchFileSystem
+	checksumManager patchChecksums
+	config          *config.Config
+	pathResolver    pathResolver
+	diagnoser       diagnoser
+}
+
+// NewApplyPatchTool creates a new ApplyPatchTool with injected dependencies.
+// diagnoser may be nil, in which case patched files are not checked.
+func NewApplyPatchTool(
+	fileOps patchFileSystem,
+	checksumManager patchChecksums,
+	pathResolver pathResolver,
+	diagnoser diagnoser,
+	cfg *config.Config,
+) *ApplyPatchTool {
+	if fileOps == nil {
+		panic("fileOps is required")
+	}
+	if checksumManager == nil {
+		panic("checksumManager is required")
+	}
+	if pathResolver == nil {
+		panic("pathResolver is required")
+	}
+	if cfg == nil {
+		panic("config is required")
+	}
+	return &ApplyPatchTool{
+		fileOps:         fileOps,
+		checksumManager: checksumManager,
+		config:          cfg,
+		pathResolver:    pathResolver,
+		diagnoser:       diagnoser,
+	}
+}
+
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+func (t *ApplyPatchTool) Declaration() tool.Declaration {
+	return tool.Declaration{
+		Name: "apply_patch",
+		Description: "Apply a unified diff (as produced by `git diff` or `diff -u`) to one or more files, " +
+			"including adding, deleting and renaming files. Every hunk is checked against the current " +
+			"files first; if any hunk does not apply, no file is changed.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"patch": {Type: tool.TypeString, Description: "Unified diff with ---/+++ file headers and @@ hunks"},
+			},
+			Required: []string{"patch"},
+		},
+	}
+}
+
+func (t *ApplyPatchTool) Request() toolmanager.ToolRequest {
+	return &ApplyPatchRequest{}
+}
+
+// fileChange is one file's part of a patch, validated and ready to write.
+type fileChange struct {
+	op             patch.Op
+	oldAbs, newAbs string // oldAbs is empty for adds, newAbs for deletes
+	oldRel, newRel string
+	oldContent     string    // Normalised to \n
+	newContent     string    // Normalised to \n
+	oldFile        *textFile // Encoding and line endings to keep; nil for adds
+	output         []byte    // newContent as written
+	perm           os.FileMode
+	notes          []string
+}
+
+// Execute applies a unified diff to the workspace.
+// Every file patch is parsed, checked for conflicts with the checksum cache and
+// applied in memory before anything is written, so a hunk that does not apply
+// leaves all files untouched. The changes are then committed in one filesystem
+// transaction, so they land together or not at all. Once committed, each added or
+// modified file is checked with the formatters and linters configured for it.
+func (t *ApplyPatchTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*ApplyPatchRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+
+	if err := r.Validate(t.config); err != nil {
+		return &ApplyPatchResponse{Error: err.Error()}, nil
+	}
+
+	patches, err := patch.Parse(r.Patch)
+	if err != nil {
+		return &ApplyPatchResponse{Error: fmt.Sprintf("invalid patch: %v", err)}, nil
+	}
+
+	changes := make([]*fileChange, 0, len(patches))
+	touched := make(map[string]bool)
+	for _, fp := range patches {
+		c, err := t.plan(fp)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			name := fp.Path()
+			if fp.Op == patch.OpRename {
+				name = fp.OldPath + " -> " + fp.NewPath
+			}
+			return &ApplyPatchResponse{Error: fmt.Sprintf("%s: %v (no files were changed)", name, err)}, nil
+		}
+		for _, abs := range []string{c.oldAbs, c.newAbs} {
+			if abs != "" && touched[abs] {
+				return &ApplyPatchResponse{Error: fmt.Sprintf("%s is changed more than once in the patch (no files were changed)", abs)}, nil
+			}
+		}
+		for _, abs := range []string{c.oldAbs, c.newAbs} {
+			if abs != "" {
+				touched[abs] = true
+			}
+		}
+		changes = append(changes, c)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var fsChanges []fs.Change
+	for _, c := range changes {
+		fsChanges = append(fsChanges, c.fsChanges()...)
+	}
+	if err := t.fileOps.ApplyChanges(fsChanges); err != nil {
+		return &ApplyPatchResponse{Error: commitError(err)}, nil
+	}
+
+	files := make([]ChangedFile, len(changes))
+	for i, c := range changes {
+		if c.oldAbs != "" && c.oldAbs != c.newAbs {
+			t.checksumManager.Remove(c.oldAbs)
+		}
+		if c.newAbs != "" {
+			t.checksumManager.Update(c.newAbs, fileChecksum(t.checksumManager, c.output))
+		}
+		files[i] = c.summary()
+	}
+	for i, c := range changes {
+		if c.newAbs == "" {
+			continue
+		}
+		report, err := checkWritten(ctx, t.diagnoser, t.fileOps, t.checksumManager, c.newAbs, c.newRel, c.output, c.perm)
+		if err != nil {
+			return nil, err
+		}
+		files[i].Diagnostics = report
+	}
+	return &ApplyPatchResponse{Files: files}, nil
+}
+
+// plan validates one file patch against the workspace and computes the new content.
+func (t *ApplyPatchTool) plan(fp patch.FilePatch) (*fileChange, error) {
+	c := &fileChange{op: fp.Op, perm: 0o644}
+
+	var err error
+	if fp.OldPath != "" {
+		if c.oldAbs, c.oldRel, err = t.resolve(fp.OldPath); err != nil {
+			return nil, err
+		}
+	}
+	if fp.NewPath != "" {
+		if c.newAbs, c.newRel, err = t.resolve(fp.NewPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.oldAbs != "" {
+		if err := t.readOld(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.newAbs != "" && c.newAbs != c.oldAbs {
+		if _, err := t.fileOps.Stat(c.newAbs); err == nil {
+			return nil, fmt.Errorf("file already exists: %s", c.newAbs)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to stat %s: %v", c.newAbs, err)
+		}
+	}
+
+	newContent, notes, err := patch.Apply(c.oldContent, fp.Hunks)
+	if err != nil {
+		return nil, err
+	}
+	if fp.Op == patch.OpDelete {
+		if newContent != "" && len(fp.Hunks) > 0 {
+			return nil, fmt.Errorf("delete patch does not remove the whole file")
+		}
+		newContent = ""
+	}
+	if int64(len(newContent)) > t.config.Tools.MaxFileSize {
+		return nil, fmt.Errorf("file too large after patch (size %d, limit %d)", len(newContent), t.config.Tools.MaxFileSize)
+	}
+	c.newContent, c.notes = newContent, notes
+	c.output = []byte(newContent)
+	if c.oldFile != nil {
+		if c.output, err = c.oldFile.encode(newContent); err != nil {
+			return nil, fmt.Errorf("cannot save %s as %s: %v", c.displayPath(), c.oldFile.enc, err)
+		}
+	}
+	return c, nil
+}
+
+func (t *ApplyPatchTool) resolve(p string) (abs, rel string, err error) {
+	if abs, err = t.pathResolver.Abs(p); err != nil {
+		return "", "", err
+	}
+	if rel, err = t.pathResolver.Rel(abs); err != nil {
+		return "", "", err
+	}
+	return abs, rel, nil
+}
+
+// readOld loads the file the patch changes and checks it has not changed since it was last read.
+func (t *ApplyPatchTool) readOld(c *fileChange) error {
+	info, err := t.fileOps.Stat(c.oldAbs)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file does not exist: %s", c.oldAbs)
+		}
+		return fmt.Errorf("failed to stat %s: %v", c.oldAbs, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("path is a directory: %s", c.oldAbs)
+	}
+
+	data, err := t.fileOps.ReadFile(c.oldAbs)
+	if err != nil {
+		return err
+	}
+	c.perm = info.Mode()
+
+	current := fileChecksum(t.checksumManager, data)
+	if prior, ok := t.checksumManager.Get(c.oldAbs); ok && prior != current {
+		return fmt.Errorf("edit conflict: file changed since last read: %s", c.oldAbs)
+	}
+
+	if c.oldFile, err = decodeText(data); err != nil {
+		return fmt.Errorf("cannot decode %s: %v", c.oldAbs, err)
+	}
+	c.oldContent = c.oldFile.text
+	return nil
+}
+
+// fsChanges returns the filesystem operations that make the change. A rename
+// writes the new file and deletes the old one.
+func (c *fileChange) fsChanges() []fs.Change {
+	content := c.output
+	switch c.op {
+	case patch.OpDelete:
+		return []fs.Change{{Path: c.oldAbs, Delete: true}}
+	case patch.OpRename:
+		return []fs.Change{
+			{Path: c.newAbs, Content: content, Perm: c.perm},
+			{Path: c.oldAbs, Delete: true},
+		}
+	}
+	return []fs.Change{{Path: c.newAbs, Content: content, Perm: c.perm}}
+}
+
+func (c *fileChange) displayPath() string {
+	if c.newRel != "" {
+		return c.newRel
+	}
+	return c.oldRel
+}
+
+func (c *fileChange) summary() ChangedFile {
+	from, to := "a/"+c.oldRel, "b/"+c.newRel
+	if c.oldRel == "" {
+		from = "/dev/null"
+	}
+	if c.newRel == "" {
+		to = "/dev/null"
+	}
+	diff, added, removed := unifiedDiff(from, to, c.oldContent, c.newContent)
+
+	f := ChangedFile{
+		Op:           c.op.String(),
+		Path:         c.displayPath(),
+		Diff:         diff,
+		AddedLines:   added,
+		RemovedLines: removed,
+		Notes:        c.notes,
+	}
+	if c.op == patch.OpRename {
+		f.OldPath = c.oldRel
+	}
+	return f
+}
@@ -0,0 +1,202 @@
+package file
+
+// Mocks are defined in write_test.go and shared across all test files in this package.
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+)
+
+func executePatch(t *testing.T, ptool *ApplyPatchTool, diff string) *ApplyPatchResponse {
+	t.Helper()
+	result, err := ptool.Execute(context.Background(), &ApplyPatchRequest{Patch: diff})
+	if err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	resp, ok := result.(*ApplyPatchResponse)
+	if !ok {
+		t.Fatalf("Execute returned wrong type: %T", result)
+	}
+	return resp
+}
+
+func assertFile(t *testing.T, fs *mockFileSystemForWrite, path, want string) {
+	t.Helper()
+	data, err := fs.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", path, string(data), want)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	workspaceRoot := "/workspace"
+
+	multiFile := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var x = 1
++var x = 2
+ var y = 3
+diff --git a/docs/new.md b/docs/new.md
+new file mode 100644
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1,1 @@
++# New
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/a.txt b/b.txt
+rename from a.txt
+rename to b.txt
+--- a/a.txt
++++ b/b.txt
+@@ -1 +1 @@
+-alpha
++beta
+`
+
+	setup := func() (*mockFileSystemForWrite, *mockChecksumManagerForWrite, *ApplyPatchTool) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createFile("/workspace/main.go", []byte("package main\nvar x = 1\nvar y = 3\n"), 0o600)
+		fs.createFile("/workspace/old.txt", []byte("bye\n"), 0o644)
+		fs.createFile("/workspace/a.txt", []byte("alpha\n"), 0o755)
+		return fs, checksumManager, NewApplyPatchTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+	}
+
+	t.Run("applies modify, add, delete and rename", func(t *testing.T) {
+		fs, checksumManager, ptool := setup()
+		checksumManager.Update("/workspace/a.txt", checksumManager.Compute([]byte("alpha\n")))
+
+		resp := executePatch(t, ptool, multiFile)
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+
+		assertFile(t, fs, "/workspace/main.go", "package main\nvar x = 2\nvar y = 3\n")
+		assertFile(t, fs, "/workspace/docs/new.md", "# New\n")
+		assertFile(t, fs, "/workspace/b.txt", "beta\n")
+		for _, gone := range []string{"/workspace/old.txt", "/workspace/a.txt"} {
+			if _, err := fs.ReadFile(gone); err == nil {
+				t.Errorf("expected %s to be removed", gone)
+			}
+		}
+		if fs.files["/workspace/main.go"].mode != 0o600 || fs.files["/workspace/b.txt"].mode != 0o755 {
+			t.Errorf("expected permissions to be preserved")
+		}
+
+		if _, ok := checksumManager.Get("/workspace/a.txt"); ok {
+			t.Errorf("expected checksum of renamed-away file to be dropped")
+		}
+		if _, ok := checksumManager.Get("/workspace/b.txt"); !ok {
+			t.Errorf("expected checksum of rename target to be cached")
+		}
+
+		llm := resp.LLMContent()
+		for _, want := range []string{"4 file(s)", "modify main.go (+1 -1)", "add docs/new.md (+1 -0)", "delete old.txt (+0 -1)", "rename a.txt -> b.txt (+1 -1)"} {
+			if !strings.Contains(llm, want) {
+				t.Errorf("LLMContent missing %q:\n%s", want, llm)
+			}
+		}
+		display, ok := resp.Display().(tool.MultiDiffDisplay)
+		if !ok || len(display) != 4 || display[0].Path != "main.go" {
+			t.Errorf("unexpected display: %#v", resp.Display())
+		}
+	})
+
+	t.Run("failing hunk changes nothing", func(t *testing.T) {
+		fs, _, ptool := setup()
+		broken := strings.Replace(multiFile, "-alpha", "-gamma", 1)
+
+		resp := executePatch(t, ptool, broken)
+
+		if !strings.Contains(resp.Error, "a.txt -> b.txt") || !strings.Contains(resp.Error, "hunk 1") {
+			t.Errorf("expected hunk error for a.txt, got: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/main.go", "package main\nvar x = 1\nvar y = 3\n")
+		assertFile(t, fs, "/workspace/old.txt", "bye\n")
+		if _, err := fs.ReadFile("/workspace/docs/new.md"); err == nil {
+			t.Errorf("expected new file not to be created")
+		}
+	})
+
+	t.Run("conflict with cached checksum changes nothing", func(t *testing.T) {
+		fs, checksumManager, ptool := setup()
+		checksumManager.Update("/workspace/old.txt", "stale")
+
+		resp := executePatch(t, ptool, multiFile)
+
+		if !strings.Contains(resp.Error, "edit conflict") {
+			t.Errorf("expected conflict error, got: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/main.go", "package main\nvar x = 1\nvar y = 3\n")
+	})
+
+	t.Run("hunk with offset and stale context applies with note", func(t *testing.T) {
+		fs, _, ptool := setup()
+		fs.createFile("/workspace/main.go", []byte("// header\npackage main\nvar x = 1\nvar y = 4\n"), 0o644)
+		diff := "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n package main\n-var x = 1\n+var x = 2\n var y = 3\n"
+
+		resp := executePatch(t, ptool, diff)
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+
+		assertFile(t, fs, "/workspace/main.go", "// header\npackage main\nvar x = 2\nvar y = 4\n")
+		if !strings.Contains(resp.LLMContent(), "hunk 1 applied at line 3 (offset +1 lines, fuzz 1)") {
+			t.Errorf("expected placement note, got:\n%s", resp.LLMContent())
+		}
+	})
+
+	t.Run("add over existing file fails", func(t *testing.T) {
+		_, _, ptool := setup()
+		diff := "--- /dev/null\n+++ b/main.go\n@@ -0,0 +1 @@\n+x\n"
+
+		resp := executePatch(t, ptool, diff)
+
+		if !strings.Contains(resp.Error, "file already exists") {
+			t.Errorf("expected exists error, got: %s", resp.Error)
+		}
+	})
+
+	t.Run("commit failure changes nothing", func(t *testing.T) {
+		fs, _, ptool := setup()
+		fs.setOperationError("ApplyChanges", errors.New("disk on fire"))
+		diff := "--- a/main.go\n+++ b/main.go\n@@ -2 +2 @@\n-var x = 1\n+var x = 2\n" +
+			"--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"
+
+		resp := executePatch(t, ptool, diff)
+
+		if !strings.Contains(resp.Error, "disk on fire") || !strings.Contains(resp.Error, "no files were changed") {
+			t.Errorf("expected rolled back write error, got: %s", resp.Error)
+		}
+		assertFile(t, fs, "/workspace/main.go", "package main\nvar x = 1\nvar y = 3\n")
+	})
+
+	t.Run("path outside workspace fails", func(t *testing.T) {
+		_, _, ptool := setup()
+		diff := "--- a/../etc/passwd\n+++ b/../etc/passwd\n@@ -1 +1 @@\n-a\n+b\n"
+
+		resp := executePatch(t, ptool, diff)
+
+		if resp.Error == "" {
+			t.Errorf("expected error for path outside workspace")
+		}
+	})
+}
@@ -2,7 +2,11 @@ package file
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/Cyclone1070/iav/internal/config"
@@ -14,12 +18,21 @@ import (
 
 // fileReader defines the minimal filesystem operations needed for reading files.
 type fileReader interface {
-	ReadFile(path string) ([]byte, error)
+	Stat(path string) (os.FileInfo, error)
+	Open(path string) (io.ReadSeekCloser, error) // For ranged reads, with no size limit
+	ReadFile(path string) ([]byte, error)        // For UTF-16 and UTF-32 files, decoded whole
+	ListDir(path string) ([]os.FileInfo, error)  // For globs
+}
+
+// ignoreMatcher defines the interface for gitignore pattern matching.
+type ignoreMatcher interface {
+	ShouldIgnore(relativePath string) bool
 }
 
 // checksumComputer defines the interface for checksum computation and updates.
 type checksumComputer interface {
 	Compute(data []byte) string
+	ComputeReader(r io.Reader) (string, error)
 	Update(path string, checksum string)
 }
 
@@ -28,14 +41,18 @@ type ReadFileTool struct {
 	fileOps         fileReader
 	checksumManager checksumComputer
 	pathResolver    pathResolver
+	ignoreMatcher   ignoreMatcher
 	config          *config.Config
+	lines           *lineIndexCache
 }
 
 // NewReadFileTool creates a new ReadFileTool with injected dependencies.
+// ignoreMatcher may be nil, in which case globs match ignored files too.
 func NewReadFileTool(
 	fileOps fileReader,
 	checksumManager checksumComputer,
 	pathResolver pathResolver,
+	ignoreMatcher ignoreMatcher,
 	cfg *config.Config,
 ) *ReadFileTool {
 	if fileOps == nil {
@@ -54,7 +71,9 @@ func NewReadFileTool(
 		fileOps:         fileOps,
 		checksumManager: checksumManager,
 		pathResolver:    pathResolver,
+		ignoreMatcher:   ignoreMatcher,
 		config:          cfg,
+		lines:           newLineIndexCache(),
 	}
 }
 
@@ -63,19 +82,33 @@ func (t *ReadFileTool) Name() string {
 	return "read_file"
 }
 
+// ReadOnly reports that reading never modifies the workspace.
+func (t *ReadFileTool) ReadOnly() bool {
+	return true
+}
+
 // Declaration returns the tool's schema for the LLM.
 func (t *ReadFileTool) Declaration() tool.Declaration {
 	return tool.Declaration{
-		Name:        "read_file",
-		Description: "Read file contents with optional pagination. Use offset/limit to read large files in chunks.",
+		Name: "read_file",
+		Description: "Read file contents with optional pagination. Use offset/limit to read large files in chunks. " +
+			"Read several related files at once with paths and/or glob; offset/limit then apply to each file. " +
+			"For logs, tail reads the last lines, and since_line with follow returns only lines appended after it.",
 		Parameters: &tool.Schema{
-			Type: tool.TypeObject,
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
 			Properties: map[string]*tool.Schema{
 				"path":   {Type: tool.TypeString, Description: "Path to file"},
-				"offset": {Type: tool.TypeInteger, Description: "Start line index (0-indexed)"},
-				"limit":  {Type: tool.TypeInteger, Description: "Max lines to return"},
+				"paths":  {Type: tool.TypeArray, Description: "Paths of several files to read", Items: &tool.Schema{Type: tool.TypeString}},
+				"glob":   {Type: tool.TypeString, Description: "Workspace-relative pattern of files to read, e.g. modules/**/*.tf"},
+				"offset": {Type: tool.TypeInteger, Description: "Start line index (0-indexed)", Minimum: tool.Ptr(0.0)},
+				"limit":  {Type: tool.TypeInteger, Description: "Max lines to return", Minimum: tool.Ptr(1.0)},
+				"tail":   {Type: tool.TypeInteger, Description: "Read the last N lines of path", Minimum: tool.Ptr(1.0)},
+				"since_line": {Type: tool.TypeInteger, Description: "Read the lines of path after this line number, e.g. the total of a previous read",
+					Minimum: tool.Ptr(0.0)},
+				"follow":  {Type: tool.TypeBoolean, Description: "Wait for new lines after since_line (or after the current end) to be appended, and return only those"},
+				"timeout": {Type: tool.TypeInteger, Description: "Seconds follow waits for new lines", Minimum: tool.Ptr(1.0)},
 			},
-			Required: []string{"path"},
 		},
 	}
 }
@@ -86,6 +119,18 @@ func (t *ReadFileTool) Request() toolmanager.ToolRequest {
 }
 
 // Execute reads a file from the workspace with line-based pagination.
+// UTF-16, UTF-32 and ISO-8859-1 files are returned as UTF-8, and the encoding is
+// shown in the result header.
+//
+// Files are read by line range through a cached sparse line index, so paging
+// through a large file does not read all of it each time, and files above
+// MaxFileSize can be paged too. One read still returns at most MaxFileSize bytes,
+// with lines longer than MaxLineLength cut. UTF-16 and UTF-32 files are the
+// exception: they are decoded whole and are subject to MaxFileSize.
+//
+// With paths or a glob it reads several files, each paginated and checksummed on
+// its own. Their content shares MaxReadFileBatchSize: the file that exceeds it is
+// cut at a line boundary and the files after it are listed as not read.
 //
 // Note: ctx is accepted for API consistency but not used - file I/O is synchronous.
 func (t *ReadFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
@@ -98,11 +143,79 @@ func (t *ReadFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest)
 		return &ReadFileResponse{Error: err.Error()}, nil
 	}
 
-	abs, err := t.pathResolver.Abs(r.Path)
+	if !r.batch() {
+		abs, err := t.pathResolver.Abs(r.Path)
+		if err != nil {
+			return &ReadFileResponse{Error: err.Error()}, nil
+		}
+		if r.log() {
+			return t.readLog(ctx, abs, r)
+		}
+		return t.readOne(ctx, abs, r.Offset, r.Limit)
+	}
+
+	targets, err := t.targets(ctx, r)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return &ReadFileResponse{Error: err.Error()}, nil
 	}
 
+	budget := t.config.Tools.MaxReadFileBatchSize
+	resp := &ReadFileResponse{Files: []*ReadFileResponse{}, BudgetSize: budget}
+	for i, target := range targets {
+		if budget <= 0 {
+			for _, rest := range targets[i:] {
+				resp.Unread = append(resp.Unread, rest.rel)
+			}
+			break
+		}
+
+		f := &ReadFileResponse{Error: target.err}
+		if f.Error == "" {
+			if f, err = t.readOne(ctx, target.abs, r.Offset, r.Limit); err != nil {
+				return nil, err
+			}
+			budget -= f.fitBudget(budget)
+		}
+		f.Path = target.rel
+		resp.Files = append(resp.Files, f)
+	}
+	return resp, nil
+}
+
+// readOne reads a line range of a single file and records its checksum.
+// Files in another encoding than UTF-8 are transcoded; the response names the encoding.
+func (t *ReadFileTool) readOne(ctx context.Context, abs string, offset, limit int) (*ReadFileResponse, error) {
+	idx, err := t.lineIndex(abs)
+	if errors.Is(err, errWideEncoding) {
+		return t.readWhole(ctx, abs, offset, limit)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &ReadFileResponse{Error: err.Error()}, nil
+	}
+
+	// The index checksums the full file (on normalized content), whatever range is read
+	t.checksumManager.Update(abs, idx.checksum)
+
+	return t.readPage(abs, idx, offset, limit), nil
+}
+
+// readPage reads up to limit lines of an indexed file from the 0-based line offset on.
+func (t *ReadFileTool) readPage(abs string, idx *lineIndex, offset, limit int) *ReadFileResponse {
+	lines, err := t.readLines(abs, idx, offset, limit)
+	if err != nil {
+		return &ReadFileResponse{Error: err.Error()}
+	}
+	return page(lines, offset, idx.lines, idx.enc)
+}
+
+// readWhole reads, decodes and paginates a file that cannot be read by line range.
+func (t *ReadFileTool) readWhole(ctx context.Context, abs string, offset, limit int) (*ReadFileResponse, error) {
 	// Read full file content
 	data, err := t.fileOps.ReadFile(abs)
 	if err != nil {
@@ -112,30 +225,123 @@ func (t *ReadFileTool) Execute(ctx context.Context, req toolmanager.ToolRequest)
 		return &ReadFileResponse{Error: err.Error()}, nil
 	}
 
-	// Normalize line endings for consistent checksum (matches EditFileTool behavior)
-	normalizedContent := strings.ReplaceAll(string(data), "\r\n", "\n")
-
 	// Always update checksum since we read the full file (on normalized content)
-	checksum := t.checksumManager.Compute([]byte(normalizedContent))
-	t.checksumManager.Update(abs, checksum)
+	t.checksumManager.Update(abs, fileChecksum(t.checksumManager, data))
 
-	// Split into lines using content.SplitLines which handles both \n and \r\n
-	lines := content.SplitLines(string(data))
+	// Transcode to UTF-8 so the lines make sense whatever the file's encoding
+	text, err := decodeText(data)
+	if err != nil {
+		return &ReadFileResponse{Error: fmt.Sprintf("cannot decode %s: %v", abs, err)}, nil
+	}
 
 	// Apply pagination
-	paginatedLines, pagRes := pagination.ApplyPagination(lines, r.Offset, r.Limit)
+	lines, pagRes := pagination.ApplyPagination(content.SplitLines(text.text), offset, limit)
+	return page(lines, offset, pagRes.TotalCount, text.enc), nil
+}
 
+// page builds the response for the lines read from the 0-based line offset on.
+func page(lines []string, offset, totalLines int, enc content.Encoding) *ReadFileResponse {
 	// Calculate display lines
-	startLine := r.Offset + 1
-	endLine := startLine + len(paginatedLines) - 1
-	if len(paginatedLines) == 0 {
+	startLine := offset + 1
+	endLine := startLine + len(lines) - 1
+	if len(lines) == 0 {
 		endLine = startLine - 1
 	}
 
 	return &ReadFileResponse{
-		Content:    strings.Join(paginatedLines, "\n"),
+		Content:    strings.Join(lines, "\n"),
 		StartLine:  startLine,
 		EndLine:    endLine,
-		TotalLines: pagRes.TotalCount,
-	}, nil
+		TotalLines: totalLines,
+		Encoding:   encodingName(enc),
+	}
+}
+
+// readTarget is one file of a multi-file read.
+type readTarget struct {
+	abs, rel string
+	err      string // Why the path cannot be read, reported in place of its content
+}
+
+// targets resolves the paths and glob of a request, in that order and without duplicates.
+func (t *ReadFileTool) targets(ctx context.Context, r *ReadFileRequest) ([]readTarget, error) {
+	var targets []readTarget
+	seen := make(map[string]bool)
+
+	paths := r.Paths
+	if r.Path != "" {
+		paths = append([]string{r.Path}, paths...)
+	}
+	for _, p := range paths {
+		abs, err := t.pathResolver.Abs(p)
+		if err != nil {
+			targets = append(targets, readTarget{rel: p, err: err.Error()})
+			continue
+		}
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		rel, err := t.pathResolver.Rel(abs)
+		if err != nil {
+			rel = p
+		}
+		targets = append(targets, readTarget{abs: abs, rel: filepath.ToSlash(rel)})
+	}
+
+	if r.Glob != "" {
+		max := t.config.Tools.MaxReadFileBatchFiles
+		matches, err := t.glob(ctx, r.Glob, max+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 && len(targets) == 0 {
+			return nil, fmt.Errorf("no files match %q", r.Glob)
+		}
+		if len(matches) > max {
+			return nil, fmt.Errorf("more than %d files match %q; use a narrower glob", max, r.Glob)
+		}
+		for _, m := range matches {
+			if !seen[m.abs] {
+				seen[m.abs] = true
+				targets = append(targets, m)
+			}
+		}
+	}
+
+	if len(targets) > t.config.Tools.MaxReadFileBatchFiles {
+		return nil, fmt.Errorf("too many files: %d (limit %d)", len(targets), t.config.Tools.MaxReadFileBatchFiles)
+	}
+	return targets, nil
+}
+
+// fitBudget shortens the content to at most budget bytes, cutting at a line
+// boundary, and returns the bytes it uses.
+func (r *ReadFileResponse) fitBudget(budget int64) int64 {
+	if int64(len(r.Content)) <= budget {
+		return int64(len(r.Content))
+	}
+	cut := strings.LastIndex(r.Content[:budget], "\n")
+	if r.Content[budget] == '\n' {
+		cut = int(budget) // The budget ends exactly at a line end
+	}
+	if cut < 0 {
+		cut = 0 // Not even the first line fits
+	}
+	kept := strings.Count(r.Content[:cut], "\n")
+	if cut > 0 {
+		kept++
+	}
+	r.Content = r.Content[:cut]
+	r.EndLine = r.StartLine + kept - 1
+	r.CutOff = true
+	return budget // Nothing else fits
+}
+
+// encodingName returns the encoding to show in the read header, or "" for plain UTF-8.
+func encodingName(enc content.Encoding) string {
+	if enc == content.UTF8 {
+		return ""
+	}
+	return enc.String()
 }
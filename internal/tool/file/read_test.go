@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool/helper/content"
 	"github.com/Cyclone1070/iav/internal/tool/service/path"
 )
 
@@ -31,6 +37,9 @@ type mockFileSystemForRead struct {
 	files  map[string][]byte
 	dirs   map[string]bool
 	config *config.Config
+	opened int    // Calls to Open
+	read   int64  // Bytes read from opened files
+	onStat func() // Runs before each Stat, to change files while a read polls
 }
 
 func newMockFileSystemForRead(cfg *config.Config) *mockFileSystemForRead {
@@ -50,6 +59,9 @@ func (m *mockFileSystemForRead) createDir(path string) {
 }
 
 func (m *mockFileSystemForRead) Stat(path string) (os.FileInfo, error) {
+	if m.onStat != nil {
+		m.onStat()
+	}
 	if m.dirs[path] {
 		return &mockFileInfoForRead{name: path, isDir: true}, nil
 	}
@@ -59,31 +71,102 @@ func (m *mockFileSystemForRead) Stat(path string) (os.FileInfo, error) {
 	return nil, os.ErrNotExist
 }
 
+// ListDir lists created files and directories directly under path, including
+// directories implied by file paths, sorted by name.
+func (m *mockFileSystemForRead) ListDir(path string) ([]os.FileInfo, error) {
+	entries := make(map[string]os.FileInfo)
+	for p := range m.dirs {
+		if filepath.Dir(p) == path {
+			entries[p] = &mockFileInfoForRead{name: filepath.Base(p), isDir: true}
+		}
+	}
+	for p, content := range m.files {
+		for dir := p; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+			if filepath.Dir(dir) != path {
+				continue
+			}
+			if dir == p {
+				entries[p] = &mockFileInfoForRead{name: filepath.Base(p), size: int64(len(content))}
+			} else {
+				entries[dir] = &mockFileInfoForRead{name: filepath.Base(dir), isDir: true}
+			}
+		}
+	}
+	if len(entries) == 0 && !m.dirs[path] {
+		return nil, os.ErrNotExist
+	}
+	names := make([]string, 0, len(entries))
+	for p := range entries {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	infos := make([]os.FileInfo, len(names))
+	for i, p := range names {
+		infos[i] = entries[p]
+	}
+	return infos, nil
+}
+
 func (m *mockFileSystemForRead) ReadFile(path string) ([]byte, error) {
 	// Check if it's a directory
 	if m.dirs[path] {
 		return nil, fmt.Errorf("read %s: is a directory", path)
 	}
 
-	content, ok := m.files[path]
+	data, ok := m.files[path]
 	if !ok {
 		return nil, os.ErrNotExist
 	}
 
-	if m.config != nil && m.config.Tools.MaxFileSize > 0 && int64(len(content)) > m.config.Tools.MaxFileSize {
+	if m.config != nil && m.config.Tools.MaxFileSize > 0 && int64(len(data)) > m.config.Tools.MaxFileSize {
 		return nil, fmt.Errorf("file %s exceeds max size (%d bytes)", path, m.config.Tools.MaxFileSize)
 	}
 
-	// Binary detection mock: if it contains null byte
-	for _, b := range content {
-		if b == 0 {
-			return nil, fmt.Errorf("binary file: %s", path)
-		}
+	// Binary detection, as OSFileSystem does it
+	if content.IsBinaryContent(data) {
+		return nil, fmt.Errorf("binary file: %s", path)
 	}
 
-	return content, nil
+	return data, nil
 }
 
+func (m *mockFileSystemForRead) Open(path string) (io.ReadSeekCloser, error) {
+	if m.dirs[path] {
+		return nil, fmt.Errorf("open %s: is a directory", path)
+	}
+	data, ok := m.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	m.opened++
+	return &countingSeekCloser{r: bytes.NewReader(data), n: &m.read}, nil
+}
+
+// countingSeekCloser serves a mock file's content to Open, counting the bytes read.
+type countingSeekCloser struct {
+	r *bytes.Reader
+	n *int64
+}
+
+func (c *countingSeekCloser) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+func (c *countingSeekCloser) Seek(offset int64, whence int) (int64, error) {
+	return c.r.Seek(offset, whence)
+}
+
+func (c *countingSeekCloser) Close() error { return nil }
+
+// nopSeekCloser serves a mock file's content to Open.
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
 type mockChecksumManagerForRead struct {
 	checksums map[string]string
 }
@@ -98,6 +181,11 @@ func (m *mockChecksumManagerForRead) Compute(content []byte) string {
 	return fmt.Sprintf("mock-checksum-%x", content)
 }
 
+func (m *mockChecksumManagerForRead) ComputeReader(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	return m.Compute(data), err
+}
+
 func (m *mockChecksumManagerForRead) Get(path string) (string, bool) {
 	checksum, ok := m.checksums[path]
 	return checksum, ok
@@ -140,7 +228,7 @@ func TestReadFile(t *testing.T) {
 		content := []byte(contentStr)
 		fs.createFile("/workspace/test.txt", content)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		readReq := &ReadFileRequest{Path: "test.txt", Offset: 0, Limit: 100}
 		resp := executeRead(t, readTool, readReq)
@@ -171,7 +259,7 @@ func TestReadFile(t *testing.T) {
 		content := []byte("line1\nline2\nline3\nline4")
 		fs.createFile("/workspace/test.txt", content)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		// Read lines 2 and 3 (Offset=1, Limit=2)
 		readReq := &ReadFileRequest{Path: "test.txt", Offset: 1, Limit: 2}
@@ -214,7 +302,7 @@ func TestReadFile(t *testing.T) {
 		content := []byte{0x00, 0x01, 0x02, 't', 'e', 's', 't'}
 		fs.createFile("/workspace/binary.bin", content)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		readReq := &ReadFileRequest{Path: "binary.bin"}
 		resp := executeRead(t, readTool, readReq)
@@ -223,16 +311,82 @@ func TestReadFile(t *testing.T) {
 		}
 	})
 
-	t.Run("file size check still occurs", func(t *testing.T) {
+	t.Run("file above MaxFileSize is paged", func(t *testing.T) {
 		cfg := config.DefaultConfig()
 		cfg.Tools.MaxFileSize = 10 // small limit
 		fs := newMockFileSystemForRead(cfg)
 		checksumManager := newMockChecksumManagerForRead()
 
-		largeContent := []byte("this is more than 10 bytes")
+		largeContent := []byte("this is more than\n10 bytes\n")
 		fs.createFile("/workspace/large.txt", largeContent)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		readReq := &ReadFileRequest{Path: "large.txt", Offset: 1, Limit: 1}
+		resp := executeRead(t, readTool, readReq)
+		if resp.Error != "" {
+			t.Fatalf("Execute failed: %s", resp.Error)
+		}
+		if resp.Content != "10 bytes" || resp.TotalLines != 2 {
+			t.Errorf("expected second line, got %q (%d lines)", resp.Content, resp.TotalLines)
+		}
+	})
+
+	t.Run("a read returns at most MaxFileSize bytes", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Tools.MaxFileSize = 1000
+		fs := newMockFileSystemForRead(cfg)
+		checksumManager := newMockChecksumManagerForRead()
+		fs.createFile("/workspace/min.js", []byte("x"+strings.Repeat("é", 25000)))
+		fs.createFile("/workspace/app.log", []byte(strings.Repeat("0123456789abcdefghi\n", 100)))
+
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "min.js"})
+		if resp.Error != "" {
+			t.Fatalf("Execute failed: %s", resp.Error)
+		}
+		if !strings.HasSuffix(resp.Content, "...[truncated]") || len(resp.Content) > 1000+len("...[truncated]") {
+			t.Errorf("expected the line cut at 1000 bytes with a marker, got %d bytes ending %q", len(resp.Content), resp.Content[len(resp.Content)-20:])
+		}
+		if !utf8.ValidString(resp.Content) {
+			t.Errorf("expected the cut to keep whole characters")
+		}
+
+		resp = executeRead(t, readTool, &ReadFileRequest{Path: "app.log"})
+		if resp.EndLine != 50 || resp.TotalLines != 100 {
+			t.Errorf("expected 50 of 100 lines to fit in 1000 bytes, got lines %d-%d of %d", resp.StartLine, resp.EndLine, resp.TotalLines)
+		}
+		assertContains(t, resp.LLMContent(), "Use offset=50 to read more")
+	})
+
+	t.Run("lines longer than MaxLineLength are cut", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Tools.MaxLineLength = 8
+		fs := newMockFileSystemForRead(cfg)
+		checksumManager := newMockChecksumManagerForRead()
+		fs.createFile("/workspace/a.txt", []byte("short\r\n0123456789\r\nexactly8\r\nlast line"))
+
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "a.txt"})
+		want := "short\n01234567...[truncated]\nexactly8\nlast lin...[truncated]"
+		if resp.Content != want || resp.TotalLines != 4 {
+			t.Errorf("expected %q (4 lines), got %q (%d lines)", want, resp.Content, resp.TotalLines)
+		}
+	})
+
+	t.Run("UTF-16 file size check still occurs", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Tools.MaxFileSize = 10 // small limit
+		fs := newMockFileSystemForRead(cfg)
+		checksumManager := newMockChecksumManagerForRead()
+
+		// UTF-16 cannot be split into lines without decoding it whole.
+		largeContent := []byte{0xFF, 0xFE, 'm', 0, 'o', 0, 'r', 0, 'e', 0, ' ', 0, 't', 0, 'h', 0, 'a', 0, 'n', 0}
+		fs.createFile("/workspace/large.txt", largeContent)
+
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		readReq := &ReadFileRequest{Path: "large.txt"}
 		resp := executeRead(t, readTool, readReq)
@@ -248,7 +402,7 @@ func TestReadFile(t *testing.T) {
 		content := []byte("line1")
 		fs.createFile("/workspace/test.txt", content)
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 		offset := 100
 
 		readReq := &ReadFileRequest{Path: "test.txt", Offset: offset, Limit: 10}
@@ -276,7 +430,7 @@ func TestReadFile(t *testing.T) {
 		checksumManager := newMockChecksumManagerForRead()
 		fs.createDir("/workspace/subdir")
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		readReq := &ReadFileRequest{Path: "subdir"}
 		resp := executeRead(t, readTool, readReq)
@@ -290,7 +444,7 @@ func TestReadFile(t *testing.T) {
 		fs := newMockFileSystemForRead(cfg)
 		checksumManager := newMockChecksumManagerForRead()
 
-		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), cfg)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
 
 		readReq := &ReadFileRequest{Path: "nonexistent.txt"}
 		resp := executeRead(t, readTool, readReq)
@@ -298,6 +452,229 @@ func TestReadFile(t *testing.T) {
 			t.Errorf("expected error for nonexistent file, got success")
 		}
 	})
+
+	t.Run("UTF-16 file is transcoded and its encoding shown", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForRead(cfg)
+		checksumManager := newMockChecksumManagerForRead()
+		data := []byte{0xFF, 0xFE, 'a', 0x00, '\r', 0x00, '\n', 0x00, 0xE9, 0x00, '\r', 0x00, '\n', 0x00}
+		fs.createFile("/workspace/notes.txt", data)
+
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "notes.txt"})
+		if resp.Error != "" {
+			t.Fatalf("Execute failed: %s", resp.Error)
+		}
+		if resp.Content != "a\né" || resp.TotalLines != 2 {
+			t.Errorf("expected decoded content, got %q (%d lines)", resp.Content, resp.TotalLines)
+		}
+		assertContains(t, resp.LLMContent(), "<file encoding=\"UTF-16LE with BOM\">\n00001| a\n00002| é\n")
+
+		// The checksum covers the bytes on disk, so edits and the watcher agree on it.
+		normalized := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		if sum, _ := checksumManager.Get("/workspace/notes.txt"); sum != checksumManager.Compute(normalized) {
+			t.Errorf("unexpected checksum %s", sum)
+		}
+	})
+}
+
+func TestReadFileRanges(t *testing.T) {
+	workspaceRoot := "/workspace"
+	var sb strings.Builder
+	for i := 1; i <= 2500; i++ {
+		fmt.Fprintf(&sb, "line %d\r\n", i)
+	}
+	data := []byte(sb.String())
+
+	t.Run("seeks past indexed lines and reuses the index", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForRead(cfg)
+		checksumManager := newMockChecksumManagerForRead()
+		fs.createFile("/workspace/app.log", data)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "app.log", Offset: 1998, Limit: 4})
+		if resp.Error != "" {
+			t.Fatalf("Execute failed: %s", resp.Error)
+		}
+		if resp.Content != "line 1999\nline 2000\nline 2001\nline 2002" {
+			t.Errorf("unexpected content %q", resp.Content)
+		}
+		if resp.StartLine != 1999 || resp.EndLine != 2002 || resp.TotalLines != 2500 {
+			t.Errorf("unexpected lines %d-%d of %d", resp.StartLine, resp.EndLine, resp.TotalLines)
+		}
+
+		// The checksum is the one a full read would record.
+		normalized := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		if sum, _ := checksumManager.Get("/workspace/app.log"); sum != checksumManager.Compute(normalized) {
+			t.Errorf("unexpected checksum %s", sum)
+		}
+
+		opened := fs.opened
+		resp = executeRead(t, readTool, &ReadFileRequest{Path: "app.log", Offset: 2498})
+		if resp.Content != "line 2499\nline 2500" {
+			t.Errorf("unexpected content %q", resp.Content)
+		}
+		if fs.opened-opened != 1 {
+			t.Errorf("expected the cached index to be used, file opened %d times", fs.opened-opened)
+		}
+	})
+
+	t.Run("index is rebuilt when the file changes", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForRead(cfg)
+		checksumManager := newMockChecksumManagerForRead()
+		fs.createFile("/workspace/app.log", data)
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		executeRead(t, readTool, &ReadFileRequest{Path: "app.log", Offset: 10, Limit: 1})
+		fs.createFile("/workspace/app.log", append([]byte("header\r\n"), data...))
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "app.log", Offset: 2000, Limit: 1})
+		if resp.Content != "line 2000" || resp.TotalLines != 2501 {
+			t.Errorf("expected the new file's lines, got %q (%d lines)", resp.Content, resp.TotalLines)
+		}
+	})
+
+	t.Run("ISO-8859-1 and BOM", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForRead(cfg)
+		checksumManager := newMockChecksumManagerForRead()
+		fs.createFile("/workspace/latin1.txt", []byte("plain\ncaf\xE9\n"))
+		fs.createFile("/workspace/bom.txt", []byte("\xEF\xBB\xBFfirst\nsecond"))
+		readTool := NewReadFileTool(fs, checksumManager, path.NewResolver(workspaceRoot), nil, cfg)
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "latin1.txt", Offset: 1})
+		if resp.Content != "café" || resp.Encoding != "ISO-8859-1" {
+			t.Errorf("unexpected content %q in %q", resp.Content, resp.Encoding)
+		}
+		resp = executeRead(t, readTool, &ReadFileRequest{Path: "bom.txt"})
+		if resp.Content != "first\nsecond" || resp.TotalLines != 2 || resp.Encoding != "UTF-8 with BOM" {
+			t.Errorf("unexpected content %q (%d lines) in %q", resp.Content, resp.TotalLines, resp.Encoding)
+		}
+	})
+}
+
+func TestReadFileLogModes(t *testing.T) {
+	workspaceRoot := "/workspace"
+	var sb strings.Builder
+	for i := 1; i <= 2500; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	data := []byte(sb.String())
+
+	setup := func() (*mockFileSystemForRead, *ReadFileTool) {
+		cfg := config.DefaultConfig()
+		cfg.Tools.FollowPollIntervalMs = 1
+		fs := newMockFileSystemForRead(cfg)
+		fs.createFile("/workspace/app.log", data)
+		return fs, NewReadFileTool(fs, newMockChecksumManagerForRead(), path.NewResolver(workspaceRoot), nil, cfg)
+	}
+
+	t.Run("tail", func(t *testing.T) {
+		_, readTool := setup()
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "app.log", Tail: 2})
+		if resp.Content != "line 2499\nline 2500" || resp.StartLine != 2499 {
+			t.Errorf("unexpected tail %q from line %d", resp.Content, resp.StartLine)
+		}
+		assertContains(t, resp.LLMContent(), "(End of file - total 2500 lines)")
+	})
+
+	t.Run("since_line", func(t *testing.T) {
+		_, readTool := setup()
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "app.log", SinceLine: 2498})
+		if resp.Content != "line 2499\nline 2500" || resp.Reset {
+			t.Errorf("unexpected content %q (reset %v)", resp.Content, resp.Reset)
+		}
+		assertContains(t, resp.LLMContent(), "Use since_line=2500 follow=true to wait for more")
+
+		// A file shorter than since_line was truncated or rotated.
+		resp = executeRead(t, readTool, &ReadFileRequest{Path: "app.log", SinceLine: 3000, Limit: 1})
+		if resp.Content != "line 1" || !resp.Reset {
+			t.Errorf("expected a reset to the start, got %q (reset %v)", resp.Content, resp.Reset)
+		}
+		assertContains(t, resp.LLMContent(), "truncated or rotated")
+	})
+
+	t.Run("follow returns appended lines", func(t *testing.T) {
+		fs, readTool := setup()
+		stats := 0
+		fs.onStat = func() {
+			if stats++; stats == 3 {
+				fs.files["/workspace/app.log"] = append(append([]byte(nil), data...), "line 2501\n"...)
+			}
+		}
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "app.log", Follow: true})
+		if resp.Error != "" {
+			t.Fatalf("Execute failed: %s", resp.Error)
+		}
+		if resp.Content != "line 2501" || resp.StartLine != 2501 || resp.TotalLines != 2501 {
+			t.Errorf("expected only the new line, got %q from line %d", resp.Content, resp.StartLine)
+		}
+	})
+
+	t.Run("follow reads only what is appended", func(t *testing.T) {
+		fs, readTool := setup()
+		// The last line is still being written, so each append leaves the line count as it is.
+		fs.files["/workspace/app.log"] = append(append([]byte(nil), data...), "line 2501"...)
+		chunks := []string{" is", " written", " one", " word", " at", " a", " time", "\nline 2502\n"}
+		fs.onStat = func() {
+			if len(chunks) > 0 {
+				fs.files["/workspace/app.log"] = append(fs.files["/workspace/app.log"], chunks[0]...)
+				chunks = chunks[1:]
+			}
+		}
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "app.log", Follow: true})
+		if resp.Error != "" {
+			t.Fatalf("Execute failed: %s", resp.Error)
+		}
+		if resp.Content != "line 2502" || resp.StartLine != 2502 || resp.TotalLines != 2502 {
+			t.Errorf("expected the line after the one being written, got %q from line %d of %d", resp.Content, resp.StartLine, resp.TotalLines)
+		}
+		// One scan to start, one for the checksum at the end, and the appended bytes.
+		if limit := 3 * int64(len(data)); fs.read > limit {
+			t.Errorf("expected at most %d bytes read while following, got %d", limit, fs.read)
+		}
+	})
+
+	t.Run("follow starts over after truncation", func(t *testing.T) {
+		fs, readTool := setup()
+		stats := 0
+		fs.onStat = func() {
+			if stats++; stats == 3 {
+				fs.files["/workspace/app.log"] = []byte("restarted\n")
+			}
+		}
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "app.log", SinceLine: 2500, Follow: true})
+		if resp.Content != "restarted" || !resp.Reset {
+			t.Errorf("expected the new file from the start, got %q (reset %v)", resp.Content, resp.Reset)
+		}
+	})
+
+	t.Run("follow times out", func(t *testing.T) {
+		_, readTool := setup()
+		resp := executeRead(t, readTool, &ReadFileRequest{Path: "app.log", Follow: true, Timeout: 1})
+		if resp.Content != "" || !resp.TimedOut {
+			t.Errorf("expected a timeout without content, got %q", resp.Content)
+		}
+		assertContains(t, resp.LLMContent(), "No new lines within 1s - total 2500 lines")
+	})
+
+	t.Run("invalid combinations", func(t *testing.T) {
+		_, readTool := setup()
+		for _, req := range []*ReadFileRequest{
+			{Paths: []string{"app.log"}, Tail: 5},
+			{Path: "app.log", Tail: 5, Follow: true},
+			{Path: "app.log", Offset: 10, SinceLine: 5},
+			{Path: "app.log", Timeout: 5},
+			{Path: "app.log", Follow: true, Timeout: 1000},
+		} {
+			if resp := executeRead(t, readTool, req); resp.Error == "" {
+				t.Errorf("expected error for %+v", req)
+			}
+		}
+	})
 }
 
 func assertContains(t *testing.T, s, substr string) {
@@ -306,3 +683,127 @@ func assertContains(t *testing.T, s, substr string) {
 		t.Errorf("expected %q to contain %q", s, substr)
 	}
 }
+
+type mockIgnoreMatcherForRead map[string]bool
+
+func (m mockIgnoreMatcherForRead) ShouldIgnore(relativePath string) bool {
+	return m[relativePath]
+}
+
+func TestReadFileBatch(t *testing.T) {
+	setup := func(ignore ignoreMatcher) (*mockFileSystemForRead, *mockChecksumManagerForRead, *ReadFileTool) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForRead(cfg)
+		checksumManager := newMockChecksumManagerForRead()
+		fs.createFile("/workspace/main.tf", []byte("a\nb\n"))
+		fs.createFile("/workspace/net/main.tf", []byte("c\r\nd\r\n"))
+		fs.createFile("/workspace/net/vars.tf", []byte("e\n"))
+		fs.createFile("/workspace/net/README.md", []byte("readme\n"))
+		fs.createFile("/workspace/build/gen.tf", []byte("generated\n"))
+		return fs, checksumManager, NewReadFileTool(fs, checksumManager, path.NewResolver("/workspace"), ignore, cfg)
+	}
+
+	t.Run("glob reads matching files and records checksums", func(t *testing.T) {
+		_, checksumManager, readTool := setup(nil)
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Glob: "**/*.tf"})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+
+		var got []string
+		for _, f := range resp.Files {
+			got = append(got, f.Path)
+		}
+		sort.Strings(got)
+		want := []string{"build/gen.tf", "main.tf", "net/main.tf", "net/vars.tf"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("expected files %v, got %v", want, got)
+		}
+		for _, rel := range want {
+			if _, ok := checksumManager.Get(filepath.Join("/workspace", rel)); !ok {
+				t.Errorf("expected checksum for %s", rel)
+			}
+		}
+		if sum, _ := checksumManager.Get("/workspace/net/main.tf"); sum != checksumManager.Compute([]byte("c\nd\n")) {
+			t.Errorf("expected checksum of normalised content, got %s", sum)
+		}
+		assertContains(t, resp.LLMContent(), "<file path=\"net/main.tf\">\n00001| c\n00002| d\n")
+	})
+
+	t.Run("paths come first and ignored files are skipped", func(t *testing.T) {
+		_, _, readTool := setup(mockIgnoreMatcherForRead{"build": true})
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Paths: []string{"net/vars.tf"}, Glob: "**/*.tf"})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		if len(resp.Files) != 3 || resp.Files[0].Path != "net/vars.tf" {
+			t.Fatalf("expected net/vars.tf first and no duplicates, got %d files", len(resp.Files))
+		}
+		for _, f := range resp.Files {
+			if f.Path == "build/gen.tf" {
+				t.Errorf("expected ignored file to be skipped")
+			}
+		}
+	})
+
+	t.Run("byte budget cuts a file and lists the rest", func(t *testing.T) {
+		_, checksumManager, readTool := setup(nil)
+		readTool.config.Tools.MaxReadFileBatchSize = 5
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Paths: []string{"main.tf", "net/main.tf", "net/vars.tf"}})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		if len(resp.Files) != 2 {
+			t.Fatalf("expected 2 files read, got %d", len(resp.Files))
+		}
+		cut := resp.Files[1]
+		if !cut.CutOff || cut.Content != "c" || cut.EndLine != 1 {
+			t.Errorf("expected net/main.tf cut after one line, got %+v", cut)
+		}
+		if len(resp.Unread) != 1 || resp.Unread[0] != "net/vars.tf" {
+			t.Errorf("expected net/vars.tf unread, got %v", resp.Unread)
+		}
+		if _, ok := checksumManager.Get("/workspace/net/vars.tf"); ok {
+			t.Errorf("expected no checksum for unread file")
+		}
+
+		llm := resp.LLMContent()
+		assertContains(t, llm, `Use path="net/main.tf" offset=1 to read more`)
+		assertContains(t, llm, "Not read: net/vars.tf.")
+	})
+
+	t.Run("per-file errors are reported inline", func(t *testing.T) {
+		_, _, readTool := setup(nil)
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Paths: []string{"missing.tf", "main.tf"}})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		if len(resp.Files) != 2 || resp.Files[0].Error == "" || resp.Files[1].Error != "" {
+			t.Fatalf("expected error only for missing.tf, got %+v", resp.Files)
+		}
+		assertContains(t, resp.LLMContent(), "<file path=\"missing.tf\">\nError: ")
+	})
+
+	t.Run("glob without matches fails", func(t *testing.T) {
+		_, _, readTool := setup(nil)
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Glob: "**/*.go"})
+		if !strings.Contains(resp.Error, "no files match") {
+			t.Errorf("expected no-match error, got: %q", resp.Error)
+		}
+	})
+
+	t.Run("too many matches fails", func(t *testing.T) {
+		_, _, readTool := setup(nil)
+		readTool.config.Tools.MaxReadFileBatchFiles = 2
+
+		resp := executeRead(t, readTool, &ReadFileRequest{Glob: "**/*.tf"})
+		if !strings.Contains(resp.Error, "more than 2 files match") {
+			t.Errorf("expected limit error, got: %q", resp.Error)
+		}
+	})
+}
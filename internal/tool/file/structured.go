@@ -0,0 +1,150 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/helper/structured"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// EditStructuredTool edits JSON and YAML files by key path instead of by text.
+type EditStructuredTool struct {
+	fileOps         fileEditor
+	checksumManager checksumManager
+	config          *config.Config
+	pathResolver    pathResolver
+	diagnoser       diagnoser
+}
+
+// NewEditStructuredTool creates a new EditStructuredTool with injected dependencies.
+// diagnoser may be nil, in which case edited files are not checked.
+func NewEditStructuredTool(
+	fileOps fileEditor,
+	checksumManager checksumManager,
+	pathResolver pathResolver,
+	diagnoser diagnoser,
+	cfg *config.Config,
+) *EditStructuredTool {
+	if fileOps == nil {
+		panic("fileOps is required")
+	}
+	if checksumManager == nil {
+		panic("checksumManager is required")
+	}
+	if pathResolver == nil {
+		panic("pathResolver is required")
+	}
+	if cfg == nil {
+		panic("config is required")
+	}
+	return &EditStructuredTool{
+		fileOps:         fileOps,
+		checksumManager: checksumManager,
+		config:          cfg,
+		pathResolver:    pathResolver,
+		diagnoser:       diagnoser,
+	}
+}
+
+func (t *EditStructuredTool) Name() string {
+	return "edit_structured"
+}
+
+func (t *EditStructuredTool) Declaration() tool.Declaration {
+	return tool.Declaration{
+		Name: "edit_structured",
+		Description: "Edit a JSON or YAML file by key path, e.g. spec.template.spec.containers[name=nginx].image. " +
+			"Key order and YAML comments are kept, and lines outside the edited keys are left as they are where possible. " +
+			"Prefer this to edit_file when the same text appears many times, as in Kubernetes manifests.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"path": {Type: tool.TypeString, Description: "Path to a .json, .yaml or .yml file"},
+				"operations": {
+					Type:        tool.TypeArray,
+					Description: "Operations to apply in order; if any fails, the file is not changed",
+					Items: &tool.Schema{
+						Type:                 tool.TypeObject,
+						AdditionalProperties: tool.Ptr(false),
+						Properties: map[string]*tool.Schema{
+							"op": {
+								Type:        tool.TypeString,
+								Enum:        []string{structured.OpSet, structured.OpDelete, structured.OpAppend},
+								Description: "set replaces or adds a value, creating missing keys; delete removes a key or list element; append adds to the end of a list, creating it if missing",
+							},
+							"key_path": {
+								Type: tool.TypeString,
+								Description: "Dot-separated keys with [index] (negative counts from the end) or [key=value] to pick list elements; " +
+									`quote keys that contain dots, as in annotations["app.kubernetes.io/name"]; $ is the document root`,
+							},
+							"value":    {Description: "JSON value for set and append"},
+							"document": {Type: tool.TypeInteger, Description: "0-based document, required for YAML files with several documents", Minimum: tool.Ptr(0.0)},
+						},
+						Required: []string{"op", "key_path"},
+					},
+				},
+			},
+			Required: []string{"path", "operations"},
+		},
+	}
+}
+
+func (t *EditStructuredTool) Request() toolmanager.ToolRequest {
+	return &EditStructuredRequest{}
+}
+
+// Execute parses the file, applies the operations to its documents in memory
+// and writes the result atomically, with the same conflict detection as
+// edit_file. The file keeps its encoding and line endings, and once written is
+// checked with the formatters and linters configured for its extension.
+func (t *EditStructuredTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*EditStructuredRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+
+	if err := r.Validate(); err != nil {
+		return &EditFileResponse{Error: err.Error()}, nil
+	}
+
+	p, err := t.plan(r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &EditFileResponse{Error: err.Error()}, nil
+	}
+	return p.commit(ctx, t.fileOps, t.checksumManager, t.pathResolver, t.diagnoser)
+}
+
+func (t *EditStructuredTool) plan(r *EditStructuredRequest) (*editPlan, error) {
+	abs, err := t.pathResolver.Abs(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	file, perm, err := loadForEdit(t.fileOps, t.checksumManager, abs)
+	if err != nil {
+		return nil, err
+	}
+
+	format, _ := structured.FormatFor(abs)
+	ops := make([]structured.Operation, len(r.Operations))
+	for i, op := range r.Operations {
+		ops[i] = structured.Operation{Op: op.Op, Path: op.KeyPath, Value: op.Value, Document: -1}
+		if op.Document != nil {
+			ops[i].Document = *op.Document
+		}
+	}
+	content, notes, err := structured.Edit(file.text, format, ops)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", abs, err)
+	}
+	if content == file.text {
+		notes = append(notes, "the operations left the file as it was")
+	}
+	return newEditPlan(t.config, t.checksumManager, abs, perm, file, content, notes)
+}
@@ -0,0 +1,110 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+)
+
+func TestEditStructured(t *testing.T) {
+	newTool := func(cfg *config.Config) (*mockFileSystemForWrite, *mockChecksumManagerForWrite, *EditStructuredTool) {
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		return fs, checksumManager, NewEditStructuredTool(fs, checksumManager, path.NewResolver("/workspace"), nil, cfg)
+	}
+	execute := func(t *testing.T, st *EditStructuredTool, req *EditStructuredRequest) *EditFileResponse {
+		t.Helper()
+		result, err := st.Execute(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Execute returned error: %v", err)
+		}
+		return result.(*EditFileResponse)
+	}
+	doc := func(i int) *int { return &i }
+
+	t.Run("edits a document of a CRLF manifest", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs, checksumManager, st := newTool(cfg)
+		manifest := "kind: Deployment\r\nspec:\r\n  replicas: 1 # scaled by HPA\r\n---\r\nkind: Service\r\n"
+		fs.createFile("/workspace/k8s/web.yaml", []byte(manifest), 0o644)
+
+		resp := execute(t, st, &EditStructuredRequest{
+			Path:       "k8s/web.yaml",
+			Operations: []StructuredOperation{{Op: "set", KeyPath: "spec.replicas", Value: json.RawMessage(`3`), Document: doc(0)}},
+		})
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		data, _ := fs.ReadFile("/workspace/k8s/web.yaml")
+		if want := strings.Replace(manifest, "replicas: 1", "replicas: 3", 1); string(data) != want {
+			t.Errorf("expected %q, got %q", want, data)
+		}
+		if sum, _ := checksumManager.Get("/workspace/k8s/web.yaml"); sum != fileChecksum(checksumManager, data) {
+			t.Errorf("expected checksum of the written file to be cached")
+		}
+		if resp.AddedLines != 1 || resp.RemovedLines != 1 {
+			t.Errorf("expected a one-line diff, got +%d -%d", resp.AddedLines, resp.RemovedLines)
+		}
+		if _, ok := resp.Display().(tool.DiffDisplay); !ok {
+			t.Errorf("expected DiffDisplay, got %T", resp.Display())
+		}
+	})
+
+	t.Run("conflict detection", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs, checksumManager, st := newTool(cfg)
+		fs.createFile("/workspace/package.json", []byte("{\"version\": \"1.0.0\"}\n"), 0o644)
+		checksumManager.Update("/workspace/package.json", "stale")
+
+		resp := execute(t, st, &EditStructuredRequest{
+			Path:       "package.json",
+			Operations: []StructuredOperation{{Op: "set", KeyPath: "version", Value: json.RawMessage(`"1.1.0"`)}},
+		})
+		assertContains(t, resp.Error, "edit conflict")
+	})
+
+	t.Run("a failing operation leaves the file unchanged", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs, _, st := newTool(cfg)
+		original := []byte("{\"a\": 1}\n")
+		fs.createFile("/workspace/a.json", original, 0o644)
+
+		resp := execute(t, st, &EditStructuredRequest{
+			Path: "a.json",
+			Operations: []StructuredOperation{
+				{Op: "set", KeyPath: "b", Value: json.RawMessage(`2`)},
+				{Op: "delete", KeyPath: "c"},
+			},
+		})
+		assertContains(t, resp.Error, "operation 2 (delete c): c does not exist")
+		if data, _ := fs.ReadFile("/workspace/a.json"); string(data) != string(original) {
+			t.Errorf("expected file to be unchanged, got %q", data)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			req  EditStructuredRequest
+			want string
+		}{
+			{EditStructuredRequest{Path: "a.toml", Operations: []StructuredOperation{{Op: "delete", KeyPath: "a"}}}, "only .json, .yaml and .yml"},
+			{EditStructuredRequest{Path: "a.json"}, "operations are required"},
+			{EditStructuredRequest{Path: "a.json", Operations: []StructuredOperation{{Op: "set", KeyPath: "a"}}}, "value is required"},
+			{EditStructuredRequest{Path: "a.json", Operations: []StructuredOperation{{Op: "set", KeyPath: "a", Value: json.RawMessage(`{`)}}}, "value must be JSON"},
+			{EditStructuredRequest{Path: "a.json", Operations: []StructuredOperation{{Op: "delete", KeyPath: "a", Value: json.RawMessage(`1`)}}}, "not allowed for delete"},
+			{EditStructuredRequest{Path: "a.json", Operations: []StructuredOperation{{Op: "rename", KeyPath: "a"}}}, "op must be set, delete or append"},
+			{EditStructuredRequest{Path: "a.json", Operations: []StructuredOperation{{Op: "delete"}}}, "key_path is required"},
+		}
+		for _, tt := range tests {
+			err := tt.req.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// readLog serves the log modes of read_file: the last lines of a file, the lines
+// after a given line, and waiting for lines to be appended. They work on the line
+// index, so UTF-16 and UTF-32 files are not supported.
+func (t *ReadFileTool) readLog(ctx context.Context, abs string, r *ReadFileRequest) (*ReadFileResponse, error) {
+	prev := t.lines.peek(abs)
+	idx, err := t.lineIndex(abs)
+	if errors.Is(err, errWideEncoding) {
+		return &ReadFileResponse{Error: "tail, since_line and follow are not supported for UTF-16 and UTF-32 files"}, nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &ReadFileResponse{Error: err.Error()}, nil
+	}
+
+	if r.Tail > 0 {
+		t.checksumManager.Update(abs, idx.checksum)
+		offset := max(idx.lines-r.Tail, 0)
+		return t.readPage(abs, idx, offset, r.Tail), nil
+	}
+
+	since := r.SinceLine
+	if r.Follow && since == 0 {
+		since = idx.lines // Only what is appended from now on
+	}
+	// The lines before since_line are no longer the ones the caller read.
+	reset := since > idx.lines || (r.SinceLine > 0 && replaced(prev, idx))
+	if reset {
+		since = 0
+	}
+
+	timedOut := false
+	if r.Follow && idx.lines <= since {
+		var rotated bool
+		idx, rotated, timedOut, err = t.follow(ctx, abs, idx, since, time.Duration(r.Timeout)*time.Second)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return &ReadFileResponse{Error: err.Error()}, nil
+		}
+		if rotated {
+			reset, since = true, 0
+		}
+	}
+
+	t.checksumManager.Update(abs, idx.checksum)
+	resp := t.readPage(abs, idx, since, r.Limit)
+	if resp.Error != "" {
+		return resp, nil
+	}
+	resp.Since = true
+	resp.Reset = reset
+	resp.TimedOut = timedOut
+	resp.Timeout = r.Timeout
+	return resp, nil
+}
+
+// follow polls the file until it has lines after since, ctx is done or timeout
+// passes, and returns its index at that point. If the file is truncated or
+// replaced meanwhile, reset is true and the new file's lines all count as new.
+func (t *ReadFileTool) follow(ctx context.Context, abs string, idx *lineIndex, since int, timeout time.Duration) (
+	_ *lineIndex, reset, timedOut bool, err error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(time.Duration(t.config.Tools.FollowPollIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for idx.lines <= since {
+		select {
+		case <-ctx.Done():
+			return nil, false, false, ctx.Err()
+		case <-deadline.C:
+			if idx.checksum == "" {
+				if idx, err = t.lineIndex(abs); err != nil {
+					return nil, false, false, err
+				}
+			}
+			return idx, reset, true, nil
+		case <-ticker.C:
+		}
+
+		info, err := t.fileOps.Stat(abs)
+		if os.IsNotExist(err) {
+			continue // Rotated away; the new file is not there yet
+		}
+		if err != nil {
+			return nil, false, false, err
+		}
+		if info.Size() == idx.size && info.ModTime().Equal(idx.modTime) {
+			continue
+		}
+
+		// Appends only need the new bytes scanned; anything else rebuilds the index.
+		next, err := t.extendIndex(abs, idx, info)
+		if err == nil && next == nil {
+			next, err = t.lineIndex(abs)
+		}
+		if err != nil {
+			return nil, false, false, err
+		}
+		if replaced(idx, next) {
+			reset, since = true, 0
+		}
+		idx = next
+	}
+	if idx.checksum == "" {
+		// The checksum covers the whole file, so it is taken once, when following ends.
+		if idx, err = t.lineIndex(abs); err != nil {
+			return nil, false, false, err
+		}
+	}
+	return idx, reset, false, nil
+}
+
+// replaced reports whether the file indexed as next is not prev with lines
+// appended: it shrank, or it is another file at the same path. Telling files
+// apart needs the platform's file identity; without it only shrinking is seen.
+func replaced(prev, next *lineIndex) bool {
+	if prev == nil || prev == next {
+		return false
+	}
+	if next.size < prev.size {
+		return true
+	}
+	return prev.info.Sys() != nil && next.info.Sys() != nil && !os.SameFile(prev.info, next.info)
+}
@@ -1,29 +1,98 @@
 package file
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
 
 	"github.com/Cyclone1070/iav/internal/config"
 	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/helper/diffview"
+	"github.com/Cyclone1070/iav/internal/tool/helper/gosource"
+	"github.com/Cyclone1070/iav/internal/tool/helper/patch"
+	"github.com/Cyclone1070/iav/internal/tool/helper/structured"
+	"github.com/Cyclone1070/iav/internal/tool/service/diagnostics"
 )
 
 // -- Read File --
 
 type ReadFileRequest struct {
-	Path   string `json:"path"`
-	Offset int    `json:"offset,omitempty"` // 0-based start line
-	Limit  int    `json:"limit,omitempty"`  // Max lines to return
+	Path   string   `json:"path,omitempty"`
+	Paths  []string `json:"paths,omitempty"`  // More files to read in the same call
+	Glob   string   `json:"glob,omitempty"`   // Workspace-relative pattern; ** matches any number of directories
+	Offset int      `json:"offset,omitempty"` // 0-based start line, per file
+	Limit  int      `json:"limit,omitempty"`  // Max lines to return, per file
+
+	// Logs; a single path only
+	Tail      int  `json:"tail,omitempty"`       // Read the last Tail lines
+	SinceLine int  `json:"since_line,omitempty"` // Read the lines after this 1-based line, e.g. a previous TotalLines
+	Follow    bool `json:"follow,omitempty"`     // Wait for lines after SinceLine (or after the current end) to be appended
+	Timeout   int  `json:"timeout,omitempty"`    // Seconds Follow waits; default tools.default_follow_timeout
+}
+
+// batch reports whether more than the single Path form is used.
+func (r *ReadFileRequest) batch() bool {
+	return len(r.Paths) > 0 || r.Glob != ""
+}
+
+// log reports whether a log mode (tail, since_line or follow) is used.
+func (r *ReadFileRequest) log() bool {
+	return r.Tail > 0 || r.SinceLine > 0 || r.Follow
 }
 
 func (r *ReadFileRequest) Display() string {
-	return filepath.Base(r.Path)
+	if r.Glob != "" {
+		return r.Glob
+	}
+	if !r.batch() {
+		return filepath.Base(r.Path)
+	}
+	n := len(r.Paths)
+	if r.Path != "" {
+		n++
+	}
+	return fmt.Sprintf("%d files", n)
 }
 
 func (r *ReadFileRequest) Validate(cfg *config.Config) error {
-	if r.Path == "" {
-		return fmt.Errorf("path is required")
+	if r.Path == "" && !r.batch() {
+		return fmt.Errorf("path, paths or glob is required")
+	}
+	for i, p := range r.Paths {
+		if p == "" {
+			return fmt.Errorf("paths[%d] is empty", i)
+		}
+	}
+	if r.Glob != "" {
+		if _, err := filepath.Match(r.Glob, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %v", r.Glob, err)
+		}
+	}
+	if r.Tail < 0 || r.SinceLine < 0 || r.Timeout < 0 {
+		return fmt.Errorf("tail, since_line and timeout must not be negative")
+	}
+	if r.log() {
+		if r.batch() {
+			return fmt.Errorf("tail, since_line and follow read a single path, not paths or glob")
+		}
+		if r.Offset > 0 {
+			return fmt.Errorf("offset cannot be combined with tail, since_line or follow")
+		}
+		if r.Tail > 0 && (r.SinceLine > 0 || r.Follow) {
+			return fmt.Errorf("tail cannot be combined with since_line or follow")
+		}
+	}
+	if r.Timeout > 0 && !r.Follow {
+		return fmt.Errorf("timeout only applies with follow")
+	}
+	if r.Follow {
+		if r.Timeout == 0 {
+			r.Timeout = cfg.Tools.DefaultFollowTimeout
+		}
+		if r.Timeout > cfg.Tools.MaxFollowTimeout {
+			return fmt.Errorf("timeout %d exceeds max %d seconds", r.Timeout, cfg.Tools.MaxFollowTimeout)
+		}
 	}
 	if r.Offset < 0 {
 		r.Offset = 0
@@ -41,6 +110,20 @@ type ReadFileResponse struct {
 	EndLine    int    // calculated: StartLine + actual_lines - 1
 	TotalLines int
 	Error      string // Set if the tool failed (e.g. file not found)
+	Encoding   string // Detected encoding, if not plain UTF-8 (e.g. "UTF-16LE with BOM")
+
+	// Log reads (since_line and follow)
+	Since    bool // Lines were read after a given line; hints continue with since_line
+	Reset    bool // The file was truncated or rotated, so its lines were read from the start
+	TimedOut bool // Follow waited Timeout seconds without new lines
+	Timeout  int
+
+	// Multi-file reads
+	Path       string              // Workspace-relative; set for each file of a multi-file read
+	CutOff     bool                // Content was shortened to fit the byte budget
+	Files      []*ReadFileResponse // One per file, in request order
+	Unread     []string            // Files skipped once the byte budget ran out
+	BudgetSize int64               // The byte budget, for the cut-off note
 }
 
 // LLMContent returns the formatted XML block with pagination hints
@@ -48,26 +131,60 @@ func (r *ReadFileResponse) LLMContent() string {
 	if r.Error != "" {
 		return fmt.Sprintf("Error: %s", r.Error)
 	}
+	if r.Files == nil {
+		return r.block(r.header())
+	}
 
-	if r.Content == "" {
-		return fmt.Sprintf("<file>\n\n(End of file - total %d lines)\n</file>", r.TotalLines)
+	blocks := make([]string, 0, len(r.Files)+1)
+	for _, f := range r.Files {
+		header := f.header()
+		if f.Error != "" {
+			blocks = append(blocks, fmt.Sprintf("%s\nError: %s\n</file>", header, f.Error))
+			continue
+		}
+		blocks = append(blocks, f.block(header))
 	}
+	if len(r.Unread) > 0 {
+		blocks = append(blocks, fmt.Sprintf("(Byte budget of %d reached. Not read: %s. Read them in another call.)",
+			r.BudgetSize, strings.Join(r.Unread, ", ")))
+	}
+	return strings.Join(blocks, "\n\n")
+}
 
+// header returns the opening tag of a file block, with the file's path in a
+// multi-file read and its encoding if it is not plain UTF-8.
+func (r *ReadFileResponse) header() string {
 	var sb strings.Builder
-	sb.WriteString("<file>\n")
+	sb.WriteString("<file")
+	if r.Path != "" {
+		fmt.Fprintf(&sb, " path=%q", r.Path)
+	}
+	if r.Encoding != "" {
+		fmt.Fprintf(&sb, " encoding=%q", r.Encoding)
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
 
-	lines := strings.Split(r.Content, "\n")
-	for i, line := range lines {
-		// Avoid extra empty line at end if content ends with \n
-		if line == "" && i == len(lines)-1 {
-			break
-		}
-		sb.WriteString(fmt.Sprintf("%05d| %s\n", r.StartLine+i, line))
+// block formats one file's lines under the given opening tag.
+func (r *ReadFileResponse) block(header string) string {
+	if r.Since {
+		return r.sinceBlock(header)
+	}
+	if r.Content == "" && !r.CutOff {
+		return fmt.Sprintf("%s\n\n(End of file - total %d lines)\n</file>", header, r.TotalLines)
 	}
 
-	if r.EndLine < r.TotalLines {
+	var sb strings.Builder
+	sb.WriteString(header + "\n")
+	r.writeLines(&sb)
+
+	switch {
+	case r.CutOff:
+		sb.WriteString(fmt.Sprintf("\n(Cut off by the byte budget. Use path=%q offset=%d to read more)", r.Path, r.EndLine))
+	case r.EndLine < r.TotalLines:
 		sb.WriteString(fmt.Sprintf("\n(File has more lines. Use offset=%d to read more)", r.EndLine))
-	} else {
+	default:
 		sb.WriteString(fmt.Sprintf("\n(End of file - total %d lines)", r.TotalLines))
 	}
 
@@ -75,6 +192,47 @@ func (r *ReadFileResponse) LLMContent() string {
 	return sb.String()
 }
 
+// sinceBlock formats the lines of a since_line or follow read, with hints that
+// carry on from where it ended.
+func (r *ReadFileResponse) sinceBlock(header string) string {
+	var sb strings.Builder
+	sb.WriteString(header + "\n")
+	if r.Reset {
+		sb.WriteString("(The file was truncated or rotated; reading it from the start)\n")
+	}
+	r.writeLines(&sb)
+
+	switch {
+	case r.TimedOut:
+		sb.WriteString(fmt.Sprintf("\n(No new lines within %ds - total %d lines. Use since_line=%d follow=true to keep waiting)",
+			r.Timeout, r.TotalLines, r.TotalLines))
+	case r.EndLine < r.TotalLines:
+		sb.WriteString(fmt.Sprintf("\n(More new lines. Use since_line=%d to read more)", r.EndLine))
+	default:
+		sb.WriteString(fmt.Sprintf("\n(End of file - total %d lines. Use since_line=%d follow=true to wait for more)",
+			r.TotalLines, r.TotalLines))
+	}
+
+	sb.WriteString("\n</file>")
+	return sb.String()
+}
+
+// writeLines writes the content with line numbers.
+func (r *ReadFileResponse) writeLines(sb *strings.Builder) {
+	if r.Content == "" {
+		return
+	}
+
+	lines := strings.Split(r.Content, "\n")
+	for i, line := range lines {
+		// Avoid extra empty line at end if content ends with \n
+		if line == "" && i == len(lines)-1 {
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%05d| %s\n", r.StartLine+i, line))
+	}
+}
+
 // Display returns the UI representation
 func (r *ReadFileResponse) Display() tool.ToolDisplay {
 	if r.Error != "" {
@@ -90,8 +248,9 @@ func (r ReadFileResponse) Success() bool {
 // -- Write File --
 
 type WriteFileRequest struct {
-	Path    string `json:"path"`
-	Content string `json:"content"`
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Overwrite bool   `json:"overwrite,omitempty"` // Replace an existing file that was read and is unchanged
 }
 
 func (r *WriteFileRequest) Validate(cfg *config.Config) error {
@@ -111,6 +270,32 @@ type WriteFileResponse struct {
 	AbsolutePath string
 	RelativePath string
 	BytesWritten int
+	Overwritten  bool // Replaced an existing file
+
+	// For DiffDisplay
+	Diff         string
+	AddedLines   int
+	RemovedLines int
+
+	Diagnostics *diagnostics.Report // From the formatters and linters run after the write; nil if none ran
+}
+
+// LLMContent returns a success message with any diagnostics
+func (r *WriteFileResponse) LLMContent() string {
+	verb := "Created"
+	if r.Overwritten {
+		verb = "Overwrote"
+	}
+	msg := fmt.Sprintf("%s file: %s (%d bytes)", verb, r.RelativePath, r.BytesWritten)
+	if note := diagnosticsNote(r.Diagnostics); note != "" {
+		msg += "\n" + note
+	}
+	return msg
+}
+
+// Display returns DiffDisplay for UI rendering
+func (r *WriteFileResponse) Display() tool.ToolDisplay {
+	return diffview.Display(r.RelativePath, r.Diff, r.AddedLines, r.RemovedLines)
 }
 
 func (r WriteFileResponse) Success() bool {
@@ -157,6 +342,10 @@ type EditFileResponse struct {
 	Diff         string // Unified diff content
 	AddedLines   int
 	RemovedLines int
+
+	MatchNotes []string // How operations that did not match exactly were located
+
+	Diagnostics *diagnostics.Report // From the formatters and linters run after the edit; nil if none ran
 }
 
 // LLMContent returns success message or error
@@ -164,7 +353,15 @@ func (r *EditFileResponse) LLMContent() string {
 	if r.Error != "" {
 		return fmt.Sprintf("Error: %s", r.Error)
 	}
-	return fmt.Sprintf("Successfully modified file: %s", r.Path)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Successfully modified file: %s", r.Path)
+	for _, note := range r.MatchNotes {
+		fmt.Fprintf(&sb, "\nNote: %s", note)
+	}
+	if note := diagnosticsNote(r.Diagnostics); note != "" {
+		sb.WriteString("\n" + note)
+	}
+	return sb.String()
 }
 
 // Display returns DiffDisplay for UI rendering
@@ -172,13 +369,473 @@ func (r *EditFileResponse) Display() tool.ToolDisplay {
 	if r.Error != "" {
 		return tool.StringDisplay("Bad request")
 	}
-	return tool.DiffDisplay{
-		Diff:         r.Diff,
-		AddedLines:   r.AddedLines,
-		RemovedLines: r.RemovedLines,
-	}
+	d := diffview.Display("", r.Diff, r.AddedLines, r.RemovedLines)
+	d.Language = diffview.Language(r.Path) // Path is absolute, so it is not shown
+	return d
 }
 
 func (r EditFileResponse) Success() bool {
 	return r.Error == ""
 }
+
+// -- Edit Structured --
+
+type StructuredOperation struct {
+	Op       string          `json:"op"`                 // set, delete or append
+	KeyPath  string          `json:"key_path"`           // e.g. spec.containers[name=nginx].image
+	Value    json.RawMessage `json:"value,omitempty"`    // For set and append
+	Document *int            `json:"document,omitempty"` // 0-based; for multi-document YAML
+}
+
+type EditStructuredRequest struct {
+	Path       string                `json:"path"`
+	Operations []StructuredOperation `json:"operations"`
+}
+
+func (r *EditStructuredRequest) Display() string {
+	return filepath.Base(r.Path)
+}
+
+func (r *EditStructuredRequest) Validate() error {
+	if r.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if _, ok := structured.FormatFor(r.Path); !ok {
+		return fmt.Errorf("only .json, .yaml and .yml files are supported")
+	}
+	if len(r.Operations) == 0 {
+		return fmt.Errorf("operations are required")
+	}
+	for i, op := range r.Operations {
+		if err := op.validate(); err != nil {
+			return fmt.Errorf("operations[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (o *StructuredOperation) validate() error {
+	if strings.TrimSpace(o.KeyPath) == "" {
+		return fmt.Errorf("key_path is required; use $ for the document root")
+	}
+	switch o.Op {
+	case structured.OpSet, structured.OpAppend:
+		if len(o.Value) == 0 {
+			return fmt.Errorf("value is required for %s (null cannot be set; delete the key instead)", o.Op)
+		}
+		if !json.Valid(o.Value) {
+			return fmt.Errorf("value must be JSON")
+		}
+	case structured.OpDelete:
+		if len(o.Value) > 0 {
+			return fmt.Errorf("value is not allowed for delete")
+		}
+	default:
+		return fmt.Errorf("op must be set, delete or append, got %q", o.Op)
+	}
+	if o.Document != nil && *o.Document < 0 {
+		return fmt.Errorf("document cannot be negative")
+	}
+	return nil
+}
+
+// -- Go Refactor --
+
+// Operations of go_refactor.
+const (
+	GoOpList         = "list"
+	GoOpRename       = "rename"
+	GoOpReplaceBody  = "replace_body"
+	GoOpAddImport    = "add_import"
+	GoOpRemoveImport = "remove_import"
+)
+
+type GoRefactorRequest struct {
+	Op         string `json:"op"`
+	Path       string `json:"path"`                  // A .go file; for rename, any file of the package
+	Name       string `json:"name,omitempty"`        // rename and replace_body: Name, or Type.Member
+	Line       int    `json:"line,omitempty"`        // rename: line of Name in path, for local identifiers
+	NewName    string `json:"new_name,omitempty"`    // rename
+	Body       string `json:"body,omitempty"`        // replace_body: statements, with or without braces
+	ImportPath string `json:"import_path,omitempty"` // add_import and remove_import
+	ImportName string `json:"import_name,omitempty"` // add_import: optional local name
+}
+
+func (r *GoRefactorRequest) Display() string {
+	if r.Name != "" {
+		return filepath.Base(r.Path) + ": " + r.Name
+	}
+	if r.ImportPath != "" {
+		return filepath.Base(r.Path) + ": " + r.ImportPath
+	}
+	return filepath.Base(r.Path)
+}
+
+func (r *GoRefactorRequest) Validate() error {
+	if r.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if !strings.HasSuffix(r.Path, ".go") {
+		return fmt.Errorf("path must be a .go file")
+	}
+	if r.Line < 0 {
+		return fmt.Errorf("line cannot be negative")
+	}
+	switch r.Op {
+	case GoOpList:
+	case GoOpRename:
+		if r.Name == "" || r.NewName == "" {
+			return fmt.Errorf("name and new_name are required for rename")
+		}
+		if r.Line > 0 && strings.Contains(r.Name, ".") {
+			return fmt.Errorf("line names a local identifier, not Type.Member")
+		}
+	case GoOpReplaceBody:
+		if r.Name == "" {
+			return fmt.Errorf("name is required for replace_body")
+		}
+		if strings.TrimSpace(r.Body) == "" {
+			return fmt.Errorf("body is required for replace_body")
+		}
+	case GoOpAddImport, GoOpRemoveImport:
+		if r.ImportPath == "" {
+			return fmt.Errorf("import_path is required for %s", r.Op)
+		}
+		if r.ImportName != "" && r.Op == GoOpRemoveImport {
+			return fmt.Errorf("import_name is only used by add_import")
+		}
+	default:
+		return fmt.Errorf("op must be list, rename, replace_body, add_import or remove_import, got %q", r.Op)
+	}
+	return nil
+}
+
+type GoRefactorResponse struct {
+	Op    string
+	Error string // Set if the tool failed; no file was changed
+
+	// list
+	Path         string // Workspace-relative
+	Package      string
+	Declarations []gosource.Declaration
+
+	Files []ChangedFile
+	Notes []string // About the package as a whole, e.g. exported names other packages use
+}
+
+// LLMContent returns the declarations, a per-file summary or error
+func (r *GoRefactorResponse) LLMContent() string {
+	if r.Error != "" {
+		return fmt.Sprintf("Error: %s", r.Error)
+	}
+	var sb strings.Builder
+	if r.Op == GoOpList {
+		fmt.Fprintf(&sb, "%s: package %s, %d declaration(s)", r.Path, r.Package, len(r.Declarations))
+		for _, d := range r.Declarations {
+			fmt.Fprintf(&sb, "\n%d-%d %s", d.StartLine, d.EndLine, d.Signature)
+		}
+		return sb.String()
+	}
+	sb.WriteString("Successfully changed " + summarizeFiles(r.Files))
+	for _, note := range r.Notes {
+		fmt.Fprintf(&sb, "\nNote: %s", note)
+	}
+	return sb.String()
+}
+
+// Display returns a diff per changed file, or a count for list
+func (r *GoRefactorResponse) Display() tool.ToolDisplay {
+	if r.Error != "" {
+		return tool.StringDisplay("Bad request")
+	}
+	if r.Op == GoOpList {
+		return tool.StringDisplay(fmt.Sprintf("%d declarations", len(r.Declarations)))
+	}
+	if len(r.Files) == 1 {
+		return diffDisplays(r.Files)[0]
+	}
+	return diffDisplays(r.Files)
+}
+
+func (r GoRefactorResponse) Success() bool {
+	return r.Error == ""
+}
+
+// -- Apply Patch --
+
+type ApplyPatchRequest struct {
+	Patch string `json:"patch"`
+}
+
+func (r *ApplyPatchRequest) Display() string {
+	patches, err := patch.Parse(r.Patch)
+	if err != nil {
+		return "patch"
+	}
+	if len(patches) == 1 {
+		return filepath.Base(patches[0].Path())
+	}
+	return fmt.Sprintf("%d files", len(patches))
+}
+
+func (r *ApplyPatchRequest) Validate(cfg *config.Config) error {
+	if strings.TrimSpace(r.Patch) == "" {
+		return fmt.Errorf("patch is required")
+	}
+	if int64(len(r.Patch)) > cfg.Tools.MaxFileSize {
+		return fmt.Errorf("patch too large: %d bytes exceeds limit %d", len(r.Patch), cfg.Tools.MaxFileSize)
+	}
+	return nil
+}
+
+// ChangedFile describes one file changed by apply_patch or edit_files.
+type ChangedFile struct {
+	Op      string // add, modify, delete or rename
+	Path    string // Workspace-relative; the new path for renames
+	OldPath string // Set for renames
+
+	Diff         string
+	AddedLines   int
+	RemovedLines int
+
+	Notes []string // Hunks or edits that needed an offset, fuzz or whitespace tolerance
+
+	Diagnostics *diagnostics.Report // From the formatters and linters run after the change; nil if none ran
+}
+
+type ApplyPatchResponse struct {
+	Files []ChangedFile
+	Error string // Set if the tool failed; no file was changed
+}
+
+// LLMContent returns a per-file summary or error
+func (r *ApplyPatchResponse) LLMContent() string {
+	if r.Error != "" {
+		return fmt.Sprintf("Error: %s", r.Error)
+	}
+	return "Successfully applied patch to " + summarizeFiles(r.Files)
+}
+
+// Display returns one diff per file for UI rendering
+func (r *ApplyPatchResponse) Display() tool.ToolDisplay {
+	if r.Error != "" {
+		return tool.StringDisplay("Bad request")
+	}
+	return diffDisplays(r.Files)
+}
+
+func (r ApplyPatchResponse) Success() bool {
+	return r.Error == ""
+}
+
+// summarizeFiles lists changed files with their line counts and notes.
+func summarizeFiles(files []ChangedFile) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d file(s):", len(files))
+	for _, f := range files {
+		name := f.Path
+		if f.OldPath != "" {
+			name = f.OldPath + " -> " + f.Path
+		}
+		fmt.Fprintf(&sb, "\n- %s %s (+%d -%d)", f.Op, name, f.AddedLines, f.RemovedLines)
+		for _, note := range f.Notes {
+			fmt.Fprintf(&sb, "\n  %s", note)
+		}
+		if note := diagnosticsNote(f.Diagnostics); note != "" {
+			sb.WriteString("\n  " + strings.ReplaceAll(note, "\n", "\n  "))
+		}
+	}
+	return sb.String()
+}
+
+func diffDisplays(files []ChangedFile) tool.MultiDiffDisplay {
+	diffs := make(tool.MultiDiffDisplay, len(files))
+	for i, f := range files {
+		diffs[i] = diffview.Display(f.Path, f.Diff, f.AddedLines, f.RemovedLines)
+	}
+	return diffs
+}
+
+// -- Edit Files --
+
+// BatchEditRequest edits and creates several files all-or-nothing.
+type BatchEditRequest struct {
+	Edits  []EditFileRequest  `json:"edits,omitempty"`
+	Writes []WriteFileRequest `json:"writes,omitempty"`
+}
+
+func (r *BatchEditRequest) Display() string {
+	if n := len(r.Edits) + len(r.Writes); n != 1 {
+		return fmt.Sprintf("%d files", n)
+	}
+	if len(r.Edits) == 1 {
+		return filepath.Base(r.Edits[0].Path)
+	}
+	return filepath.Base(r.Writes[0].Path)
+}
+
+func (r *BatchEditRequest) Validate(cfg *config.Config) error {
+	if len(r.Edits) == 0 && len(r.Writes) == 0 {
+		return fmt.Errorf("edits or writes are required")
+	}
+	for i := range r.Edits {
+		if err := r.Edits[i].Validate(); err != nil {
+			return fmt.Errorf("edits[%d]: %w", i, err)
+		}
+	}
+	for i := range r.Writes {
+		if err := r.Writes[i].Validate(cfg); err != nil {
+			return fmt.Errorf("writes[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+type BatchEditResponse struct {
+	Files []ChangedFile
+	Error string // Set if the tool failed; no file was changed
+}
+
+// LLMContent returns a per-file summary or error
+func (r *BatchEditResponse) LLMContent() string {
+	if r.Error != "" {
+		return fmt.Sprintf("Error: %s", r.Error)
+	}
+	return "Successfully changed " + summarizeFiles(r.Files)
+}
+
+// Display returns one diff per file for UI rendering
+func (r *BatchEditResponse) Display() tool.ToolDisplay {
+	if r.Error != "" {
+		return tool.StringDisplay("Bad request")
+	}
+	return diffDisplays(r.Files)
+}
+
+func (r BatchEditResponse) Success() bool {
+	return r.Error == ""
+}
+
+// -- Manage Files --
+
+type MoveFileRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Recursive   bool   `json:"recursive,omitempty"` // Required to move a directory
+	Overwrite   bool   `json:"overwrite,omitempty"` // Replace an existing file that was read and is unchanged
+}
+
+func (r *MoveFileRequest) Display() string {
+	return filepath.Base(r.Source) + " -> " + filepath.Base(r.Destination)
+}
+
+func (r *MoveFileRequest) Validate(cfg *config.Config) error {
+	return validateSourceAndDestination(r.Source, r.Destination)
+}
+
+type CopyFileRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Recursive   bool   `json:"recursive,omitempty"` // Required to copy a directory
+	Overwrite   bool   `json:"overwrite,omitempty"` // Replace an existing file that was read and is unchanged
+}
+
+func (r *CopyFileRequest) Display() string {
+	return filepath.Base(r.Source) + " -> " + filepath.Base(r.Destination)
+}
+
+func (r *CopyFileRequest) Validate(cfg *config.Config) error {
+	return validateSourceAndDestination(r.Source, r.Destination)
+}
+
+func validateSourceAndDestination(source, destination string) error {
+	if source == "" {
+		return fmt.Errorf("source is required")
+	}
+	if destination == "" {
+		return fmt.Errorf("destination is required")
+	}
+	return nil
+}
+
+type DeleteFileRequest struct {
+	Path      string `json:"path"`
+	Recursive bool   `json:"recursive,omitempty"` // Required to delete a directory
+}
+
+func (r *DeleteFileRequest) Display() string {
+	return filepath.Base(r.Path)
+}
+
+func (r *DeleteFileRequest) Validate(cfg *config.Config) error {
+	if r.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	return nil
+}
+
+type CreateDirectoryRequest struct {
+	Path string `json:"path"`
+}
+
+func (r *CreateDirectoryRequest) Display() string {
+	return filepath.Base(r.Path)
+}
+
+func (r *CreateDirectoryRequest) Validate(cfg *config.Config) error {
+	if r.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	return nil
+}
+
+// FileOperationResponse is the result of move_file, copy_file, delete_file or create_directory.
+type FileOperationResponse struct {
+	Action   string // Moved, Copied, Deleted or Created
+	Path     string // Workspace-relative; the destination of a move or copy
+	From     string // Workspace-relative source of a move or copy
+	IsDir    bool
+	Files    int  // Files in a directory that was moved, copied or deleted
+	Replaced bool // An existing destination file was overwritten
+	Existed  bool // create_directory found the directory already there
+	Error    string
+}
+
+// LLMContent returns a one-line summary or error
+func (r *FileOperationResponse) LLMContent() string {
+	if r.Error != "" {
+		return fmt.Sprintf("Error: %s", r.Error)
+	}
+	if r.Existed {
+		return fmt.Sprintf("Directory %s already exists", r.Path)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(r.Action)
+	if r.IsDir {
+		sb.WriteString(" directory")
+	}
+	if r.From != "" {
+		fmt.Fprintf(&sb, " %s to", r.From)
+	}
+	sb.WriteString(" " + r.Path)
+	if r.IsDir && r.Action != "Created" {
+		fmt.Fprintf(&sb, " (%d files)", r.Files)
+	}
+	if r.Replaced {
+		sb.WriteString(", replacing the existing file")
+	}
+	return sb.String()
+}
+
+// Display returns the summary for UI rendering
+func (r *FileOperationResponse) Display() tool.ToolDisplay {
+	if r.Error != "" {
+		return tool.StringDisplay("Bad request")
+	}
+	return tool.StringDisplay(r.LLMContent())
+}
+
+func (r FileOperationResponse) Success() bool {
+	return r.Error == ""
+}
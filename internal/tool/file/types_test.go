@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
 )
 
 func TestReadFileRequest_Validation(t *testing.T) {
@@ -100,3 +102,41 @@ func TestEditFileRequest_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestDeclarations_RefuseUnknownProperties(t *testing.T) {
+	cfg := config.DefaultConfig()
+	fs := newMockFileSystemForWrite(cfg)
+	checksums := newMockChecksumManagerForWrite()
+	resolver := path.NewResolver("/workspace")
+
+	tools := []interface{ Declaration() tool.Declaration }{
+		NewReadFileTool(newMockFileSystemForRead(cfg), newMockChecksumManagerForRead(), resolver, nil, cfg),
+		NewEditFileTool(fs, checksums, resolver, nil, cfg),
+		NewBatchEditTool(fs, checksums, resolver, nil, cfg),
+		NewApplyPatchTool(fs, checksums, resolver, nil, cfg),
+		NewEditStructuredTool(fs, checksums, resolver, nil, cfg),
+		NewGoRefactorTool(fs, checksums, resolver, nil, cfg),
+		NewMoveFileTool(fs, checksums, resolver, cfg),
+		NewCopyFileTool(fs, checksums, resolver, cfg),
+		NewDeleteFileTool(fs, checksums, resolver, cfg),
+		NewCreateDirectoryTool(fs, checksums, resolver, cfg),
+	}
+
+	var check func(name, at string, s *tool.Schema)
+	check = func(name, at string, s *tool.Schema) {
+		if s == nil {
+			return
+		}
+		if s.Type == tool.TypeObject && (s.AdditionalProperties == nil || *s.AdditionalProperties) {
+			t.Errorf("%s: object at %s accepts unknown properties", name, at)
+		}
+		for prop, ps := range s.Properties {
+			check(name, at+"."+prop, ps)
+		}
+		check(name, at+"[]", s.Items)
+	}
+	for _, ft := range tools {
+		decl := ft.Declaration()
+		check(decl.Name, "$", decl.Parameters)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/Cyclone1070/iav/internal/config"
 	"github.com/Cyclone1070/iav/internal/tool/helper/content"
@@ -13,30 +14,28 @@ import (
 // fileWriter defines the minimal filesystem operations needed for writing files.
 type fileWriter interface {
 	Stat(path string) (os.FileInfo, error)
+	ReadFile(path string) ([]byte, error)
 	WriteFileAtomic(path string, content []byte, perm os.FileMode) error
 	EnsureDirs(path string) error
 }
 
-// checksumUpdater defines the interface for checksum computation and updates.
-type checksumUpdater interface {
-	Compute(data []byte) string
-	Update(path string, checksum string)
-}
-
 // WriteFileTool handles file writing operations.
 type WriteFileTool struct {
 	fileOps         fileWriter
-	checksumManager checksumUpdater
+	checksumManager checksumManager
 	config          *config.Config
 	pathResolver    pathResolver
+	diagnoser       diagnoser
 }
 
 // NewWriteFileTool creates a new WriteFileTool with injected dependencies.
+// diagnoser may be nil, in which case written files are not checked.
 func NewWriteFileTool(
 	fileOps fileWriter,
-	checksumManager checksumUpdater,
+	checksumManager checksumManager,
 	cfg *config.Config,
 	pathResolver pathResolver,
+	diagnoser diagnoser,
 ) *WriteFileTool {
 	if fileOps == nil {
 		panic("fileOps is required")
@@ -55,6 +54,7 @@ func NewWriteFileTool(
 		checksumManager: checksumManager,
 		config:          cfg,
 		pathResolver:    pathResolver,
+		diagnoser:       diagnoser,
 	}
 }
 
@@ -63,8 +63,59 @@ func NewWriteFileTool(
 // enforces size limits, and writes atomically using a temp file + rename pattern.
 // Returns an error if the file already exists, is binary, too large, or outside the workspace.
 //
-// Note: ctx is accepted for API consistency but not used - file I/O is synchronous.
+// With Overwrite set, an existing file is replaced instead, but only if it was read
+// earlier and has not changed since, as for edit_file. Its permissions and encoding are kept.
+// The written file is then checked by the configured formatters and linters; only a
+// cancelled ctx stops that with an error.
 func (t *WriteFileTool) Run(ctx context.Context, req *WriteFileRequest) (*WriteFileResponse, error) {
+	p, err := t.plan(req)
+	if err != nil {
+		return nil, err
+	}
+
+	parentDir := filepath.Dir(p.abs)
+	if err := t.fileOps.EnsureDirs(parentDir); err != nil {
+		return nil, fmt.Errorf("failed to create directories for %s: %w", parentDir, err)
+	}
+
+	// Write the file atomically
+	if err := t.fileOps.WriteFileAtomic(p.abs, p.content, p.perm); err != nil {
+		return nil, fmt.Errorf("failed to write file %s: %w", p.abs, err)
+	}
+
+	// Compute checksum and update cache
+	t.checksumManager.Update(p.abs, fileChecksum(t.checksumManager, p.content))
+
+	report, err := checkWritten(ctx, t.diagnoser, t.fileOps, t.checksumManager, p.abs, p.rel, p.content, p.perm)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, added, removed := p.diff()
+	return &WriteFileResponse{
+		AbsolutePath: p.abs,
+		RelativePath: p.rel,
+		BytesWritten: len(p.content),
+		Overwritten:  p.exists,
+		Diff:         diff,
+		AddedLines:   added,
+		RemovedLines: removed,
+		Diagnostics:  report,
+	}, nil
+}
+
+// writePlan is a validated write, ready to go to disk.
+type writePlan struct {
+	abs, rel   string
+	text       string // The requested content
+	content    []byte // text in the file's encoding
+	perm       os.FileMode
+	exists     bool   // Overwriting an existing file
+	oldContent string // Normalised to \n; empty for new files
+}
+
+// plan resolves and validates a write request without touching the filesystem.
+func (t *WriteFileTool) plan(req *WriteFileRequest) (*writePlan, error) {
 	if err := req.Validate(t.config); err != nil {
 		return nil, err
 	}
@@ -78,40 +129,70 @@ func (t *WriteFileTool) Run(ctx context.Context, req *WriteFileRequest) (*WriteF
 		return nil, err
 	}
 
+	p := &writePlan{abs: abs, rel: rel, text: req.Content, content: []byte(req.Content), perm: 0o644}
+
+	// Check for binary content
+	if content.IsBinaryContent(p.content) {
+		return nil, fmt.Errorf("cannot write binary content to: %s", abs)
+	}
+
 	// Check if file already exists
-	_, err = t.fileOps.Stat(abs)
+	info, err := t.fileOps.Stat(abs)
 	if err == nil {
-		return nil, fmt.Errorf("file already exists: %s", abs)
-	}
-	if !os.IsNotExist(err) {
+		if !req.Overwrite {
+			return nil, fmt.Errorf("file already exists: %s", abs)
+		}
+		if err := t.checkOverwrite(p, info); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to stat %s: %w", abs, err)
 	}
 
-	parentDir := filepath.Dir(abs)
-	if err := t.fileOps.EnsureDirs(parentDir); err != nil {
-		return nil, fmt.Errorf("failed to create directories for %s: %w", parentDir, err)
-	}
+	return p, nil
+}
 
-	contentBytes := []byte(req.Content)
+// checkOverwrite allows replacing an existing file only if it was read earlier
+// and is unchanged since, and records its content and permissions.
+func (t *WriteFileTool) checkOverwrite(p *writePlan, info os.FileInfo) error {
+	if info.IsDir() {
+		return fmt.Errorf("path is a directory: %s", p.abs)
+	}
 
-	// Check for binary content
-	if content.IsBinaryContent(contentBytes) {
-		return nil, fmt.Errorf("cannot write binary content to: %s", abs)
+	data, err := t.fileOps.ReadFile(p.abs)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", p.abs, err)
+	}
+	prior, ok := t.checksumManager.Get(p.abs)
+	if !ok {
+		return fmt.Errorf("cannot overwrite %s: read the file first", p.abs)
+	}
+	if prior != fileChecksum(t.checksumManager, data) {
+		return fmt.Errorf("edit conflict: file changed since last read: %s", p.abs)
 	}
 
-	perm := os.FileMode(0o644)
-	// Write the file atomically
-	if err := t.fileOps.WriteFileAtomic(abs, contentBytes, perm); err != nil {
-		return nil, fmt.Errorf("failed to write file %s: %w", abs, err)
+	old, err := decodeText(data)
+	if err != nil {
+		return fmt.Errorf("cannot decode %s: %w", p.abs, err)
+	}
+	// Keep the file's encoding and BOM; line endings are written as given.
+	if old.enc != content.UTF8 {
+		if p.content, err = content.Encode(string(p.content), old.enc); err != nil {
+			return fmt.Errorf("cannot save %s as %s: %w", p.abs, old.enc, err)
+		}
 	}
 
-	// Compute checksum and update cache
-	checksum := t.checksumManager.Compute(contentBytes)
-	t.checksumManager.Update(abs, checksum)
+	p.exists = true
+	p.oldContent = old.text
+	p.perm = info.Mode()
+	return nil
+}
 
-	return &WriteFileResponse{
-		AbsolutePath: abs,
-		RelativePath: rel,
-		BytesWritten: len(contentBytes),
-	}, nil
+// diff returns the change the write makes, against /dev/null for new files.
+func (p *writePlan) diff() (diff string, added, removed int) {
+	newContent := strings.ReplaceAll(p.text, "\r\n", "\n")
+	if !p.exists {
+		return unifiedDiff("/dev/null", "b/"+p.rel, "", newContent)
+	}
+	return unifiedDiff("a/"+p.rel, "b/"+p.rel, p.oldContent, newContent)
 }
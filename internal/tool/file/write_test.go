@@ -1,9 +1,11 @@
 package file
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,6 +13,9 @@ import (
 	"time"
 
 	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/diagnostics"
+	"github.com/Cyclone1070/iav/internal/tool/service/fs"
 	"github.com/Cyclone1070/iav/internal/tool/service/path"
 )
 
@@ -104,13 +109,40 @@ func (m *mockFileSystemForWrite) ReadFile(path string) ([]byte, error) {
 	return entry.content, nil
 }
 
+func (m *mockFileSystemForWrite) Open(path string) (io.ReadSeekCloser, error) {
+	entry, ok := m.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return nopSeekCloser{bytes.NewReader(entry.content)}, nil
+}
+
+func (m *mockFileSystemForWrite) ListDir(path string) ([]os.FileInfo, error) {
+	var infos []os.FileInfo
+	for p, entry := range m.files {
+		if filepath.Dir(p) == path {
+			infos = append(infos, &mockFileInfoForWrite{name: filepath.Base(p), size: int64(len(entry.content)), mode: entry.mode})
+		}
+	}
+	for p := range m.dirs {
+		if filepath.Dir(p) == path {
+			infos = append(infos, &mockFileInfoForWrite{name: filepath.Base(p), isDir: true, mode: 0755})
+		}
+	}
+	for p := range m.symlinks {
+		if filepath.Dir(p) == path {
+			infos = append(infos, &mockFileInfoForWrite{name: filepath.Base(p), mode: os.ModeSymlink | 0o777})
+		}
+	}
+	return infos, nil
+}
+
 func (m *mockFileSystemForWrite) EnsureDirs(path string) error {
 	if m.operationErrors["EnsureDirs"] != nil {
 		return m.operationErrors["EnsureDirs"]
 	}
-	// Create all parent directories
-	dir := filepath.Dir(path)
-	parts := strings.Split(dir, "/")
+	// Create the directory and all its parents, as os.MkdirAll does
+	parts := strings.Split(path, "/")
 	current := ""
 	for _, part := range parts {
 		if part == "" {
@@ -136,6 +168,121 @@ func (m *mockFileSystemForWrite) WriteFileAtomic(path string, content []byte, pe
 	return nil
 }
 
+func (m *mockFileSystemForWrite) Remove(path string) error {
+	if m.operationErrors["Remove"] != nil {
+		return m.operationErrors["Remove"]
+	}
+	if _, ok := m.files[path]; !ok {
+		return os.ErrNotExist
+	}
+	delete(m.files, path)
+	return nil
+}
+
+func (m *mockFileSystemForWrite) RemoveAll(path string) error {
+	if m.operationErrors["RemoveAll"] != nil {
+		return m.operationErrors["RemoveAll"]
+	}
+	under := func(p string) bool { return p == path || strings.HasPrefix(p, path+"/") }
+	for p := range m.files {
+		if under(p) {
+			delete(m.files, p)
+		}
+	}
+	for p := range m.dirs {
+		if under(p) {
+			delete(m.dirs, p)
+		}
+	}
+	for p := range m.symlinks {
+		if under(p) {
+			delete(m.symlinks, p)
+		}
+	}
+	return nil
+}
+
+// Rename moves a file, symlink or directory tree, replacing a file at newPath.
+func (m *mockFileSystemForWrite) Rename(oldPath, newPath string) error {
+	if m.operationErrors["Rename"] != nil {
+		return m.operationErrors["Rename"]
+	}
+	if _, err := m.Stat(oldPath); err != nil {
+		return err
+	}
+	moved := func(p string) (string, bool) {
+		if p == oldPath || strings.HasPrefix(p, oldPath+"/") {
+			return newPath + strings.TrimPrefix(p, oldPath), true
+		}
+		return "", false
+	}
+	for p, e := range m.files {
+		if to, ok := moved(p); ok {
+			delete(m.files, p)
+			m.files[to] = e
+		}
+	}
+	for p := range m.dirs {
+		if to, ok := moved(p); ok {
+			delete(m.dirs, p)
+			m.dirs[to] = true
+		}
+	}
+	for p, e := range m.symlinks {
+		if to, ok := moved(p); ok {
+			delete(m.symlinks, p)
+			m.symlinks[to] = e
+		}
+	}
+	return nil
+}
+
+func (m *mockFileSystemForWrite) CopyFile(src, dst string) error {
+	if m.operationErrors["CopyFile"] != nil {
+		return m.operationErrors["CopyFile"]
+	}
+	entry, ok := m.files[src]
+	if !ok {
+		return os.ErrNotExist
+	}
+	m.files[dst] = fileEntry{content: append([]byte(nil), entry.content...), mode: entry.mode}
+	return nil
+}
+
+func (m *mockFileSystemForWrite) Readlink(path string) (string, error) {
+	link, ok := m.symlinks[path]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	return link.target, nil
+}
+
+func (m *mockFileSystemForWrite) Symlink(target, link string) error {
+	m.symlinks[link] = symlinkEntry{target: target}
+	return nil
+}
+
+// ApplyChanges applies all changes or, if one would fail, none.
+func (m *mockFileSystemForWrite) ApplyChanges(changes []fs.Change) error {
+	if m.operationErrors["ApplyChanges"] != nil {
+		return m.operationErrors["ApplyChanges"]
+	}
+	for _, c := range changes {
+		if _, ok := m.files[c.Path]; c.Delete && !ok {
+			return os.ErrNotExist
+		}
+	}
+	for _, c := range changes {
+		if c.Delete {
+			delete(m.files, c.Path)
+			continue
+		}
+		_ = m.EnsureDirs(filepath.Dir(c.Path))
+		m.files[c.Path] = fileEntry{content: c.Content, mode: c.Perm}
+	}
+	return nil
+}
+
 type mockChecksumManagerForWrite struct {
 	checksums map[string]string
 }
@@ -150,6 +297,11 @@ func (m *mockChecksumManagerForWrite) Compute(content []byte) string {
 	return fmt.Sprintf("checksum-%d", len(content))
 }
 
+func (m *mockChecksumManagerForWrite) ComputeReader(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	return m.Compute(data), err
+}
+
 func (m *mockChecksumManagerForWrite) Get(path string) (string, bool) {
 	checksum, ok := m.checksums[path]
 	return checksum, ok
@@ -159,6 +311,18 @@ func (m *mockChecksumManagerForWrite) Update(path, checksum string) {
 	m.checksums[path] = checksum
 }
 
+func (m *mockChecksumManagerForWrite) Remove(path string) {
+	delete(m.checksums, path)
+}
+
+func (m *mockChecksumManagerForWrite) Paths() []string {
+	paths := make([]string, 0, len(m.checksums))
+	for p := range m.checksums {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
 func (m *mockChecksumManagerForWrite) Clear() {
 	m.checksums = make(map[string]string)
 }
@@ -175,7 +339,7 @@ func TestWriteFile(t *testing.T) {
 		fs := newMockFileSystemForWrite(cfg)
 		checksumManager := newMockChecksumManagerForWrite()
 
-		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot))
+		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot), nil)
 		content := "test content"
 
 		req := &WriteFileRequest{Path: "new.txt", Content: content}
@@ -213,7 +377,7 @@ func TestWriteFile(t *testing.T) {
 		checksumManager := newMockChecksumManagerForWrite()
 		fs.createFile("/workspace/existing.txt", []byte("existing"), 0o644)
 
-		writeTool := NewWriteFileTool(fs, checksumManager, config.DefaultConfig(), path.NewResolver(workspaceRoot))
+		writeTool := NewWriteFileTool(fs, checksumManager, config.DefaultConfig(), path.NewResolver(workspaceRoot), nil)
 
 		req := &WriteFileRequest{Path: "existing.txt", Content: "new content"}
 		_, err := writeTool.Run(context.Background(), req)
@@ -222,6 +386,66 @@ func TestWriteFile(t *testing.T) {
 		}
 	})
 
+	t.Run("overwrite replaces read file and keeps mode", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createFile("/workspace/gen.yaml", []byte("a: 1\r\nb: 2\r\n"), 0o600)
+		checksumManager.Update("/workspace/gen.yaml", checksumManager.Compute([]byte("a: 1\nb: 2\n")))
+
+		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot), nil)
+
+		req := &WriteFileRequest{Path: "gen.yaml", Content: "a: 1\nb: 3\n", Overwrite: true}
+		resp, err := writeTool.Run(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := string(fs.files["/workspace/gen.yaml"].content); got != "a: 1\nb: 3\n" {
+			t.Errorf("unexpected content %q", got)
+		}
+		if fs.files["/workspace/gen.yaml"].mode != 0o600 {
+			t.Errorf("expected mode 0600 to be preserved, got %v", fs.files["/workspace/gen.yaml"].mode)
+		}
+		if !resp.Overwritten || resp.AddedLines != 1 || resp.RemovedLines != 1 || !strings.Contains(resp.Diff, "-b: 2") {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+		if d, ok := resp.Display().(tool.DiffDisplay); !ok || d.Path != "gen.yaml" {
+			t.Errorf("unexpected display: %#v", resp.Display())
+		}
+	})
+
+	t.Run("overwrite of unread file fails", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		fs.createFile("/workspace/gen.yaml", []byte("a: 1\n"), 0o644)
+
+		writeTool := NewWriteFileTool(fs, newMockChecksumManagerForWrite(), cfg, path.NewResolver(workspaceRoot), nil)
+
+		_, err := writeTool.Run(context.Background(), &WriteFileRequest{Path: "gen.yaml", Content: "a: 2\n", Overwrite: true})
+		if err == nil || !strings.Contains(err.Error(), "read the file first") {
+			t.Errorf("expected read-first error, got %v", err)
+		}
+		if got := string(fs.files["/workspace/gen.yaml"].content); got != "a: 1\n" {
+			t.Errorf("file should be unchanged, got %q", got)
+		}
+	})
+
+	t.Run("overwrite of changed file fails", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		fs.createFile("/workspace/gen.yaml", []byte("a: 1\nb: 2\n"), 0o644)
+		checksumManager.Update("/workspace/gen.yaml", checksumManager.Compute([]byte("a: 1\n")))
+
+		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot), nil)
+
+		_, err := writeTool.Run(context.Background(), &WriteFileRequest{Path: "gen.yaml", Content: "a: 2\n", Overwrite: true})
+		if err == nil || !strings.Contains(err.Error(), "edit conflict") {
+			t.Errorf("expected conflict error, got %v", err)
+		}
+	})
+
 	t.Run("large content rejection", func(t *testing.T) {
 		cfg := config.DefaultConfig()
 		cfg.Tools.MaxFileSize = maxFileSize
@@ -234,7 +458,7 @@ func TestWriteFile(t *testing.T) {
 			largeContent[i] = 'A'
 		}
 
-		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot))
+		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot), nil)
 
 		req := &WriteFileRequest{Path: "large.txt", Content: string(largeContent)}
 		_, err := writeTool.Run(context.Background(), req)
@@ -248,7 +472,7 @@ func TestWriteFile(t *testing.T) {
 		fs := newMockFileSystemForWrite(cfg)
 		checksumManager := newMockChecksumManagerForWrite()
 
-		writeTool := NewWriteFileTool(fs, checksumManager, config.DefaultConfig(), path.NewResolver(workspaceRoot))
+		writeTool := NewWriteFileTool(fs, checksumManager, config.DefaultConfig(), path.NewResolver(workspaceRoot), nil)
 		// Content with NUL byte
 		binaryContent := []byte{0x48, 0x65, 0x6C, 0x00, 0x6C, 0x6F}
 
@@ -263,7 +487,7 @@ func TestWriteFile(t *testing.T) {
 		cfg := config.DefaultConfig()
 		fs := newMockFileSystemForWrite(cfg)
 		checksumManager := newMockChecksumManagerForWrite()
-		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot))
+		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot), nil)
 
 		expectedPerm := os.FileMode(0o644)
 
@@ -287,7 +511,7 @@ func TestWriteFile(t *testing.T) {
 		cfg := config.DefaultConfig()
 		fs := newMockFileSystemForWrite(cfg)
 		checksumManager := newMockChecksumManagerForWrite()
-		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot))
+		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot), nil)
 
 		req := &WriteFileRequest{Path: "nested/deep/file.txt", Content: "content"}
 		_, err := writeTool.Run(context.Background(), req)
@@ -305,13 +529,44 @@ func TestWriteFile(t *testing.T) {
 		}
 	})
 
+	t.Run("diagnostics are reported and formatting is applied", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		fs := newMockFileSystemForWrite(cfg)
+		checksumManager := newMockChecksumManagerForWrite()
+		diag := &mockDiagnoser{report: &diagnostics.Report{
+			Commands:    []string{"gofmt"},
+			Formatted:   []byte("package main\n\nfunc main() {}\n"),
+			FormattedBy: "gofmt",
+		}}
+
+		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot), diag)
+
+		resp, err := writeTool.Run(context.Background(), &WriteFileRequest{Path: "cmd/main.go", Content: "package main\nfunc main() {}\n"})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if diag.rel != "cmd/main.go" || string(diag.data) != "package main\nfunc main() {}\n" {
+			t.Errorf("diagnoser got %q with %q", diag.rel, diag.data)
+		}
+		assertContains(t, resp.LLMContent(), "Created file: cmd/main.go")
+		assertContains(t, resp.LLMContent(), "Formatted with gofmt")
+
+		data, _ := fs.ReadFile("/workspace/cmd/main.go")
+		if string(data) != "package main\n\nfunc main() {}\n" {
+			t.Errorf("expected formatted content to be written, got %q", data)
+		}
+		if sum, _ := checksumManager.Get("/workspace/cmd/main.go"); sum != checksumManager.Compute(data) {
+			t.Errorf("expected checksum of the formatted content to be cached")
+		}
+	})
+
 	t.Run("ensure dirs failure", func(t *testing.T) {
 		cfg := config.DefaultConfig()
 		fs := newMockFileSystemForWrite(cfg)
 		checksumManager := newMockChecksumManagerForWrite()
 		fs.setOperationError("EnsureDirs", errors.New("failed to mkdir"))
 
-		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot))
+		writeTool := NewWriteFileTool(fs, checksumManager, cfg, path.NewResolver(workspaceRoot), nil)
 
 		req := &WriteFileRequest{Path: "nested/deep/file.txt", Content: "content"}
 		_, err := writeTool.Run(context.Background(), req)
@@ -6,7 +6,8 @@ package content
 const binarySampleSize = 8000
 
 // IsBinaryContent checks if content bytes contain binary data by looking for null bytes.
-// It handles UTF-16 and UTF-32 BOMs specially to avoid false positives.
+// It handles UTF-16 and UTF-32 specially to avoid false positives: files with their
+// BOM, and BOM-less files whose null bytes fall in the pattern DetectEncoding expects.
 // This is a pure function with no state - import and use directly.
 func IsBinaryContent(content []byte) bool {
 	// Check for common text file BOMs (UTF-16, UTF-32)
@@ -23,6 +24,10 @@ func IsBinaryContent(content []byte) bool {
 		}
 	}
 
+	if wideCharset(content) != "" {
+		return false // UTF-16 or UTF-32 without a BOM
+	}
+
 	// Check for null bytes in sample
 	sampleSize := min(len(content), binarySampleSize)
 	for i := range sampleSize {
@@ -58,6 +58,11 @@ func TestIsBinaryContent(t *testing.T) {
 			content:  []byte{0x00, 0x00, 0xFE, 0xFF, 0x00, 0x00, 0x00, 'a'}, // Valid UTF-32 BE text
 			expected: false,
 		},
+		{
+			name:     "UTF16LEWithoutBOMNotBinary",
+			content:  []byte{'a', 0x00, '=', 0x00, '1', 0x00, '\n', 0x00},
+			expected: false,
+		},
 		{
 			name:     "SingleNullByte",
 			content:  []byte{0},
@@ -0,0 +1,240 @@
+package content
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Character sets recognised by DetectEncoding.
+const (
+	CharsetUTF8    = "UTF-8"
+	CharsetUTF16LE = "UTF-16LE"
+	CharsetUTF16BE = "UTF-16BE"
+	CharsetUTF32LE = "UTF-32LE"
+	CharsetUTF32BE = "UTF-32BE"
+	CharsetLatin1  = "ISO-8859-1"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF32LE = []byte{0xFF, 0xFE, 0x00, 0x00}
+	bomUTF32BE = []byte{0x00, 0x00, 0xFE, 0xFF}
+)
+
+// Encoding is the text encoding of a file: a character set and whether the file
+// starts with a byte order mark.
+type Encoding struct {
+	Charset string
+	BOM     bool
+}
+
+// UTF8 is plain UTF-8 without a BOM, the encoding the tools work in.
+var UTF8 = Encoding{Charset: CharsetUTF8}
+
+func (e Encoding) String() string {
+	if e.BOM {
+		return e.Charset + " with BOM"
+	}
+	return e.Charset
+}
+
+// BOMSize returns the length of the byte order mark a file in e starts with, or 0.
+func (e Encoding) BOMSize() int {
+	return len(e.bom())
+}
+
+func (e Encoding) bom() []byte {
+	if !e.BOM {
+		return nil
+	}
+	switch e.Charset {
+	case CharsetUTF8:
+		return bomUTF8
+	case CharsetUTF16LE:
+		return bomUTF16LE
+	case CharsetUTF16BE:
+		return bomUTF16BE
+	case CharsetUTF32LE:
+		return bomUTF32LE
+	case CharsetUTF32BE:
+		return bomUTF32BE
+	}
+	return nil
+}
+
+// DetectEncoding guesses the encoding of data.
+//
+// A BOM decides it outright. Without one, text whose null bytes fall in the
+// pattern of mostly-ASCII UTF-16 or UTF-32 is taken to be that; valid UTF-8 is
+// UTF-8; anything else without null bytes is taken to be ISO-8859-1. Data that
+// fits none of these (binary) is reported as UTF-8.
+func DetectEncoding(data []byte) Encoding {
+	switch {
+	case hasPrefix(data, bomUTF8):
+		return Encoding{Charset: CharsetUTF8, BOM: true}
+	case hasPrefix(data, bomUTF32LE): // Before UTF-16LE, which shares its first two bytes
+		return Encoding{Charset: CharsetUTF32LE, BOM: true}
+	case hasPrefix(data, bomUTF32BE):
+		return Encoding{Charset: CharsetUTF32BE, BOM: true}
+	case hasPrefix(data, bomUTF16LE):
+		return Encoding{Charset: CharsetUTF16LE, BOM: true}
+	case hasPrefix(data, bomUTF16BE):
+		return Encoding{Charset: CharsetUTF16BE, BOM: true}
+	}
+	if charset := wideCharset(data); charset != "" {
+		return Encoding{Charset: charset}
+	}
+	if utf8.Valid(data) {
+		return UTF8
+	}
+	sample := data[:min(len(data), binarySampleSize)]
+	for _, b := range sample {
+		if b == 0 {
+			return UTF8
+		}
+	}
+	return Encoding{Charset: CharsetLatin1}
+}
+
+// wideCharset recognises UTF-16 and UTF-32 without a BOM from where the null
+// bytes fall in the first binarySampleSize bytes, or returns "". It only catches
+// text that is mostly ASCII, which is what source and config files are.
+func wideCharset(data []byte) string {
+	sample := data[:min(len(data), binarySampleSize)]
+
+	if len(data)%4 == 0 && len(sample) >= 4 {
+		le, be := true, true
+		for i := 0; i+3 < len(sample); i += 4 {
+			u := sample[i : i+4]
+			le = le && u[3] == 0 && u[2] <= 0x10 && (u[0] != 0 || u[1] != 0)
+			be = be && u[0] == 0 && u[1] <= 0x10 && (u[2] != 0 || u[3] != 0)
+		}
+		switch {
+		case le:
+			return CharsetUTF32LE
+		case be:
+			return CharsetUTF32BE
+		}
+	}
+
+	if len(data)%2 == 0 && len(sample) >= 2 {
+		units := len(sample) / 2
+		var evenZeros, oddZeros int
+		for i := 0; i+1 < len(sample); i += 2 {
+			if sample[i] == 0 {
+				evenZeros++
+			}
+			if sample[i+1] == 0 {
+				oddZeros++
+			}
+		}
+		// Mostly one zero byte per unit, always on the same side.
+		switch {
+		case oddZeros*2 > units && evenZeros*10 < units:
+			return CharsetUTF16LE
+		case evenZeros*2 > units && oddZeros*10 < units:
+			return CharsetUTF16BE
+		}
+	}
+	return ""
+}
+
+// Decode detects the encoding of data and returns it as UTF-8 text, without the BOM.
+func Decode(data []byte) (string, Encoding, error) {
+	enc := DetectEncoding(data)
+	text, err := DecodeAs(data[len(enc.bom()):], enc)
+	return text, enc, err
+}
+
+// DecodeAs converts data in enc to UTF-8 text. data must not start with the BOM,
+// so a run of lines from the middle of a file can be decoded on its own.
+func DecodeAs(data []byte, enc Encoding) (string, error) {
+	switch enc.Charset {
+	case CharsetUTF16LE, CharsetUTF16BE:
+		if len(data)%2 != 0 {
+			return "", fmt.Errorf("invalid %s: odd number of bytes", enc.Charset)
+		}
+		order := orderOf(enc)
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+
+	case CharsetUTF32LE, CharsetUTF32BE:
+		if len(data)%4 != 0 {
+			return "", fmt.Errorf("invalid %s: length is not a multiple of 4", enc.Charset)
+		}
+		order := orderOf(enc)
+		runes := make([]rune, len(data)/4)
+		for i := range runes {
+			r := rune(order.Uint32(data[4*i:]))
+			if !utf8.ValidRune(r) {
+				return "", fmt.Errorf("invalid %s: code point %#x at byte %d", enc.Charset, r, 4*i)
+			}
+			runes[i] = r
+		}
+		return string(runes), nil
+
+	case CharsetLatin1:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	}
+	return string(data), nil
+}
+
+// Encode converts UTF-8 text to enc, adding the BOM if enc has one. It fails if
+// the text has a character enc cannot represent.
+func Encode(text string, enc Encoding) ([]byte, error) {
+	out := append([]byte(nil), enc.bom()...)
+
+	switch enc.Charset {
+	case CharsetUTF16LE, CharsetUTF16BE:
+		order := orderOf(enc)
+		for _, u := range utf16.Encode([]rune(text)) {
+			out = order.AppendUint16(out, u)
+		}
+		return out, nil
+
+	case CharsetUTF32LE, CharsetUTF32BE:
+		order := orderOf(enc)
+		for _, r := range text {
+			out = order.AppendUint32(out, uint32(r))
+		}
+		return out, nil
+
+	case CharsetLatin1:
+		for i, r := range text {
+			if r > 0xFF {
+				return nil, fmt.Errorf("character %q at byte %d cannot be encoded in %s", r, i, enc.Charset)
+			}
+			out = append(out, byte(r))
+		}
+		return out, nil
+	}
+	return append(out, text...), nil
+}
+
+// byteOrder reads and appends the code units of a UTF-16 or UTF-32 encoding.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+func orderOf(enc Encoding) byteOrder {
+	if enc.Charset == CharsetUTF16BE || enc.Charset == CharsetUTF32BE {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+func hasPrefix(data, prefix []byte) bool {
+	return len(data) >= len(prefix) && string(data[:len(prefix)]) == string(prefix)
+}
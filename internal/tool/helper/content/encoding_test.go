@@ -0,0 +1,156 @@
+package content
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		expected Encoding
+	}{
+		{
+			name:     "EmptyInput",
+			content:  []byte{},
+			expected: UTF8,
+		},
+		{
+			name:     "UTF8Multibyte",
+			content:  []byte("こんにちは"),
+			expected: UTF8,
+		},
+		{
+			name:     "UTF8BOM",
+			content:  []byte("\xEF\xBB\xBFhi"),
+			expected: Encoding{Charset: CharsetUTF8, BOM: true},
+		},
+		{
+			name:     "UTF16LEBOM",
+			content:  []byte{0xFF, 0xFE, 'h', 0x00, 'i', 0x00},
+			expected: Encoding{Charset: CharsetUTF16LE, BOM: true},
+		},
+		{
+			name:     "UTF16BEBOM",
+			content:  []byte{0xFE, 0xFF, 0x00, 'h', 0x00, 'i'},
+			expected: Encoding{Charset: CharsetUTF16BE, BOM: true},
+		},
+		{
+			name:     "UTF32LEBOM",
+			content:  []byte{0xFF, 0xFE, 0x00, 0x00, 'h', 0x00, 0x00, 0x00},
+			expected: Encoding{Charset: CharsetUTF32LE, BOM: true},
+		},
+		{
+			name:     "UTF32BEBOM",
+			content:  []byte{0x00, 0x00, 0xFE, 0xFF, 0x00, 0x00, 0x00, 'h'},
+			expected: Encoding{Charset: CharsetUTF32BE, BOM: true},
+		},
+		{
+			name:     "UTF16LEWithoutBOM",
+			content:  []byte{'k', 0x00, '=', 0x00, 'v', 0x00, '\n', 0x00},
+			expected: Encoding{Charset: CharsetUTF16LE},
+		},
+		{
+			name:     "UTF16BEWithoutBOM",
+			content:  []byte{0x00, 'k', 0x00, '=', 0x00, 'v', 0x00, '\n'},
+			expected: Encoding{Charset: CharsetUTF16BE},
+		},
+		{
+			name:     "UTF32LEWithoutBOM",
+			content:  []byte{'k', 0x00, 0x00, 0x00, '=', 0x00, 0x00, 0x00},
+			expected: Encoding{Charset: CharsetUTF32LE},
+		},
+		{
+			name:     "Latin1",
+			content:  []byte("caf\xE9\n"),
+			expected: Encoding{Charset: CharsetLatin1},
+		},
+		{
+			name:     "BinaryIsUTF8",
+			content:  []byte{0x00, 0x01, 0xFF, 0x00, 0x00, 0xC3},
+			expected: UTF8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectEncoding(tt.content); got != tt.expected {
+				t.Errorf("DetectEncoding() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		text    string
+	}{
+		{
+			name:    "UTF8",
+			content: []byte("héllo\n"),
+			text:    "héllo\n",
+		},
+		{
+			name:    "UTF8BOM",
+			content: []byte("\xEF\xBB\xBFhéllo\n"),
+			text:    "héllo\n",
+		},
+		{
+			name:    "UTF16LEBOMWithSurrogatePair",
+			content: []byte{0xFF, 0xFE, 'a', 0x00, 0x3D, 0xD8, 0x00, 0xDE, '\n', 0x00},
+			text:    "a😀\n",
+		},
+		{
+			name:    "UTF16BE",
+			content: []byte{0xFE, 0xFF, 0x00, 'a', 0x00, 0xE9},
+			text:    "aé",
+		},
+		{
+			name:    "UTF32BE",
+			content: []byte{0x00, 0x00, 0xFE, 0xFF, 0x00, 0x01, 0xF6, 0x00},
+			text:    "😀",
+		},
+		{
+			name:    "Latin1",
+			content: []byte("caf\xE9\n"),
+			text:    "café\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, enc, err := Decode(tt.content)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if text != tt.text {
+				t.Errorf("Decode() = %q, want %q", text, tt.text)
+			}
+			out, err := Encode(text, enc)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if !bytes.Equal(out, tt.content) {
+				t.Errorf("Encode() = %x, want %x", out, tt.content)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	if _, _, err := Decode([]byte{0xFF, 0xFE, 'a', 0x00, 'b'}); err == nil {
+		t.Errorf("expected error for odd-length UTF-16")
+	}
+	if _, _, err := Decode([]byte{0xFF, 0xFE, 0x00, 0x00, 0x00, 0x00, 0x11, 0x00}); err == nil {
+		t.Errorf("expected error for code point beyond U+10FFFF")
+	}
+}
+
+func TestEncodeUnrepresentable(t *testing.T) {
+	if _, err := Encode("price: €5", Encoding{Charset: CharsetLatin1}); err == nil {
+		t.Errorf("expected error encoding € in ISO-8859-1")
+	}
+}
@@ -0,0 +1,111 @@
+package diffview
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/tool"
+)
+
+const manifestDiff = `--- a/k8s/web.yaml
++++ b/k8s/web.yaml
+@@ -1,4 +1,4 @@
+ spec:
+-  replicas: 1 # scaled by HPA
++  replicas: 3 # scaled by HPA
+   template:
+-    image: nginx
++    command: ["/bin/sh", "-c", "exec nginx -g 'daemon off;'"]
+@@ -9 +9,2 @@
+ end
++more
+`
+
+func TestHunks(t *testing.T) {
+	hunks := Hunks(manifestDiff)
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+	if h := hunks[1]; h.OldStart != 9 || h.OldLines != 1 || h.NewStart != 9 || h.NewLines != 2 {
+		t.Errorf("unexpected header %+v", h)
+	}
+
+	lines := hunks[0].Lines
+	kinds := []tool.DiffLineKind{tool.DiffContext, tool.DiffRemoved, tool.DiffAdded, tool.DiffContext, tool.DiffRemoved, tool.DiffAdded}
+	if len(lines) != len(kinds) {
+		t.Fatalf("expected %d lines, got %d", len(kinds), len(lines))
+	}
+	for i, kind := range kinds {
+		if lines[i].Kind != kind {
+			t.Errorf("line %d: expected kind %d, got %d", i, kind, lines[i].Kind)
+		}
+	}
+	if lines[1].Text != "  replicas: 1 # scaled by HPA" {
+		t.Errorf("expected the prefix to be stripped, got %q", lines[1].Text)
+	}
+
+	// One changed token in a long line is all that is marked.
+	if want := []tool.Span{{Start: 12, End: 13}}; !reflect.DeepEqual(lines[1].Changes, want) || !reflect.DeepEqual(lines[2].Changes, want) {
+		t.Errorf("expected the replica count to be marked, got %v and %v", lines[1].Changes, lines[2].Changes)
+	}
+	// Unrelated lines are changed as a whole.
+	if lines[4].Changes != nil || lines[5].Changes != nil {
+		t.Errorf("expected no word changes for unrelated lines, got %v and %v", lines[4].Changes, lines[5].Changes)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	for path, want := range map[string]string{"k8s/web.yaml": "YAML", "main.go": "Go", "Makefile": "Makefile", "notes.unknownext": ""} {
+		if got := Language(path); got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	d := Display("k8s/web.yaml", manifestDiff, 3, 2)
+
+	t.Run("plain", func(t *testing.T) {
+		var sb strings.Builder
+		if err := Render(&sb, d, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		if sb.String() != "k8s/web.yaml (+3 -2)\n"+manifestDiff {
+			t.Errorf("expected the unified diff as it is, got:\n%s", sb.String())
+		}
+	})
+
+	t.Run("color", func(t *testing.T) {
+		var sb strings.Builder
+		if err := Render(&sb, d, Options{Color: true}); err != nil {
+			t.Fatal(err)
+		}
+		out := sb.String()
+		if strings.Contains(out, "+++") {
+			t.Errorf("expected file headers to be replaced by the path")
+		}
+		// The changed count is on the word background, the rest of its line on the line background.
+		word := "48;2;43;117;58m3"
+		if !strings.Contains(out, word) {
+			t.Errorf("expected the changed word to be marked, got:\n%q", out)
+		}
+		if plain := stripANSI(out); !strings.Contains(plain, "+  replicas: 3 # scaled by HPA\n") || !strings.Contains(plain, "@@ -9,1 +9,2 @@\n end\n+more\n") {
+			t.Errorf("expected the text of every line, got:\n%s", plain)
+		}
+	})
+}
+
+func stripANSI(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x1b {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
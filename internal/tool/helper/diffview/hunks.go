@@ -0,0 +1,200 @@
+// Package diffview turns the unified diffs tools produce into structured hunks
+// with word-level changes, and renders them for terminals: syntax highlighted
+// with the changed words marked, or as plain text when colour is off.
+package diffview
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// minSimilarity is how alike a removed line and the added line paired with it
+// must be for their changed words to be marked; below it the lines are
+// treated as unrelated and only marked as whole lines.
+const minSimilarity = 0.5
+
+// Display builds a DiffDisplay for path from a unified diff, with its hunks
+// and language filled in.
+func Display(path, diff string, added, removed int) tool.DiffDisplay {
+	return tool.DiffDisplay{
+		Path:         path,
+		Diff:         diff,
+		AddedLines:   added,
+		RemovedLines: removed,
+		Language:     Language(path),
+		Hunks:        Hunks(diff),
+	}
+}
+
+// Language returns the name of the chroma lexer for path, or "" if none matches.
+func Language(path string) string {
+	if path == "" {
+		return ""
+	}
+	lexer := lexers.Match(filepath.Base(path))
+	if lexer == nil {
+		return ""
+	}
+	return lexer.Config().Name
+}
+
+// Hunks parses a unified diff into hunks and marks the changed words of each
+// removed line and the added line that replaces it. File headers and lines
+// outside hunks are skipped.
+func Hunks(diff string) []tool.DiffHunk {
+	var hunks []tool.DiffHunk
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if strings.HasPrefix(line, "@@") {
+			if h, ok := parseHeader(line); ok {
+				hunks = append(hunks, h)
+			}
+			continue
+		}
+		if len(hunks) == 0 || line == "" {
+			continue
+		}
+		h := &hunks[len(hunks)-1]
+		switch line[0] {
+		case ' ':
+			h.Lines = append(h.Lines, tool.DiffLine{Kind: tool.DiffContext, Text: line[1:]})
+		case '-':
+			h.Lines = append(h.Lines, tool.DiffLine{Kind: tool.DiffRemoved, Text: line[1:]})
+		case '+':
+			h.Lines = append(h.Lines, tool.DiffLine{Kind: tool.DiffAdded, Text: line[1:]})
+		}
+	}
+	for i := range hunks {
+		markChanges(hunks[i].Lines)
+	}
+	return hunks
+}
+
+// parseHeader parses "@@ -l,s +l,s @@", where a missing count means 1.
+func parseHeader(line string) (tool.DiffHunk, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return tool.DiffHunk{}, false
+	}
+	oldStart, oldLines, ok1 := parseRange(fields[1][1:])
+	newStart, newLines, ok2 := parseRange(fields[2][1:])
+	if !ok1 || !ok2 {
+		return tool.DiffHunk{}, false
+	}
+	return tool.DiffHunk{OldStart: oldStart, OldLines: oldLines, NewStart: newStart, NewLines: newLines}, true
+}
+
+func parseRange(s string) (start, lines int, ok bool) {
+	first, count, found := strings.Cut(s, ",")
+	start, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !found {
+		return start, 1, true
+	}
+	lines, err = strconv.Atoi(count)
+	return start, lines, err == nil
+}
+
+// markChanges pairs each run of removed lines with the run of added lines
+// after it, line by line, and sets the changed words of pairs that are alike.
+func markChanges(lines []tool.DiffLine) {
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != tool.DiffRemoved {
+			i++
+			continue
+		}
+		removed := i
+		for i < len(lines) && lines[i].Kind == tool.DiffRemoved {
+			i++
+		}
+		added := i
+		for i < len(lines) && lines[i].Kind == tool.DiffAdded {
+			i++
+		}
+		for j := 0; j < added-removed && added+j < i; j++ {
+			before, after := &lines[removed+j], &lines[added+j]
+			before.Changes, after.Changes = wordChanges(before.Text, after.Text)
+		}
+	}
+}
+
+// wordChanges returns the byte ranges of the words that differ between a and
+// b, or none if the lines have too little in common.
+func wordChanges(a, b string) (aSpans, bSpans []tool.Span) {
+	aWords, bWords := words(a), words(b)
+	m := difflib.NewMatcherWithJunk(aWords, bWords, false, nil)
+	if m.Ratio() < minSimilarity {
+		return nil, nil
+	}
+	aOffsets, bOffsets := offsets(aWords), offsets(bWords)
+	for _, op := range m.GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		if op.I2 > op.I1 {
+			aSpans = addSpan(aSpans, aOffsets[op.I1], aOffsets[op.I2])
+		}
+		if op.J2 > op.J1 {
+			bSpans = addSpan(bSpans, bOffsets[op.J1], bOffsets[op.J2])
+		}
+	}
+	return aSpans, bSpans
+}
+
+// addSpan appends [start, end), merging it into the last span if they touch.
+func addSpan(spans []tool.Span, start, end int) []tool.Span {
+	if start == end {
+		return spans
+	}
+	if n := len(spans); n > 0 && spans[n-1].End == start {
+		spans[n-1].End = end
+		return spans
+	}
+	return append(spans, tool.Span{Start: start, End: end})
+}
+
+// words splits s into runs of letters and digits, runs of spaces, and single
+// other characters, so that punctuation changes stay small.
+func words(s string) []string {
+	var out []string
+	start := 0
+	class := -1
+	for i, r := range s {
+		c := charClass(r)
+		if i > start && (c != class || c == 2) {
+			out = append(out, s[start:i])
+			start = i
+		}
+		class = c
+	}
+	if start < len(s) {
+		out = append(out, s[start:])
+	}
+	return out
+}
+
+func charClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return 0
+	case unicode.IsSpace(r):
+		return 1
+	}
+	return 2
+}
+
+// offsets returns the byte offset of each word and, last, the total length.
+func offsets(words []string) []int {
+	out := make([]int, len(words)+1)
+	for i, w := range words {
+		out[i+1] = out[i] + len(w)
+	}
+	return out
+}
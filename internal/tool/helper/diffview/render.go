@@ -0,0 +1,206 @@
+package diffview
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// DefaultStyle is the chroma style used when Options.Style is empty.
+const DefaultStyle = "github-dark"
+
+// Options controls how a diff is rendered.
+type Options struct {
+	Color bool   // ANSI colour; false writes the plain unified diff
+	Style string // chroma style name; default DefaultStyle
+}
+
+// Colours of the signs of removed and added lines, and the backgrounds of
+// the lines and of their changed words.
+var (
+	removedSign = rgb{0xf8, 0x51, 0x49}
+	addedSign   = rgb{0x3f, 0xb9, 0x50}
+	removedLine = rgb{0x3c, 0x1f, 0x1f}
+	removedWord = rgb{0x80, 0x2b, 0x2b}
+	addedLine   = rgb{0x1f, 0x3a, 0x24}
+	addedWord   = rgb{0x2b, 0x75, 0x3a}
+)
+
+// ColorEnabled reports whether f is a terminal that colour should be written
+// to, honouring NO_COLOR and TERM=dumb.
+func ColorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Render writes d to w. With colour, lines are highlighted for d.Language
+// with removed and added lines on red and green and their changed words on
+// brighter shades; without, d.Diff is written as it is. A display without
+// hunks, as from an older producer, is parsed from d.Diff first.
+func Render(w io.Writer, d tool.DiffDisplay, opts Options) error {
+	if !opts.Color {
+		if d.Path != "" {
+			if _, err := fmt.Fprintf(w, "%s (+%d -%d)\n", d.Path, d.AddedLines, d.RemovedLines); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(w, withNewline(d.Diff))
+		return err
+	}
+
+	hunks := d.Hunks
+	if hunks == nil {
+		hunks = Hunks(d.Diff)
+	}
+	style := styles.Get(opts.Style)
+	if opts.Style == "" {
+		style = styles.Get(DefaultStyle)
+	}
+	lexer := lexers.Get(d.Language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	var sb strings.Builder
+	if d.Path != "" {
+		fmt.Fprintf(&sb, "\x1b[1m%s\x1b[0m \x1b[32m+%d\x1b[0m \x1b[31m-%d\x1b[0m\n", d.Path, d.AddedLines, d.RemovedLines)
+	}
+	for _, h := range hunks {
+		fmt.Fprintf(&sb, "\x1b[36m@@ -%d,%d +%d,%d @@\x1b[0m\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+		oldTokens, newTokens := tokenise(lexer, h.Lines)
+		var o, n int
+		for _, line := range h.Lines {
+			var tokens []chroma.Token
+			switch line.Kind {
+			case tool.DiffRemoved:
+				tokens = lineTokens(oldTokens, o)
+				o++
+			case tool.DiffAdded:
+				tokens = lineTokens(newTokens, n)
+				n++
+			default:
+				tokens = lineTokens(newTokens, n)
+				o++
+				n++
+			}
+			writeLine(&sb, style, line, tokens)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// tokenise highlights the old and new sides of a hunk as whole texts, so that
+// constructs spanning lines, like block comments, keep their colour, and
+// returns the tokens of each side split into lines.
+func tokenise(lexer chroma.Lexer, lines []tool.DiffLine) (oldSide, newSide [][]chroma.Token) {
+	var oldText, newText strings.Builder
+	for _, line := range lines {
+		if line.Kind != tool.DiffAdded {
+			oldText.WriteString(line.Text + "\n")
+		}
+		if line.Kind != tool.DiffRemoved {
+			newText.WriteString(line.Text + "\n")
+		}
+	}
+	split := func(text string) [][]chroma.Token {
+		it, err := lexer.Tokenise(nil, text)
+		if err != nil {
+			return nil
+		}
+		return chroma.SplitTokensIntoLines(it.Tokens())
+	}
+	return split(oldText.String()), split(newText.String())
+}
+
+func lineTokens(lines [][]chroma.Token, i int) []chroma.Token {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return nil
+}
+
+// writeLine writes one diff line: its sign, then its text in the colours of
+// tokens on the background of its kind, with its changed spans brighter.
+// tokens that do not add up to the line's text, which a lexer error can
+// cause, are ignored and the line is written uncoloured.
+func writeLine(sb *strings.Builder, style *chroma.Style, line tool.DiffLine, tokens []chroma.Token) {
+	var sign string
+	var signFg, lineBg, wordBg *rgb
+	switch line.Kind {
+	case tool.DiffRemoved:
+		sign, signFg, lineBg, wordBg = "-", &removedSign, &removedLine, &removedWord
+	case tool.DiffAdded:
+		sign, signFg, lineBg, wordBg = "+", &addedSign, &addedLine, &addedWord
+	default:
+		sign = " "
+	}
+
+	var text strings.Builder
+	for _, t := range tokens {
+		text.WriteString(t.Value)
+	}
+	if strings.TrimSuffix(text.String(), "\n") != line.Text {
+		tokens = []chroma.Token{{Type: chroma.Text, Value: line.Text}}
+	}
+
+	writeSegment(sb, sign, signFg, lineBg)
+	offset := 0
+	for _, t := range tokens {
+		value := strings.TrimSuffix(t.Value, "\n")
+		entry := style.Get(t.Type)
+		var fg *rgb
+		if entry.Colour.IsSet() {
+			fg = &rgb{entry.Colour.Red(), entry.Colour.Green(), entry.Colour.Blue()}
+		}
+		// Split the token where changed spans start and end.
+		for len(value) > 0 {
+			end, changed := len(value), false
+			for _, s := range line.Changes {
+				switch {
+				case offset >= s.Start && offset < s.End:
+					changed, end = true, min(end, s.End-offset)
+				case s.Start > offset && s.Start-offset < end:
+					end = s.Start - offset
+				}
+			}
+			bg := lineBg
+			if changed {
+				bg = wordBg
+			}
+			writeSegment(sb, value[:end], fg, bg)
+			value, offset = value[end:], offset+end
+		}
+	}
+	sb.WriteString("\x1b[0m\n")
+}
+
+type rgb struct{ r, g, b uint8 }
+
+func writeSegment(sb *strings.Builder, s string, fg, bg *rgb) {
+	sb.WriteString("\x1b[0")
+	if fg != nil {
+		fmt.Fprintf(sb, ";38;2;%d;%d;%d", fg.r, fg.g, fg.b)
+	}
+	if bg != nil {
+		fmt.Fprintf(sb, ";48;2;%d;%d;%d", bg.r, bg.g, bg.b)
+	}
+	sb.WriteString("m" + s)
+}
+
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
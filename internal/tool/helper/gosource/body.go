@@ -0,0 +1,87 @@
+package gosource
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// ReplaceBody replaces the body of the function or method target, named as
+// Func or Type.Method, with body. body may be given with or without its
+// enclosing braces. The signature and doc comment are kept.
+func ReplaceBody(name string, src []byte, target, body string) (*Result, error) {
+	f, err := parse(token.NewFileSet(), name, src)
+	if err != nil {
+		return nil, err
+	}
+
+	fn := findFunc(f, target)
+	if fn == nil {
+		return nil, fmt.Errorf("%s has no function %s; it has: %s", name, target, strings.Join(funcNames(f), ", "))
+	}
+	if fn.Body == nil {
+		return nil, fmt.Errorf("%s in %s has no body to replace", target, name)
+	}
+
+	block, err := parseBlock(body)
+	if err != nil {
+		return nil, err
+	}
+	edited := splice(src, f.offset(fn.Body.Lbrace), f.offset(fn.Body.Rbrace)+1, block)
+	return finish(name, src, edited)
+}
+
+// findFunc returns the function or method declaration named target, or nil.
+func findFunc(f *astFile, target string) *ast.FuncDecl {
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && funcName(fn) == target {
+			return fn
+		}
+	}
+	return nil
+}
+
+func funcNames(f *astFile) []string {
+	var names []string
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok {
+			names = append(names, funcName(fn))
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return []string{"(no functions)"}
+	}
+	return names
+}
+
+// parseBlock checks that body is a list of statements, adding braces if they
+// are missing, and returns it as a block.
+func parseBlock(body string) (string, error) {
+	block := strings.TrimSpace(body)
+	if strings.HasPrefix(block, "{") && strings.HasSuffix(block, "}") && checkBlock(block) == nil {
+		return block, nil
+	}
+	block = "{\n" + strings.TrimRight(body, " \t\n") + "\n}"
+	if err := checkBlock(block); err != nil {
+		return "", fmt.Errorf("body does not parse as Go statements: %v", err)
+	}
+	return block, nil
+}
+
+// checkBlock checks that block is a single block statement, not one that
+// closes early and declares more after it.
+func checkBlock(block string) error {
+	const prefix = "package p\nfunc _() "
+	f, err := parser.ParseFile(token.NewFileSet(), "body", prefix+block, 0)
+	if err != nil {
+		return parseError(err)
+	}
+	if len(f.Decls) != 1 {
+		return fmt.Errorf("the body closes before its end")
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package gosource
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+)
+
+// Declaration is a top-level declaration of a file.
+type Declaration struct {
+	Kind      string // func, method, type, var or const
+	Name      string // Type.Method for methods
+	Signature string // e.g. "func (s *Server) Start(ctx context.Context) error" or "type Server struct"
+	StartLine int    // 1-based, including the doc comment
+	EndLine   int
+}
+
+// Declarations lists the top-level declarations of a file in source order,
+// along with its package name. Imports are not listed.
+func Declarations(name string, src []byte) (string, []Declaration, error) {
+	f, err := parse(token.NewFileSet(), name, src)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var decls []Declaration
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			decl := Declaration{Kind: "func", Name: funcName(d), Signature: funcSignature(f, d)}
+			if d.Recv != nil {
+				decl.Kind = "method"
+			}
+			decl.StartLine, decl.EndLine = f.line(startOf(d, d.Doc)), f.line(d.End())
+			decls = append(decls, decl)
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				decl := Declaration{Kind: d.Tok.String()}
+				doc := d.Doc
+				if d.Lparen.IsValid() {
+					doc = specDoc(spec)
+				}
+				decl.StartLine, decl.EndLine = f.line(startOf(spec, doc)), f.line(spec.End())
+				if !d.Lparen.IsValid() {
+					decl.StartLine, decl.EndLine = f.line(startOf(d, doc)), f.line(d.End())
+				}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					decl.Name = s.Name.Name
+					decl.Signature = "type " + s.Name.Name + typeParams(f, s.TypeParams) + " " + typeKind(f, s)
+					decls = append(decls, decl)
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						decl.Name = n.Name
+						decl.Signature = decl.Kind + " " + n.Name
+						if s.Type != nil {
+							decl.Signature += " " + nodeString(f, s.Type)
+						}
+						decls = append(decls, decl)
+					}
+				}
+			}
+		}
+	}
+	return f.Name.Name, decls, nil
+}
+
+func startOf(n ast.Node, doc *ast.CommentGroup) token.Pos {
+	if doc != nil {
+		return doc.Pos()
+	}
+	return n.Pos()
+}
+
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc
+	case *ast.ValueSpec:
+		return s.Doc
+	}
+	return nil
+}
+
+// funcName returns the name of a function, or Type.Method for a method.
+func funcName(d *ast.FuncDecl) string {
+	if recv := receiverType(d); recv != "" {
+		return recv + "." + d.Name.Name
+	}
+	return d.Name.Name
+}
+
+// receiverType returns the name of the type a method is declared on, without
+// pointer or type parameters, or "" for a function.
+func receiverType(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return ""
+	}
+	t := d.Recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.ParenExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func funcSignature(f *astFile, d *ast.FuncDecl) string {
+	return nodeString(f, &ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type})
+}
+
+// typeParams returns the type parameter list of a type as written, e.g. "[K comparable, V any]".
+func typeParams(f *astFile, params *ast.FieldList) string {
+	if params == nil {
+		return ""
+	}
+	return string(f.src[f.offset(params.Opening) : f.offset(params.Closing)+1])
+}
+
+// typeKind describes what a type declaration declares without its full body.
+func typeKind(f *astFile, s *ast.TypeSpec) string {
+	prefix := ""
+	if s.Assign.IsValid() {
+		prefix = "= "
+	}
+	switch s.Type.(type) {
+	case *ast.StructType:
+		return prefix + "struct"
+	case *ast.InterfaceType:
+		return prefix + "interface"
+	}
+	return prefix + nodeString(f, s.Type)
+}
+
+func nodeString(f *astFile, n ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, f.fset, n); err != nil {
+		return fmt.Sprintf("<%T>", n)
+	}
+	return buf.String()
+}
@@ -0,0 +1,99 @@
+// Package gosource edits Go source files through go/ast: listing declarations,
+// replacing function bodies, adding and removing imports, and renaming
+// identifiers across a package. Every result is parsed again and gofmt-ed, so
+// an edit that would leave a file that does not parse is refused.
+package gosource
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+)
+
+// File is a Go source file of a package.
+type File struct {
+	Name string // Base name, e.g. "server.go"
+	Src  []byte
+}
+
+// Result is the outcome of an edit to one file.
+type Result struct {
+	Src   []byte
+	Notes []string
+}
+
+// astFile is a parsed file with what is needed to map positions back to its source.
+type astFile struct {
+	*ast.File
+	fset *token.FileSet
+	src  []byte
+}
+
+func (f *astFile) offset(p token.Pos) int {
+	return f.fset.Position(p).Offset
+}
+
+func (f *astFile) line(p token.Pos) int {
+	return f.fset.Position(p).Line
+}
+
+// lineStart returns the offset of the start of the line holding offset.
+func (f *astFile) lineStart(offset int) int {
+	return bytes.LastIndexByte(f.src[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset just past the newline ending the line holding offset.
+func (f *astFile) lineEnd(offset int) int {
+	if i := bytes.IndexByte(f.src[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(f.src)
+}
+
+// parse parses src with comments, naming the file name in errors.
+func parse(fset *token.FileSet, name string, src []byte) (*astFile, error) {
+	f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, parseError(err)
+	}
+	return &astFile{File: f, fset: fset, src: src}, nil
+}
+
+// parseError keeps the first few errors of a scanner.ErrorList.
+func parseError(err error) error {
+	list, ok := err.(scanner.ErrorList)
+	if !ok || len(list) <= 3 {
+		return err
+	}
+	return fmt.Errorf("%v (and %d more errors)", list[:3], len(list)-3)
+}
+
+// finish checks that edited source parses and gofmt-s it. A note is returned
+// if the original was not gofmt-ed, since formatting then changes other lines too.
+func finish(name string, original, edited []byte) (*Result, error) {
+	fset := token.NewFileSet()
+	if _, err := parser.ParseFile(fset, name, edited, parser.ParseComments); err != nil {
+		return nil, fmt.Errorf("the edit would leave %s unparsable: %v", name, parseError(err))
+	}
+	out, err := format.Source(edited)
+	if err != nil {
+		return nil, fmt.Errorf("gofmt %s: %v", name, err)
+	}
+	res := &Result{Src: out}
+	if formatted, err := format.Source(original); err == nil && !bytes.Equal(formatted, original) {
+		res.Notes = append(res.Notes, fmt.Sprintf("%s was not gofmt-formatted; gofmt also reformatted lines outside the edit", name))
+	}
+	return res, nil
+}
+
+// splice replaces src[start:end] with repl.
+func splice(src []byte, start, end int, repl string) []byte {
+	out := make([]byte, 0, len(src)-(end-start)+len(repl))
+	out = append(out, src[:start]...)
+	out = append(out, repl...)
+	return append(out, src[end:]...)
+}
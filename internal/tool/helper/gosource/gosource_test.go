@@ -0,0 +1,184 @@
+package gosource
+
+import (
+	"strings"
+	"testing"
+)
+
+const server = `package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/example/log"
+)
+
+// Server serves requests.
+type Server struct {
+	addr string
+}
+
+type Pair[K comparable, V any] struct{}
+
+// Version is the version.
+const Version = "1"
+
+var (
+	hits, misses int
+	client       *http.Client
+)
+
+// Start starts the server.
+func (s *Server) Start() error {
+	log.Info("starting")
+	return fmt.Errorf("not implemented")
+}
+
+func New(addr string) *Server { return &Server{addr: addr} }
+`
+
+func TestDeclarations(t *testing.T) {
+	pkg, decls, err := Declarations("server.go", []byte(server))
+	if err != nil {
+		t.Fatalf("Declarations failed: %v", err)
+	}
+	if pkg != "server" {
+		t.Errorf("expected package server, got %s", pkg)
+	}
+	var got []string
+	for _, d := range decls {
+		got = append(got, strings.Join([]string{d.Kind, d.Name, d.Signature}, " | "))
+	}
+	want := []string{
+		"type | Server | type Server struct",
+		"type | Pair | type Pair[K comparable, V any] struct",
+		"const | Version | const Version",
+		"var | hits | var hits int",
+		"var | misses | var misses int",
+		"var | client | var client *http.Client",
+		"method | Server.Start | func (s *Server) Start() error",
+		"func | New | func New(addr string) *Server",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected declarations:\n%s", strings.Join(got, "\n"))
+	}
+	if decls[0].StartLine != 10 || decls[0].EndLine != 13 {
+		t.Errorf("expected Server at lines 10-13 with its doc comment, got %d-%d", decls[0].StartLine, decls[0].EndLine)
+	}
+}
+
+func TestReplaceBody(t *testing.T) {
+	t.Run("statements without braces", func(t *testing.T) {
+		res, err := ReplaceBody("server.go", []byte(server), "Server.Start", "log.Info(\"starting\")\nreturn http.ListenAndServe(s.addr, nil)")
+		if err != nil {
+			t.Fatalf("ReplaceBody failed: %v", err)
+		}
+		want := strings.Replace(server, "return fmt.Errorf(\"not implemented\")", "return http.ListenAndServe(s.addr, nil)", 1)
+		if string(res.Src) != want {
+			t.Errorf("unexpected output:\n%s", res.Src)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		res, err := ReplaceBody("server.go", []byte(server), "New", "{\n\treturn nil\n}")
+		if err != nil {
+			t.Fatalf("ReplaceBody failed: %v", err)
+		}
+		if !strings.Contains(string(res.Src), "func New(addr string) *Server {\n\treturn nil\n}\n") {
+			t.Errorf("unexpected output:\n%s", res.Src)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			target, body, want string
+		}{
+			{"Start", "return nil", "server.go has no function Start; it has: New, Server.Start"},
+			{"New", "return &Server{", "body does not parse as Go statements"},
+			{"New", "}\nfunc x() {", "body does not parse as Go statements"},
+		}
+		for _, tt := range tests {
+			if _, err := ReplaceBody("server.go", []byte(server), tt.target, tt.body); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s: expected error containing %q, got %v", tt.target, tt.want, err)
+			}
+		}
+	})
+}
+
+func TestImports(t *testing.T) {
+	t.Run("add to groups", func(t *testing.T) {
+		res, err := AddImport("server.go", []byte(server), "context", "")
+		if err != nil {
+			t.Fatalf("AddImport failed: %v", err)
+		}
+		res, err = AddImport("server.go", res.Src, "github.com/example/metrics", "m")
+		if err != nil {
+			t.Fatalf("AddImport failed: %v", err)
+		}
+		want := "import (\n\t\"context\"\n\t\"fmt\"\n\t\"net/http\"\n\n\t\"github.com/example/log\"\n\tm \"github.com/example/metrics\"\n)\n"
+		if !strings.Contains(string(res.Src), want) {
+			t.Errorf("unexpected output:\n%s", res.Src)
+		}
+	})
+
+	t.Run("add to a single import and to none", func(t *testing.T) {
+		res, err := AddImport("a.go", []byte("package a\n\nimport \"github.com/example/log\"\n"), "os", "")
+		if err != nil {
+			t.Fatalf("AddImport failed: %v", err)
+		}
+		if want := "package a\n\nimport (\n\t\"os\"\n\n\t\"github.com/example/log\"\n)\n"; string(res.Src) != want {
+			t.Errorf("unexpected output %q", res.Src)
+		}
+		res, err = AddImport("b.go", []byte("package b // import \"example.com/b\"\n\nvar x = 1\n"), "os", "")
+		if err != nil {
+			t.Fatalf("AddImport failed: %v", err)
+		}
+		if want := "package b // import \"example.com/b\"\n\nimport \"os\"\n\nvar x = 1\n"; string(res.Src) != want {
+			t.Errorf("unexpected output %q", res.Src)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		src := "package a\n\nimport (\n\t\"os\"\n\tyaml \"gopkg.in/yaml.v3\"\n)\n\nvar _ = os.Args\n"
+		res, err := RemoveImport("a.go", []byte(src), "gopkg.in/yaml.v3")
+		if err != nil {
+			t.Fatalf("RemoveImport failed: %v", err)
+		}
+		if want := "package a\n\nimport (\n\t\"os\"\n)\n\nvar _ = os.Args\n"; string(res.Src) != want {
+			t.Errorf("unexpected output %q", res.Src)
+		}
+		res, err = RemoveImport("a.go", []byte("package a\n\nimport \"os\"\n\nfunc f(os int) int { return os }\n"), "os")
+		if err != nil {
+			t.Fatalf("RemoveImport failed: %v", err)
+		}
+		if want := "package a\n\nfunc f(os int) int { return os }\n"; string(res.Src) != want {
+			t.Errorf("unexpected output %q", res.Src)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := AddImport("server.go", []byte(server), "fmt", ""); err == nil || !strings.Contains(err.Error(), `already imports "fmt"`) {
+			t.Errorf("expected already imported error, got %v", err)
+		}
+		if _, err := RemoveImport("server.go", []byte(server), "github.com/example/log"); err == nil || !strings.Contains(err.Error(), "still uses log at line 27") {
+			t.Errorf("expected still used error, got %v", err)
+		}
+		if _, err := RemoveImport("server.go", []byte(server), "os"); err == nil || !strings.Contains(err.Error(), `does not import "os"`) {
+			t.Errorf("expected not imported error, got %v", err)
+		}
+	})
+}
+
+func TestFinish(t *testing.T) {
+	if _, err := ReplaceBody("server.go", []byte(server), "New", "return }{"); err == nil {
+		t.Fatal("expected an error")
+	}
+	res, err := ReplaceBody("a.go", []byte("package a\nfunc f()  {  }\n"), "f", "return")
+	if err != nil {
+		t.Fatalf("ReplaceBody failed: %v", err)
+	}
+	if string(res.Src) != "package a\n\nfunc f() {\n\treturn\n}\n" || len(res.Notes) != 1 || !strings.Contains(res.Notes[0], "not gofmt-formatted") {
+		t.Errorf("unexpected result %q %v", res.Src, res.Notes)
+	}
+}
@@ -0,0 +1,184 @@
+package gosource
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// AddImport adds an import of path to a file, named localName if it is not
+// empty. Standard library imports go with the other standard library imports
+// and the rest with the rest; gofmt then sorts them within their group.
+func AddImport(name string, src []byte, path, localName string) (*Result, error) {
+	f, err := parse(token.NewFileSet(), name, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, imp := range f.Imports {
+		if importPath(imp) == path && importName(imp) == localName {
+			return nil, fmt.Errorf("%s already imports %s", name, strconv.Quote(path))
+		}
+	}
+
+	spec := strconv.Quote(path)
+	if localName != "" {
+		spec = localName + " " + spec
+	}
+
+	var decl *ast.GenDecl
+	for _, d := range f.Decls {
+		if g, ok := d.(*ast.GenDecl); ok && g.Tok == token.IMPORT {
+			decl = g
+			break
+		}
+	}
+
+	var edited []byte
+	switch {
+	case decl == nil:
+		at := f.lineEnd(f.offset(f.Name.End()))
+		edited = splice(src, at, at, "\nimport "+spec+"\n")
+
+	case !decl.Lparen.IsValid():
+		old := string(src[f.offset(decl.Specs[0].Pos()):f.offset(decl.Specs[0].End())])
+		specs := "\t" + old + "\n\t" + spec + "\n"
+		if isStdlib(path) != isStdlib(importPath(decl.Specs[0].(*ast.ImportSpec))) {
+			specs = "\t" + old + "\n\n\t" + spec + "\n"
+			if isStdlib(path) {
+				specs = "\t" + spec + "\n\n\t" + old + "\n"
+			}
+		}
+		edited = splice(src, f.offset(decl.Pos()), f.offset(decl.End()), "import (\n"+specs+")")
+
+	default:
+		edited = insertSpec(f, decl, path, spec)
+	}
+	return finish(name, src, edited)
+}
+
+// insertSpec adds spec to a parenthesised import declaration after the last
+// import of the same kind, or as a new group if there is none.
+func insertSpec(f *astFile, decl *ast.GenDecl, path, spec string) []byte {
+	var last *ast.ImportSpec
+	for _, s := range decl.Specs {
+		if s := s.(*ast.ImportSpec); isStdlib(importPath(s)) == isStdlib(path) {
+			last = s
+		}
+	}
+	if last != nil {
+		at := f.lineEnd(f.offset(last.End()))
+		return splice(f.src, at, at, "\t"+spec+"\n")
+	}
+	if isStdlib(path) {
+		at := f.lineEnd(f.offset(decl.Lparen))
+		return splice(f.src, at, at, "\t"+spec+"\n\n")
+	}
+	at := f.lineStart(f.offset(decl.Rparen))
+	return splice(f.src, at, at, "\n\t"+spec+"\n")
+}
+
+// RemoveImport removes the import of path from a file. It is refused while
+// the file still refers to the package.
+func RemoveImport(name string, src []byte, path string) (*Result, error) {
+	f, err := parse(token.NewFileSet(), name, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var decl *ast.GenDecl
+	var spec *ast.ImportSpec
+	for _, d := range f.Decls {
+		g, ok := d.(*ast.GenDecl)
+		if !ok || g.Tok != token.IMPORT {
+			continue
+		}
+		for _, s := range g.Specs {
+			if s := s.(*ast.ImportSpec); importPath(s) == path {
+				decl, spec = g, s
+			}
+		}
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("%s does not import %s", name, strconv.Quote(path))
+	}
+
+	var notes []string
+	switch local := importName(spec); local {
+	case "_":
+	case ".":
+		notes = append(notes, fmt.Sprintf("%s was a dot import, so uses of it could not be checked", strconv.Quote(path)))
+	default:
+		if local == "" {
+			local = defaultName(path)
+		}
+		if pos := findUse(f, local); pos.IsValid() {
+			return nil, fmt.Errorf("%s still uses %s at line %d; remove the uses first", name, local, f.line(pos))
+		}
+	}
+
+	start, end := f.offset(startOf(spec, spec.Doc)), f.offset(spec.End())
+	if len(decl.Specs) == 1 {
+		start, end = f.offset(startOf(decl, decl.Doc)), f.offset(decl.End())
+	}
+	if strings.TrimSpace(string(f.src[f.lineStart(start):start])) == "" {
+		start, end = f.lineStart(start), f.lineEnd(end)
+	}
+	res, err := finish(name, src, splice(src, start, end, ""))
+	if err != nil {
+		return nil, err
+	}
+	res.Notes = append(notes, res.Notes...)
+	return res, nil
+}
+
+// findUse returns the position of the first qualified identifier local.X
+// where local is not declared in the file, or token.NoPos.
+func findUse(f *astFile, local string) token.Pos {
+	pos := token.NoPos
+	ast.Inspect(f.File, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && !pos.IsValid() {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == local && x.Obj == nil {
+				pos = x.Pos()
+			}
+		}
+		return !pos.IsValid()
+	})
+	return pos
+}
+
+func importPath(s *ast.ImportSpec) string {
+	path, _ := strconv.Unquote(s.Path.Value)
+	return path
+}
+
+func importName(s *ast.ImportSpec) string {
+	if s.Name == nil {
+		return ""
+	}
+	return s.Name.Name
+}
+
+// isStdlib reports whether path looks like a standard library package, whose
+// first element has no dot.
+func isStdlib(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// defaultName guesses the name of the package at path from its last element,
+// skipping major version suffixes and trimming go- prefixes and .vN suffixes,
+// as in github.com/mattn/go-isatty and gopkg.in/yaml.v3.
+func defaultName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	return strings.NewReplacer("-", "", ".", "").Replace(name)
+}
@@ -0,0 +1,651 @@
+package gosource
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Target names what to rename: a package-level identifier Name, a method or
+// field Type.Member, or, with Line set, the identifier Name declared or used
+// on that line of File, which is how local variables are named.
+type Target struct {
+	File string // Base name of a file of the package
+	Name string
+	Line int
+}
+
+// Renamed is the outcome of a rename across a package.
+type Renamed struct {
+	Files map[string]*Result // By base name; only files that changed
+	Notes []string
+}
+
+// Rename renames target to newName in the package in dir, whose .go files are
+// files. The files of the current build are type-checked, so only references
+// to target are renamed, and the rename is refused if it would redeclare a
+// name, make a reference refer to something else or add type errors. Files
+// excluded by build constraints and external test files are renamed by name,
+// and the notes say so.
+func Rename(dir string, files []File, target Target, newName string) (*Renamed, error) {
+	if !token.IsIdentifier(newName) || newName == "_" {
+		return nil, fmt.Errorf("%q is not a valid Go identifier", newName)
+	}
+	if types.Universe.Lookup(newName) != nil {
+		return nil, fmt.Errorf("%s is a predeclared identifier", newName)
+	}
+
+	r, err := newRenamer(dir, files, target.File)
+	if err != nil {
+		return nil, err
+	}
+	before := r.check(r.checked)
+	obj, owner, err := r.resolve(before, target)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Name() == newName {
+		return nil, fmt.Errorf("%s is already named %s", target.Name, newName)
+	}
+	if err := r.conflicts(before, obj, owner, newName); err != nil {
+		return nil, err
+	}
+
+	targets := r.targets(before, obj)
+	edits := map[*astFile][]int{}
+	for _, f := range r.checked {
+		edits[f] = r.typedEdits(before, f, targets)
+	}
+	var notes []string
+	if len(r.excluded) > 0 || len(r.external) > 0 {
+		notes = r.syntacticEdits(obj, edits)
+	}
+
+	renamed := map[*astFile][]byte{}
+	for f, offsets := range edits {
+		if len(offsets) > 0 {
+			renamed[f] = applyRename(f.src, offsets, obj.Name(), newName)
+		}
+	}
+	if err := r.verify(before, renamed); err != nil {
+		return nil, err
+	}
+
+	out := &Renamed{Files: map[string]*Result{}}
+	for f, src := range renamed {
+		res, err := finish(r.name(f), f.src, src)
+		if err != nil {
+			return nil, err
+		}
+		out.Files[r.name(f)] = res
+	}
+	out.Notes = append(r.renameNotes(before, obj, newName), notes...)
+	if len(r.stubs) > 0 {
+		out.Notes = append(out.Notes, fmt.Sprintf("could not load %s, so uses through them were not checked", strings.Join(sortedKeys(r.stubs), ", ")))
+	}
+	return out, nil
+}
+
+// renamer holds the parsed files of a package split by how they are renamed.
+type renamer struct {
+	pkgName  string
+	fset     *token.FileSet
+	importer *stubImporter
+	stubs    map[string]*types.Package
+	checked  []*astFile // In the current build; type-checked
+	excluded []*astFile // Excluded by build constraints; renamed by name
+	external []*astFile // External test package; renamed by name
+}
+
+func newRenamer(dir string, files []File, targetFile string) (*renamer, error) {
+	r := &renamer{fset: token.NewFileSet(), stubs: map[string]*types.Package{}}
+	r.importer = &stubImporter{source: importer.ForCompiler(r.fset, "source", nil).(types.ImporterFrom), stubs: r.stubs}
+
+	srcs := map[string][]byte{}
+	parsed := map[string]*astFile{}
+	for _, file := range files {
+		f, err := parse(r.fset, filepath.Join(dir, file.Name), file.Src)
+		if err != nil {
+			return nil, err
+		}
+		srcs[file.Name], parsed[file.Name] = file.Src, f
+	}
+	if parsed[targetFile] == nil {
+		return nil, fmt.Errorf("%s is not a Go file of the package", targetFile)
+	}
+	r.pkgName = parsed[targetFile].Name.Name
+
+	ctxt := build.Default
+	ctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+		if src, ok := srcs[filepath.Base(path)]; ok {
+			return io.NopCloser(bytes.NewReader(src)), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	for _, file := range files {
+		f := parsed[file.Name]
+		switch f.Name.Name {
+		case r.pkgName:
+			if ok, err := ctxt.MatchFile(dir, file.Name); ok && err == nil {
+				r.checked = append(r.checked, f)
+			} else {
+				r.excluded = append(r.excluded, f)
+			}
+		case r.pkgName + "_test":
+			r.external = append(r.external, f)
+		}
+	}
+	for _, f := range r.excluded {
+		if r.name(f) == targetFile {
+			return nil, fmt.Errorf("%s is excluded by build constraints for %s/%s; name the target from a file in the current build", targetFile, ctxt.GOOS, ctxt.GOARCH)
+		}
+	}
+	return r, nil
+}
+
+func (r *renamer) name(f *astFile) string {
+	return filepath.Base(r.fset.Position(f.Package).Filename)
+}
+
+func (r *renamer) position(pos token.Pos) string {
+	p := r.fset.Position(pos)
+	return fmt.Sprintf("%s:%d", filepath.Base(p.Filename), p.Line)
+}
+
+// checkedPackage is a type-checked package and the objects its identifiers
+// refer to, in the order the identifiers appear.
+type checkedPackage struct {
+	pkg    *types.Package
+	info   *types.Info
+	errors []types.Error
+	idents map[*astFile][]*ast.Ident
+}
+
+func (r *renamer) check(files []*astFile) *checkedPackage {
+	c := &checkedPackage{
+		info: &types.Info{
+			Defs:      map[*ast.Ident]types.Object{},
+			Uses:      map[*ast.Ident]types.Object{},
+			Implicits: map[ast.Node]types.Object{},
+			Scopes:    map[ast.Node]*types.Scope{},
+		},
+		idents: map[*astFile][]*ast.Ident{},
+	}
+	conf := types.Config{
+		Importer:    r.importer,
+		FakeImportC: true,
+		Error:       func(err error) { c.errors = append(c.errors, err.(types.Error)) },
+	}
+	asts := make([]*ast.File, len(files))
+	for i, f := range files {
+		asts[i] = f.File
+		ast.Inspect(f.File, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				c.idents[f] = append(c.idents[f], id)
+			}
+			return true
+		})
+	}
+	c.pkg, _ = conf.Check(r.pkgName, r.fset, asts, c.info)
+	return c
+}
+
+func (c *checkedPackage) object(id *ast.Ident) types.Object {
+	if obj := c.info.Defs[id]; obj != nil {
+		return origin(obj)
+	}
+	if obj := c.info.Uses[id]; obj != nil {
+		return origin(obj)
+	}
+	return nil
+}
+
+// origin returns the generic object an instantiated method or field comes from.
+func origin(obj types.Object) types.Object {
+	switch o := obj.(type) {
+	case *types.Func:
+		return o.Origin()
+	case *types.Var:
+		return o.Origin()
+	}
+	return obj
+}
+
+// resolve finds the object target names and, for a method or field named as
+// Type.Member, the type it belongs to.
+func (r *renamer) resolve(c *checkedPackage, target Target) (types.Object, *types.TypeName, error) {
+	var obj types.Object
+	var owner *types.TypeName
+	switch {
+	case target.Line > 0:
+		for f, idents := range c.idents {
+			if r.name(f) != target.File {
+				continue
+			}
+			for _, id := range idents {
+				if id.Name == target.Name && f.line(id.Pos()) == target.Line {
+					if obj = c.object(id); obj == nil {
+						obj = implicitAt(c, id.Pos())
+					}
+					if obj != nil {
+						break
+					}
+				}
+			}
+		}
+		if obj == nil {
+			return nil, nil, fmt.Errorf("line %d of %s has no identifier %s", target.Line, target.File, target.Name)
+		}
+
+	case strings.Contains(target.Name, "."):
+		typeName, member, _ := strings.Cut(target.Name, ".")
+		tn, ok := c.pkg.Scope().Lookup(typeName).(*types.TypeName)
+		if !ok {
+			return nil, nil, fmt.Errorf("package %s has no type %s", r.pkgName, typeName)
+		}
+		owner = tn
+		if obj, _, _ = types.LookupFieldOrMethod(tn.Type(), true, c.pkg, member); obj == nil {
+			return nil, nil, fmt.Errorf("type %s has no field or method %s", typeName, member)
+		}
+		obj = origin(obj)
+
+	default:
+		if obj = c.pkg.Scope().Lookup(target.Name); obj == nil {
+			return nil, nil, fmt.Errorf("package %s has no package-level %s in the current build; name a local identifier with its line", r.pkgName, target.Name)
+		}
+	}
+
+	switch {
+	case obj.Pkg() != c.pkg:
+		return nil, nil, fmt.Errorf("%s is declared outside package %s and cannot be renamed here", target.Name, r.pkgName)
+	case obj.Name() == "_":
+		return nil, nil, fmt.Errorf("the blank identifier cannot be renamed")
+	}
+	if _, ok := obj.(*types.PkgName); ok {
+		return nil, nil, fmt.Errorf("%s is an imported package name; use remove_import and add_import with import_name instead", obj.Name())
+	}
+	if obj.Parent() == c.pkg.Scope() && (obj.Name() == "init" || obj.Name() == "main" && r.pkgName == "main") {
+		return nil, nil, fmt.Errorf("%s cannot be renamed", obj.Name())
+	}
+	return obj, owner, nil
+}
+
+// implicitAt returns the object a type switch declares at pos, whose
+// identifier has no object of its own.
+func implicitAt(c *checkedPackage, pos token.Pos) types.Object {
+	for _, obj := range c.info.Implicits {
+		if obj.Pos() == pos {
+			return obj
+		}
+	}
+	return nil
+}
+
+// conflicts reports a declaration newName would collide with.
+func (r *renamer) conflicts(c *checkedPackage, obj types.Object, owner *types.TypeName, newName string) error {
+	if owner != nil || obj.Parent() == nil {
+		// Fields and methods live in their type, not in a scope.
+		recv := owner
+		if recv == nil {
+			recv = ownerOf(c, obj)
+		}
+		if recv != nil {
+			if other, _, _ := types.LookupFieldOrMethod(recv.Type(), true, c.pkg, newName); other != nil {
+				return fmt.Errorf("%s already has %s, declared at %s", recv.Name(), newName, r.position(other.Pos()))
+			}
+		}
+		return nil
+	}
+
+	if other := obj.Parent().Lookup(newName); other != nil {
+		return fmt.Errorf("%s is already declared in the same scope at %s", newName, r.position(other.Pos()))
+	}
+	if obj.Parent() == c.pkg.Scope() {
+		for _, f := range r.checked {
+			if other := c.info.Scopes[f.File].Lookup(newName); other != nil {
+				return fmt.Errorf("%s imports a package as %s", r.name(f), newName)
+			}
+		}
+	}
+	return nil
+}
+
+// ownerOf returns the package-level named type whose field or method obj is.
+func ownerOf(c *checkedPackage, obj types.Object) *types.TypeName {
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Signature().Recv(); recv != nil {
+			if named, ok := deref(recv.Type()).(*types.Named); ok {
+				return named.Obj()
+			}
+		}
+	}
+	for _, name := range c.pkg.Scope().Names() {
+		tn, ok := c.pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if found, _, _ := types.LookupFieldOrMethod(tn.Type(), true, c.pkg, obj.Name()); found != nil && origin(found) == obj {
+			return tn
+		}
+	}
+	return nil
+}
+
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+// targets returns obj and, for a type, the fields that embed it, whose names
+// are the type's name.
+func (r *renamer) targets(c *checkedPackage, obj types.Object) map[types.Object]bool {
+	targets := map[types.Object]bool{obj: true}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return targets
+	}
+	for id, def := range c.info.Defs {
+		if v, ok := def.(*types.Var); ok && v.Embedded() && origin(c.info.Uses[id]) == obj {
+			targets[origin(v)] = true
+		}
+	}
+	return targets
+}
+
+// typedEdits returns the offsets of the identifiers of f that refer to targets.
+func (r *renamer) typedEdits(c *checkedPackage, f *astFile, targets map[types.Object]bool) []int {
+	var offsets []int
+	for _, id := range c.idents[f] {
+		if obj := c.object(id); obj != nil && targets[obj] || obj == nil && targets[implicitAt(c, id.Pos())] {
+			offsets = append(offsets, f.offset(id.Pos()))
+		}
+	}
+	return offsets
+}
+
+// syntacticEdits adds the edits for the files that are not type-checked and
+// returns notes on what was done there. Package-level names are renamed where
+// they are not shadowed, and exported ones also in selectors on the package
+// in external tests; methods are renamed where they are declared. Other
+// selectors cannot be resolved and are only counted.
+func (r *renamer) syntacticEdits(obj types.Object, edits map[*astFile][]int) []string {
+	old := obj.Name()
+	packageLevel := obj.Parent() != nil && obj.Parent() == obj.Pkg().Scope()
+	member := false
+	var recv string
+	switch o := obj.(type) {
+	case *types.Func:
+		if sig := o.Signature(); sig.Recv() != nil {
+			member = true
+			if named, ok := deref(sig.Recv().Type()).(*types.Named); ok {
+				recv = named.Obj().Name()
+			}
+		}
+	case *types.Var:
+		member = o.IsField()
+	}
+	if !packageLevel && !member {
+		return nil
+	}
+
+	var renamedIn []string
+	unresolved := map[string]int{}
+	visit := func(f *astFile, external bool) {
+		pkgNames := r.packageNames(f)
+		var offsets []int
+		var walk func(n ast.Node) bool
+		walk = func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if n.Sel.Name == old {
+					if x, ok := n.X.(*ast.Ident); ok && external && packageLevel && pkgNames[x.Name] && x.Obj == nil {
+						offsets = append(offsets, f.offset(n.Sel.Pos()))
+					} else if member {
+						unresolved[r.name(f)]++
+					}
+				}
+				ast.Inspect(n.X, walk)
+				return false
+			case *ast.FuncDecl:
+				if recv != "" && n.Name.Name == old && receiverType(n) == recv {
+					offsets = append(offsets, f.offset(n.Name.Pos()))
+				}
+			case *ast.Ident:
+				if !external && packageLevel && n.Name == old && (n.Obj == nil || f.Scope.Lookup(old) == n.Obj) {
+					offsets = append(offsets, f.offset(n.Pos()))
+				}
+			}
+			return true
+		}
+		ast.Inspect(f.File, walk)
+		if len(offsets) > 0 {
+			edits[f] = offsets
+			renamedIn = append(renamedIn, r.name(f))
+		}
+	}
+	for _, f := range r.excluded {
+		visit(f, false)
+	}
+	for _, f := range r.external {
+		visit(f, true)
+	}
+
+	var notes []string
+	if len(renamedIn) > 0 {
+		sort.Strings(renamedIn)
+		notes = append(notes, fmt.Sprintf("%s %s outside the current build and renamed by name only; check them",
+			strings.Join(renamedIn, ", "), plural(len(renamedIn), "is", "are")))
+	}
+	if len(unresolved) > 0 {
+		var where []string
+		for _, name := range sortedKeys(unresolved) {
+			where = append(where, fmt.Sprintf("%s (%d)", name, unresolved[name]))
+		}
+		notes = append(notes, fmt.Sprintf("selectors .%s in files outside the current build could not be resolved and were left as they were: %s",
+			old, strings.Join(where, ", ")))
+	}
+	return notes
+}
+
+// packageNames returns the names under which f imports the package being
+// renamed in, which an external test file does by the package's name.
+func (r *renamer) packageNames(f *astFile) map[string]bool {
+	names := map[string]bool{}
+	for _, imp := range f.Imports {
+		switch name := importName(imp); {
+		case name != "" && name != "_" && name != "." && defaultName(importPath(imp)) == r.pkgName:
+			names[name] = true
+		case name == "" && defaultName(importPath(imp)) == r.pkgName:
+			names[r.pkgName] = true
+		}
+	}
+	return names
+}
+
+// applyRename replaces the identifier old at each offset with newName,
+// skipping offsets that do not hold old.
+func applyRename(src []byte, offsets []int, old, newName string) []byte {
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	out := src
+	last := -1
+	for _, off := range offsets {
+		if off == last || !bytes.HasPrefix(src[off:], []byte(old)) {
+			continue
+		}
+		out = splice(out, off, off+len(old), newName)
+		last = off
+	}
+	return out
+}
+
+// verify type-checks the renamed files and refuses the rename if any
+// identifier would refer to a different declaration than before, or if there
+// would be more type errors.
+func (r *renamer) verify(before *checkedPackage, renamed map[*astFile][]byte) error {
+	files := make([]*astFile, len(r.checked))
+	for i, f := range r.checked {
+		files[i] = f
+		if src, ok := renamed[f]; ok {
+			edited, err := parse(r.fset, r.fset.Position(f.Package).Filename, src)
+			if err != nil {
+				return fmt.Errorf("the rename would leave %s unparsable: %v", r.name(f), err)
+			}
+			files[i] = edited
+		}
+	}
+	after := r.check(files)
+
+	for i, f := range r.checked {
+		beforeKeys, afterKeys := before.keys(r, r.checked, f), after.keys(r, files, files[i])
+		for j, key := range beforeKeys {
+			if key != "" && j < len(afterKeys) && afterKeys[j] != key {
+				id := before.idents[f][j]
+				return fmt.Errorf("the rename would change what %s at %s refers to", id.Name, r.position(id.Pos()))
+			}
+		}
+	}
+
+	if len(after.errors) > len(before.errors) {
+		seen := map[string]bool{}
+		for _, err := range before.errors {
+			seen[err.Msg] = true
+		}
+		var added []string
+		for _, err := range after.errors {
+			if !seen[err.Msg] && len(added) < 3 {
+				added = append(added, fmt.Sprintf("%s: %s", r.position(err.Pos), err.Msg))
+			}
+		}
+		return fmt.Errorf("the rename would not compile:\n  %s", strings.Join(added, "\n  "))
+	}
+	return nil
+}
+
+// keys names what each identifier of f refers to in a way that does not
+// depend on names: declarations in the package by the file and index of
+// their identifier, and others by their package and position.
+func (c *checkedPackage) keys(r *renamer, files []*astFile, f *astFile) []string {
+	index := map[token.Pos]string{}
+	for i, file := range files {
+		for j, id := range c.idents[file] {
+			index[id.Pos()] = fmt.Sprintf("%d#%d", i, j)
+		}
+	}
+	keys := make([]string, len(c.idents[f]))
+	for i, id := range c.idents[f] {
+		obj := c.object(id)
+		switch {
+		case obj == nil:
+		case obj.Pkg() == c.pkg:
+			keys[i] = index[obj.Pos()]
+		case obj.Pkg() == nil:
+			keys[i] = "universe." + obj.Name()
+		default:
+			keys[i] = obj.Pkg().Path() + "." + obj.Name() + "@" + r.fset.Position(obj.Pos()).String()
+		}
+	}
+	return keys
+}
+
+// renameNotes describes effects of the rename the type checker cannot see.
+func (r *renamer) renameNotes(c *checkedPackage, obj types.Object, newName string) []string {
+	var notes []string
+	old := obj.Name()
+	if token.IsExported(old) && r.pkgName != "main" && (obj.Parent() == c.pkg.Scope() || obj.Parent() == nil) {
+		notes = append(notes, fmt.Sprintf("%s is exported; code in other packages that uses it was not updated", old))
+	}
+	if token.IsExported(old) != token.IsExported(newName) {
+		if token.IsExported(newName) {
+			notes = append(notes, fmt.Sprintf("%s is now exported", newName))
+		} else {
+			notes = append(notes, fmt.Sprintf("%s is no longer exported", newName))
+		}
+	}
+
+	fn, ok := obj.(*types.Func)
+	if !ok || fn.Signature().Recv() == nil {
+		return notes
+	}
+	recv, ok := deref(fn.Signature().Recv().Type()).(*types.Named)
+	if !ok {
+		return notes
+	}
+	iface, isInterface := recv.Underlying().(*types.Interface)
+	for _, name := range c.pkg.Scope().Names() {
+		tn, ok := c.pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok || tn == recv.Obj() {
+			continue
+		}
+		other, otherIsInterface := tn.Type().Underlying().(*types.Interface)
+		switch {
+		case isInterface && !otherIsInterface && implements(tn.Type(), iface):
+			notes = append(notes, fmt.Sprintf("%s implements %s; rename %s.%s too or it no longer will", tn.Name(), recv.Obj().Name(), tn.Name(), old))
+		case !isInterface && otherIsInterface && hasMethod(other, old) && implements(recv, other):
+			notes = append(notes, fmt.Sprintf("%s no longer implements %s, which has %s", recv.Obj().Name(), tn.Name(), old))
+		}
+	}
+	return notes
+}
+
+func implements(t types.Type, iface *types.Interface) bool {
+	return types.Implements(t, iface) || types.Implements(types.NewPointer(t), iface)
+}
+
+func hasMethod(iface *types.Interface, name string) bool {
+	for i := 0; i < iface.NumMethods(); i++ {
+		if iface.Method(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stubImporter imports packages from source, and packages that cannot be
+// loaded as empty packages, so that the rest of the package still type-checks.
+type stubImporter struct {
+	source types.ImporterFrom
+	stubs  map[string]*types.Package
+}
+
+func (im *stubImporter) Import(path string) (*types.Package, error) {
+	return im.ImportFrom(path, "", 0)
+}
+
+func (im *stubImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if stub, ok := im.stubs[path]; ok {
+		return stub, nil
+	}
+	if pkg, err := im.source.ImportFrom(path, dir, mode); pkg != nil && (err == nil || pkg.Complete()) {
+		return pkg, nil
+	}
+	stub := types.NewPackage(path, defaultName(path))
+	stub.MarkComplete()
+	im.stubs[path] = stub
+	return stub, nil
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
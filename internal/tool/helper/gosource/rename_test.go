@@ -0,0 +1,158 @@
+package gosource
+
+import (
+	"strings"
+	"testing"
+)
+
+var store = []File{
+	{Name: "store.go", Src: []byte(`package store
+
+import "strings"
+
+// Store holds items.
+type Store struct {
+	items map[string]string
+}
+
+func (s *Store) Get(key string) string {
+	return s.items[normalize(key)]
+}
+
+func normalize(key string) string {
+	return strings.ToLower(key)
+}
+`)},
+	{Name: "cache.go", Src: []byte(`package store
+
+type Getter interface {
+	Get(key string) string
+}
+
+// Cache wraps a Store.
+type Cache struct {
+	*Store
+	hits int
+}
+
+func (c *Cache) Lookup(key string) string {
+	c.hits++
+	get := func(name string) string { return c.Get(name) }
+	return get(normalize(key))
+}
+`)},
+	{Name: "legacy.go", Src: []byte(`//go:build ignore
+
+package store
+
+func normalizePath(key string) string {
+	return normalize(key)
+}
+`)},
+	{Name: "store_test.go", Src: []byte(`package store_test
+
+import (
+	"testing"
+
+	"example.com/store"
+)
+
+func TestGet(t *testing.T) {
+	var s store.Store
+	_ = s.Get("a")
+}
+`)},
+}
+
+func rename(t *testing.T, target Target, newName string) *Renamed {
+	t.Helper()
+	res, err := Rename(t.TempDir(), store, target, newName)
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	return res
+}
+
+func TestRename(t *testing.T) {
+	t.Run("package-level function across files and build constraints", func(t *testing.T) {
+		res := rename(t, Target{File: "store.go", Name: "normalize"}, "canonical")
+		if len(res.Files) != 3 {
+			t.Fatalf("expected 3 changed files, got %d", len(res.Files))
+		}
+		for name, want := range map[string]string{
+			"store.go":  "s.items[canonical(key)]",
+			"cache.go":  "get(canonical(key))",
+			"legacy.go": "return canonical(key)",
+		} {
+			if !strings.Contains(string(res.Files[name].Src), want) {
+				t.Errorf("%s: expected %q in:\n%s", name, want, res.Files[name].Src)
+			}
+		}
+		if !strings.Contains(string(res.Files["store.go"].Src), "func canonical(key string) string") {
+			t.Errorf("expected the declaration to be renamed")
+		}
+		if len(res.Notes) != 1 || !strings.Contains(res.Notes[0], "legacy.go is outside the current build") {
+			t.Errorf("unexpected notes %v", res.Notes)
+		}
+	})
+
+	t.Run("type renames embedded fields and external tests", func(t *testing.T) {
+		res := rename(t, Target{File: "cache.go", Name: "Store"}, "Backend")
+		if !strings.Contains(string(res.Files["cache.go"].Src), "\t*Backend\n") {
+			t.Errorf("unexpected cache.go:\n%s", res.Files["cache.go"].Src)
+		}
+		if !strings.Contains(string(res.Files["store_test.go"].Src), "var s store.Backend") {
+			t.Errorf("unexpected store_test.go:\n%s", res.Files["store_test.go"].Src)
+		}
+		if len(res.Notes) != 2 || !strings.Contains(res.Notes[0], "Store is exported") {
+			t.Errorf("unexpected notes %v", res.Notes)
+		}
+	})
+
+	t.Run("method through promotion", func(t *testing.T) {
+		res := rename(t, Target{File: "store.go", Name: "Store.Get"}, "Fetch")
+		if !strings.Contains(string(res.Files["cache.go"].Src), "return c.Fetch(name)") {
+			t.Errorf("unexpected cache.go:\n%s", res.Files["cache.go"].Src)
+		}
+		if strings.Contains(string(res.Files["cache.go"].Src), "Fetch(key string)") {
+			t.Errorf("the interface method should not be renamed")
+		}
+		want := []string{"Store no longer implements Getter, which has Get", "selectors .Get in files outside the current build"}
+		for _, w := range want {
+			if !strings.Contains(strings.Join(res.Notes, "\n"), w) {
+				t.Errorf("expected a note containing %q, got %v", w, res.Notes)
+			}
+		}
+	})
+
+	t.Run("local by line", func(t *testing.T) {
+		res := rename(t, Target{File: "cache.go", Name: "get", Line: 15}, "lookup")
+		if !strings.Contains(string(res.Files["cache.go"].Src), "return lookup(normalize(key))") || len(res.Files) != 1 {
+			t.Errorf("unexpected result:\n%s", res.Files["cache.go"].Src)
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		tests := []struct {
+			target  Target
+			newName string
+			want    string
+		}{
+			{Target{File: "store.go", Name: "normalize"}, "Store", "Store is already declared in the same scope at store.go:6"},
+			{Target{File: "store.go", Name: "normalize"}, "strings", "store.go imports a package as strings"},
+			{Target{File: "cache.go", Name: "Cache.hits"}, "Store", "Cache already has Store, declared at cache.go:9"},
+			{Target{File: "cache.go", Name: "get", Line: 15}, "key", "key is already declared in the same scope at cache.go:13"},
+			{Target{File: "cache.go", Name: "name", Line: 15}, "c", "the rename would change what c at cache.go:15 refers to"},
+			{Target{File: "store.go", Name: "normalize"}, "len", "len is a predeclared identifier"},
+			{Target{File: "store.go", Name: "missing"}, "x", "package store has no package-level missing"},
+			{Target{File: "legacy.go", Name: "normalize"}, "x", "legacy.go is excluded by build constraints"},
+			{Target{File: "store.go", Name: "Store.Get"}, "2x", `"2x" is not a valid Go identifier`},
+		}
+		for _, tt := range tests {
+			_, err := Rename(t.TempDir(), store, tt.target, tt.newName)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s -> %s: expected error containing %q, got %v", tt.target.Name, tt.newName, tt.want, err)
+			}
+		}
+	})
+}
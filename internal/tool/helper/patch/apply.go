@@ -0,0 +1,212 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxFuzz is the number of context lines that may be ignored at each end of a hunk
+// when it does not apply as written, as with GNU patch's default.
+const MaxFuzz = 2
+
+// HunkError reports a hunk that could not be placed in the file.
+type HunkError struct {
+	Index  int // 1-based
+	Hunk   Hunk
+	Reason string
+}
+
+func (e *HunkError) Error() string {
+	return fmt.Sprintf("hunk %d (%s) %s", e.Index, e.Hunk.Header(), e.Reason)
+}
+
+// Apply applies hunks to content, which must use \n line endings. Each hunk is looked
+// for nearest its stated position after the previous hunk, first exactly, then
+// ignoring trailing whitespace, then with up to MaxFuzz context lines ignored at each
+// end. It returns the new content and a note for every hunk that needed any of that.
+func Apply(content string, hunks []Hunk) (string, []string, error) {
+	lines, eol := splitContent(content)
+
+	var notes []string
+	offset := 0 // Where hunks land relative to their stated positions
+	minPos := 0 // Hunks apply in order and may not overlap
+	for i, h := range hunks {
+		body := h.Lines
+
+		expected := h.OldStart - 1
+		if h.OldCount == 0 {
+			expected = h.OldStart // Pure insertion after line OldStart
+		}
+		expected += offset
+
+		pos, fuzz, loose, found := locate(lines, h, expected, minPos)
+		if !found {
+			return "", nil, &HunkError{Index: i + 1, Hunk: h, Reason: notFoundReason(h.Old(), expected)}
+		}
+		if fuzz > 0 {
+			body = trimContext(h, fuzz)
+			expected += leadingContext(h, fuzz)
+		}
+		old, repl := replacement(body, lines, pos)
+
+		if note := placementNote(i+1, pos, expected, fuzz, loose); note != "" {
+			notes = append(notes, note)
+		}
+
+		tail := append([]string{}, lines[pos+len(old):]...)
+		lines = append(append(lines[:pos], repl...), tail...)
+		offset += pos - expected + len(repl) - len(old)
+		minPos = pos + len(repl)
+
+		// End-of-file markers only matter for a hunk that reaches the end of the file.
+		if minPos == len(lines) && (h.OldNoEOL || h.NewNoEOL) {
+			eol = !h.NewNoEOL
+		}
+	}
+
+	if len(lines) == 0 {
+		return "", notes, nil
+	}
+	out := strings.Join(lines, "\n")
+	if eol {
+		out += "\n"
+	}
+	return out, notes, nil
+}
+
+// splitContent returns the lines of content and whether it ends with a newline.
+// Empty content has no lines and is treated as newline-terminated, so added
+// lines end with a newline unless the patch says otherwise.
+func splitContent(content string) ([]string, bool) {
+	if content == "" {
+		return nil, true
+	}
+	eol := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), eol
+}
+
+// locate finds where the hunk's old lines sit. It returns the position, the fuzz
+// used, whether trailing whitespace was ignored, and whether the hunk was found.
+func locate(lines []string, h Hunk, expected, minPos int) (pos, fuzz int, loose, found bool) {
+	for fuzz = 0; fuzz <= MaxFuzz; fuzz++ {
+		if fuzz > 0 && leadingContext(h, fuzz) == 0 && trailingContext(h, fuzz) == 0 {
+			break // Nothing left to ignore
+		}
+		old := Hunk{Lines: trimContext(h, fuzz)}.Old()
+		at := expected + leadingContext(h, fuzz)
+		for _, loose = range []bool{false, true} {
+			if pos, found = nearest(lines, old, at, minPos, loose); found {
+				return pos, fuzz, loose, true
+			}
+		}
+	}
+	return 0, 0, false, false
+}
+
+// nearest returns the position of old in lines closest to at, not before minPos.
+func nearest(lines, old []string, at, minPos int, loose bool) (int, bool) {
+	last := len(lines) - len(old)
+	if last < minPos {
+		return 0, false
+	}
+	at = min(max(at, minPos), last)
+	if len(old) == 0 {
+		return at, true
+	}
+	for d := 0; at-d >= minPos || at+d <= last; d++ {
+		if p := at - d; p >= minPos && matchesAt(lines, old, p, loose) {
+			return p, true
+		}
+		if p := at + d; d > 0 && p <= last && matchesAt(lines, old, p, loose) {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+func matchesAt(lines, old []string, pos int, loose bool) bool {
+	for i, want := range old {
+		got := lines[pos+i]
+		if loose {
+			got, want = strings.TrimRight(got, " \t"), strings.TrimRight(want, " \t")
+		}
+		if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// trimContext returns the hunk's lines without up to fuzz context lines at each end.
+func trimContext(h Hunk, fuzz int) []Line {
+	lead, trail := leadingContext(h, fuzz), trailingContext(h, fuzz)
+	return h.Lines[lead : len(h.Lines)-trail]
+}
+
+// replacement returns the file lines body covers at pos and the lines that replace
+// them. Context lines are taken from the file, so whitespace the match tolerated is kept.
+func replacement(body []Line, lines []string, pos int) (old, repl []string) {
+	for _, l := range body {
+		switch l.Kind {
+		case ' ':
+			line := lines[pos+len(old)]
+			old = append(old, line)
+			repl = append(repl, line)
+		case '-':
+			old = append(old, lines[pos+len(old)])
+		case '+':
+			repl = append(repl, l.Text)
+		}
+	}
+	return old, repl
+}
+
+// leadingContext is how many of the hunk's leading context lines fuzz drops.
+// A hunk keeps at least one line so it still anchors somewhere.
+func leadingContext(h Hunk, fuzz int) int {
+	n := 0
+	for n < fuzz && n < len(h.Lines)-1 && h.Lines[n].Kind == ' ' {
+		n++
+	}
+	return n
+}
+
+func trailingContext(h Hunk, fuzz int) int {
+	lead := leadingContext(h, fuzz)
+	n := 0
+	for n < fuzz && len(h.Lines)-1-n > lead && h.Lines[len(h.Lines)-1-n].Kind == ' ' {
+		n++
+	}
+	return n
+}
+
+func placementNote(index, pos, expected, fuzz int, loose bool) string {
+	var parts []string
+	if pos != expected {
+		parts = append(parts, fmt.Sprintf("offset %+d lines", pos-expected))
+	}
+	if fuzz > 0 {
+		parts = append(parts, fmt.Sprintf("fuzz %d", fuzz))
+	}
+	if loose {
+		parts = append(parts, "ignoring trailing whitespace")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("hunk %d applied at line %d (%s)", index, pos+1, strings.Join(parts, ", "))
+}
+
+func notFoundReason(old []string, expected int) string {
+	const maxShown = 10
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "does not match the file; expected near line %d:", expected+1)
+	for i, l := range old {
+		if i == maxShown {
+			fmt.Fprintf(&sb, "\n  ... (%d more lines)", len(old)-maxShown)
+			break
+		}
+		sb.WriteString("\n  " + l)
+	}
+	return sb.String()
+}
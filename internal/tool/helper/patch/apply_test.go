@@ -0,0 +1,105 @@
+package patch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func mustParseHunks(t *testing.T, diff string) []Hunk {
+	t.Helper()
+	patches, err := Parse(diff)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return patches[0].Hunks
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		diff    string
+		want    string
+		note    string
+	}{
+		{
+			name:    "exact",
+			content: "a\nb\nc\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:    "a\nB\nc\n",
+		},
+		{
+			name:    "offset",
+			content: "x\ny\na\nb\nc\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:    "x\ny\na\nB\nc\n",
+			note:    "hunk 1 applied at line 3 (offset +2 lines)",
+		},
+		{
+			name:    "fuzz drops stale context",
+			content: "a\nb\nchanged\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:    "a\nB\nchanged\n",
+			note:    "fuzz 1",
+		},
+		{
+			name:    "trailing whitespace",
+			content: "a  \nb\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+B\n",
+			want:    "a  \nB\n",
+			note:    "ignoring trailing whitespace",
+		},
+		{
+			name:    "second hunk follows offset of first",
+			content: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,2 +1,3 @@\n 1\n+1.5\n 2\n@@ -7,2 +8,1 @@\n 7\n-8\n",
+			want:    "1\n1.5\n2\n3\n4\n5\n6\n7\n",
+		},
+		{
+			name:    "no newline marker on new side",
+			content: "a\nb\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n\\ No newline at end of file\n",
+			want:    "a\nc",
+		},
+		{
+			name:    "add to empty file",
+			content: "",
+			diff:    "--- /dev/null\n+++ b/f\n@@ -0,0 +1,2 @@\n+hello\n+world\n",
+			want:    "hello\nworld\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, notes, err := Apply(tt.content, mustParseHunks(t, tt.diff))
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			joined := strings.Join(notes, "\n")
+			if tt.note == "" && joined != "" {
+				t.Errorf("unexpected notes: %s", joined)
+			}
+			if !strings.Contains(joined, tt.note) {
+				t.Errorf("notes %q missing %q", joined, tt.note)
+			}
+		})
+	}
+}
+
+func TestApply_MismatchReturnsHunkError(t *testing.T) {
+	hunks := mustParseHunks(t, "--- a/f\n+++ b/f\n@@ -1,1 +1,1 @@\n-missing\n+new\n")
+
+	_, _, err := Apply("a\nb\n", hunks)
+
+	var hunkErr *HunkError
+	if !errors.As(err, &hunkErr) {
+		t.Fatalf("expected HunkError, got %v", err)
+	}
+	if hunkErr.Index != 1 || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+// Package patch parses multi-file unified diffs and applies their hunks with
+// offset and fuzz tolerance, in the manner of GNU patch.
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Op is what a file patch does to its file.
+type Op int
+
+const (
+	OpModify Op = iota
+	OpAdd
+	OpDelete
+	OpRename
+)
+
+func (o Op) String() string {
+	switch o {
+	case OpAdd:
+		return "add"
+	case OpDelete:
+		return "delete"
+	case OpRename:
+		return "rename"
+	default:
+		return "modify"
+	}
+}
+
+// FilePatch is the part of a diff that applies to one file.
+type FilePatch struct {
+	OldPath string // Empty when the file is added
+	NewPath string // Empty when the file is deleted
+	Op      Op
+	Hunks   []Hunk
+}
+
+// Path returns the path the patch is best known by: the new path unless the file is deleted.
+func (p FilePatch) Path() string {
+	if p.NewPath != "" {
+		return p.NewPath
+	}
+	return p.OldPath
+}
+
+// Hunk is one @@ section of a file patch.
+type Hunk struct {
+	OldStart, OldCount int // 1-based; OldStart is the line before the hunk when OldCount is 0
+	NewStart, NewCount int
+	Lines              []Line
+
+	OldNoEOL bool // "\ No newline at end of file" after the hunk's last old line
+	NewNoEOL bool // "\ No newline at end of file" after the hunk's last new line
+}
+
+// Header returns the hunk's @@ line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+}
+
+// Old returns the lines the hunk expects to find: context and removals.
+func (h Hunk) Old() []string {
+	return h.side('-')
+}
+
+// New returns the lines the hunk leaves behind: context and additions.
+func (h Hunk) New() []string {
+	return h.side('+')
+}
+
+func (h Hunk) side(kind byte) []string {
+	var out []string
+	for _, l := range h.Lines {
+		if l.Kind == ' ' || l.Kind == kind {
+			out = append(out, l.Text)
+		}
+	}
+	return out
+}
+
+// Line is a hunk line. Kind is ' ' for context, '-' for a removal and '+' for an addition.
+type Line struct {
+	Kind byte
+	Text string
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Parse splits a unified diff into file patches. It accepts plain `diff -u` output and
+// git diffs, including new, deleted and renamed files. Text outside file sections, such
+// as a commit message, is ignored. Hunk line counts are used only to recover blank
+// context lines whose leading space was lost; otherwise a hunk runs until the next
+// hunk or file header, which tolerates the miscounted headers hand-written diffs often have.
+func Parse(diff string) ([]FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+
+	var patches []FilePatch
+	var cur *FilePatch
+	sawHeader := false // Current patch has its ---/+++ pair
+	flush := func() {
+		if cur != nil {
+			patches = append(patches, *cur)
+		}
+		cur, sawHeader = nil, false
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			oldPath, newPath := parseGitHeader(strings.TrimPrefix(line, "diff --git "))
+			cur = &FilePatch{OldPath: oldPath, NewPath: newPath, Op: -1}
+
+		case cur != nil && strings.HasPrefix(line, "new file mode"):
+			cur.Op, cur.OldPath = OpAdd, ""
+		case cur != nil && strings.HasPrefix(line, "deleted file mode"):
+			cur.Op, cur.NewPath = OpDelete, ""
+		case cur != nil && strings.HasPrefix(line, "rename from "):
+			cur.Op, cur.OldPath = OpRename, strings.TrimPrefix(line, "rename from ")
+		case cur != nil && strings.HasPrefix(line, "rename to "):
+			cur.Op, cur.NewPath = OpRename, strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "GIT binary patch"), strings.HasPrefix(line, "Binary files "):
+			return nil, fmt.Errorf("line %d: binary patches are not supported", i+1)
+
+		case isFileHeader(lines, i):
+			if cur == nil || sawHeader || len(cur.Hunks) > 0 {
+				flush()
+				cur = &FilePatch{Op: -1}
+			}
+			cur.OldPath = headerPath(strings.TrimPrefix(line, "--- "), "a/")
+			cur.NewPath = headerPath(strings.TrimPrefix(lines[i+1], "+++ "), "b/")
+			sawHeader = true
+			i++
+
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk before any file header", i+1)
+			}
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			cur.Hunks = append(cur.Hunks, h)
+			i = next - 1
+		}
+	}
+	flush()
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no file changes found in patch")
+	}
+	for i := range patches {
+		if err := finish(&patches[i]); err != nil {
+			return nil, err
+		}
+	}
+	return patches, nil
+}
+
+// finish settles the operation of a parsed patch and checks it is complete.
+func finish(p *FilePatch) error {
+	if p.Op < 0 {
+		switch {
+		case p.OldPath == "" && p.NewPath == "":
+			return fmt.Errorf("file patch has no paths")
+		case p.OldPath == "":
+			p.Op = OpAdd
+		case p.NewPath == "":
+			p.Op = OpDelete
+		case p.OldPath != p.NewPath:
+			p.Op = OpRename
+		default:
+			p.Op = OpModify
+		}
+	}
+	if p.Op == OpRename && p.OldPath == p.NewPath {
+		p.Op = OpModify
+	}
+	if p.Op == OpModify && len(p.Hunks) == 0 {
+		return fmt.Errorf("%s: no hunks", p.Path())
+	}
+	if p.Op == OpAdd {
+		for _, h := range p.Hunks {
+			if len(h.Old()) > 0 {
+				return fmt.Errorf("%s: new file hunk %s has context or removed lines", p.Path(), h.Header())
+			}
+		}
+	}
+	return nil
+}
+
+// isFileHeader reports whether lines[i] starts a ---/+++ pair. Requiring the +++ line
+// keeps a removed line that begins with "-- " from being taken for a header.
+func isFileHeader(lines []string, i int) bool {
+	return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// headerPath extracts the path from a ---/+++ header value, dropping a timestamp and
+// the conventional a/ or b/ prefix. /dev/null becomes "".
+func headerPath(value, prefix string) string {
+	if i := strings.IndexByte(value, '\t'); i >= 0 {
+		value = value[:i]
+	}
+	value = strings.TrimSpace(value)
+	if value == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(value, prefix)
+}
+
+// parseGitHeader splits "a/old b/new" from a diff --git line.
+func parseGitHeader(rest string) (oldPath, newPath string) {
+	i := strings.LastIndex(rest, " b/")
+	if i < 0 {
+		return "", ""
+	}
+	return strings.TrimPrefix(rest[:i], "a/"), rest[i+len(" b/"):]
+}
+
+// parseHunk reads the hunk whose header is lines[start] and returns it with the index
+// of the first line after it.
+func parseHunk(lines []string, start int) (Hunk, int, error) {
+	m := hunkHeader.FindStringSubmatch(lines[start])
+	if m == nil {
+		return Hunk{}, 0, fmt.Errorf("line %d: malformed hunk header %q", start+1, lines[start])
+	}
+	h := Hunk{
+		OldStart: atoi(m[1], 0), OldCount: atoi(m[2], 1),
+		NewStart: atoi(m[3], 0), NewCount: atoi(m[4], 1),
+	}
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff --git ") || isFileHeader(lines, i) {
+			break
+		}
+		if line == "" {
+			h.Lines = append(h.Lines, Line{Kind: ' '})
+			continue
+		}
+		switch line[0] {
+		case ' ', '-', '+':
+			h.Lines = append(h.Lines, Line{Kind: line[0], Text: line[1:]})
+		case '\\':
+			if len(h.Lines) > 0 {
+				switch h.Lines[len(h.Lines)-1].Kind {
+				case '-':
+					h.OldNoEOL = true
+				case '+':
+					h.NewNoEOL = true
+				default:
+					h.OldNoEOL, h.NewNoEOL = true, true
+				}
+			}
+		default:
+			// Anything else ends the hunk: trailing prose or another tool's metadata.
+			return trimBlankTail(h), i, nil
+		}
+	}
+	return trimBlankTail(h), i, nil
+}
+
+// trimBlankTail drops bare empty lines at the end of a hunk, which are usually
+// separators rather than context. If the header's counts show some of them were blank
+// context lines that lost their leading space, those are kept.
+func trimBlankTail(h Hunk) Hunk {
+	blank := 0
+	for blank < len(h.Lines) {
+		last := h.Lines[len(h.Lines)-1-blank]
+		if last.Kind != ' ' || last.Text != "" {
+			break
+		}
+		blank++
+	}
+	h.Lines = h.Lines[:len(h.Lines)-blank]
+	if need := h.OldCount - len(h.Old()); need > 0 && need <= blank && h.NewCount-len(h.New()) == need {
+		for range need {
+			h.Lines = append(h.Lines, Line{Kind: ' '})
+		}
+	}
+	return h
+}
+
+func atoi(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,124 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse_GitMultiFile(t *testing.T) {
+	diff := `Some commit message that is ignored.
+
+diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var x = 1
++var x = 2
+
+diff --git a/new.txt b/new.txt
+new file mode 100644
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+diff --git a/a.txt b/b.txt
+similarity index 100%
+rename from a.txt
+rename to b.txt
+`
+	patches, err := Parse(diff)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := []struct {
+		op       Op
+		old, new string
+		hunks    int
+	}{
+		{OpModify, "main.go", "main.go", 1},
+		{OpAdd, "", "new.txt", 1},
+		{OpDelete, "old.txt", "", 1},
+		{OpRename, "a.txt", "b.txt", 0},
+	}
+	if len(patches) != len(want) {
+		t.Fatalf("got %d patches, want %d", len(patches), len(want))
+	}
+	for i, w := range want {
+		p := patches[i]
+		if p.Op != w.op || p.OldPath != w.old || p.NewPath != w.new || len(p.Hunks) != w.hunks {
+			t.Errorf("patch %d = %v %q -> %q (%d hunks), want %v %q -> %q (%d hunks)",
+				i, p.Op, p.OldPath, p.NewPath, len(p.Hunks), w.op, w.old, w.new, w.hunks)
+		}
+	}
+
+	h := patches[0].Hunks[0]
+	if got := strings.Join(h.Old(), "|"); got != "package main|var x = 1|" {
+		t.Errorf("old lines = %q", got)
+	}
+	if got := strings.Join(h.New(), "|"); got != "package main|var x = 2|" {
+		t.Errorf("new lines = %q", got)
+	}
+}
+
+func TestParse_PlainDiffWithWrongCountsAndNoNewlineMarker(t *testing.T) {
+	diff := "--- a/f.txt\t2024-01-01\n+++ b/f.txt\t2024-01-02\n@@ -1,9 +1,9 @@\n a\n-b\n+c\n\\ No newline at end of file\n\n"
+
+	patches, err := Parse(diff)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	h := patches[0].Hunks[0]
+	if patches[0].Op != OpModify || patches[0].Path() != "f.txt" {
+		t.Errorf("patch = %v %q", patches[0].Op, patches[0].Path())
+	}
+	if len(h.Lines) != 3 {
+		t.Errorf("got %d hunk lines, want 3 (trailing blank is a separator)", len(h.Lines))
+	}
+	if !h.NewNoEOL || h.OldNoEOL {
+		t.Errorf("NoEOL = old %v new %v, want new only", h.OldNoEOL, h.NewNoEOL)
+	}
+}
+
+func TestParse_RemovedLineLookingLikeHeader(t *testing.T) {
+	diff := "--- a/x.sql\n+++ b/x.sql\n@@ -1,2 +1,1 @@\n--- a comment\n select 1;\n"
+
+	patches, err := Parse(diff)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(patches) != 1 || len(patches[0].Hunks[0].Lines) != 2 {
+		t.Fatalf("expected one patch with two hunk lines, got %+v", patches)
+	}
+	if got := patches[0].Hunks[0].Lines[0]; got.Kind != '-' || got.Text != "-- a comment" {
+		t.Errorf("first line = %c %q", got.Kind, got.Text)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"empty":          "just some text\n",
+		"hunk first":     "@@ -1 +1 @@\n-a\n+b\n",
+		"binary":         "diff --git a/x b/x\nGIT binary patch\n",
+		"no hunks":       "--- a/x\n+++ b/x\n",
+		"bad add hunk":   "--- /dev/null\n+++ b/x\n@@ -0,0 +1 @@\n a\n+b\n",
+		"malformed @@":   "--- a/x\n+++ b/x\n@@ nonsense @@\n",
+		"no paths found": "--- /dev/null\n+++ /dev/null\n@@ -0,0 +1 @@\n+a\n",
+	}
+	for name, diff := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(diff); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
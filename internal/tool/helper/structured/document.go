@@ -0,0 +1,235 @@
+package structured
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// document is one document of a file: the whole of a JSON file, or the part of
+// a YAML stream between two "---" lines.
+type document struct {
+	format  Format
+	header  string     // The "---" line that starts the document, kept as it is
+	body    string     // The text after the header, up to the next document
+	node    *yaml.Node // A DocumentNode, or a zero Node for an empty document
+	blank   bool       // The body has no content, only comments or whitespace
+	changed bool
+
+	yamlStyle  yamlStyle
+	jsonIndent string
+}
+
+// parseDocuments splits text into its documents and parses each.
+func parseDocuments(text string, format Format) ([]*document, error) {
+	if format == FormatJSON {
+		d := &document{format: format, body: text, jsonIndent: jsonIndent(text)}
+		var err error
+		if d.node, err = parseJSON(text); err != nil {
+			return nil, err
+		}
+		d.blank = d.root() == nil
+		return []*document{d}, nil
+	}
+
+	var docs []*document
+	line := 1
+	for _, chunk := range splitYAML(text) {
+		d := &document{format: format, header: chunk[0], body: chunk[1], yamlStyle: detectYAMLStyle(chunk[1])}
+		var err error
+		if d.node, err = parseYAML(d.body); err != nil {
+			return nil, fmt.Errorf("invalid YAML in the document at line %d: %v", line+strings.Count(d.header, "\n"), err)
+		}
+		d.blank = d.root() == nil
+		docs = append(docs, d)
+		line += strings.Count(d.header+d.body, "\n")
+	}
+	return docs, nil
+}
+
+func parseYAML(text string) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// splitYAML cuts a YAML stream at its "---" lines into header and body pairs.
+// A "---" line followed only by a comment is kept as the header; one followed
+// by content, such as "--- !!map", starts the body, so the parser sees it.
+func splitYAML(text string) [][2]string {
+	var chunks [][2]string
+	var header string
+	var body strings.Builder
+	flush := func() {
+		chunks = append(chunks, [2]string{header, body.String()})
+		header = ""
+		body.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		trimmed := strings.TrimRight(line, "\n")
+		if trimmed == "---" || strings.HasPrefix(trimmed, "--- ") || strings.HasPrefix(trimmed, "---\t") {
+			if header != "" || body.Len() > 0 {
+				flush()
+			}
+			if rest := strings.TrimSpace(trimmed[3:]); rest == "" || strings.HasPrefix(rest, "#") {
+				header = line
+				continue
+			}
+		}
+		body.WriteString(line)
+	}
+	flush()
+	return chunks
+}
+
+func (d *document) root() *yaml.Node {
+	if d.node == nil || len(d.node.Content) == 0 {
+		return nil
+	}
+	return d.node.Content[0]
+}
+
+func (d *document) setRoot(n *yaml.Node) {
+	d.node.Kind = yaml.DocumentNode
+	d.node.Content = []*yaml.Node{n}
+}
+
+func (d *document) parse(text string) (*yaml.Node, error) {
+	if d.format == FormatJSON {
+		return parseJSON(text)
+	}
+	return parseYAML(text)
+}
+
+func (d *document) encode(n *yaml.Node) (string, error) {
+	if d.format == FormatJSON {
+		return encodeJSON(n, d.jsonIndent)
+	}
+	return encodeYAML(n, d.yamlStyle)
+}
+
+// render returns the text of the edited document. The line changes between
+// encoding the original tree and encoding the edited one are spliced into the
+// original text; if that cannot be done, or the result does not parse to the
+// edited tree, the encoded tree is returned and spliced is false.
+func (d *document) render() (text string, spliced bool, err error) {
+	after, err := d.encode(d.node)
+	if err != nil {
+		return "", false, err
+	}
+	if !strings.HasSuffix(d.body, "\n") && d.body != "" {
+		after = strings.TrimSuffix(after, "\n")
+	}
+
+	orig, err := d.parse(d.body)
+	if err != nil {
+		return "", false, err
+	}
+	before, err := d.encode(orig)
+	if err != nil {
+		return after, false, nil
+	}
+	if before == d.body || d.blank && strings.TrimSpace(d.body) == "" {
+		return after, true, nil
+	}
+
+	out, ok := splice(d.body, before, after)
+	if !ok {
+		return after, false, nil
+	}
+	if check, err := d.parse(out); err != nil || !sameTree(check, d.node) {
+		return after, false, nil
+	}
+	return out, true, nil
+}
+
+// splice applies the line changes from before to after to text, where before is
+// a re-encoding of text. The lines of before are matched to the lines of text
+// ignoring whitespace, and each changed run of lines is replaced at the place
+// its neighbours have in text. It fails if a changed line has no counterpart.
+func splice(text, before, after string) (string, bool) {
+	t, b, a := splitLines(text), splitLines(before), splitLines(after)
+
+	// pos[i] is the index in t of line i of b, or -1 if it has none.
+	pos := make([]int, len(b))
+	for i := range pos {
+		pos[i] = -1
+	}
+	align := difflib.NewMatcherWithJunk(normalizeLines(b), normalizeLines(t), false, nil)
+	for _, op := range align.GetOpCodes() {
+		if op.Tag == 'e' {
+			for k := 0; k < op.I2-op.I1; k++ {
+				pos[op.I1+k] = op.J1 + k
+			}
+		}
+	}
+
+	var out []string
+	last := 0 // Lines of t before last have been copied or replaced
+	for _, op := range difflib.NewMatcherWithJunk(b, a, false, nil).GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		var start, end int
+		switch {
+		case op.I1 < op.I2: // Lines of b are replaced or removed
+			for i := op.I1; i < op.I2; i++ {
+				if pos[i] < 0 {
+					return "", false
+				}
+			}
+			start, end = pos[op.I1], pos[op.I2-1]+1
+		case op.I1 > 0: // Lines are inserted after a line of b
+			if pos[op.I1-1] < 0 {
+				return "", false
+			}
+			start = pos[op.I1-1] + 1
+			end = start
+		case len(b) > 0:
+			if pos[0] < 0 {
+				return "", false
+			}
+			start, end = pos[0], pos[0]
+		default: // b is empty: add after whatever comments t has
+			start, end = len(t), len(t)
+		}
+		if start < last {
+			return "", false
+		}
+		out = append(out, t[last:start]...)
+		out = append(out, a[op.J1:op.J2]...)
+		last = end
+	}
+	out = append(out, t[last:]...)
+
+	result := strings.Join(out, "\n")
+	if len(out) > 0 && (strings.HasSuffix(text, "\n") || text == "") {
+		result += "\n"
+	}
+	return result, true
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// normalizeLines collapses the whitespace in each line, so lines that differ
+// only in indentation or spacing match.
+func normalizeLines(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = strings.Join(strings.Fields(l), " ")
+	}
+	return out
+}
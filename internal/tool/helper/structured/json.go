@@ -0,0 +1,186 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseJSON reads a JSON document into a node tree, keeping key order and the
+// literal text of numbers. Blank text is an empty document.
+func parseJSON(text string) (*yaml.Node, error) {
+	if strings.TrimSpace(text) == "" {
+		return &yaml.Node{}, nil
+	}
+	root, err := decodeJSON([]byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}, nil
+}
+
+// parseJSONValue reads the JSON value of an operation.
+func parseJSONValue(data []byte) (*yaml.Node, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errors.New("a value is required")
+	}
+	return decodeJSON(data)
+}
+
+func decodeJSON(data []byte) (*yaml.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+	return n, nil
+}
+
+func decodeJSONValue(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if v == '[' {
+			n.Kind, n.Tag = yaml.SequenceNode, "!!seq"
+		}
+		for dec.More() {
+			if n.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, stringNode(key.(string)))
+			}
+			child, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, child)
+		}
+		if _, err := dec.Token(); err != nil { // The closing delimiter
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return stringNode(v), nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// jsonIndent returns the indentation unit of a JSON document, or "" if it is
+// written on one line.
+func jsonIndent(text string) string {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n")[1:] {
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	if strings.Contains(strings.TrimSpace(text), "\n") {
+		return "  "
+	}
+	return ""
+}
+
+// encodeJSON writes a node tree as JSON indented by indent, or on one line if
+// indent is "". Values that came from YAML are written by their resolved tag.
+func encodeJSON(doc *yaml.Node, indent string) (string, error) {
+	if len(doc.Content) == 0 {
+		return "", nil
+	}
+	var sb strings.Builder
+	if err := writeJSON(&sb, doc.Content[0], indent, 0); err != nil {
+		return "", err
+	}
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+func writeJSON(sb *strings.Builder, n *yaml.Node, indent string, depth int) error {
+	newline := func(depth int) {
+		if indent != "" {
+			sb.WriteString("\n" + strings.Repeat(indent, depth))
+		}
+	}
+	switch n.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close := "{", "}"
+		if n.Kind == yaml.SequenceNode {
+			open, close = "[", "]"
+		}
+		sb.WriteString(open)
+		step := 1
+		if n.Kind == yaml.MappingNode {
+			step = 2
+		}
+		for i := 0; i < len(n.Content); i += step {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			newline(depth + 1)
+			if n.Kind == yaml.MappingNode {
+				sb.WriteString(jsonString(n.Content[i].Value))
+				sb.WriteString(":")
+				if indent != "" {
+					sb.WriteString(" ")
+				}
+			}
+			if err := writeJSON(sb, n.Content[i+step-1], indent, depth+1); err != nil {
+				return err
+			}
+		}
+		if len(n.Content) > 0 {
+			newline(depth)
+		}
+		sb.WriteString(close)
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			sb.WriteString(n.Value)
+		case "!!null":
+			sb.WriteString("null")
+		default:
+			sb.WriteString(jsonString(n.Value))
+		}
+	default:
+		return fmt.Errorf("cannot write %s as JSON", kindName(n))
+	}
+	return nil
+}
+
+// jsonString quotes s as JSON does, without escaping HTML characters.
+func jsonString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // Cannot fail for a string
+	return strings.TrimSuffix(buf.String(), "\n")
+}
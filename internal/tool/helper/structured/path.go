@@ -0,0 +1,190 @@
+package structured
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	stepKey   stepKind = iota // .name or ["name"]
+	stepIndex                 // [2] or [-1]
+	stepMatch                 // [name=nginx]: the list element whose name is nginx
+)
+
+// step is one element of a key path.
+type step struct {
+	kind  stepKind
+	key   string // Mapping key for stepKey, the element's key for stepMatch
+	index int    // For stepIndex; negative counts from the end
+	value string // For stepMatch
+}
+
+func (s step) String() string {
+	switch s.kind {
+	case stepIndex:
+		return fmt.Sprintf("[%d]", s.index)
+	case stepMatch:
+		return fmt.Sprintf("[%s=%s]", quoteIfNeeded(s.key), quoteIfNeeded(s.value))
+	}
+	if s.key == "" || strings.ContainsAny(s.key, `.[]"'=`) {
+		return "[" + strconv.Quote(s.key) + "]"
+	}
+	return "." + s.key
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, `[]"'= `) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// formatPath renders steps the way ParsePath reads them.
+func formatPath(steps []step) string {
+	if len(steps) == 0 {
+		return "$"
+	}
+	var sb strings.Builder
+	for _, s := range steps {
+		sb.WriteString(s.String())
+	}
+	return strings.TrimPrefix(sb.String(), ".")
+}
+
+// parsePath reads a JSONPath-like key path:
+//
+//	spec.template.spec.containers[0].image
+//	spec.containers[name=nginx].image
+//	metadata.annotations["kubernetes.io/ingress.class"]
+//
+// A leading "$" or "$." is allowed, and "" or "$" is the document root. Keys
+// with dots or brackets are quoted in brackets, with double or single quotes;
+// the key and value of a [key=value] match may be quoted the same way.
+func parsePath(path string) ([]step, error) {
+	p := &pathParser{s: strings.TrimSpace(path)}
+	if strings.HasPrefix(p.s, "$") {
+		p.pos = 1
+		if strings.HasPrefix(p.s[1:], ".") {
+			p.pos = 2
+		}
+	} else if strings.HasPrefix(p.s, ".") {
+		return nil, fmt.Errorf("path %q: must not start with '.'", path)
+	}
+
+	var steps []step
+	first := true
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; {
+		case c == '[':
+			s, err := p.bracket()
+			if err != nil {
+				return nil, fmt.Errorf("path %q: %v", path, err)
+			}
+			steps = append(steps, s)
+		case c == '.' && !first:
+			p.pos++
+			fallthrough
+		default:
+			key := p.bareKey()
+			if key == "" {
+				return nil, fmt.Errorf("path %q: empty key at offset %d", path, p.pos)
+			}
+			steps = append(steps, step{kind: stepKey, key: key})
+		}
+		first = false
+	}
+	return steps, nil
+}
+
+type pathParser struct {
+	s   string
+	pos int
+}
+
+// bareKey reads an unquoted key up to the next '.' or '['.
+func (p *pathParser) bareKey() string {
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != '.' && p.s[p.pos] != '[' {
+		p.pos++
+	}
+	return strings.TrimSpace(p.s[start:p.pos])
+}
+
+// bracket reads [index], ["key"] or [key=value], starting at the '['.
+func (p *pathParser) bracket() (step, error) {
+	open := p.pos
+	p.pos++
+	first, quoted, err := p.term("=]")
+	if err != nil {
+		return step{}, err
+	}
+	if p.pos >= len(p.s) {
+		return step{}, fmt.Errorf("unclosed '[' at offset %d", open)
+	}
+	if p.s[p.pos] == ']' {
+		p.pos++
+		if quoted {
+			return step{kind: stepKey, key: first}, nil
+		}
+		n, err := strconv.Atoi(first)
+		if err != nil {
+			return step{}, fmt.Errorf("[%s] is not an index; quote keys as [\"%s\"] or match list elements with [key=value]", first, first)
+		}
+		return step{kind: stepIndex, index: n}, nil
+	}
+
+	p.pos++ // '='
+	value, _, err := p.term("]")
+	if err != nil {
+		return step{}, err
+	}
+	if p.pos >= len(p.s) {
+		return step{}, fmt.Errorf("unclosed '[' at offset %d", open)
+	}
+	p.pos++
+	if first == "" {
+		return step{}, fmt.Errorf("empty key in [=%s]", value)
+	}
+	return step{kind: stepMatch, key: first, value: value}, nil
+}
+
+// term reads a quoted string or bare text up to one of the stop characters.
+func (p *pathParser) term(stop string) (string, bool, error) {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos < len(p.s) && (p.s[p.pos] == '"' || p.s[p.pos] == '\'') {
+		q := p.s[p.pos]
+		end := p.pos + 1
+		for end < len(p.s) && p.s[end] != q {
+			if p.s[end] == '\\' && q == '"' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.s) {
+			return "", false, fmt.Errorf("unterminated quote at offset %d", p.pos)
+		}
+		raw := p.s[p.pos : end+1]
+		p.pos = end + 1
+		for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+			p.pos++
+		}
+		if q == '\'' {
+			return raw[1 : len(raw)-1], true, nil
+		}
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", false, fmt.Errorf("bad quoted string %s", raw)
+		}
+		return s, true, nil
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(stop, rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return strings.TrimSpace(p.s[start:p.pos]), false, nil
+}
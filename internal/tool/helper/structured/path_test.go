@@ -0,0 +1,58 @@
+package structured
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []step
+	}{
+		{"", nil},
+		{"$", nil},
+		{"spec.replicas", []step{{kind: stepKey, key: "spec"}, {kind: stepKey, key: "replicas"}}},
+		{"$.items[0]", []step{{kind: stepKey, key: "items"}, {kind: stepIndex, index: 0}}},
+		{"$[-1]", []step{{kind: stepIndex, index: -1}}},
+		{"containers[name=nginx].image", []step{
+			{kind: stepKey, key: "containers"},
+			{kind: stepMatch, key: "name", value: "nginx"},
+			{kind: stepKey, key: "image"},
+		}},
+		{`metadata.annotations["kubernetes.io/ingress.class"]`, []step{
+			{kind: stepKey, key: "metadata"},
+			{kind: stepKey, key: "annotations"},
+			{kind: stepKey, key: "kubernetes.io/ingress.class"},
+		}},
+		{`env['my key'][value="a b"]`, []step{
+			{kind: stepKey, key: "env"},
+			{kind: stepKey, key: "my key"},
+			{kind: stepMatch, key: "value", value: "a b"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := parsePath(tt.path)
+		if err != nil {
+			t.Errorf("parsePath(%q) failed: %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePath(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+		if len(got) > 0 {
+			again, err := parsePath(formatPath(got))
+			if err != nil || !reflect.DeepEqual(again, got) {
+				t.Errorf("formatPath(%q) = %q does not parse back", tt.path, formatPath(got))
+			}
+		}
+	}
+}
+
+func TestParsePath_Errors(t *testing.T) {
+	for _, path := range []string{".a", "a..b", "a.", "a[", "a[x]", `a["b]`, "a[=b]"} {
+		if _, err := parsePath(path); err == nil {
+			t.Errorf("parsePath(%q) should fail", path)
+		}
+	}
+}
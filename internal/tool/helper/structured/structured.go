@@ -0,0 +1,197 @@
+// Package structured edits JSON and YAML documents by key path. Documents are
+// parsed into yaml.v3 node trees, which keep key order and, for YAML, comments.
+// Edited documents are spliced back into the original text line by line, so
+// formatting outside the edited keys is kept whenever the result can be shown
+// to parse to the edited tree.
+package structured
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a structured file format.
+type Format int
+
+const (
+	FormatJSON Format = iota
+	FormatYAML
+)
+
+func (f Format) String() string {
+	if f == FormatYAML {
+		return "YAML"
+	}
+	return "JSON"
+}
+
+// FormatFor returns the format of a file by its extension.
+func FormatFor(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, true
+	case ".yaml", ".yml":
+		return FormatYAML, true
+	}
+	return 0, false
+}
+
+// Operations
+const (
+	OpSet    = "set"    // Replace the value at the path, creating missing mapping keys on the way
+	OpDelete = "delete" // Remove the key or list element at the path
+	OpAppend = "append" // Add the value to the end of the list at the path, creating the list if missing
+)
+
+// Operation is one change to a document.
+type Operation struct {
+	Op       string
+	Path     string
+	Value    []byte // JSON; for set and append
+	Document int    // 0-based document in a multi-document YAML file; -1 if not given
+}
+
+// Edit applies the operations in order to text, a JSON or YAML file with \n line
+// endings, and returns the new text and notes on anything the caller should
+// know, such as keys that were created or a document that had to be re-serialised.
+func Edit(text string, format Format, ops []Operation) (string, []string, error) {
+	docs, err := parseDocuments(text, format)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var notes []string
+	for i, op := range ops {
+		note, err := applyOperation(docs, op)
+		if err != nil {
+			return "", nil, fmt.Errorf("operation %d (%s %s): %v", i+1, op.Op, op.Path, err)
+		}
+		if note != "" {
+			notes = append(notes, fmt.Sprintf("operation %d %s", i+1, note))
+		}
+	}
+
+	var sb strings.Builder
+	index := 0 // Of the document as the user counts them, skipping blank ones
+	for _, d := range docs {
+		sb.WriteString(d.header)
+		if !d.changed {
+			sb.WriteString(d.body)
+		} else {
+			body, spliced, err := d.render()
+			if err != nil {
+				return "", nil, err
+			}
+			if !spliced {
+				notes = append(notes, reformatNote(format, len(docs), index))
+			}
+			sb.WriteString(body)
+		}
+		if !d.blank {
+			index++
+		}
+	}
+	return sb.String(), notes, nil
+}
+
+func reformatNote(format Format, docs, i int) string {
+	what := "the file"
+	if docs > 1 {
+		what = fmt.Sprintf("document %d", i)
+	}
+	return fmt.Sprintf("the change could not be spliced into the original text, so %s was re-serialised as %s; "+
+		"indentation, blank lines, quoting or comment spacing may have changed outside the edited keys", what, format)
+}
+
+func applyOperation(docs []*document, op Operation) (string, error) {
+	steps, err := parsePath(op.Path)
+	if err != nil {
+		return "", err
+	}
+	d, err := selectDocument(docs, op.Document)
+	if err != nil {
+		return "", err
+	}
+
+	var value *yaml.Node
+	if op.Op != OpDelete {
+		if value, err = parseJSONValue(op.Value); err != nil {
+			return "", fmt.Errorf("value: %v", err)
+		}
+	}
+
+	var note string
+	switch op.Op {
+	case OpSet:
+		note, err = d.set(steps, value)
+	case OpDelete:
+		err = d.delete(steps)
+	case OpAppend:
+		note, err = d.append(steps, value)
+	default:
+		err = fmt.Errorf("unknown operation %q", op.Op)
+	}
+	if err != nil {
+		return "", err
+	}
+	d.changed = true
+	return note, nil
+}
+
+// selectDocument returns the document an operation applies to. An index is
+// needed only when the file has more than one document.
+func selectDocument(docs []*document, index int) (*document, error) {
+	var real []*document // Documents with content, as the user counts them
+	for _, d := range docs {
+		if !d.blank {
+			real = append(real, d)
+		}
+	}
+	if len(real) == 0 {
+		real = docs[len(docs)-1:] // An empty file is edited as one empty document
+	}
+
+	if index < 0 {
+		if len(real) > 1 {
+			return nil, fmt.Errorf("the file has %d documents; choose one with document:\n%s", len(real), describeDocuments(real))
+		}
+		return real[0], nil
+	}
+	if index >= len(real) {
+		return nil, fmt.Errorf("document %d does not exist; the file has %d", index, len(real))
+	}
+	return real[index], nil
+}
+
+// describeDocuments lists documents with their kind and name, as Kubernetes
+// manifests have them, to help pick the right one.
+func describeDocuments(docs []*document) string {
+	var sb strings.Builder
+	for i, d := range docs {
+		fmt.Fprintf(&sb, "  %d:", i)
+		root := d.root()
+		if kind := scalarAt(root, "kind"); kind != "" {
+			sb.WriteString(" " + kind)
+		}
+		if name := scalarAt(mappingValue(root, "metadata"), "name"); name != "" {
+			sb.WriteString(" " + name)
+		}
+		if root != nil && root.Kind != yaml.MappingNode {
+			sb.WriteString(" " + kindName(root))
+		}
+		if i < len(docs)-1 {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func scalarAt(n *yaml.Node, key string) string {
+	if v := mappingValue(n, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
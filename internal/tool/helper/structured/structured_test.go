@@ -0,0 +1,180 @@
+package structured
+
+import (
+	"strings"
+	"testing"
+)
+
+const manifests = `---
+# The web deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web   # the name
+  labels: {app: web}
+spec:
+  replicas: 3
+
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: "nginx:1.25"
+      # sidecar
+      - name: log
+        image: fluentd
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+
+func op(kind, path, value string, document int) Operation {
+	var v []byte
+	if value != "" {
+		v = []byte(value)
+	}
+	return Operation{Op: kind, Path: path, Value: v, Document: document}
+}
+
+func mustEdit(t *testing.T, text string, format Format, ops ...Operation) (string, []string) {
+	t.Helper()
+	out, notes, err := Edit(text, format, ops)
+	if err != nil {
+		t.Fatalf("Edit failed: %v", err)
+	}
+	return out, notes
+}
+
+func TestEdit_YAML(t *testing.T) {
+	t.Run("set keeps everything else as written", func(t *testing.T) {
+		out, notes := mustEdit(t, manifests, FormatYAML,
+			op(OpSet, "spec.template.spec.containers[name=nginx].image", `"nginx:1.26"`, 0),
+			op(OpSet, "spec.replicas", `5`, 0))
+		want := strings.Replace(strings.Replace(manifests, "nginx:1.25", "nginx:1.26", 1), "replicas: 3", "replicas: 5", 1)
+		if out != want {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		if len(notes) != 0 {
+			t.Errorf("unexpected notes %v", notes)
+		}
+	})
+
+	t.Run("append and delete in a compact list", func(t *testing.T) {
+		out, _ := mustEdit(t, manifests, FormatYAML,
+			op(OpDelete, "spec.template.spec.containers[-1]", "", 0),
+			op(OpAppend, "spec.template.spec.containers", `{"name": "proxy", "ports": [{"containerPort": 8080}]}`, 0))
+		want := strings.Replace(manifests,
+			"      # sidecar\n      - name: log\n        image: fluentd\n",
+			"      - name: proxy\n        ports:\n        - containerPort: 8080\n", 1)
+		if out != want {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+	})
+
+	t.Run("missing keys are created and reported", func(t *testing.T) {
+		out, notes := mustEdit(t, manifests, FormatYAML,
+			op(OpSet, `metadata.annotations["example.com/owner"]`, `"team-a"`, 1))
+		if !strings.HasSuffix(out, "  name: web\n  annotations:\n    example.com/owner: team-a\n") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		if len(notes) != 1 || notes[0] != "operation 1 created metadata.annotations" {
+			t.Errorf("unexpected notes %v", notes)
+		}
+	})
+
+	t.Run("strings that look like other types are quoted", func(t *testing.T) {
+		out, _ := mustEdit(t, "port: 80\n", FormatYAML, op(OpSet, "port", `"8080"`, -1))
+		if out != "port: \"8080\"\n" {
+			t.Errorf("unexpected output %q", out)
+		}
+	})
+
+	t.Run("strings YAML 1.1 reads as other types are quoted", func(t *testing.T) {
+		text := "on: push\ndebug: yes\nenv:\n  TLS: enabled\n"
+		for _, value := range []string{"no", "Off", "y", "N", "010", "12:30"} {
+			out, _ := mustEdit(t, text, FormatYAML, op(OpSet, "env.TLS", `"`+value+`"`, -1),
+				op(OpSet, "env.MODE", `"`+value+`"`, -1))
+			// Keys and values that were already there are left as written.
+			want := "on: push\ndebug: yes\nenv:\n  TLS: \"" + value + "\"\n  MODE: \"" + value + "\"\n"
+			if out != want {
+				t.Errorf("%s: unexpected output %q", value, out)
+			}
+		}
+	})
+
+	t.Run("empty file", func(t *testing.T) {
+		out, _ := mustEdit(t, "", FormatYAML, op(OpSet, "a.b", `true`, -1))
+		if out != "a:\n  b: true\n" {
+			t.Errorf("unexpected output %q", out)
+		}
+	})
+
+	t.Run("falls back to re-serialising", func(t *testing.T) {
+		// The flow mapping is rewritten on one line, which has no counterpart to splice into.
+		text := "a: {x: 1,\n  y: 2}\nb: 1\n"
+		out, notes := mustEdit(t, text, FormatYAML, op(OpSet, "a.z", `3`, -1))
+		if out != "a: {x: 1, y: 2, z: 3}\nb: 1\n" {
+			t.Errorf("unexpected output %q", out)
+		}
+		if len(notes) != 2 || !strings.Contains(notes[1], "the file was re-serialised as YAML") {
+			t.Errorf("unexpected notes %v", notes)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			op   Operation
+			want string
+		}{
+			{op(OpSet, "kind", `"X"`, -1), "the file has 2 documents; choose one with document:\n  0: Deployment web\n  1: Service web"},
+			{op(OpSet, "kind", `"X"`, 2), "document 2 does not exist"},
+			{op(OpSet, "kind", `X`, 0), "value: invalid character"},
+			{op(OpDelete, "spec.paused", "", 0), "spec.paused does not exist"},
+			{op(OpSet, "spec.template.spec.containers[name=db].image", `"x"`, 0), "no element matches spec.template.spec.containers[name=db]"},
+			{op(OpSet, "spec.template.spec.containers[5]", `{}`, 0), "out of range; the list has 2 elements"},
+			{op(OpAppend, "spec.replicas", `1`, 0), "spec.replicas is a number, not a list"},
+			{op(OpSet, "spec.template.spec.containers.name", `"x"`, 0), "is a list; select elements with [index] or [key=value]"},
+		}
+		for _, tt := range tests {
+			_, _, err := Edit(manifests, FormatYAML, []Operation{tt.op})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%s %s: expected error containing %q, got %v", tt.op.Op, tt.op.Path, tt.want, err)
+			}
+		}
+	})
+}
+
+func TestEdit_JSON(t *testing.T) {
+	const pkg = "{\n\t\"name\": \"app\",\n\t\"scripts\": {\n\t\t\"test\": \"jest <all>\"\n\t},\n\t\"version\": 1.0\n}\n"
+
+	t.Run("set keeps indentation and number text", func(t *testing.T) {
+		out, _ := mustEdit(t, pkg, FormatJSON, op(OpSet, "scripts.build", `"tsc"`, -1))
+		want := "{\n\t\"name\": \"app\",\n\t\"scripts\": {\n\t\t\"test\": \"jest <all>\",\n\t\t\"build\": \"tsc\"\n\t},\n\t\"version\": 1.0\n}\n"
+		if out != want {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		out, _ := mustEdit(t, pkg, FormatJSON, op(OpDelete, "scripts", "", -1))
+		if out != "{\n\t\"name\": \"app\",\n\t\"version\": 1.0\n}\n" {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+	})
+
+	t.Run("one-line document", func(t *testing.T) {
+		out, _ := mustEdit(t, `{"a": [1, 2]}`, FormatJSON, op(OpAppend, "a", `{"b": null}`, -1))
+		if out != `{"a":[1,2,{"b":null}]}` {
+			t.Errorf("unexpected output %q", out)
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		if _, _, err := Edit("{\"a\": 1,}", FormatJSON, []Operation{op(OpDelete, "a", "", -1)}); err == nil ||
+			!strings.Contains(err.Error(), "invalid JSON") {
+			t.Errorf("expected invalid JSON error, got %v", err)
+		}
+	})
+}
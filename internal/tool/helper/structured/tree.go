@@ -0,0 +1,287 @@
+package structured
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// set replaces the value at the path, or adds it if the last key is missing.
+// Missing mapping keys before it are created as mappings.
+func (d *document) set(steps []step, value *yaml.Node) (string, error) {
+	if len(steps) == 0 {
+		if old := d.root(); old != nil {
+			keepComments(old, value)
+		}
+		d.setRoot(value)
+		return "", nil
+	}
+	parent, created, err := d.walk(steps[:len(steps)-1], true)
+	if err != nil {
+		return "", err
+	}
+	last := steps[len(steps)-1]
+	i, err := find(parent, last, steps)
+	if err != nil {
+		return "", err
+	}
+	if i < 0 {
+		parent.Content = append(parent.Content, stringNode(last.key), value)
+		created = append(created, formatPath(steps))
+	} else {
+		keepComments(parent.Content[i], value)
+		parent.Content[i] = value
+	}
+	return createdNote(created), nil
+}
+
+// delete removes the key or list element at the path.
+func (d *document) delete(steps []step) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("cannot delete the document root")
+	}
+	parent, _, err := d.walk(steps[:len(steps)-1], false)
+	if err != nil {
+		return err
+	}
+	last := steps[len(steps)-1]
+	i, err := find(parent, last, steps)
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		return fmt.Errorf("%s does not exist", formatPath(steps))
+	}
+	if parent.Kind == yaml.MappingNode {
+		parent.Content = append(parent.Content[:i-1], parent.Content[i+1:]...)
+	} else {
+		parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+	}
+	return nil
+}
+
+// append adds the value to the end of the list at the path. A missing list is
+// created, as are missing mapping keys before it.
+func (d *document) append(steps []step, value *yaml.Node) (string, error) {
+	if len(steps) == 0 {
+		root := d.root()
+		if root == nil {
+			d.setRoot(&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{value}})
+			return "created $", nil
+		}
+		if root.Kind != yaml.SequenceNode {
+			return "", fmt.Errorf("$ is %s, not a list", kindName(root))
+		}
+		root.Content = append(root.Content, value)
+		return "", nil
+	}
+
+	parent, created, err := d.walk(steps[:len(steps)-1], true)
+	if err != nil {
+		return "", err
+	}
+	last := steps[len(steps)-1]
+	i, err := find(parent, last, steps)
+	if err != nil {
+		return "", err
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	switch {
+	case i < 0:
+		parent.Content = append(parent.Content, stringNode(last.key), list)
+		created = append(created, formatPath(steps))
+	case parent.Content[i].Kind == yaml.SequenceNode:
+		list = parent.Content[i]
+	case isNull(parent.Content[i]):
+		keepComments(parent.Content[i], list)
+		parent.Content[i] = list
+	default:
+		return "", fmt.Errorf("%s is %s, not a list", formatPath(steps), kindName(parent.Content[i]))
+	}
+	list.Content = append(list.Content, value)
+	return createdNote(created), nil
+}
+
+// createdNote tells which key did not exist, so a misspelt path does not go unnoticed.
+func createdNote(created []string) string {
+	if len(created) == 0 {
+		return ""
+	}
+	return "created " + created[0]
+}
+
+// walk follows steps from the document root and returns the node they lead to,
+// which is a mapping or a list. With create, missing keys and null values on
+// the way become mappings, and the paths created are returned.
+func (d *document) walk(steps []step, create bool) (*yaml.Node, []string, error) {
+	var created []string
+	n := d.root()
+	if n == nil || (create && isNull(n) && len(steps) > 0) {
+		if !create || (len(steps) > 0 && steps[0].kind != stepKey) {
+			return nil, nil, fmt.Errorf("the document is empty")
+		}
+		n = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		d.setRoot(n)
+	}
+
+	for k, s := range steps {
+		path := steps[:k+1]
+		i, err := find(n, s, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i < 0 {
+			if !create || (k+1 < len(steps) && steps[k+1].kind != stepKey) {
+				return nil, nil, fmt.Errorf("%s does not exist", formatPath(path))
+			}
+			child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			n.Content = append(n.Content, stringNode(s.key), child)
+			created = append(created, formatPath(path))
+			n = child
+			continue
+		}
+
+		child := n.Content[i]
+		if child.Kind == yaml.AliasNode {
+			return nil, nil, fmt.Errorf("%s is an alias of &%s; edit the anchored value instead", formatPath(path), child.Value)
+		}
+		if create && isNull(child) && (k+1 == len(steps) || steps[k+1].kind == stepKey) {
+			replacement := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			keepComments(child, replacement)
+			n.Content[i], child = replacement, replacement
+		}
+		n = child
+	}
+
+	if n.Kind != yaml.MappingNode && n.Kind != yaml.SequenceNode {
+		return nil, nil, fmt.Errorf("%s is %s, which has no keys or elements", formatPath(steps), kindName(n))
+	}
+	return n, created, nil
+}
+
+// find returns the index in n.Content of the value s selects, or -1 if s is a
+// key that n does not have. path is the path up to and including s, for errors.
+func find(n *yaml.Node, s step, path []step) (int, error) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		if s.kind != stepKey {
+			return -1, fmt.Errorf("%s is a mapping; select keys with .key or [\"key\"], not %s", formatPath(path[:len(path)-1]), s)
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == s.key {
+				return i + 1, nil
+			}
+		}
+		return -1, nil
+
+	case yaml.SequenceNode:
+		switch s.kind {
+		case stepIndex:
+			i := s.index
+			if i < 0 {
+				i += len(n.Content)
+			}
+			if i < 0 || i >= len(n.Content) {
+				return -1, fmt.Errorf("%s is out of range; the list has %d elements", formatPath(path), len(n.Content))
+			}
+			return i, nil
+		case stepMatch:
+			found := -1
+			for i, el := range n.Content {
+				if v := mappingValue(el, s.key); v != nil && v.Kind == yaml.ScalarNode && v.Value == s.value {
+					if found >= 0 {
+						return -1, fmt.Errorf("more than one element matches %s; use an index", formatPath(path))
+					}
+					found = i
+				}
+			}
+			if found < 0 {
+				return -1, fmt.Errorf("no element matches %s", formatPath(path))
+			}
+			return found, nil
+		}
+		return -1, fmt.Errorf("%s is a list; select elements with [index] or [key=value], not %s", formatPath(path[:len(path)-1]), s)
+	}
+	return -1, fmt.Errorf("%s is %s, which has no keys or elements", formatPath(path[:len(path)-1]), kindName(n))
+}
+
+// mappingValue returns the value of key in the mapping n, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+// keepComments carries the comments of a replaced node over to its replacement,
+// and the quoting style when a string replaces a string.
+func keepComments(old, n *yaml.Node) {
+	if n.HeadComment == "" {
+		n.HeadComment = old.HeadComment
+	}
+	if n.LineComment == "" {
+		n.LineComment = old.LineComment
+	}
+	if n.FootComment == "" {
+		n.FootComment = old.FootComment
+	}
+	if old.Kind == yaml.ScalarNode && n.Kind == yaml.ScalarNode && old.ShortTag() == "!!str" && n.ShortTag() == "!!str" &&
+		!strings.Contains(n.Value, "\n") {
+		n.Style = old.Style &^ (yaml.LiteralStyle | yaml.FoldedStyle)
+	}
+}
+
+func kindName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.AliasNode:
+		return "an alias"
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			return "null"
+		case "!!str":
+			return "a string"
+		case "!!bool":
+			return "a boolean"
+		}
+		return "a number"
+	}
+	return "empty"
+}
+
+// sameTree reports whether two node trees hold the same data, ignoring
+// comments, quoting and layout.
+func sameTree(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+	switch a.Kind {
+	case yaml.ScalarNode:
+		return a.ShortTag() == b.ShortTag() && a.Value == b.Value
+	case yaml.AliasNode:
+		return a.Value == b.Value
+	}
+	for i := range a.Content {
+		if !sameTree(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,166 @@
+package structured
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlStyle is the layout of a YAML document that the encoder can be made to follow.
+type yamlStyle struct {
+	indent  int  // Spaces per nesting level
+	compact bool // Lists under a key start at the key's column ("key:\n- a")
+}
+
+// blockScalar matches a line that starts a literal or folded block scalar.
+var blockScalar = regexp.MustCompile(`(?:^|:\s)[|>][-+0-9]*(?:\s+#.*)?$`)
+
+// yaml11Scalar matches plain scalars that YAML 1.1 parsers read as booleans or
+// numbers but the encoder, which follows YAML 1.2, writes unquoted: yes/no/on/off
+// and y/n in any case, octals with a bare leading 0 and base-60 numbers like 12:30.
+var yaml11Scalar = regexp.MustCompile(`^(?i:y|yes|n|no|on|off)$|^[-+]?0[0-7_]+$|^[-+]?[1-9][0-9_]*(?::[0-5]?[0-9])+(?:\.[0-9_]*)?$`)
+
+// detectYAMLStyle looks at how a document nests mappings and lists.
+func detectYAMLStyle(text string) yamlStyle {
+	style := yamlStyle{indent: 2}
+	lines := significantLines(text)
+	found := false
+	for i := 0; i+1 < len(lines); i++ {
+		keyCol, rest := itemContent(lines[i])
+		if !strings.HasSuffix(rest, ":") {
+			continue
+		}
+		next := indentation(lines[i+1])
+		isList := strings.HasPrefix(strings.TrimLeft(lines[i+1], " "), "-")
+		switch {
+		case isList && next == keyCol:
+			style.compact = true
+		case !isList && next > keyCol && !found:
+			style.indent, found = next-keyCol, true
+		}
+	}
+	return style
+}
+
+// significantLines returns the lines that hold content, without blank lines and comments.
+func significantLines(text string) []string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// itemContent strips the indentation and any "- " list markers from a line and
+// returns the column where what is left starts.
+func itemContent(line string) (int, string) {
+	col := indentation(line)
+	rest := strings.TrimRight(line[col:], " ")
+	for strings.HasPrefix(rest, "- ") {
+		rest = strings.TrimLeft(rest[2:], " ")
+		col = len(line) - len(strings.TrimLeft(line[col:], "- "))
+	}
+	return col, rest
+}
+
+// encodeYAML writes a document node in the given style.
+func encodeYAML(doc *yaml.Node, style yamlStyle) (string, error) {
+	if len(doc.Content) == 0 {
+		return "", nil
+	}
+	quoteYAML11Strings(doc, false)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(style.indent)
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	out := buf.String()
+	if style.compact {
+		out = compactSequences(out, style.indent)
+	}
+	return out, nil
+}
+
+// quoteYAML11Strings double-quotes plain string values that a YAML 1.1 parser,
+// as many tools still use, would read as something else, so a string set to
+// "no" or "010" stays a string. Mapping keys are left as they are, so that keys
+// like GitHub Actions' on are not rewritten.
+func quoteYAML11Strings(n *yaml.Node, isKey bool) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if !isKey && n.Style == 0 && n.ShortTag() == "!!str" && yaml11Scalar.MatchString(n.Value) {
+			n.Style = yaml.DoubleQuotedStyle
+		}
+	case yaml.MappingNode:
+		for i, c := range n.Content {
+			quoteYAML11Strings(c, i%2 == 0)
+		}
+	default:
+		for _, c := range n.Content {
+			quoteYAML11Strings(c, false)
+		}
+	}
+}
+
+// compactSequences moves lists that the encoder indented under their key back
+// to the key's column, the layout Kubernetes manifests commonly use.
+func compactSequences(text string, indent int) string {
+	lines := strings.Split(text, "\n")
+	type level struct{ keyCol, shift int }
+	var stack []level
+	shift := 0
+	scalarCol := -1 // Indentation of the line that opened a block scalar, if in one
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		ind := indentation(line)
+		if scalarCol >= 0 {
+			if ind > scalarCol {
+				lines[i] = line[shift:]
+				continue
+			}
+			scalarCol = -1
+		}
+		for len(stack) > 0 && ind <= stack[len(stack)-1].keyCol {
+			shift -= stack[len(stack)-1].shift
+			stack = stack[:len(stack)-1]
+		}
+		lines[i] = line[shift:]
+
+		keyCol, rest := itemContent(line)
+		if blockScalar.MatchString(rest) {
+			scalarCol = ind
+			continue
+		}
+		if !strings.HasSuffix(rest, ":") {
+			continue
+		}
+		for j := i + 1; j < len(lines); j++ {
+			if strings.TrimSpace(lines[j]) == "" {
+				continue
+			}
+			next := strings.TrimLeft(lines[j], " ")
+			if indentation(lines[j]) == keyCol+indent && (next == "-" || strings.HasPrefix(next, "- ")) {
+				stack = append(stack, level{keyCol: keyCol, shift: indent})
+				shift += indent
+			}
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -3,6 +3,7 @@ package search
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,7 +11,10 @@ import (
 	"strings"
 
 	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
 	"github.com/Cyclone1070/iav/internal/tool/helper/pagination"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
 )
 
 // SearchContentTool handles content searching operations.
@@ -48,6 +52,59 @@ func NewSearchContentTool(
 	}
 }
 
+// Name returns the tool's identifier.
+func (t *SearchContentTool) Name() string {
+	return "search_content"
+}
+
+// ReadOnly reports that searching never modifies the workspace.
+func (t *SearchContentTool) ReadOnly() bool {
+	return true
+}
+
+// Declaration returns the tool's schema for the LLM.
+func (t *SearchContentTool) Declaration() tool.Declaration {
+	return tool.Declaration{
+		Name:        "search_content",
+		Description: "Search file contents with a regular expression (ripgrep syntax). Matches are grouped by file with line numbers.",
+		Parameters: &tool.Schema{
+			Type:                 tool.TypeObject,
+			AdditionalProperties: tool.Ptr(false),
+			Properties: map[string]*tool.Schema{
+				"query":           {Type: tool.TypeString, Description: "Regular expression to search for"},
+				"search_path":     {Type: tool.TypeString, Description: "Directory to search (default: workspace root)"},
+				"case_sensitive":  {Type: tool.TypeBoolean, Description: "Match case exactly (default: false)"},
+				"include_ignored": {Type: tool.TypeBoolean, Description: "Also search gitignored files"},
+				"offset":          {Type: tool.TypeInteger, Description: "Number of matches to skip", Minimum: tool.Ptr(0.0)},
+				"limit":           {Type: tool.TypeInteger, Description: "Max matches to return"},
+			},
+			Required: []string{"query"},
+		},
+	}
+}
+
+// Request returns a new request struct for JSON unmarshalling.
+func (t *SearchContentTool) Request() toolmanager.ToolRequest {
+	return &SearchContentRequest{}
+}
+
+// Execute runs the search and encodes failures in the response for the LLM.
+func (t *SearchContentTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*SearchContentRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+
+	resp, err := t.Run(ctx, r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &SearchContentResponse{Error: err.Error()}, nil
+	}
+	return resp, nil
+}
+
 // Run searches for content matching a regex pattern using ripgrep.
 // It validates the search path is within workspace boundaries, respects gitignore rules
 // (unless includeIgnored is true), and returns matches with pagination support.
@@ -139,6 +196,9 @@ func (t *SearchContentTool) Run(ctx context.Context, req *SearchContentRequest)
 
 		if rgMatch.Type == "match" {
 			relPath, err := t.pathResolver.Rel(rgMatch.Data.Path.Text)
+			if errors.Is(err, path.ErrProtected) {
+				continue
+			}
 			if err != nil {
 				relPath = rgMatch.Data.Path.Text
 			}
@@ -551,3 +551,43 @@ func TestSearchContent_HitMaxResults(t *testing.T) {
 		t.Errorf("expected 2 matches (capped), got %d", resp.TotalCount)
 	}
 }
+
+func TestSearchContent_Execute_EncodesFailureForLLM(t *testing.T) {
+	fs := newMockFileSystemForSearch()
+	fs.createDir("/workspace")
+	cfg := config.DefaultConfig()
+
+	searchTool := NewSearchContentTool(fs, &mockCommandExecutorForSearch{}, cfg, path.NewResolver("/workspace"))
+
+	res, err := searchTool.Execute(context.Background(), &SearchContentRequest{Query: "x", SearchPath: "../outside"})
+	if err != nil {
+		t.Fatalf("Execute returned infra error: %v", err)
+	}
+	if res.Success() {
+		t.Error("expected failure result")
+	}
+	if !strings.Contains(res.LLMContent(), "Error: path is outside workspace root") {
+		t.Errorf("unexpected LLM content: %q", res.LLMContent())
+	}
+}
+
+func TestSearchContent_Execute_PaginationHint(t *testing.T) {
+	fs := newMockFileSystemForSearch()
+	fs.createDir("/workspace")
+	cfg := config.DefaultConfig()
+
+	rgOutput := `{"type":"match","data":{"path":{"text":"/workspace/a.go"},"lines":{"text":"x"},"line_number":1}}
+{"type":"match","data":{"path":{"text":"/workspace/a.go"},"lines":{"text":"x"},"line_number":2}}`
+	mockRunner := &mockCommandExecutorForSearch{runFunc: func(ctx context.Context, cmd []string, dir string, env []string) (*executor.Result, error) {
+		return &executor.Result{Stdout: rgOutput}, nil
+	}}
+	searchTool := NewSearchContentTool(fs, mockRunner, cfg, path.NewResolver("/workspace"))
+
+	res, err := searchTool.Execute(context.Background(), &SearchContentRequest{Query: "x", Limit: 1})
+	if err != nil {
+		t.Fatalf("Execute returned infra error: %v", err)
+	}
+	if !strings.Contains(res.LLMContent(), "Use offset=1 to see more") {
+		t.Errorf("expected pagination hint, got %q", res.LLMContent())
+	}
+}
@@ -2,8 +2,10 @@ package search
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
 )
 
 // -- Contract Types --
@@ -25,6 +27,10 @@ type SearchContentRequest struct {
 	Limit          int    `json:"limit,omitempty"`
 }
 
+func (r *SearchContentRequest) Display() string {
+	return r.Query
+}
+
 func (r *SearchContentRequest) Validate(cfg *config.Config) error {
 	if r.Query == "" {
 		return fmt.Errorf("query is required")
@@ -48,8 +54,36 @@ type SearchContentResponse struct {
 	Limit            int    `json:"limit"`
 	TotalCount       int    `json:"total_count"` // Total matches found (may be capped for performance)
 	HitMaxResults    bool   `json:"hit_max_results"`
+	Error            string `json:"error,omitempty"` // Set if the tool failed
+}
+
+// LLMContent returns the grep-style matches with pagination hints
+func (r *SearchContentResponse) LLMContent() string {
+	if r.Error != "" {
+		return fmt.Sprintf("Error: %s", r.Error)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(r.FormattedMatches)
+
+	shown := r.Offset + r.Limit
+	if shown < r.TotalCount {
+		sb.WriteString(fmt.Sprintf("\n(Showing matches %d-%d of %d. Use offset=%d to see more)", r.Offset+1, shown, r.TotalCount, shown))
+	}
+	if r.HitMaxResults {
+		sb.WriteString("\n(Result cap reached, narrow the query or search_path for complete results)")
+	}
+	return sb.String()
+}
+
+// Display returns the UI representation
+func (r *SearchContentResponse) Display() tool.ToolDisplay {
+	if r.Error != "" {
+		return tool.StringDisplay("Bad request")
+	}
+	return tool.StringDisplay(fmt.Sprintf("%d matches", r.TotalCount))
 }
 
 func (r SearchContentResponse) Success() bool {
-	return true
+	return r.Error == ""
 }
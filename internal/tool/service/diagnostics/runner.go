@@ -0,0 +1,229 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool/service/executor"
+)
+
+// defaultTimeout bounds a formatter or linter without a configured timeout.
+const defaultTimeout = 30 * time.Second
+
+// maxDiagnostics caps the diagnostics reported per command, so a file full of
+// errors does not flood the LLM's context.
+const maxDiagnostics = 20
+
+// filePlaceholder is replaced by the file's workspace-relative path in commands.
+const filePlaceholder = "{{file}}"
+
+// locationPattern matches file:line[:column]: message, the form compilers and
+// most linters print.
+var locationPattern = regexp.MustCompile(`^(.+?):(\d+)(?::(\d+))?:\s*(.*)$`)
+
+// commandExecutor defines the command execution needed to run formatters and linters.
+type commandExecutor interface {
+	RunWithTimeout(ctx context.Context, cmd []string, dir string, env []string, timeout time.Duration) (*executor.Result, error)
+}
+
+// Diagnostic is one problem a formatter or linter reported.
+type Diagnostic struct {
+	File    string // As the command printed it; empty if the line had no location
+	Line    int    // 1-based; 0 if unknown
+	Column  int    // 1-based; 0 if unknown
+	Message string
+	Source  string // The command, e.g. "gofmt"
+}
+
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	if d.File != "" {
+		sb.WriteString(d.File + ":")
+		if d.Line > 0 {
+			fmt.Fprintf(&sb, "%d:", d.Line)
+		}
+		if d.Column > 0 {
+			fmt.Fprintf(&sb, "%d:", d.Column)
+		}
+		sb.WriteString(" ")
+	}
+	fmt.Fprintf(&sb, "%s (%s)", d.Message, d.Source)
+	return sb.String()
+}
+
+// Report is what the commands configured for a file found.
+type Report struct {
+	Commands    []string // Names of the commands that ran
+	Diagnostics []Diagnostic
+	Omitted     int    // Diagnostics left out by the per-command cap
+	Formatted   []byte // Content to replace the file with, from a formatter with Apply; nil if none
+	FormattedBy string
+}
+
+// Runner runs the formatters and linters of the diagnostics config section.
+type Runner struct {
+	commandExecutor commandExecutor
+	config          *config.Config
+	workspaceRoot   string
+}
+
+// NewRunner creates a Runner that runs commands in workspaceRoot.
+func NewRunner(commandExecutor commandExecutor, cfg *config.Config, workspaceRoot string) *Runner {
+	if commandExecutor == nil {
+		panic("commandExecutor is required")
+	}
+	if cfg == nil {
+		panic("cfg is required")
+	}
+	if workspaceRoot == "" {
+		panic("workspaceRoot is required")
+	}
+	return &Runner{
+		commandExecutor: commandExecutor,
+		config:          cfg,
+		workspaceRoot:   workspaceRoot,
+	}
+}
+
+// Check runs the commands configured for the extension of the file at the
+// workspace-relative path rel, whose content on disk is data. It returns nil if
+// no command is configured for the file.
+//
+// Commands run in order; once a formatter has produced content to apply, the
+// commands after it see the file as it is on disk, not as formatted. A command
+// that cannot be run is reported as a diagnostic. Only a cancelled ctx is an error.
+func (r *Runner) Check(ctx context.Context, rel string, data []byte) (*Report, error) {
+	ext := strings.ToLower(filepath.Ext(rel))
+	var report *Report
+	for _, d := range r.config.Tools.Diagnostics {
+		if !hasExtension(d.Extensions, ext) {
+			continue
+		}
+		if report == nil {
+			report = &Report{}
+		}
+		if err := r.run(ctx, d, rel, data, report); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+func (r *Runner) run(ctx context.Context, d config.DiagnosticConfig, rel string, data []byte, report *Report) error {
+	argv := renderCommand(d.Command, rel)
+	name := filepath.Base(argv[0])
+	report.Commands = append(report.Commands, name)
+
+	timeout := defaultTimeout
+	if d.TimeoutSeconds > 0 {
+		timeout = time.Duration(d.TimeoutSeconds) * time.Second
+	}
+
+	res, err := r.commandExecutor.RunWithTimeout(ctx, argv, r.workspaceRoot, os.Environ(), timeout)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	fail := func(msg string) {
+		report.Diagnostics = append(report.Diagnostics, Diagnostic{Message: msg, Source: name})
+	}
+	switch {
+	case errors.Is(err, executor.ErrTimeout):
+		fail(fmt.Sprintf("timed out after %s", timeout))
+		return nil
+	case err != nil:
+		fail(fmt.Sprintf("could not run: %v", err))
+		return nil
+	}
+
+	if d.Format {
+		if res.ExitCode != 0 {
+			// A formatter that fails usually cannot parse the file.
+			if !report.add(parse(res.Stderr, name)) {
+				fail(fmt.Sprintf("exited with status %d", res.ExitCode))
+			}
+			return nil
+		}
+		if res.Stdout == string(data) {
+			return nil
+		}
+		if res.Truncated {
+			fail("output was truncated; the file was left as it is")
+			return nil
+		}
+		if d.Apply && report.Formatted == nil {
+			report.Formatted, report.FormattedBy = []byte(res.Stdout), name
+			return nil
+		}
+		report.Diagnostics = append(report.Diagnostics, Diagnostic{File: rel, Message: "not formatted", Source: name})
+		return nil
+	}
+
+	if !report.add(parse(res.Stdout+"\n"+res.Stderr, name)) && res.ExitCode != 0 {
+		fail(fmt.Sprintf("exited with status %d", res.ExitCode))
+	}
+	return nil
+}
+
+// add appends diagnostics up to the cap and reports whether there were any.
+func (r *Report) add(diags []Diagnostic) bool {
+	kept := min(len(diags), maxDiagnostics)
+	r.Diagnostics = append(r.Diagnostics, diags[:kept]...)
+	r.Omitted += len(diags) - kept
+	return len(diags) > 0
+}
+
+// parse turns command output into diagnostics, one per non-empty line.
+func parse(output, source string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		m := locationPattern.FindStringSubmatch(line)
+		if m == nil {
+			diags = append(diags, Diagnostic{Message: line, Source: source})
+			continue
+		}
+		d := Diagnostic{File: m[1], Message: m[4], Source: source}
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// renderCommand replaces {{file}} in the argv template with rel, or appends rel
+// if the template has no placeholder.
+func renderCommand(template []string, rel string) []string {
+	argv := make([]string, 0, len(template)+1)
+	found := false
+	for _, arg := range template {
+		if strings.Contains(arg, filePlaceholder) {
+			arg = strings.ReplaceAll(arg, filePlaceholder, rel)
+			found = true
+		}
+		argv = append(argv, arg)
+	}
+	if !found {
+		argv = append(argv, rel)
+	}
+	return argv
+}
+
+func hasExtension(extensions []string, ext string) bool {
+	for _, e := range extensions {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
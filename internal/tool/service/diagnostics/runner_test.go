@@ -0,0 +1,144 @@
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool/service/executor"
+)
+
+type mockExecutor struct {
+	results map[string]*executor.Result // Keyed by command name
+	errs    map[string]error
+	calls   [][]string
+}
+
+func (m *mockExecutor) RunWithTimeout(ctx context.Context, cmd []string, dir string, env []string, timeout time.Duration) (*executor.Result, error) {
+	m.calls = append(m.calls, cmd)
+	if err := m.errs[cmd[0]]; err != nil {
+		return nil, err
+	}
+	if res, ok := m.results[cmd[0]]; ok {
+		return res, nil
+	}
+	return &executor.Result{}, nil
+}
+
+func newRunner(exec *mockExecutor, diags ...config.DiagnosticConfig) *Runner {
+	cfg := config.DefaultConfig()
+	cfg.Tools.Diagnostics = diags
+	return NewRunner(exec, cfg, "/workspace")
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("no command for the extension", func(t *testing.T) {
+		exec := &mockExecutor{}
+		r := newRunner(exec, config.DiagnosticConfig{Extensions: []string{".go"}, Command: []string{"gofmt", "-l"}})
+		report, err := r.Check(context.Background(), "README.md", nil)
+		if err != nil || report != nil || len(exec.calls) != 0 {
+			t.Errorf("expected nothing to run, got %+v, %v", report, err)
+		}
+	})
+
+	t.Run("linter output is parsed", func(t *testing.T) {
+		exec := &mockExecutor{results: map[string]*executor.Result{
+			"go": {Stderr: "# example\npkg/a.go:3:9: undefined: x\npkg/a.go:7: missing return\n", ExitCode: 1},
+		}}
+		r := newRunner(exec, config.DiagnosticConfig{Extensions: []string{".GO"}, Command: []string{"go", "vet", "./{{file}}"}})
+		report, err := r.Check(context.Background(), "pkg/a.go", []byte("package pkg\n"))
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if !reflect.DeepEqual(exec.calls[0], []string{"go", "vet", "./pkg/a.go"}) {
+			t.Errorf("unexpected command %v", exec.calls[0])
+		}
+		want := []Diagnostic{
+			{Message: "# example", Source: "go"},
+			{File: "pkg/a.go", Line: 3, Column: 9, Message: "undefined: x", Source: "go"},
+			{File: "pkg/a.go", Line: 7, Message: "missing return", Source: "go"},
+		}
+		if !reflect.DeepEqual(report.Diagnostics, want) {
+			t.Errorf("unexpected diagnostics %+v", report.Diagnostics)
+		}
+		if got := report.Diagnostics[1].String(); got != "pkg/a.go:3:9: undefined: x (go)" {
+			t.Errorf("unexpected string %q", got)
+		}
+	})
+
+	t.Run("quiet linter that fails", func(t *testing.T) {
+		exec := &mockExecutor{results: map[string]*executor.Result{"tflint": {ExitCode: 2}}}
+		r := newRunner(exec, config.DiagnosticConfig{Extensions: []string{".tf"}, Command: []string{"tflint"}})
+		report, _ := r.Check(context.Background(), "main.tf", nil)
+		if len(report.Diagnostics) != 1 || report.Diagnostics[0].Message != "exited with status 2" {
+			t.Errorf("unexpected diagnostics %+v", report.Diagnostics)
+		}
+	})
+
+	t.Run("formatter", func(t *testing.T) {
+		data := []byte("package main\nfunc main() {}\n")
+		formatted := "package main\n\nfunc main() {}\n"
+		exec := &mockExecutor{results: map[string]*executor.Result{"gofmt": {Stdout: formatted}}}
+		gofmt := config.DiagnosticConfig{Extensions: []string{".go"}, Command: []string{"gofmt"}, Format: true}
+
+		report, _ := newRunner(exec, gofmt).Check(context.Background(), "main.go", data)
+		if report.Formatted != nil || len(report.Diagnostics) != 1 || report.Diagnostics[0].Message != "not formatted" {
+			t.Errorf("expected an unformatted file to be reported, got %+v", report)
+		}
+
+		gofmt.Apply = true
+		report, _ = newRunner(exec, gofmt).Check(context.Background(), "main.go", data)
+		if string(report.Formatted) != formatted || report.FormattedBy != "gofmt" || len(report.Diagnostics) != 0 {
+			t.Errorf("expected formatted content, got %+v", report)
+		}
+
+		report, _ = newRunner(exec, gofmt).Check(context.Background(), "main.go", []byte(formatted))
+		if report.Formatted != nil || len(report.Diagnostics) != 0 {
+			t.Errorf("expected a formatted file to pass, got %+v", report)
+		}
+	})
+
+	t.Run("formatter that cannot parse the file", func(t *testing.T) {
+		exec := &mockExecutor{results: map[string]*executor.Result{
+			"gofmt": {Stderr: "main.go:2:14: expected '}', found 'EOF'\n", ExitCode: 2},
+		}}
+		r := newRunner(exec, config.DiagnosticConfig{Extensions: []string{".go"}, Command: []string{"gofmt"}, Format: true, Apply: true})
+		report, _ := r.Check(context.Background(), "main.go", []byte("package main\nfunc main() {\n"))
+		if report.Formatted != nil || len(report.Diagnostics) != 1 || report.Diagnostics[0].Line != 2 {
+			t.Errorf("unexpected report %+v", report)
+		}
+	})
+
+	t.Run("commands that cannot run", func(t *testing.T) {
+		exec := &mockExecutor{errs: map[string]error{
+			"yamllint": errors.New("command yamllint failed to start: executable file not found"),
+			"slow":     executor.ErrTimeout,
+		}}
+		r := newRunner(exec, config.DiagnosticConfig{Extensions: []string{".yaml"}, Command: []string{"yamllint"}},
+			config.DiagnosticConfig{Extensions: []string{".yaml"}, Command: []string{"slow"}, TimeoutSeconds: 5})
+		report, err := r.Check(context.Background(), "ci.yaml", nil)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		if len(report.Diagnostics) != 2 ||
+			!strings.HasPrefix(report.Diagnostics[0].Message, "could not run") ||
+			report.Diagnostics[1].Message != "timed out after 5s" {
+			t.Errorf("unexpected diagnostics %+v", report.Diagnostics)
+		}
+	})
+
+	t.Run("diagnostics are capped", func(t *testing.T) {
+		exec := &mockExecutor{results: map[string]*executor.Result{
+			"lint": {Stdout: strings.Repeat("a.py:1: bad\n", maxDiagnostics+5), ExitCode: 1},
+		}}
+		r := newRunner(exec, config.DiagnosticConfig{Extensions: []string{".py"}, Command: []string{"lint"}})
+		report, _ := r.Check(context.Background(), "a.py", nil)
+		if len(report.Diagnostics) != maxDiagnostics || report.Omitted != 5 {
+			t.Errorf("expected %d diagnostics and 5 omitted, got %d and %d", maxDiagnostics, len(report.Diagnostics), report.Omitted)
+		}
+	})
+}
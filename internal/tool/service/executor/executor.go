@@ -4,10 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
-	"sync"
 	"time"
 
 	"github.com/Cyclone1070/iav/internal/config"
@@ -45,32 +43,21 @@ func (f *OSCommandExecutor) Run(ctx context.Context, command []string, dir strin
 	cmd.Env = env
 	cmd.Stdin = nil
 
-	stdoutPipe, err := cmd.StdoutPipe()
+	stdout, stderr, err := f.start(cmd)
 	if err != nil {
-		return nil, fmt.Errorf("command %s failed to start: %w", command[0], err)
-	}
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("command %s failed to start: %w", command[0], err)
+		return nil, err
 	}
 
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("command %s failed to start: %w", command[0], err)
-	}
-
-	stdoutStr, stderrStr, truncated := f.collectOutput(stdoutPipe, stderrPipe)
-
-	err = cmd.Wait()
 	exitCode := 0
-	if err != nil {
+	if err := wait(cmd); err != nil {
 		exitCode = f.getExitCode(err)
 	}
 
 	return &Result{
-		Stdout:    stdoutStr,
-		Stderr:    stderrStr,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
 		ExitCode:  exitCode,
-		Truncated: truncated,
+		Truncated: stdout.Truncated() || stderr.Truncated(),
 	}, nil
 }
 
@@ -86,55 +73,37 @@ func (f *OSCommandExecutor) RunWithTimeout(ctx context.Context, command []string
 	cmd.Env = env
 	cmd.Stdin = nil
 
-	stdoutPipe, err := cmd.StdoutPipe()
+	stdout, stderr, err := f.start(cmd)
 	if err != nil {
-		return nil, fmt.Errorf("command %s failed to start: %w", command[0], err)
+		return nil, err
 	}
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("command %s failed to start: %w", command[0], err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("command %s failed to start: %w", command[0], err)
-	}
-
-	// Start output collection concurrently so it doesn't block the timeout select
-	var stdoutStr, stderrStr string
-	var truncated bool
-	collectDone := make(chan struct{})
-	go func() {
-		stdoutStr, stderrStr, truncated = f.collectOutput(stdoutPipe, stderrPipe)
-		close(collectDone)
-	}()
 
 	done := make(chan error, 1)
 	go func() {
-		done <- cmd.Wait()
+		done <- wait(cmd)
 	}()
 
+	// Each path waits for Wait, which returns once the output has been copied.
 	var execErr error
 	select {
 	case err := <-done:
 		execErr = err
 	case <-ctx.Done():
 		_ = cmd.Process.Kill()
+		<-done
 		execErr = ctx.Err()
 	case <-time.After(timeout):
 		// Try graceful shutdown
 		_ = cmd.Process.Signal(os.Interrupt)
 		select {
 		case <-done:
-			execErr = ErrTimeout
 		case <-time.After(time.Duration(f.config.Tools.DockerGracefulShutdownMs) * time.Millisecond):
 			_ = cmd.Process.Kill()
-			execErr = ErrTimeout
+			<-done
 		}
+		execErr = ErrTimeout
 	}
 
-	// Wait for output collection to finish (it should when cmd.Wait/Kill happens)
-	<-collectDone
-
 	exitCode := 0
 	if execErr != nil {
 		exitCode = f.getExitCode(execErr)
@@ -144,10 +113,10 @@ func (f *OSCommandExecutor) RunWithTimeout(ctx context.Context, command []string
 	}
 
 	res := &Result{
-		Stdout:    stdoutStr,
-		Stderr:    stderrStr,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
 		ExitCode:  exitCode,
-		Truncated: truncated,
+		Truncated: stdout.Truncated() || stderr.Truncated(),
 	}
 
 	// Only return error for infrastructure failures (timeout or context cancelled)
@@ -161,29 +130,30 @@ func (f *OSCommandExecutor) RunWithTimeout(ctx context.Context, command []string
 	return res, nil
 }
 
-func (f *OSCommandExecutor) collectOutput(stdout, stderr io.Reader) (string, string, bool) {
+// start starts cmd with its output going to collectors. Writers rather than
+// pipes make Wait return only once the output has been copied, so none is lost;
+// WaitDelay bounds that copy when the command leaves children holding the output open.
+func (f *OSCommandExecutor) start(cmd *exec.Cmd) (stdout, stderr *collector, err error) {
 	maxBytes := int(f.config.Tools.DefaultMaxCommandOutputSize)
+	stdout = newCollector(maxBytes, 8000)
+	stderr = newCollector(maxBytes, 8000)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Duration(f.config.Tools.DockerGracefulShutdownMs) * time.Millisecond
 
-	stdoutCollector := newCollector(maxBytes, 8000)
-	stderrCollector := newCollector(maxBytes, 8000)
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go func() {
-		defer wg.Done()
-		_, _ = io.Copy(stdoutCollector, stdout)
-	}()
-
-	go func() {
-		defer wg.Done()
-		_, _ = io.Copy(stderrCollector, stderr)
-	}()
-
-	wg.Wait()
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("command %s failed to start: %w", cmd.Args[0], err)
+	}
+	return stdout, stderr, nil
+}
 
-	truncated := stdoutCollector.Truncated() || stderrCollector.Truncated()
-	return stdoutCollector.String(), stderrCollector.String(), truncated
+// wait waits for cmd to exit. exec.ErrWaitDelay is not an error here: it only
+// means a background child kept the output open after the command exited.
+func wait(cmd *exec.Cmd) error {
+	if err := cmd.Wait(); !errors.Is(err, exec.ErrWaitDelay) {
+		return err
+	}
+	return nil
 }
 
 func (f *OSCommandExecutor) getExitCode(err error) int {
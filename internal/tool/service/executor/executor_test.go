@@ -13,6 +13,7 @@ import (
 
 func TestRun(t *testing.T) {
 	cfg := config.DefaultConfig()
+	cfg.Tools.DockerGracefulShutdownMs = 100
 	exec := NewOSCommandExecutor(cfg)
 
 	t.Run("SimpleCommand", func(t *testing.T) {
@@ -28,6 +29,24 @@ func TestRun(t *testing.T) {
 		}
 	})
 
+	t.Run("BackgroundChildDoesNotHoldTheResult", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("Skipping background process test on Windows")
+		}
+		// The child keeps stdout open after the command itself has exited.
+		start := time.Now()
+		res, err := exec.Run(context.Background(), []string{"sh", "-c", "sleep 3 & echo done"}, "", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.TrimSpace(res.Stdout) != "done" {
+			t.Errorf("expected stdout 'done', got %q", res.Stdout)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("expected the result once the command exited, waited %v", elapsed)
+		}
+	})
+
 	t.Run("EmptyCommand", func(t *testing.T) {
 		_, err := exec.Run(context.Background(), []string{}, "", nil)
 		if err != os.ErrInvalid {
@@ -97,6 +116,19 @@ func TestRunWithTimeout(t *testing.T) {
 		}
 	})
 
+	t.Run("OutputIsNotLost", func(t *testing.T) {
+		// The output must be read in full before the command counts as finished.
+		for range 50 {
+			res, err := exec.RunWithTimeout(context.Background(), []string{"echo", "hi"}, "", nil, 1*time.Second)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.TrimSpace(res.Stdout) != "hi" {
+				t.Fatalf("expected stdout 'hi', got %q", res.Stdout)
+			}
+		}
+	})
+
 	t.Run("TimeoutKillsProcess", func(t *testing.T) {
 		if runtime.GOOS == "windows" {
 			t.Skip("Skipping timeout test on Windows")
@@ -108,6 +140,24 @@ func TestRunWithTimeout(t *testing.T) {
 		}
 	})
 
+	t.Run("BackgroundChildDoesNotHoldTheResult", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("Skipping background process test on Windows")
+		}
+		// The child keeps stdout open after the command itself has exited.
+		start := time.Now()
+		res, err := exec.RunWithTimeout(context.Background(), []string{"sh", "-c", "sleep 3 & echo done"}, "", nil, 5*time.Second)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.TrimSpace(res.Stdout) != "done" {
+			t.Errorf("expected stdout 'done', got %q", res.Stdout)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("expected the result once the command exited, waited %v", elapsed)
+		}
+	})
+
 	t.Run("OutputCollectedOnTimeout", func(t *testing.T) {
 		if runtime.GOOS == "windows" {
 			t.Skip("Skipping timeout test on Windows")
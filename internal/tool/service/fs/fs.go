@@ -50,6 +50,16 @@ func (fs *OSFileSystem) ReadFile(path string) ([]byte, error) {
 	return data, nil
 }
 
+// Open opens a file for reading, without ReadFile's size and binary checks, so
+// a part of a large file can be read without loading the rest.
+func (fs *OSFileSystem) Open(path string) (io.ReadSeekCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
 // WriteFileAtomic writes content to a file atomically using temp file + rename pattern.
 // This ensures that if the process crashes mid-write, the original file remains intact.
 // The temp file is created in the same directory as the target to ensure atomic rename.
@@ -106,6 +116,72 @@ func (fs *OSFileSystem) EnsureDirs(path string) error {
 	return os.MkdirAll(path, 0o755)
 }
 
+// Remove deletes a file or empty directory.
+func (fs *OSFileSystem) Remove(path string) error {
+	return os.Remove(path)
+}
+
+// RemoveAll deletes a path and everything below it. Symlinks are removed, not followed.
+func (fs *OSFileSystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// Rename moves a file or directory. Both paths must be on the same filesystem.
+func (fs *OSFileSystem) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+// CopyFile copies a regular file's content and permissions to dst atomically,
+// replacing dst if it exists. Unlike ReadFile it applies no size or binary checks.
+func (fs *OSFileSystem) CopyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file: %s", src)
+	}
+
+	dir := filepath.Dir(dst)
+	tmpFile, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp in %s: %w", dir, err)
+	}
+	tmpPath := tmpFile.Name()
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpPath) // No-op once renamed
+	}()
+
+	if _, err := io.Copy(tmpFile, in); err != nil {
+		return fmt.Errorf("copy %s: %w", src, err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return fmt.Errorf("sync temp %s: %w", tmpPath, err)
+	}
+	if err := tmpFile.Chmod(info.Mode().Perm()); err != nil {
+		return fmt.Errorf("chmod temp %s: %w", tmpPath, err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("close temp %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return fmt.Errorf("rename %s to %s: %w", tmpPath, dst, err)
+	}
+	return nil
+}
+
+// Symlink creates link pointing at target.
+func (fs *OSFileSystem) Symlink(target, link string) error {
+	return os.Symlink(target, link)
+}
+
 // Readlink reads the target of a symlink.
 func (fs *OSFileSystem) Readlink(path string) (string, error) {
 	return os.Readlink(path)
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package fs
+
+import "os"
+
+// lockFile is a no-op where advisory locks are unavailable. Recover must then only
+// run while no other process is committing a transaction.
+func lockFile(f *os.File, block bool) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,461 @@
+package fs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrIncompleteRollback reports a failed commit whose changes could not all be
+// undone. The journal is kept so Recover can finish the rollback later.
+var ErrIncompleteRollback = errors.New("rollback incomplete")
+
+// Change is one file operation in a transaction.
+type Change struct {
+	Path    string
+	Content []byte
+	Perm    os.FileMode
+	Delete  bool // Remove Path instead of writing Content
+}
+
+// Transaction changes several files all-or-nothing.
+//
+// Staging only records the changes. Commit first writes a journal naming every file
+// it will create: a temp file next to each target for its content and a pre-image of
+// every existing target (a hard link, or a copy where linking is not possible). Then
+// it creates them and renames the temp files into place. If a step fails the
+// pre-images are moved back; if the process dies mid-commit, Recover does the same
+// on the next start and removes whatever of the temp files and pre-images exist.
+// Parent directories created for new files are kept.
+type Transaction struct {
+	fs          *OSFileSystem
+	id          string
+	entries     []journalEntry
+	journal     *os.File // Held and locked while committing
+	journalPath string
+	done        bool
+
+	beforePrepare func(i int) error // Test hook, called before each change's files are created
+	beforeApply   func(i int) error // Test hook, called before each change is made
+}
+
+// journalEntry is one change as recorded in the journal.
+type journalEntry struct {
+	Path   string `json:"path"`
+	Temp   string `json:"temp,omitempty"`   // Staged content; empty for deletes
+	Backup string `json:"backup,omitempty"` // Pre-image; empty if the file did not exist
+
+	content []byte // Staged content, written to Temp on commit
+	perm    os.FileMode
+	delete  bool
+}
+
+// Begin starts a transaction.
+func (fs *OSFileSystem) Begin() *Transaction {
+	return &Transaction{fs: fs, id: uuid.New().String()}
+}
+
+// ApplyChanges makes all changes in one transaction.
+func (fs *OSFileSystem) ApplyChanges(changes []Change) error {
+	tx := fs.Begin()
+	for _, c := range changes {
+		var err error
+		if c.Delete {
+			err = tx.StageDelete(c.Path)
+		} else {
+			err = tx.Stage(c.Path, c.Content, c.Perm)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Stage schedules content to be written to path on commit. Missing parent
+// directories are created on commit.
+func (tx *Transaction) Stage(path string, content []byte, perm os.FileMode) error {
+	if err := tx.checkStage(path); err != nil {
+		return err
+	}
+	tx.entries = append(tx.entries, journalEntry{Path: path, content: content, perm: perm})
+	return nil
+}
+
+// StageDelete schedules path to be removed on commit.
+func (tx *Transaction) StageDelete(path string) error {
+	if err := tx.checkStage(path); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	tx.entries = append(tx.entries, journalEntry{Path: path, delete: true})
+	return nil
+}
+
+func (tx *Transaction) checkStage(path string) error {
+	if tx.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	for _, e := range tx.entries {
+		if e.Path == path {
+			return fmt.Errorf("%s is already staged in this transaction", path)
+		}
+	}
+	return nil
+}
+
+// Rollback discards the staged changes. It is a no-op after Commit.
+func (tx *Transaction) Rollback() error {
+	tx.done = true
+	return nil
+}
+
+// Commit makes every staged change or, if any fails, none of them.
+func (tx *Transaction) Commit() error {
+	if tx.done {
+		return fmt.Errorf("transaction already finished")
+	}
+	if len(tx.entries) == 0 {
+		tx.done = true
+		return nil
+	}
+
+	tx.done = true
+	if err := tx.plan(); err != nil {
+		return err
+	}
+	if err := tx.writeJournal(); err != nil {
+		return err
+	}
+	// Every file created from here on is named in the journal, so Recover can
+	// remove it if the process dies.
+	if n, err := tx.prepare(); err != nil {
+		tx.discardPrepared(n)
+		tx.closeJournal(true)
+		return err
+	}
+
+	for i, e := range tx.entries {
+		err := tx.apply(i, e)
+		if err != nil {
+			if undoErr := undo(tx.entries); undoErr != nil {
+				// Keep the journal so Recover can retry.
+				tx.closeJournal(false)
+				return fmt.Errorf("commit %s: %w; %w: %v", e.Path, err, ErrIncompleteRollback, undoErr)
+			}
+			tx.closeJournal(true)
+			return fmt.Errorf("commit %s: %w", e.Path, err)
+		}
+	}
+
+	// The committed marker is the point of no return: from here on Recover
+	// cleans up instead of rolling back.
+	if err := os.WriteFile(committedPath(tx.journalPath), nil, 0o600); err != nil {
+		if undoErr := undo(tx.entries); undoErr != nil {
+			tx.closeJournal(false)
+			return fmt.Errorf("mark commit: %w; %w: %v", err, ErrIncompleteRollback, undoErr)
+		}
+		tx.closeJournal(true)
+		return fmt.Errorf("mark commit: %w", err)
+	}
+	tx.discardBackups()
+	tx.closeJournal(true)
+	return nil
+}
+
+func (tx *Transaction) apply(i int, e journalEntry) error {
+	if tx.beforeApply != nil {
+		if err := tx.beforeApply(i); err != nil {
+			return err
+		}
+	}
+	if e.Temp == "" {
+		return os.Remove(e.Path)
+	}
+	return os.Rename(e.Temp, e.Path)
+}
+
+// plan names the temp file of every write and the pre-image of every target that
+// exists, without creating them.
+func (tx *Transaction) plan() error {
+	for i := range tx.entries {
+		e := &tx.entries[i]
+		dir := filepath.Dir(e.Path)
+		if !e.delete {
+			e.Temp = filepath.Join(dir, fmt.Sprintf(".iav-tx-%s-%d.tmp", tx.id[:8], i))
+		}
+		info, err := os.Lstat(e.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("not a regular file: %s", e.Path)
+		}
+		e.Backup = filepath.Join(dir, fmt.Sprintf(".iav-tx-%s-%s.bak", tx.id[:8], filepath.Base(e.Path)))
+	}
+	return nil
+}
+
+// prepare creates the planned files: the staged content in each temp file, with
+// missing parent directories, and the pre-image of each existing target. On error
+// it removes what it created for the failing entry and returns the entry's index.
+func (tx *Transaction) prepare() (int, error) {
+	for i, e := range tx.entries {
+		if tx.beforePrepare != nil {
+			if err := tx.beforePrepare(i); err != nil {
+				return i, err
+			}
+		}
+		if e.Temp != "" {
+			dir := filepath.Dir(e.Path)
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return i, fmt.Errorf("create dir %s: %w", dir, err)
+			}
+			// O_EXCL, so a file that happens to have the name is never overwritten or removed.
+			f, err := os.OpenFile(e.Temp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, e.perm)
+			if err != nil {
+				return i, fmt.Errorf("create temp in %s: %w", dir, err)
+			}
+			if err := writeAndClose(f, e.content, e.perm); err != nil {
+				_ = os.Remove(e.Temp)
+				return i, err
+			}
+		}
+		if e.Backup != "" {
+			info, err := os.Lstat(e.Path)
+			if err == nil {
+				err = linkOrCopy(e.Path, e.Backup, info.Mode().Perm())
+			}
+			if err != nil {
+				if e.Temp != "" {
+					_ = os.Remove(e.Temp)
+				}
+				return i, fmt.Errorf("back up %s: %w", e.Path, err)
+			}
+		}
+	}
+	return len(tx.entries), nil
+}
+
+// discardPrepared removes the temp files and pre-images prepare created for the
+// first n entries.
+func (tx *Transaction) discardPrepared(n int) {
+	for _, e := range tx.entries[:n] {
+		if e.Temp != "" {
+			_ = os.Remove(e.Temp)
+		}
+		if e.Backup != "" {
+			_ = os.Remove(e.Backup)
+		}
+	}
+}
+
+func (tx *Transaction) discardBackups() {
+	for _, e := range tx.entries {
+		if e.Backup != "" {
+			_ = os.Remove(e.Backup)
+		}
+	}
+}
+
+// writeJournal records the entries and keeps the journal locked until the commit ends.
+// It is written under a temporary name and renamed so Recover never sees a partial journal.
+func (tx *Transaction) writeJournal() error {
+	dir := tx.fs.config.Tools.TransactionJournalDir
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create journal dir: %w", err)
+	}
+	data, err := json.Marshal(tx.entries)
+	if err != nil {
+		return fmt.Errorf("encode journal: %w", err)
+	}
+
+	path := filepath.Join(dir, tx.id+".json")
+	f, err := os.OpenFile(path+".tmp", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("create journal: %w", err)
+	}
+	if _, err := lockFile(f, true); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("lock journal: %w", err)
+	}
+	if err := writeAndClose(nopCloser{f}, data, 0o600); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("write journal: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("write journal: %w", err)
+	}
+	tx.journal, tx.journalPath = f, path
+	return nil
+}
+
+// closeJournal releases the journal, removing it when the transaction is resolved.
+func (tx *Transaction) closeJournal(remove bool) {
+	if remove {
+		_ = os.Remove(tx.journalPath)
+		_ = os.Remove(committedPath(tx.journalPath))
+	}
+	_ = tx.journal.Close()
+}
+
+// Recover resolves transactions interrupted by a crash. Uncommitted ones are rolled
+// back from their pre-images; committed ones have their pre-images removed. Journals
+// locked by a transaction still running are skipped. It returns how many were resolved.
+func (fs *OSFileSystem) Recover() (int, error) {
+	dir := fs.config.Tools.TransactionJournalDir
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	var errs []error
+	for _, path := range paths {
+		ok, err := recoverJournal(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+		}
+		if ok {
+			recovered++
+		}
+	}
+	return recovered, errors.Join(errs...)
+}
+
+func recoverJournal(path string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return false, nil // Finished while we were listing
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	locked, err := lockFile(f, false)
+	if err != nil || !locked {
+		return false, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil // Finished between open and lock
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return false, err
+	}
+	var entries []journalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return false, fmt.Errorf("malformed journal: %w", err)
+	}
+
+	if _, err := os.Stat(committedPath(path)); err == nil {
+		for _, e := range entries {
+			if e.Backup != "" {
+				_ = os.Remove(e.Backup)
+			}
+		}
+	} else if err := undo(entries); err != nil {
+		return false, err
+	}
+
+	_ = os.Remove(path)
+	_ = os.Remove(committedPath(path))
+	return true, nil
+}
+
+// undo puts every entry's target back to its pre-image, in reverse order.
+// It is safe to run on entries that were never applied.
+func undo(entries []journalEntry) error {
+	var errs []error
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Backup != "" {
+			if err := os.Rename(e.Backup, e.Path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("restore %s: %w", e.Path, err))
+				continue
+			}
+			// Renaming a hard link onto the same file leaves the link in place.
+			_ = os.Remove(e.Backup)
+		} else if e.Temp != "" {
+			if err := os.Remove(e.Path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("remove %s: %w", e.Path, err))
+			}
+		}
+		if e.Temp != "" {
+			_ = os.Remove(e.Temp)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func committedPath(journalPath string) string {
+	return strings.TrimSuffix(journalPath, ".json") + ".committed"
+}
+
+// linkOrCopy makes dst hold the current content of src.
+func linkOrCopy(src, dst string, perm os.FileMode) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if err := writeAndClose(f, data, perm); err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+	return nil
+}
+
+// writeAndClose writes, syncs, sets the mode of and closes f.
+func writeAndClose(f interface {
+	io.Writer
+	Sync() error
+	Chmod(os.FileMode) error
+	Close() error
+	Name() string
+}, content []byte, perm os.FileMode) error {
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write %s: %w", f.Name(), err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("sync %s: %w", f.Name(), err)
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("chmod %s: %w", f.Name(), err)
+	}
+	return f.Close()
+}
+
+// nopCloser keeps the journal open (and locked) through writeAndClose.
+type nopCloser struct {
+	*os.File
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,280 @@
+package fs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+)
+
+func newTestFS(t *testing.T) (*OSFileSystem, string) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Tools.TransactionJournalDir = filepath.Join(t.TempDir(), "journal")
+	return NewOSFileSystem(cfg), t.TempDir()
+}
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), data, want)
+	}
+}
+
+func assertMissing(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to exist, got %v", filepath.Base(path), err)
+	}
+}
+
+// assertOnly checks dir holds exactly the named entries, i.e. no temp files or backups remain.
+func assertOnly(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if len(got) != len(names) {
+		t.Fatalf("dir has %v, want %v", got, names)
+	}
+	for i := range names {
+		if got[i] != names[i] {
+			t.Fatalf("dir has %v, want %v", got, names)
+		}
+	}
+}
+
+func TestTransaction_CommitAppliesAllChanges(t *testing.T) {
+	fs, dir := newTestFS(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "old a", 0o600)
+	writeFile(t, filepath.Join(dir, "gone.txt"), "bye", 0o644)
+
+	err := fs.ApplyChanges([]Change{
+		{Path: filepath.Join(dir, "a.txt"), Content: []byte("new a"), Perm: 0o600},
+		{Path: filepath.Join(dir, "sub", "b.txt"), Content: []byte("b"), Perm: 0o644},
+		{Path: filepath.Join(dir, "gone.txt"), Delete: true},
+	})
+	if err != nil {
+		t.Fatalf("ApplyChanges: %v", err)
+	}
+
+	assertContent(t, filepath.Join(dir, "a.txt"), "new a")
+	assertContent(t, filepath.Join(dir, "sub", "b.txt"), "b")
+	assertMissing(t, filepath.Join(dir, "gone.txt"))
+	if info, _ := os.Stat(filepath.Join(dir, "a.txt")); info.Mode().Perm() != 0o600 {
+		t.Errorf("perm = %v, want 0600", info.Mode().Perm())
+	}
+	assertOnly(t, dir, "a.txt", "sub")
+	assertOnly(t, fs.config.Tools.TransactionJournalDir)
+}
+
+func TestTransaction_FailedChangeRestoresPreImages(t *testing.T) {
+	fs, dir := newTestFS(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "old a", 0o644)
+	writeFile(t, filepath.Join(dir, "c.txt"), "old c", 0o644)
+
+	tx := fs.Begin()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := tx.Stage(filepath.Join(dir, name), []byte("new"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx.beforeApply = func(i int) error {
+		if i == 2 {
+			return errors.New("disk full")
+		}
+		return nil
+	}
+
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected commit error")
+	}
+
+	assertContent(t, filepath.Join(dir, "a.txt"), "old a")
+	assertMissing(t, filepath.Join(dir, "b.txt"))
+	assertContent(t, filepath.Join(dir, "c.txt"), "old c")
+	assertOnly(t, dir, "a.txt", "c.txt")
+	assertOnly(t, fs.config.Tools.TransactionJournalDir)
+}
+
+func TestTransaction_RollbackDiscardsStagedWrites(t *testing.T) {
+	fs, dir := newTestFS(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "old", 0o644)
+
+	tx := fs.Begin()
+	if err := tx.Stage(filepath.Join(dir, "a.txt"), []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertContent(t, filepath.Join(dir, "a.txt"), "old")
+	assertOnly(t, dir, "a.txt")
+	if err := tx.Commit(); err == nil {
+		t.Error("expected commit after rollback to fail")
+	}
+}
+
+func TestTransaction_StageTwiceFails(t *testing.T) {
+	fs, dir := newTestFS(t)
+	tx := fs.Begin()
+	defer tx.Rollback()
+
+	path := filepath.Join(dir, "a.txt")
+	if err := tx.Stage(path, []byte("1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Stage(path, []byte("2"), 0o644); err == nil {
+		t.Error("expected error staging the same path twice")
+	}
+}
+
+// crash runs a commit that stops dead before change i, leaving the journal behind
+// as a killed process would.
+func crash(t *testing.T, tx *Transaction, i int) {
+	t.Helper()
+	tx.beforeApply = func(n int) error {
+		if n == i {
+			panic("crash")
+		}
+		return nil
+	}
+	func() {
+		defer func() { _ = recover() }()
+		_ = tx.Commit()
+	}()
+	// The process is gone, so its lock on the journal is too.
+	_ = tx.journal.Close()
+}
+
+func TestRecover_RollsBackInterruptedCommit(t *testing.T) {
+	fs, dir := newTestFS(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "old a", 0o644)
+	writeFile(t, filepath.Join(dir, "c.txt"), "old c", 0o644)
+
+	tx := fs.Begin()
+	_ = tx.Stage(filepath.Join(dir, "a.txt"), []byte("new a"), 0o644)
+	_ = tx.Stage(filepath.Join(dir, "b.txt"), []byte("new b"), 0o644)
+	_ = tx.StageDelete(filepath.Join(dir, "c.txt"))
+	crash(t, tx, 2)
+	assertContent(t, filepath.Join(dir, "a.txt"), "new a") // Half applied
+
+	n, err := fs.Recover()
+	if err != nil || n != 1 {
+		t.Fatalf("Recover = %d, %v; want 1, nil", n, err)
+	}
+
+	assertContent(t, filepath.Join(dir, "a.txt"), "old a")
+	assertMissing(t, filepath.Join(dir, "b.txt"))
+	assertContent(t, filepath.Join(dir, "c.txt"), "old c")
+	assertOnly(t, dir, "a.txt", "c.txt")
+	assertOnly(t, fs.config.Tools.TransactionJournalDir)
+}
+
+func TestRecover_RemovesFilesOfCommitKilledWhilePreparing(t *testing.T) {
+	fs, dir := newTestFS(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "old a", 0o644)
+	writeFile(t, filepath.Join(dir, "c.txt"), "old c", 0o644)
+
+	tx := fs.Begin()
+	_ = tx.Stage(filepath.Join(dir, "a.txt"), []byte("new a"), 0o644)
+	_ = tx.Stage(filepath.Join(dir, "sub", "b.txt"), []byte("new b"), 0o644)
+	_ = tx.Stage(filepath.Join(dir, "c.txt"), []byte("new c"), 0o644)
+	tx.beforePrepare = func(i int) error {
+		if i == 2 {
+			panic("crash")
+		}
+		return nil
+	}
+	func() {
+		defer func() { _ = recover() }()
+		_ = tx.Commit()
+	}()
+	_ = tx.journal.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) <= 3 {
+		t.Fatalf("expected temp files and a pre-image to be left behind, got %d entries", len(entries))
+	}
+
+	n, err := fs.Recover()
+	if err != nil || n != 1 {
+		t.Fatalf("Recover = %d, %v; want 1, nil", n, err)
+	}
+
+	assertContent(t, filepath.Join(dir, "a.txt"), "old a")
+	assertContent(t, filepath.Join(dir, "c.txt"), "old c")
+	assertOnly(t, dir, "a.txt", "c.txt", "sub")
+	assertOnly(t, filepath.Join(dir, "sub"))
+	assertOnly(t, fs.config.Tools.TransactionJournalDir)
+}
+
+func TestTransaction_FailedPrepareRemovesCreatedFiles(t *testing.T) {
+	fs, dir := newTestFS(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "old a", 0o644)
+
+	tx := fs.Begin()
+	_ = tx.Stage(filepath.Join(dir, "a.txt"), []byte("new a"), 0o644)
+	_ = tx.Stage(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0o644)
+	tx.beforePrepare = func(i int) error {
+		if i == 1 {
+			return errors.New("disk full")
+		}
+		return nil
+	}
+
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected commit error")
+	}
+
+	assertContent(t, filepath.Join(dir, "a.txt"), "old a")
+	assertOnly(t, dir, "a.txt")
+	assertOnly(t, fs.config.Tools.TransactionJournalDir)
+}
+
+func TestRecover_SkipsJournalLockedByRunningCommit(t *testing.T) {
+	fs, dir := newTestFS(t)
+	writeFile(t, filepath.Join(dir, "a.txt"), "old", 0o644)
+
+	tx := fs.Begin()
+	_ = tx.Stage(filepath.Join(dir, "a.txt"), []byte("new"), 0o644)
+	tx.beforeApply = func(int) error {
+		// Another process recovering mid-commit must leave this transaction alone.
+		if n, err := fs.Recover(); n != 0 || err != nil {
+			t.Errorf("Recover during commit = %d, %v; want 0, nil", n, err)
+		}
+		return nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(t, filepath.Join(dir, "a.txt"), "new")
+}
+
+func TestRecover_NothingToDo(t *testing.T) {
+	fs, _ := newTestFS(t)
+
+	n, err := fs.Recover()
+
+	if n != 0 || err != nil {
+		t.Errorf("Recover = %d, %v; want 0, nil", n, err)
+	}
+}
@@ -3,11 +3,14 @@ package hash
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"io"
 	"sync"
 )
 
 // ChecksumManager is a thread-safe checksum manager.
 // It uses SHA-256 for checksum computation and stores checksums in an in-memory map.
+// PersistentChecksumManager keeps them across restarts; this one suits tests and
+// one-off runs.
 type ChecksumManager struct {
 	mu    sync.RWMutex
 	store map[string]string
@@ -26,6 +29,16 @@ func (m *ChecksumManager) Compute(data []byte) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// ComputeReader computes the SHA-256 checksum of everything read from r, so a
+// large file can be checksummed without holding it in memory.
+func (m *ChecksumManager) ComputeReader(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // Get retrieves the cached checksum for a file path.
 // Returns the checksum and true if found, or empty string and false if not cached.
 func (m *ChecksumManager) Get(path string) (checksum string, ok bool) {
@@ -42,6 +55,24 @@ func (m *ChecksumManager) Update(path string, checksum string) {
 	m.store[path] = checksum
 }
 
+// Paths returns every file path with a cached checksum, in no particular order.
+func (m *ChecksumManager) Paths() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	paths := make([]string, 0, len(m.store))
+	for p := range m.store {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// Remove drops the cached checksum for a file path, e.g. after the file is deleted.
+func (m *ChecksumManager) Remove(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.store, path)
+}
+
 // Clear removes all cached checksums from the manager.
 func (m *ChecksumManager) Clear() {
 	m.mu.Lock()
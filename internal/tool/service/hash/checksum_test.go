@@ -1,6 +1,9 @@
 package hash
 
 import (
+	"bytes"
+	"sort"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -70,6 +73,21 @@ func TestChecksumManagerClear(t *testing.T) {
 	}
 }
 
+func TestChecksumManagerRemove(t *testing.T) {
+	manager := NewChecksumManager()
+	manager.Update("/file1.txt", "hash1")
+	manager.Update("/file2.txt", "hash2")
+
+	manager.Remove("/file1.txt")
+
+	if _, ok := manager.Get("/file1.txt"); ok {
+		t.Error("removed entry should be gone")
+	}
+	if _, ok := manager.Get("/file2.txt"); !ok {
+		t.Error("other entries should remain")
+	}
+}
+
 func TestCompute(t *testing.T) {
 	manager := NewChecksumManager()
 
@@ -90,4 +108,28 @@ func TestCompute(t *testing.T) {
 			t.Errorf("got %s, want %s", hash, expected)
 		}
 	})
+
+	t.Run("ReaderMatchesBytes", func(t *testing.T) {
+		data := []byte(strings.Repeat("line of a larger file\n", 10000))
+		hash, err := manager.ComputeReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("ComputeReader failed: %v", err)
+		}
+		if hash != manager.Compute(data) {
+			t.Errorf("got %s, want %s", hash, manager.Compute(data))
+		}
+	})
+}
+
+func TestChecksumManagerPaths(t *testing.T) {
+	manager := NewChecksumManager()
+	manager.Update("/file1.txt", "hash1")
+	manager.Update("/file2.txt", "hash2")
+
+	paths := manager.Paths()
+	sort.Strings(paths)
+
+	if len(paths) != 2 || paths[0] != "/file1.txt" || paths[1] != "/file2.txt" {
+		t.Errorf("unexpected paths: %v", paths)
+	}
 }
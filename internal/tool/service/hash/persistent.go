@@ -0,0 +1,172 @@
+package hash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileState is what is known about a file when its checksum was cached.
+type fileState struct {
+	Checksum string    `json:"checksum"`
+	ModTime  time.Time `json:"mtime"`
+	Size     int64     `json:"size"`
+}
+
+// PersistentChecksumManager is a ChecksumManager that keeps its cache in a JSON file,
+// so edit-conflict detection survives a restart or a resumed session.
+//
+// Alongside each checksum it records the file's mtime and size at the time of the
+// update. Changed uses them to tell a file is untouched without reading it.
+type PersistentChecksumManager struct {
+	*ChecksumManager // Compute and Get, from the entries mirrored into it
+	mu               sync.Mutex
+	path             string
+	states           map[string]fileState
+}
+
+// LoadChecksumManager opens the checksum cache stored at path. A missing file
+// yields an empty cache; the file is created on the first update.
+//
+// A file that cannot be read or parsed also yields an empty cache, returned with
+// the error so the caller can report it. The cache only spares re-reading files,
+// so it is rebuilt as the tools read them and overwrites the bad file on update.
+func LoadChecksumManager(path string) (*PersistentChecksumManager, error) {
+	m := &PersistentChecksumManager{
+		ChecksumManager: NewChecksumManager(),
+		path:            path,
+		states:          make(map[string]fileState),
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, fmt.Errorf("read checksums: %w", err)
+	}
+	if err := json.Unmarshal(data, &m.states); err != nil {
+		m.states = make(map[string]fileState) // Unmarshal may have filled part of it
+		return m, fmt.Errorf("unmarshal checksums: %w", err)
+	}
+	for p, s := range m.states {
+		m.ChecksumManager.Update(p, s.Checksum)
+	}
+	return m, nil
+}
+
+// Update caches the checksum for path with the file's current mtime and size,
+// and saves the cache.
+//
+// Note: A change made between the caller reading the file and this call is
+// recorded as the cached state. The window is as narrow as for the edit tools.
+func (m *PersistentChecksumManager) Update(path string, checksum string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ChecksumManager.Update(path, checksum)
+	s := fileState{Checksum: checksum}
+	if info, err := os.Stat(path); err == nil {
+		s.ModTime, s.Size = info.ModTime(), info.Size()
+	}
+	m.states[path] = s
+	_ = m.save() // Best effort
+}
+
+// Remove drops the cached checksum for a file path and saves the cache.
+func (m *PersistentChecksumManager) Remove(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ChecksumManager.Remove(path)
+	delete(m.states, path)
+	_ = m.save() // Best effort
+}
+
+// Clear removes all cached checksums and saves the empty cache.
+func (m *PersistentChecksumManager) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ChecksumManager.Clear()
+	m.states = make(map[string]fileState)
+	_ = m.save() // Best effort
+}
+
+// Changed reports whether the file at path differs from its cached checksum.
+// If its mtime and size are as recorded, the file is taken to be unchanged
+// without reading it. Otherwise it is hashed with \r\n normalised to \n, as
+// the file tools do; if it still matches, the new mtime and size are recorded.
+// Paths with no cached checksum report false.
+func (m *PersistentChecksumManager) Changed(path string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.states[path]
+	if !ok {
+		return false, nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(s.ModTime) && info.Size() == s.Size {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if m.Compute(data) != s.Checksum {
+		return true, nil
+	}
+	s.ModTime, s.Size = info.ModTime(), info.Size()
+	m.states[path] = s
+	_ = m.save() // Best effort
+	return false, nil
+}
+
+// save writes the cache atomically. Callers hold m.mu.
+func (m *PersistentChecksumManager) save() error {
+	data, err := json.MarshalIndent(m.states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal checksums: %w", err)
+	}
+	dir := filepath.Dir(m.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create checksum dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".checksums-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Delete removes the saved cache file.
+func (m *PersistentChecksumManager) Delete() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := os.Remove(m.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package hash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersistentChecksumManager_SurvivesReload(t *testing.T) {
+	dir := t.TempDir()
+	store := filepath.Join(dir, "s.checksums")
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadChecksumManager(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Update(file, m.Compute([]byte("hello")))
+	m.Update("/gone.txt", "x")
+	m.Remove("/gone.txt")
+
+	reloaded, err := LoadChecksumManager(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := reloaded.Get(file); !ok || got != m.Compute([]byte("hello")) {
+		t.Errorf("Get after reload = %q, %v", got, ok)
+	}
+	if _, ok := reloaded.Get("/gone.txt"); ok {
+		t.Error("removed entry should not be restored")
+	}
+}
+
+func TestPersistentChecksumManager_MissingFileIsEmpty(t *testing.T) {
+	m, err := LoadChecksumManager(filepath.Join(t.TempDir(), "none.checksums"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get("/a.txt"); ok {
+		t.Error("expected empty cache")
+	}
+}
+
+func TestPersistentChecksumManager_CorruptFileIsEmpty(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "s.checksums")
+	if err := os.WriteFile(store, []byte(`{"/a.txt": {"checksum": "ab`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadChecksumManager(store)
+	if err == nil {
+		t.Fatal("expected the parse error to be reported")
+	}
+	if m == nil {
+		t.Fatal("expected an empty cache alongside the error")
+	}
+	if _, ok := m.Get("/a.txt"); ok {
+		t.Error("expected empty cache")
+	}
+}
+
+func TestPersistentChecksumManager_Changed(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(file, []byte("a\r\nb\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadChecksumManager(filepath.Join(dir, "s.checksums"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Update(file, m.Compute([]byte("a\nb\n")))
+
+	if changed, err := m.Changed(file); changed || err != nil {
+		t.Errorf("untouched file: Changed = %v, %v", changed, err)
+	}
+
+	// Touched but same content: hashed, found equal, and the new mtime is recorded.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := m.Changed(file); changed || err != nil {
+		t.Errorf("touched file: Changed = %v, %v", changed, err)
+	}
+	if s := m.states[file]; !s.ModTime.Equal(later) {
+		t.Errorf("expected mtime to be refreshed, got %v", s.ModTime)
+	}
+
+	if err := os.WriteFile(file, []byte("a\r\nc\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := m.Changed(file); !changed || err != nil {
+		t.Errorf("modified file: Changed = %v, %v", changed, err)
+	}
+
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := m.Changed(file); !changed || err != nil {
+		t.Errorf("deleted file: Changed = %v, %v", changed, err)
+	}
+
+	if changed, err := m.Changed(filepath.Join(dir, "unknown.txt")); changed || err != nil {
+		t.Errorf("uncached file: Changed = %v, %v", changed, err)
+	}
+}
@@ -8,4 +8,6 @@ import (
 
 var (
 	ErrOutsideWorkspace = errors.New("path is outside workspace root")
+	ErrSymlinkEscape    = errors.New("path leaves workspace root through a symlink")
+	ErrProtected        = errors.New("path is protected")
 )
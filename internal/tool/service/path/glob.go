@@ -0,0 +1,54 @@
+package path
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Match reports whether a slash-separated, workspace-relative name matches pattern.
+// ** matches any number of directories; other segments follow filepath.Match.
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// CouldMatch reports whether names below the directory dir could match pattern,
+// so a walk can skip directories that cannot contain a match.
+func CouldMatch(pattern, dir string) bool {
+	if dir == "" {
+		return true
+	}
+	return couldMatch(strings.Split(pattern, "/"), strings.Split(dir, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pattern[0], name[0])
+	return ok && matchSegments(pattern[1:], name[1:])
+}
+
+func couldMatch(pattern, dir []string) bool {
+	if len(dir) == 0 {
+		return len(pattern) > 0
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if pattern[0] == "**" {
+		return true
+	}
+	ok, _ := filepath.Match(pattern[0], dir[0])
+	return ok && couldMatch(pattern[1:], dir[1:])
+}
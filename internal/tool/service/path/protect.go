@@ -0,0 +1,139 @@
+package path
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/config"
+)
+
+// Access is a kind of use a tool makes of a path.
+type Access uint8
+
+const (
+	AccessRead  Access = 1 << iota // Reading or searching file content
+	AccessWrite                    // Creating, changing, moving or deleting
+	AccessList                     // Showing the path in a listing or search result
+)
+
+func (a Access) String() string {
+	var verbs []string
+	if a&AccessRead != 0 {
+		verbs = append(verbs, "reading")
+	}
+	if a&AccessWrite != 0 {
+		verbs = append(verbs, "writing")
+	}
+	if a&AccessList != 0 {
+		verbs = append(verbs, "listing")
+	}
+	return strings.Join(verbs, " or ")
+}
+
+// Protection holds the protected path rules of the config.
+type Protection struct {
+	rules []protectedPath
+}
+
+type protectedPath struct {
+	glob   string
+	denied Access
+}
+
+// NewProtection builds the rules from config. Rules that deny nothing are dropped.
+func NewProtection(paths []config.ProtectedPathConfig) *Protection {
+	p := &Protection{}
+	for _, pp := range paths {
+		var denied Access
+		if pp.NoRead {
+			denied |= AccessRead
+		}
+		if pp.NoWrite {
+			denied |= AccessWrite
+		}
+		if pp.NoList {
+			denied |= AccessList
+		}
+		if denied != 0 {
+			p.rules = append(p.rules, protectedPath{glob: strings.TrimSuffix(pp.Glob, "/"), denied: denied})
+		}
+	}
+	return p
+}
+
+// Check returns an error wrapping ErrProtected if a rule denies access to the
+// workspace-relative path rel. A rule matching a parent directory of rel covers
+// rel as well. The workspace root itself is never protected.
+func (p *Protection) Check(rel string, access Access) error {
+	if rel == "" {
+		return nil
+	}
+	for _, rule := range p.rules {
+		denied := rule.denied & access
+		if denied == 0 {
+			continue
+		}
+		for name := rel; ; {
+			if Match(rule.glob, name) {
+				return fmt.Errorf("%w: %s matches %q, which does not allow %s", ErrProtected, rel, rule.glob, denied)
+			}
+			i := strings.LastIndex(name, "/")
+			if i < 0 {
+				break
+			}
+			name = name[:i]
+		}
+	}
+	return nil
+}
+
+// Guard is a Resolver that also refuses protected paths, so every tool given
+// one gets the same answer. Each tool gets a Guard for the access it needs.
+type Guard struct {
+	resolver   *Resolver
+	protection *Protection
+	access     Access
+}
+
+// NewGuard creates a Guard that resolves paths with resolver and checks them
+// against protection for access.
+func NewGuard(resolver *Resolver, protection *Protection, access Access) *Guard {
+	if resolver == nil {
+		panic("resolver is required")
+	}
+	if protection == nil {
+		panic("protection is required")
+	}
+	return &Guard{
+		resolver:   resolver,
+		protection: protection,
+		access:     access,
+	}
+}
+
+// Abs resolves path like Resolver.Abs and fails if it is protected.
+func (g *Guard) Abs(path string) (string, error) {
+	if _, err := g.Rel(path); err != nil {
+		return "", err
+	}
+	return g.resolver.Abs(path)
+}
+
+// Rel resolves path like Resolver.Rel and fails if it is protected. Where the
+// path leads once symlinks are followed is checked too, so a link to a
+// protected file is refused like the file itself.
+func (g *Guard) Rel(path string) (string, error) {
+	rel, realRel, err := g.resolver.rels(path)
+	if err != nil {
+		return "", err
+	}
+	if err := g.protection.Check(rel, g.access); err != nil {
+		return "", err
+	}
+	if realRel != rel {
+		if err := g.protection.Check(realRel, g.access); err != nil {
+			return "", fmt.Errorf("%s leads to a protected path: %w", rel, err)
+		}
+	}
+	return rel, nil
+}
@@ -0,0 +1,128 @@
+package path
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+)
+
+func TestProtectionCheck(t *testing.T) {
+	protection := NewProtection(config.DefaultProtectedPaths())
+
+	tests := []struct {
+		name      string
+		rel       string
+		access    Access
+		protected bool
+	}{
+		{"env file at root", ".env", AccessRead, true},
+		{"env file in subdirectory", "services/api/.env", AccessRead, true},
+		{"env variant", "config/.env.production", AccessWrite, true},
+		{"private key", "certs/server.key", AccessRead, true},
+		{"terraform state", "infra/terraform.tfstate", AccessRead, true},
+		{"env template is not a secret", "config/env.example", AccessRead, false},
+		{"git config can be read", ".git/config", AccessRead, false},
+		{"git config cannot be written", ".git/config", AccessWrite, true},
+		{"git directory is not listed", ".git", AccessList, true},
+		{"nested git entry is not listed", ".git/objects/ab", AccessList, true},
+		{"gitignore is ordinary", ".gitignore", AccessRead | AccessWrite | AccessList, false},
+		{"workspace root", "", AccessRead | AccessWrite | AccessList, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := protection.Check(tt.rel, tt.access)
+			if tt.protected {
+				if !errors.Is(err, ErrProtected) {
+					t.Fatalf("Check(%q) = %v, want ErrProtected", tt.rel, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Check(%q) = %v, want nil", tt.rel, err)
+			}
+		})
+	}
+}
+
+func TestProtectionCheckMessage(t *testing.T) {
+	protection := NewProtection([]config.ProtectedPathConfig{{Glob: "secrets/", NoRead: true, NoWrite: true}})
+
+	err := protection.Check("secrets/db.json", AccessRead|AccessList)
+	if err == nil {
+		t.Fatal("expected error for a file in a protected directory")
+	}
+	want := `path is protected: secrets/db.json matches "secrets", which does not allow reading`
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	protection := NewProtection(config.DefaultProtectedPaths())
+	guard := NewGuard(NewResolver("/workspace"), protection, AccessRead)
+
+	abs, err := guard.Abs("src/main.go")
+	if err != nil || abs != "/workspace/src/main.go" {
+		t.Errorf("Abs(src/main.go) = %q, %v", abs, err)
+	}
+
+	if _, err := guard.Abs("/workspace/deploy/.env"); !errors.Is(err, ErrProtected) {
+		t.Errorf("Abs(.env) error = %v, want ErrProtected", err)
+	}
+	if _, err := guard.Rel("deploy/.env"); !errors.Is(err, ErrProtected) {
+		t.Errorf("Rel(.env) error = %v, want ErrProtected", err)
+	}
+	if _, err := guard.Abs("../outside"); !errors.Is(err, ErrOutsideWorkspace) {
+		t.Errorf("Abs(../outside) error = %v, want ErrOutsideWorkspace", err)
+	}
+
+	// The guard only checks the access it was created for.
+	rel, err := guard.Rel(".git/HEAD")
+	if err != nil || rel != ".git/HEAD" {
+		t.Errorf("Rel(.git/HEAD) = %q, %v", rel, err)
+	}
+	writer := NewGuard(NewResolver("/workspace"), protection, AccessWrite)
+	if _, err := writer.Abs(".git/HEAD"); err == nil || !strings.Contains(err.Error(), "does not allow writing") {
+		t.Errorf("write guard Abs(.git/HEAD) error = %v", err)
+	}
+}
+
+func TestGuardSymlinks(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve tmp dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "secrets"), 0o755); err != nil {
+		t.Fatalf("failed to create secrets: %v", err)
+	}
+	for _, name := range []string{".env", "secrets/db.json", "README.md"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), 0o600); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	links := map[string]string{
+		"notes.txt": ".env",      // Link to a protected file
+		"config":    "secrets",   // Link to a protected directory
+		"docs.md":   "README.md", // Link to an ordinary file
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatalf("failed to create link %s: %v", name, err)
+		}
+	}
+
+	protection := NewProtection(append(config.DefaultProtectedPaths(), config.ProtectedPathConfig{Glob: "secrets/", NoRead: true}))
+	guard := NewGuard(NewResolver(root), protection, AccessRead)
+
+	for _, p := range []string{".env", "notes.txt", "config/db.json"} {
+		if _, err := guard.Abs(p); !errors.Is(err, ErrProtected) {
+			t.Errorf("Abs(%s) error = %v, want ErrProtected", p, err)
+		}
+	}
+	if abs, err := guard.Abs("docs.md"); err != nil || abs != filepath.Join(root, "docs.md") {
+		t.Errorf("Abs(docs.md) = %q, %v", abs, err)
+	}
+}
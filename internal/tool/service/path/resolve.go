@@ -7,9 +7,14 @@ import (
 	"strings"
 )
 
+// maxSymlinks caps the links followed while resolving one path, as the kernel's
+// ELOOP limit does, so a symlink loop fails instead of hanging.
+const maxSymlinks = 40
+
 // Resolver provides path resolution within a workspace boundary.
 type Resolver struct {
 	workspaceRoot string
+	realRoot      string // workspaceRoot with symlinks resolved, for containment checks
 }
 
 // NewResolver creates a new path resolver for the given workspace.
@@ -17,8 +22,13 @@ func NewResolver(workspaceRoot string) *Resolver {
 	if workspaceRoot == "" {
 		panic("workspaceRoot is required")
 	}
+	realRoot, err := filepath.EvalSymlinks(workspaceRoot)
+	if err != nil {
+		realRoot = workspaceRoot // Not created yet; nothing to resolve
+	}
 	return &Resolver{
 		workspaceRoot: workspaceRoot,
+		realRoot:      realRoot,
 	}
 }
 
@@ -47,13 +57,27 @@ func CanonicaliseRoot(root string) (string, error) {
 }
 
 // Abs resolves any path to absolute and validates it is within the workspace boundary.
-// It cleans the path and ensures it does not escape the workspace root.
+// It cleans the path and ensures it does not escape the workspace root, lexically
+// and then through symlinks: the components that exist are resolved, and whatever
+// does not exist yet is taken to be created where the resolved prefix points.
+//
+// The returned path is the cleaned one, not the resolved one, so a symlink inside
+// the workspace can still be moved or deleted as itself.
+//
+// Note: A symlink created or changed after the check is not caught. The check
+// guards against links already in the workspace, not against a concurrent attacker.
 func (r *Resolver) Abs(path string) (string, error) {
+	abs, _, err := r.resolve(path)
+	return abs, err
+}
+
+// resolve returns the cleaned absolute path Abs returns and where it really
+// leads, after checking both are inside the workspace.
+func (r *Resolver) resolve(path string) (abs, real string, err error) {
 	if r.workspaceRoot == "" {
-		return "", fmt.Errorf("workspace root not set")
+		return "", "", fmt.Errorf("workspace root not set")
 	}
 
-	var abs string
 	if filepath.IsAbs(path) {
 		abs = filepath.Clean(path)
 	} else {
@@ -61,23 +85,106 @@ func (r *Resolver) Abs(path string) (string, error) {
 	}
 
 	// Boundary check: must be the root itself or a child of the root
-	if !strings.HasPrefix(abs, r.workspaceRoot+"/") && abs != r.workspaceRoot {
-		return "", ErrOutsideWorkspace
+	if !within(abs, r.workspaceRoot) {
+		return "", "", ErrOutsideWorkspace
 	}
 
-	return abs, nil
+	// Symlink check: where the path really leads must be inside as well
+	real, err = resolve(abs)
+	if err != nil {
+		return "", "", fmt.Errorf("resolve %s: %w", abs, err)
+	}
+	if !within(real, r.realRoot) {
+		return "", "", fmt.Errorf("%w: %s -> %s", ErrSymlinkEscape, abs, real)
+	}
+
+	return abs, real, nil
+}
+
+// within reports whether path is root or a child of root.
+func within(path, root string) bool {
+	return path == root || strings.HasPrefix(path, root+"/")
+}
+
+// resolve follows the symlinks in an absolute, clean path component by component,
+// including a dangling link at the end. Components that do not exist are kept as
+// they are, so a file that is about to be created resolves to where it will land.
+func resolve(path string) (string, error) {
+	links := 0
+	return resolveFrom("/", strings.Split(strings.TrimPrefix(path, "/"), "/"), &links)
+}
+
+// resolveFrom resolves parts one at a time below current, which is already
+// resolved. A ".." goes up from where the path so far really leads, as the
+// kernel does, so "link/.." is the link target's parent, not the link's.
+func resolveFrom(current string, parts []string, links *int) (string, error) {
+	for _, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+			continue
+		}
+		next := filepath.Join(current, part)
+		info, err := os.Lstat(next)
+		if os.IsNotExist(err) {
+			current = next // Nothing below it exists either, but a later ".." may come back up
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		*links++
+		if *links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links")
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			current = "/"
+		}
+		if current, err = resolveFrom(current, strings.Split(target, "/"), links); err != nil {
+			return "", err
+		}
+	}
+	return current, nil
 }
 
 // Rel resolves any path to relative to the workspace root and validates it is within the boundary.
 func (r *Resolver) Rel(path string) (string, error) {
-	abs, err := r.Abs(path)
+	rel, _, err := r.rels(path)
+	return rel, err
+}
+
+// rels returns the path Rel returns and, relative to the workspace as well,
+// where it really leads once symlinks are followed.
+func (r *Resolver) rels(path string) (rel, realRel string, err error) {
+	abs, real, err := r.resolve(path)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	if rel, err = relTo(r.workspaceRoot, abs); err != nil {
+		return "", "", err
 	}
+	if realRel, err = relTo(r.realRoot, real); err != nil {
+		return "", "", err
+	}
+	return rel, realRel, nil
+}
 
-	rel, err := filepath.Rel(r.workspaceRoot, abs)
+// relTo returns path relative to root with slashes, or "" for root itself.
+func relTo(root, path string) (string, error) {
+	rel, err := filepath.Rel(root, path)
 	if err != nil {
-		// This should theoretically not happen if Abs passed
+		// This should theoretically not happen once the boundary check passed
 		return "", ErrOutsideWorkspace
 	}
 
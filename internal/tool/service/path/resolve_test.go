@@ -169,3 +169,152 @@ func TestCanonicaliseRoot(t *testing.T) {
 		}
 	})
 }
+
+func TestAbsSymlinks(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve tmp dir: %v", err)
+	}
+	root := filepath.Join(base, "workspace")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "src"), filepath.Join(outside, "deep")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("key"), 0o600); err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+
+	links := map[string]string{
+		"etc":        outside,                       // Absolute link out
+		"up":         "../outside",                  // Relative link out
+		"chain":      "etc",                         // Link to a link out
+		"dangling":   filepath.Join(outside, "new"), // Points out at nothing yet
+		"alias":      "src",                         // Stays inside
+		"src/parent": "..",                          // Back to the root, still inside
+		"loop":       "loop",
+		"deep":       filepath.Join(outside, "deep"), // A directory out, for ".." after it
+		"updeep":     "deep/../secret",               // ".." from where deep leads, not from the root
+		"hop":        "alias/../src",                 // Up from the resolved alias, still inside
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatalf("failed to create link %s: %v", name, err)
+		}
+	}
+
+	resolver := NewResolver(root)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		err      error
+	}{
+		{
+			name:     "existing file through link out",
+			input:    "etc/secret",
+			expected: "",
+			err:      ErrSymlinkEscape,
+		},
+		{
+			name:     "link out itself",
+			input:    "etc",
+			expected: "",
+			err:      ErrSymlinkEscape,
+		},
+		{
+			name:     "new file through link out",
+			input:    "etc/new/file.txt",
+			expected: "",
+			err:      ErrSymlinkEscape,
+		},
+		{
+			name:     "relative link out",
+			input:    "up/secret",
+			expected: "",
+			err:      ErrSymlinkEscape,
+		},
+		{
+			name:     "chained links out",
+			input:    "chain/secret",
+			expected: "",
+			err:      ErrSymlinkEscape,
+		},
+		{
+			name:     "dangling link out",
+			input:    "dangling",
+			expected: "",
+			err:      ErrSymlinkEscape,
+		},
+		{
+			name:     "dot-dot after a link out",
+			input:    "updeep",
+			expected: "",
+			err:      ErrSymlinkEscape,
+		},
+		{
+			name:     "dot-dot after a link inside",
+			input:    "hop/main.go",
+			expected: filepath.Join(root, "hop", "main.go"),
+			err:      nil,
+		},
+		{
+			name:     "link inside keeps the link path",
+			input:    "alias/main.go",
+			expected: filepath.Join(root, "alias", "main.go"),
+			err:      nil,
+		},
+		{
+			name:     "link back to root",
+			input:    "src/parent/src",
+			expected: filepath.Join(root, "src", "parent", "src"),
+			err:      nil,
+		},
+		{
+			name:     "new file in plain directory",
+			input:    "src/new/file.go",
+			expected: filepath.Join(root, "src", "new", "file.go"),
+			err:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			abs, err := resolver.Abs(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if abs != tt.expected {
+				t.Errorf("expected abs %q, got %q", tt.expected, abs)
+			}
+		})
+	}
+
+	t.Run("symlink loop fails", func(t *testing.T) {
+		if _, err := resolver.Abs("loop/file"); err == nil {
+			t.Fatal("expected error for symlink loop")
+		}
+	})
+
+	t.Run("Rel rejects link out", func(t *testing.T) {
+		if _, err := resolver.Rel(filepath.Join(root, "etc", "secret")); !errors.Is(err, ErrSymlinkEscape) {
+			t.Fatalf("expected ErrSymlinkEscape, got %v", err)
+		}
+	})
+
+	t.Run("root given through a symlink", func(t *testing.T) {
+		rootLink := filepath.Join(base, "workspace-link")
+		if err := os.Symlink(root, rootLink); err != nil {
+			t.Fatalf("failed to create root link: %v", err)
+		}
+		abs, err := NewResolver(rootLink).Abs("src/main.go")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if abs != filepath.Join(rootLink, "src", "main.go") {
+			t.Errorf("unexpected abs %q", abs)
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package watch
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/tool/helper/content"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	// maxSnapshotSize is the largest file whose last-read content is kept for diffs.
+	// Larger files are still watched, but changes to them are reported without a diff.
+	maxSnapshotSize = 256 * 1024
+
+	// maxDiffLines caps the diff shown for one file.
+	maxDiffLines = 20
+)
+
+// fileSystem defines the filesystem operations needed to watch files.
+type fileSystem interface {
+	Stat(path string) (os.FileInfo, error)
+	ReadFile(path string) ([]byte, error)
+}
+
+// checksumSource lists the files the agent has read or written, with the
+// checksum of the content it last saw.
+type checksumSource interface {
+	Paths() []string
+	Get(path string) (checksum string, ok bool)
+	Compute(data []byte) string
+}
+
+// pathResolver maps absolute paths into the workspace.
+type pathResolver interface {
+	Rel(path string) (string, error)
+}
+
+// ignoreMatcher reports gitignored paths.
+type ignoreMatcher interface {
+	ShouldIgnore(relativePath string) bool
+}
+
+// Change is a file that differs from what the agent last read.
+type Change struct {
+	Path    string // Workspace-relative
+	Deleted bool
+	Diff    string // Unified diff from the last-read content; empty if that is unknown
+}
+
+// snapshot is the watcher's view of one file.
+type snapshot struct {
+	checksum string // Cached checksum the snapshot was taken for
+	content  string // Content matching checksum, \n-normalised; empty if unknown
+	known    bool   // Whether content is set
+	modTime  time.Time
+	size     int64
+	deleted  bool
+	reported string // Checksum of the content last reported, so a change is reported once
+}
+
+// Watcher polls the files known to a checksum cache for changes made outside the agent.
+//
+// A file is checked by mtime and size first and only read when those differ. The
+// content the agent last read is kept (up to maxSnapshotSize) so a change can be
+// shown as a diff. Files outside the workspace or matched by .gitignore are skipped.
+type Watcher struct {
+	mu        sync.Mutex
+	fs        fileSystem
+	checksums checksumSource
+	resolver  pathResolver
+	ignore    ignoreMatcher
+	files     map[string]*snapshot
+}
+
+// NewWatcher creates a new Watcher with injected dependencies.
+func NewWatcher(fs fileSystem, checksums checksumSource, resolver pathResolver, ignore ignoreMatcher) *Watcher {
+	if fs == nil {
+		panic("fs is required")
+	}
+	if checksums == nil {
+		panic("checksums is required")
+	}
+	if resolver == nil {
+		panic("resolver is required")
+	}
+	if ignore == nil {
+		panic("ignore is required")
+	}
+	return &Watcher{
+		fs:        fs,
+		checksums: checksums,
+		resolver:  resolver,
+		ignore:    ignore,
+		files:     make(map[string]*snapshot),
+	}
+}
+
+// Poll returns the files changed outside the agent since the previous poll, in
+// path order. A file is reported again only if it changes again; once the agent
+// reads or writes it, the new content becomes the baseline.
+func (w *Watcher) Poll() []Change {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	paths := w.checksums.Paths()
+	sort.Strings(paths)
+
+	live := make(map[string]bool, len(paths))
+	var changes []Change
+	for _, abs := range paths {
+		rel, err := w.resolver.Rel(abs)
+		if err != nil || w.ignore.ShouldIgnore(rel) {
+			continue
+		}
+		checksum, ok := w.checksums.Get(abs)
+		if !ok {
+			continue
+		}
+		live[abs] = true
+		if c, changed := w.check(abs, rel, checksum); changed {
+			changes = append(changes, c)
+		}
+	}
+
+	for abs := range w.files {
+		if !live[abs] {
+			delete(w.files, abs)
+		}
+	}
+	return changes
+}
+
+// Changes polls and describes the changes for the LLM, or returns "" if there are none.
+func (w *Watcher) Changes() string {
+	return Note(w.Poll())
+}
+
+// check compares one file with the agent's last view of it.
+func (w *Watcher) check(abs, rel, checksum string) (Change, bool) {
+	snap := w.files[abs]
+	if snap == nil || snap.checksum != checksum {
+		// First sight, or the agent has read or written the file since: new baseline.
+		snap = &snapshot{checksum: checksum}
+		w.files[abs] = snap
+		return w.baseline(abs, rel, snap)
+	}
+
+	info, err := w.fs.Stat(abs)
+	if os.IsNotExist(err) {
+		if snap.deleted {
+			return Change{}, false
+		}
+		snap.deleted = true
+		return Change{Path: rel, Deleted: true}, true
+	}
+	if err != nil {
+		return Change{}, false
+	}
+	if !snap.deleted && info.ModTime().Equal(snap.modTime) && info.Size() == snap.size {
+		return Change{}, false
+	}
+	snap.deleted = false
+	snap.modTime, snap.size = info.ModTime(), info.Size()
+
+	current, sum, ok := w.read(abs)
+	if !ok || sum == snap.reported {
+		return Change{}, false
+	}
+	if sum == checksum {
+		snap.reported = "" // Changed back to what the agent read
+		return Change{}, false
+	}
+	snap.reported = sum
+	return w.change(rel, snap, current), true
+}
+
+// baseline records the file as the agent last saw it. If it already differs from
+// the cached checksum, it changed after the read and before this poll.
+func (w *Watcher) baseline(abs, rel string, snap *snapshot) (Change, bool) {
+	info, err := w.fs.Stat(abs)
+	if os.IsNotExist(err) {
+		snap.deleted = true
+		return Change{Path: rel, Deleted: true}, true
+	}
+	if err != nil {
+		return Change{}, false
+	}
+	snap.modTime, snap.size = info.ModTime(), info.Size()
+
+	current, sum, ok := w.read(abs)
+	if !ok {
+		return Change{}, false
+	}
+	if sum != snap.checksum {
+		snap.reported = sum
+		return Change{Path: rel}, true
+	}
+	if len(current) <= maxSnapshotSize {
+		snap.content, snap.known = current, true
+	}
+	return Change{}, false
+}
+
+// read returns the content of abs, transcoded to UTF-8 and \n-normalised for diffs,
+// and its checksum, as the file tools compute it.
+func (w *Watcher) read(abs string) (string, string, bool) {
+	data, err := w.fs.ReadFile(abs)
+	if err != nil {
+		return "", "", false
+	}
+	sum := w.checksums.Compute(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")))
+	text, _, err := content.Decode(data)
+	if err != nil {
+		text = string(data)
+	}
+	return strings.ReplaceAll(text, "\r\n", "\n"), sum, true
+}
+
+func (w *Watcher) change(rel string, snap *snapshot, current string) Change {
+	c := Change{Path: rel}
+	if snap.known {
+		c.Diff = shortDiff(snap.content, current)
+	}
+	return c
+}
+
+// shortDiff returns a unified diff with one line of context, cut to maxDiffLines.
+func shortDiff(old, new string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:       difflib.SplitLines(old),
+		B:       difflib.SplitLines(new),
+		Context: 1,
+	})
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	if len(lines) > maxDiffLines {
+		more := len(lines) - maxDiffLines
+		lines = append(lines[:maxDiffLines], fmt.Sprintf("... (%d more diff lines)", more))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Note describes changes for the LLM, or returns "" if there are none.
+func Note(changes []Change) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("[Files changed outside the agent since your last read. Re-read them before editing.]")
+	for _, c := range changes {
+		switch {
+		case c.Deleted:
+			fmt.Fprintf(&sb, "\n- %s (deleted)", c.Path)
+		case c.Diff == "":
+			fmt.Fprintf(&sb, "\n- %s (modified)", c.Path)
+		default:
+			fmt.Fprintf(&sb, "\n- %s:", c.Path)
+			for _, line := range strings.Split(c.Diff, "\n") {
+				sb.WriteString("\n    " + line)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,174 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool/service/fs"
+	"github.com/Cyclone1070/iav/internal/tool/service/hash"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+)
+
+type mockIgnore map[string]bool
+
+func (m mockIgnore) ShouldIgnore(rel string) bool { return m[rel] }
+
+type fixture struct {
+	root      string
+	checksums *hash.ChecksumManager
+	watcher   *Watcher
+}
+
+func newFixture(t *testing.T, ignored ...string) *fixture {
+	t.Helper()
+	root, err := path.CanonicaliseRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignore := mockIgnore{}
+	for _, p := range ignored {
+		ignore[p] = true
+	}
+	checksums := hash.NewChecksumManager()
+	w := NewWatcher(fs.NewOSFileSystem(config.DefaultConfig()), checksums, path.NewResolver(root), ignore)
+	return &fixture{root: root, checksums: checksums, watcher: w}
+}
+
+// read writes a file and caches its checksum, as if the agent had read it.
+func (f *fixture) read(t *testing.T, name, content string) string {
+	t.Helper()
+	abs := filepath.Join(f.root, name)
+	f.write(t, abs, content)
+	f.checksums.Update(abs, f.checksums.Compute([]byte(content)))
+	return abs
+}
+
+// write changes a file with a distinct mtime, as an editor would.
+func (f *fixture) write(t *testing.T, abs, content string) {
+	t.Helper()
+	if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Duration(len(content)) * time.Second)
+	if err := os.Chtimes(abs, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher_ReportsExternalEditOnce(t *testing.T) {
+	f := newFixture(t)
+	abs := f.read(t, "main.go", "package main\n\nvar x = 1\n")
+
+	if changes := f.watcher.Poll(); len(changes) != 0 {
+		t.Fatalf("expected no changes on first poll, got %+v", changes)
+	}
+
+	f.write(t, abs, "package main\n\nvar x = 22\n")
+	changes := f.watcher.Poll()
+	if len(changes) != 1 || changes[0].Path != "main.go" {
+		t.Fatalf("expected main.go to be reported, got %+v", changes)
+	}
+	if !strings.Contains(changes[0].Diff, "-var x = 1") || !strings.Contains(changes[0].Diff, "+var x = 22") {
+		t.Errorf("unexpected diff:\n%s", changes[0].Diff)
+	}
+
+	if changes := f.watcher.Poll(); len(changes) != 0 {
+		t.Errorf("expected change to be reported once, got %+v", changes)
+	}
+
+	// Once the agent re-reads the file, the new content is the baseline.
+	f.checksums.Update(abs, f.checksums.Compute([]byte("package main\n\nvar x = 22\n")))
+	if changes := f.watcher.Poll(); len(changes) != 0 {
+		t.Errorf("expected no changes after re-read, got %+v", changes)
+	}
+}
+
+func TestWatcher_TouchWithoutChangeIsNotReported(t *testing.T) {
+	f := newFixture(t)
+	abs := f.read(t, "a.txt", "same\r\n")
+	f.checksums.Update(abs, f.checksums.Compute([]byte("same\n"))) // Normalised, as read_file stores it
+	f.watcher.Poll()
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(abs, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := f.watcher.Poll(); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestWatcher_ReportsDeletion(t *testing.T) {
+	f := newFixture(t)
+	abs := f.read(t, "a.txt", "a\n")
+	f.watcher.Poll()
+
+	if err := os.Remove(abs); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := f.watcher.Poll()
+	if len(changes) != 1 || !changes[0].Deleted {
+		t.Fatalf("expected deletion, got %+v", changes)
+	}
+	if note := Note(changes); !strings.Contains(note, "- a.txt (deleted)") {
+		t.Errorf("unexpected note: %s", note)
+	}
+	if changes := f.watcher.Poll(); len(changes) != 0 {
+		t.Errorf("expected deletion to be reported once, got %+v", changes)
+	}
+}
+
+func TestWatcher_SkipsIgnoredAndOutsideWorkspace(t *testing.T) {
+	f := newFixture(t, "build/out.txt")
+	if err := os.Mkdir(filepath.Join(f.root, "build"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ignored := f.read(t, "build/out.txt", "a\n")
+	f.checksums.Update("/elsewhere/x.txt", "x")
+	f.watcher.Poll()
+
+	f.write(t, ignored, "b\n")
+
+	if changes := f.watcher.Poll(); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestWatcher_ChangedBeforeFirstPollHasNoDiff(t *testing.T) {
+	f := newFixture(t)
+	abs := f.read(t, "a.txt", "a\n")
+	f.write(t, abs, "bb\n")
+
+	changes := f.watcher.Poll()
+
+	if len(changes) != 1 || changes[0].Diff != "" {
+		t.Fatalf("expected change without diff, got %+v", changes)
+	}
+	if note := f.watcher.Changes(); note != "" {
+		t.Errorf("expected nothing new, got %s", note)
+	}
+	if note := Note(changes); !strings.Contains(note, "- a.txt (modified)") {
+		t.Errorf("unexpected note: %s", note)
+	}
+}
+
+func TestShortDiff_Truncates(t *testing.T) {
+	var old, new strings.Builder
+	for i := range 40 {
+		old.WriteString("old " + string(rune('a'+i%26)) + "\n")
+		new.WriteString("new " + string(rune('a'+i%26)) + "\n")
+	}
+
+	diff := shortDiff(old.String(), new.String())
+
+	lines := strings.Split(diff, "\n")
+	if len(lines) != maxDiffLines+1 || !strings.Contains(lines[maxDiffLines], "more diff lines") {
+		t.Errorf("expected truncated diff, got %d lines:\n%s", len(lines), diff)
+	}
+}
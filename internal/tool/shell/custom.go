@@ -0,0 +1,190 @@
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/executor"
+	"github.com/Cyclone1070/iav/internal/workflow/toolmanager"
+)
+
+// CustomTool runs a command declared in the custom_tools config section.
+// Unlike ShellTool, the LLM only supplies the template's parameters, never the command.
+type CustomTool struct {
+	spec            config.CustomToolConfig
+	commandExecutor commandExecutor
+	config          *config.Config
+	pathResolver    pathResolver
+}
+
+// NewCustomTool creates a tool from its config entry.
+// Returns an error if the command template references an undeclared parameter
+// or puts a placeholder where a shell script cannot quote it.
+func NewCustomTool(
+	spec config.CustomToolConfig,
+	commandExecutor commandExecutor,
+	cfg *config.Config,
+	pathResolver pathResolver,
+) (*CustomTool, error) {
+	if commandExecutor == nil {
+		panic("commandExecutor is required")
+	}
+	if cfg == nil {
+		panic("cfg is required")
+	}
+	if pathResolver == nil {
+		panic("pathResolver is required")
+	}
+
+	for _, name := range templateParams(spec.Command) {
+		if spec.Parameters == nil || spec.Parameters.Properties[name] == nil {
+			return nil, fmt.Errorf("custom tool %s: command references undeclared parameter %s", spec.Name, name)
+		}
+	}
+	if err := checkTemplate(spec.Command); err != nil {
+		return nil, fmt.Errorf("custom tool %s: %w", spec.Name, err)
+	}
+
+	return &CustomTool{
+		spec:            spec,
+		commandExecutor: commandExecutor,
+		config:          cfg,
+		pathResolver:    pathResolver,
+	}, nil
+}
+
+// Name returns the tool's identifier.
+func (t *CustomTool) Name() string {
+	return t.spec.Name
+}
+
+// Declaration returns the tool's schema for the LLM.
+func (t *CustomTool) Declaration() tool.Declaration {
+	// Only the declared parameters reach the command, so others are refused
+	// unless the config allows them.
+	params := &tool.Schema{Type: tool.TypeObject}
+	if t.spec.Parameters != nil {
+		copied := *t.spec.Parameters
+		params = &copied
+	}
+	if params.AdditionalProperties == nil {
+		params.AdditionalProperties = tool.Ptr(false)
+	}
+	return tool.Declaration{
+		Name:        t.spec.Name,
+		Description: t.spec.Description,
+		Parameters:  params,
+	}
+}
+
+// ReadOnly reports whether the command is declared not to modify the workspace.
+func (t *CustomTool) ReadOnly() bool {
+	return t.spec.ReadOnly
+}
+
+// Timeout returns the tool's deadline for the tool manager. It leaves room for the
+// graceful shutdown after the command timeout, so the partial output is still returned.
+func (t *CustomTool) Timeout() time.Duration {
+	return t.commandTimeout() + time.Duration(t.config.Tools.DockerGracefulShutdownMs)*time.Millisecond + time.Second
+}
+
+// Request returns a new request struct for JSON unmarshalling.
+func (t *CustomTool) Request() toolmanager.ToolRequest {
+	return &CustomToolRequest{command: t.spec.Command}
+}
+
+// Execute runs the command and encodes failures in the response for the LLM.
+func (t *CustomTool) Execute(ctx context.Context, req toolmanager.ToolRequest) (toolmanager.ToolResult, error) {
+	r, ok := req.(*CustomToolRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type: %T", req)
+	}
+
+	resp, err := t.Run(ctx, r)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, executor.ErrTimeout) {
+			resp.Error = fmt.Sprintf("command timed out after %s", t.commandTimeout())
+			return resp, nil
+		}
+		return &ShellResponse{ExitCode: -1, Error: err.Error()}, nil
+	}
+	return resp, nil
+}
+
+// Run renders the command template with the request's arguments and executes it
+// in the configured working directory.
+func (t *CustomTool) Run(ctx context.Context, req *CustomToolRequest) (*ShellResponse, error) {
+	argv, err := renderArgv(t.spec.Command, req.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	workingDir := t.spec.WorkingDir
+	if workingDir == "" {
+		workingDir = "."
+	}
+	wdAbs, err := t.pathResolver.Abs(workingDir)
+	if err != nil {
+		return nil, err
+	}
+	wdRel, err := t.pathResolver.Rel(wdAbs)
+	if err != nil {
+		return nil, err
+	}
+
+	result, execErr := t.commandExecutor.RunWithTimeout(ctx, argv, wdAbs, os.Environ(), t.commandTimeout())
+	if result == nil {
+		result = &executor.Result{ExitCode: -1}
+	}
+
+	resp := &ShellResponse{
+		Stdout:     result.Stdout,
+		Stderr:     result.Stderr,
+		WorkingDir: wdRel,
+		ExitCode:   result.ExitCode,
+		Truncated:  result.Truncated,
+	}
+	if execErr != nil {
+		return resp, execErr
+	}
+	return resp, nil
+}
+
+func (t *CustomTool) commandTimeout() time.Duration {
+	seconds := t.spec.TimeoutSeconds
+	if seconds <= 0 {
+		seconds = t.config.Tools.DefaultShellTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// CustomToolRequest holds the arguments for a custom tool's command template.
+type CustomToolRequest struct {
+	Args map[string]any
+
+	command []string // Template, kept for Display
+}
+
+// UnmarshalJSON captures the arguments object as-is.
+func (r *CustomToolRequest) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &r.Args)
+}
+
+// Display returns the command line that will run.
+func (r *CustomToolRequest) Display() string {
+	argv, err := renderArgv(r.command, r.Args)
+	if err != nil {
+		argv = r.command
+	}
+	return strings.Join(argv, " ")
+}
@@ -0,0 +1,194 @@
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/Cyclone1070/iav/internal/tool/service/executor"
+	"github.com/Cyclone1070/iav/internal/tool/service/path"
+)
+
+func newLintSpec() config.CustomToolConfig {
+	return config.CustomToolConfig{
+		Name:        "lint",
+		Description: "Run the linter",
+		Parameters: &tool.Schema{
+			Type:       tool.TypeObject,
+			Properties: map[string]*tool.Schema{"target": {Type: tool.TypeString}},
+		},
+		Command:        []string{"make", "{{target}}"},
+		WorkingDir:     "sub",
+		TimeoutSeconds: 30,
+		ReadOnly:       true,
+	}
+}
+
+func newCustomRequest(t *testing.T, ct *CustomTool, args string) *CustomToolRequest {
+	t.Helper()
+	req := ct.Request().(*CustomToolRequest)
+	if err := json.Unmarshal([]byte(args), req); err != nil {
+		t.Fatalf("unmarshal args: %v", err)
+	}
+	return req
+}
+
+func TestNewCustomTool_UndeclaredParameter_Fails(t *testing.T) {
+	spec := newLintSpec()
+	spec.Command = []string{"make", "{{goal}}"}
+
+	_, err := NewCustomTool(spec, &mockCommandExecutorForShell{}, config.DefaultConfig(), path.NewResolver("/workspace"))
+	if err == nil || !strings.Contains(err.Error(), "undeclared parameter goal") {
+		t.Fatalf("expected undeclared parameter error, got %v", err)
+	}
+}
+
+func TestNewCustomTool_QuotedShellPlaceholder_Fails(t *testing.T) {
+	spec := newLintSpec()
+	spec.Command = []string{"bash", "-lc", "make '{{target}}'"}
+
+	_, err := NewCustomTool(spec, &mockCommandExecutorForShell{}, config.DefaultConfig(), path.NewResolver("/workspace"))
+	if err == nil || !strings.Contains(err.Error(), "parameter target is inside quotes") {
+		t.Fatalf("expected quoted placeholder error, got %v", err)
+	}
+}
+
+func TestNewCustomTool_PlaceholderInInterpreterCode_Fails(t *testing.T) {
+	spec := newLintSpec()
+	spec.Command = []string{"python3", "-c", "print({{target}})"}
+
+	_, err := NewCustomTool(spec, &mockCommandExecutorForShell{}, config.DefaultConfig(), path.NewResolver("/workspace"))
+	if err == nil || !strings.Contains(err.Error(), "parameter target is in the code run by python3") {
+		t.Fatalf("expected inline code placeholder error, got %v", err)
+	}
+}
+
+func TestCustomTool_Declaration(t *testing.T) {
+	ct, err := NewCustomTool(newLintSpec(), &mockCommandExecutorForShell{}, config.DefaultConfig(), path.NewResolver("/workspace"))
+	if err != nil {
+		t.Fatalf("NewCustomTool failed: %v", err)
+	}
+
+	decl := ct.Declaration()
+	if decl.Name != "lint" || decl.Description != "Run the linter" {
+		t.Errorf("unexpected declaration: %+v", decl)
+	}
+	if decl.Parameters.Properties["target"] == nil {
+		t.Error("expected target parameter in declaration")
+	}
+	if !ct.ReadOnly() {
+		t.Error("expected tool to be read-only")
+	}
+	if ct.Timeout() <= 30*time.Second {
+		t.Errorf("expected tool timeout to exceed the command timeout, got %s", ct.Timeout())
+	}
+}
+
+func TestCustomTool_Execute_RunsRenderedCommand(t *testing.T) {
+	var gotCmd []string
+	var gotDir string
+	var gotTimeout time.Duration
+	exec := &mockCommandExecutorForShell{}
+	exec.runWithTimeoutFunc = func(ctx context.Context, command []string, dir string, env []string, timeout time.Duration) (*executor.Result, error) {
+		gotCmd, gotDir, gotTimeout = command, dir, timeout
+		return &executor.Result{Stdout: "ok\n", ExitCode: 0}, nil
+	}
+	ct, err := NewCustomTool(newLintSpec(), exec, config.DefaultConfig(), path.NewResolver("/workspace"))
+	if err != nil {
+		t.Fatalf("NewCustomTool failed: %v", err)
+	}
+
+	req := newCustomRequest(t, ct, `{"target": "lint"}`)
+	if req.Display() != "make lint" {
+		t.Errorf("unexpected request display %q", req.Display())
+	}
+
+	res, err := ct.Execute(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !slices.Equal(gotCmd, []string{"make", "lint"}) {
+		t.Errorf("unexpected command %q", gotCmd)
+	}
+	if gotDir != "/workspace/sub" {
+		t.Errorf("unexpected working dir %q", gotDir)
+	}
+	if gotTimeout != 30*time.Second {
+		t.Errorf("unexpected timeout %s", gotTimeout)
+	}
+	if !res.Success() {
+		t.Error("expected success")
+	}
+	if !strings.Contains(res.LLMContent(), "Exit code: 0") || !strings.Contains(res.LLMContent(), "Stdout:\nok") {
+		t.Errorf("unexpected LLM content %q", res.LLMContent())
+	}
+}
+
+func TestCustomTool_Execute_NonZeroExit_IsFailure(t *testing.T) {
+	exec := &mockCommandExecutorForShell{}
+	exec.runWithTimeoutFunc = func(ctx context.Context, command []string, dir string, env []string, timeout time.Duration) (*executor.Result, error) {
+		return &executor.Result{Stderr: "lint errors", ExitCode: 2}, nil
+	}
+	ct, _ := NewCustomTool(newLintSpec(), exec, config.DefaultConfig(), path.NewResolver("/workspace"))
+
+	res, err := ct.Execute(context.Background(), newCustomRequest(t, ct, `{}`))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if res.Success() {
+		t.Error("expected failure for non-zero exit")
+	}
+	if !strings.Contains(res.LLMContent(), "Exit code: 2") || !strings.Contains(res.LLMContent(), "Stderr:\nlint errors") {
+		t.Errorf("unexpected LLM content %q", res.LLMContent())
+	}
+}
+
+func TestCustomTool_Execute_Timeout_KeepsPartialOutput(t *testing.T) {
+	exec := &mockCommandExecutorForShell{}
+	exec.runWithTimeoutFunc = func(ctx context.Context, command []string, dir string, env []string, timeout time.Duration) (*executor.Result, error) {
+		return &executor.Result{Stdout: "partial", ExitCode: -1}, executor.ErrTimeout
+	}
+	ct, _ := NewCustomTool(newLintSpec(), exec, config.DefaultConfig(), path.NewResolver("/workspace"))
+
+	res, err := ct.Execute(context.Background(), newCustomRequest(t, ct, `{}`))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if res.Success() {
+		t.Error("expected failure on timeout")
+	}
+	if !strings.Contains(res.LLMContent(), "timed out after 30s") || !strings.Contains(res.LLMContent(), "partial") {
+		t.Errorf("unexpected LLM content %q", res.LLMContent())
+	}
+}
+
+func TestCustomTool_Execute_BadArgument_IsFailure(t *testing.T) {
+	ct, _ := NewCustomTool(newLintSpec(), &mockCommandExecutorForShell{}, config.DefaultConfig(), path.NewResolver("/workspace"))
+
+	res, err := ct.Execute(context.Background(), newCustomRequest(t, ct, `{"target": "--version"}`))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if res.Success() || !strings.Contains(res.LLMContent(), "must not start with '-'") {
+		t.Errorf("expected argument error, got %q", res.LLMContent())
+	}
+}
+
+func TestCustomTool_Execute_ContextCancelled_ReturnsError(t *testing.T) {
+	exec := &mockCommandExecutorForShell{}
+	exec.runWithTimeoutFunc = func(ctx context.Context, command []string, dir string, env []string, timeout time.Duration) (*executor.Result, error) {
+		return nil, ctx.Err()
+	}
+	ct, _ := NewCustomTool(newLintSpec(), exec, config.DefaultConfig(), path.NewResolver("/workspace"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ct.Execute(ctx, newCustomRequest(t, ct, `{}`)); err == nil {
+		t.Error("expected context error")
+	}
+}
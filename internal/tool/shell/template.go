@@ -0,0 +1,327 @@
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// placeholderPattern matches {{param}} in a custom tool's argv template.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+
+// shells whose -c script argument needs values quoted rather than substituted verbatim.
+var shells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true}
+
+// Options of env and of the shells that take the next argument as their value.
+var (
+	envValueOptions   = map[string]bool{"-u": true, "--unset": true, "-C": true, "--chdir": true}
+	shellValueOptions = map[string]bool{"-o": true, "+o": true, "-O": true, "+O": true, "--rcfile": true, "--init-file": true}
+)
+
+// interpreter describes how a language runtime takes code on its command line.
+// There is no quoting that keeps a value inert in every language, so placeholders
+// are refused in that code; values have to be passed as separate arguments.
+type interpreter struct {
+	codeOptions  []string // Options whose value is code, like python's -c
+	valueOptions []string // Other options that take the next argument as their value
+	fileOptions  []string // Options naming a program file instead of inline code
+	programArg   bool     // Without a code or file option the first operand is the program, as for awk and sed
+}
+
+// interpreters are keyed by command name without a version suffix, so python3.12 is python.
+var interpreters = map[string]interpreter{
+	"python":    {codeOptions: []string{"-c"}, valueOptions: []string{"-W", "-X"}},
+	"pypy":      {codeOptions: []string{"-c"}, valueOptions: []string{"-W", "-X"}},
+	"node":      {codeOptions: []string{"-e", "--eval", "-p", "--print"}, valueOptions: []string{"-r", "--require", "--import", "-C", "--conditions"}},
+	"nodejs":    {codeOptions: []string{"-e", "--eval", "-p", "--print"}, valueOptions: []string{"-r", "--require", "--import", "-C", "--conditions"}},
+	"bun":       {codeOptions: []string{"-e", "--eval", "-p", "--print"}},
+	"perl":      {codeOptions: []string{"-e", "-E"}},
+	"ruby":      {codeOptions: []string{"-e"}, valueOptions: []string{"-I", "-r", "-C"}},
+	"php":       {codeOptions: []string{"-r"}, valueOptions: []string{"-c", "-d"}, fileOptions: []string{"-f"}},
+	"lua":       {codeOptions: []string{"-e"}, valueOptions: []string{"-l"}},
+	"luajit":    {codeOptions: []string{"-e"}, valueOptions: []string{"-l"}},
+	"Rscript":   {codeOptions: []string{"-e"}},
+	"osascript": {codeOptions: []string{"-e"}},
+	"pwsh":      {codeOptions: []string{"-c", "-Command", "-EncodedCommand"}},
+	"awk":       {codeOptions: []string{"-e", "--source"}, valueOptions: []string{"-F", "-v", "--field-separator", "--assign"}, fileOptions: []string{"-f", "--file"}, programArg: true},
+	"gawk":      {codeOptions: []string{"-e", "--source"}, valueOptions: []string{"-F", "-v", "--field-separator", "--assign"}, fileOptions: []string{"-f", "--file"}, programArg: true},
+	"mawk":      {valueOptions: []string{"-F", "-v"}, fileOptions: []string{"-f"}, programArg: true},
+	"nawk":      {valueOptions: []string{"-F", "-v"}, fileOptions: []string{"-f"}, programArg: true},
+	"sed":       {codeOptions: []string{"-e", "--expression"}, valueOptions: []string{"-l", "--line-length"}, fileOptions: []string{"-f", "--file"}, programArg: true},
+	"gsed":      {codeOptions: []string{"-e", "--expression"}, valueOptions: []string{"-l", "--line-length"}, fileOptions: []string{"-f", "--file"}, programArg: true},
+}
+
+// templateParams returns the parameter names referenced by an argv template.
+func templateParams(argv []string) []string {
+	var names []string
+	for _, arg := range argv {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(arg, -1) {
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// checkTemplate reports an error if argv has placeholders in code that they cannot
+// be substituted into safely: anywhere in the inline code of an interpreter such as
+// python -c, or in a shell script run with -c that is a placeholder as a whole or
+// has one inside quotes, where the quoted value would end the quoting early.
+func checkTemplate(argv []string) error {
+	for _, i := range codeArgs(argv) {
+		if m := placeholderPattern.FindStringSubmatch(argv[i]); m != nil {
+			return fmt.Errorf("parameter %s is in the code run by %s, where its value cannot be quoted; pass it as a separate argument and read it from the program's arguments",
+				m[1], filepath.Base(argv[commandIndex(argv)]))
+		}
+	}
+
+	i := scriptArg(argv)
+	if i < 0 {
+		return nil
+	}
+	script := argv[i]
+	if m := placeholderPattern.FindStringSubmatch(script); m != nil && m[0] == script {
+		return fmt.Errorf("parameter %s must not be the whole shell script", m[1])
+	}
+
+	locs := placeholderPattern.FindAllStringSubmatchIndex(script, -1)
+	var quote byte
+	for i, next := 0, 0; i < len(script) && next < len(locs); i++ {
+		if i >= locs[next][0] {
+			if quote != 0 {
+				return fmt.Errorf("parameter %s is inside quotes in the shell script; leave it unquoted, its value is quoted when substituted", script[locs[next][2]:locs[next][3]])
+			}
+			i = locs[next][1] - 1
+			next++
+			continue
+		}
+		switch c := script[i]; {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	return nil
+}
+
+// scriptArg returns the index of the script in argv when it runs a shell with
+// -c, or -1. The shell may follow env and its options and assignments, and -c
+// may be part of a flag cluster such as -lc or -euc.
+func scriptArg(argv []string) int {
+	cmd := commandIndex(argv)
+	if cmd >= len(argv) || !shells[filepath.Base(argv[cmd])] {
+		return -1
+	}
+
+	command := false
+	for i := cmd + 1; i < len(argv); i++ {
+		arg := argv[i]
+		switch {
+		case arg == "--":
+			if command && i+1 < len(argv) {
+				return i + 1
+			}
+			return -1
+		case len(arg) < 2 || (arg[0] != '-' && arg[0] != '+'):
+			if command {
+				return i
+			}
+			return -1 // A script file rather than -c
+		case shellValueOptions[arg]:
+			i++
+		case arg[0] == '-' && arg[1] != '-' && strings.ContainsRune(arg, 'c'):
+			command = true
+		}
+	}
+	return -1
+}
+
+// commandIndex returns the index of the command argv runs, past env and its
+// options and assignments, or len(argv) if there is none.
+func commandIndex(argv []string) int {
+	cmd := 0
+	if len(argv) > 0 && filepath.Base(argv[0]) == "env" {
+		for cmd = 1; cmd < len(argv); cmd++ {
+			arg := argv[cmd]
+			if envValueOptions[arg] {
+				cmd++
+			} else if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") {
+				break
+			}
+		}
+	}
+	return min(cmd, len(argv))
+}
+
+// codeArgs returns the indexes of the arguments of argv that hold inline code for
+// one of interpreters. Option parsing errs towards finding code: a short option
+// containing a code option's letter counts as one, with the code attached or next.
+func codeArgs(argv []string) []int {
+	cmd := commandIndex(argv)
+	if cmd >= len(argv) {
+		return nil
+	}
+	in, ok := interpreters[strings.TrimRight(filepath.Base(argv[cmd]), "0123456789.")]
+	if !ok {
+		return nil
+	}
+
+	var code []int
+	program := false // The program was given by an option
+	for i := cmd + 1; i < len(argv); i++ {
+		arg := argv[i]
+		switch {
+		case arg == "--":
+			if in.programArg && !program && i+1 < len(argv) {
+				code = append(code, i+1)
+			}
+			return code
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			if in.programArg && !program {
+				code = append(code, i)
+			}
+			return code // The rest are the program's arguments or input files
+		case slices.Contains(in.valueOptions, arg):
+			i++
+		case slices.Contains(in.fileOptions, arg):
+			program = true
+			i++
+		default:
+			if at := codeOption(in.codeOptions, arg); at >= 0 {
+				program = true
+				if at == 0 { // The code is the next argument
+					code = append(code, i+1)
+					i++
+				} else {
+					code = append(code, i)
+				}
+			}
+		}
+	}
+	return code
+}
+
+// codeOption reports where the code of a code option in arg is: 0 for the next
+// argument, 1 for attached to arg itself, or -1 if arg is not a code option.
+func codeOption(options []string, arg string) int {
+	for _, opt := range options {
+		switch {
+		case arg == opt:
+			return 0
+		case strings.HasPrefix(opt, "--") || len(opt) != 2:
+			if strings.HasPrefix(arg, opt+"=") {
+				return 1
+			}
+		case !strings.HasPrefix(arg, "--"):
+			// A cluster such as perl's -ne, or code attached as in -eprint.
+			if j := strings.IndexByte(arg[1:], opt[1]); j >= 0 {
+				if j == len(arg)-2 {
+					return 0
+				}
+				return 1
+			}
+		}
+	}
+	return -1
+}
+
+// renderArgv substitutes args into an argv template.
+//
+// The command runs without a shell, so each substituted value stays a single
+// argument regardless of spaces or metacharacters. An element that is exactly one
+// placeholder is dropped when the argument is absent and expands to one element per
+// item for arrays. Values substituted into the script of a shell run with -c are
+// single-quoted; see checkTemplate for the scripts that are refused.
+func renderArgv(argv []string, args map[string]any) ([]string, error) {
+	if err := checkTemplate(argv); err != nil {
+		return nil, err
+	}
+	scriptIndex := scriptArg(argv)
+
+	out := make([]string, 0, len(argv))
+	for i, arg := range argv {
+		if m := placeholderPattern.FindStringSubmatch(arg); m != nil && m[0] == arg {
+			values, err := wholeValues(m[1], args[m[1]])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, values...)
+			continue
+		}
+
+		script := i == scriptIndex
+		var renderErr error
+		rendered := placeholderPattern.ReplaceAllStringFunc(arg, func(ph string) string {
+			name := placeholderPattern.FindStringSubmatch(ph)[1]
+			v, ok := args[name]
+			if !ok || v == nil {
+				return ""
+			}
+			if _, isArray := v.([]any); isArray {
+				renderErr = fmt.Errorf("parameter %s is an array and must be the whole argument", name)
+				return ""
+			}
+			s := formatValue(v)
+			if script {
+				return shellQuote(s)
+			}
+			return s
+		})
+		if renderErr != nil {
+			return nil, renderErr
+		}
+		out = append(out, rendered)
+	}
+	return out, nil
+}
+
+// wholeValues renders a value that makes up entire arguments.
+func wholeValues(name string, v any) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	items, isArray := v.([]any)
+	if !isArray {
+		items = []any{v}
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		s := formatValue(item)
+		// A leading dash would let the value be parsed as an option of the command.
+		if _, isString := item.(string); isString && strings.HasPrefix(s, "-") {
+			return nil, fmt.Errorf("parameter %s must not start with '-': %q", name, s)
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+func formatValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// shellQuote wraps s in single quotes for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,89 @@
+package shell
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRenderArgv(t *testing.T) {
+	tests := []struct {
+		name     string
+		template []string
+		args     map[string]any
+		want     []string
+		wantErr  bool
+	}{
+		{"NoPlaceholders", []string{"make", "lint"}, nil, []string{"make", "lint"}, false},
+		{"WholeArgument", []string{"make", "{{target}}"}, map[string]any{"target": "test"}, []string{"make", "test"}, false},
+		{"ValueWithSpacesStaysOneArgument", []string{"grep", "{{q}}"}, map[string]any{"q": "a b; rm -rf /"}, []string{"grep", "a b; rm -rf /"}, false},
+		{"EmbeddedPlaceholder", []string{"go", "test", "-run={{name}}"}, map[string]any{"name": "TestX"}, []string{"go", "test", "-run=TestX"}, false},
+		{"AbsentWholeArgumentDropped", []string{"make", "{{target}}"}, map[string]any{}, []string{"make"}, false},
+		{"AbsentEmbeddedIsEmpty", []string{"deploy", "--env={{env}}"}, map[string]any{}, []string{"deploy", "--env="}, false},
+		{"ArrayExpands", []string{"go", "vet", "{{pkgs}}"}, map[string]any{"pkgs": []any{"./a", "./b"}}, []string{"go", "vet", "./a", "./b"}, false},
+		{"NumberAndBool", []string{"run", "{{n}}", "--dry={{dry}}"}, map[string]any{"n": 3.0, "dry": true}, []string{"run", "3", "--dry=true"}, false},
+		{"ShellScriptValuesQuoted", []string{"sh", "-c", "echo {{msg}}"}, map[string]any{"msg": "it's $HOME"}, []string{"sh", "-c", `echo 'it'\''s $HOME'`}, false},
+		{"ShellFlagClusterQuoted", []string{"bash", "-lc", "echo {{msg}}"}, map[string]any{"msg": "x; rm -rf /"}, []string{"bash", "-lc", "echo 'x; rm -rf /'"}, false},
+		{"ShellOptionsBeforeScriptQuoted", []string{"bash", "-o", "pipefail", "-euc", "grep {{q}} | wc -l"}, map[string]any{"q": "$(id)"}, []string{"bash", "-o", "pipefail", "-euc", "grep '$(id)' | wc -l"}, false},
+		{"EnvPrefixQuoted", []string{"/usr/bin/env", "-u", "HOME", "LANG=C", "sh", "-ec", "ls {{dir}}"}, map[string]any{"dir": "`id`"}, []string{"/usr/bin/env", "-u", "HOME", "LANG=C", "sh", "-ec", "ls '`id`'"}, false},
+		{"ShellPositionalArgumentsVerbatim", []string{"sh", "-c", `echo "$1"`, "sh", "{{msg}}"}, map[string]any{"msg": "a b"}, []string{"sh", "-c", `echo "$1"`, "sh", "a b"}, false},
+		{"ShellScriptFileNotQuoted", []string{"bash", "deploy.sh", "--name={{name}}"}, map[string]any{"name": "x y"}, []string{"bash", "deploy.sh", "--name=x y"}, false},
+		{"PlaceholderInDoubleQuotesRejected", []string{"sh", "-c", `echo "{{msg}}"`}, map[string]any{"msg": `"; id; "`}, nil, true},
+		{"PlaceholderInSingleQuotesRejected", []string{"bash", "-euc", "echo 'v={{msg}}'"}, map[string]any{"msg": "'; id; '"}, nil, true},
+		{"WholeScriptPlaceholderRejected", []string{"env", "sh", "-c", "{{script}}"}, map[string]any{"script": "id"}, nil, true},
+		{"LeadingDashRejected", []string{"rm", "{{file}}"}, map[string]any{"file": "-rf"}, nil, true},
+		{"EmbeddedArrayRejected", []string{"x", "--items={{items}}"}, map[string]any{"items": []any{"a"}}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderArgv(tt.template, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderArgv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("renderArgv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template []string
+		wantErr  bool
+	}{
+		{"NotAShell", []string{"echo", "'{{msg}}'"}, false},
+		{"UnquotedInScript", []string{"sh", "-c", `printf '%s\n' {{msg}} "$HOME"`}, false},
+		{"EscapedQuoteDoesNotOpen", []string{"sh", "-c", `echo \" {{msg}}`}, false},
+		{"InsideDoubleQuotesAfterEscape", []string{"sh", "-c", `echo "a\"b {{msg}}"`}, true},
+		{"InsideSingleQuotesAfterOthers", []string{"zsh", "-c", `echo {{a}} 'x {{b}}'`}, true},
+		{"PythonCode", []string{"python3", "-c", "print('{{msg}}')"}, true},
+		{"VersionedPythonAfterEnv", []string{"env", "PYTHONPATH=.", "python3.12", "-W", "ignore", "-c", "import {{mod}}"}, true},
+		{"PythonCodeWithArgumentsApart", []string{"python3", "-c", "import sys; print(sys.argv[1])", "{{msg}}"}, false},
+		{"PythonScriptOption", []string{"python", "tool.py", "-c", "{{msg}}"}, false},
+		{"NodeEvalAttached", []string{"node", "--eval=console.log({{n}})"}, true},
+		{"NodePrint", []string{"node", "-p", "{{expr}}"}, true},
+		{"PerlCluster", []string{"perl", "-lne", "print if /{{re}}/", "log.txt"}, true},
+		{"RubyCode", []string{"ruby", "-e", "puts {{x}}"}, true},
+		{"AwkProgram", []string{"awk", "-F", ",", "/{{re}}/ {print $1}", "data.csv"}, true},
+		{"AwkVariableAndFile", []string{"awk", "-v", "re={{re}}", "$0 ~ re", "{{file}}"}, false},
+		{"SedExpression", []string{"sed", "-n", "-e", "s/{{from}}/x/p", "f"}, true},
+		{"SedScriptFile", []string{"sed", "-f", "edit.sed", "{{file}}"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkTemplate(tt.template); (err != nil) != tt.wantErr {
+				t.Errorf("checkTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTemplateParams(t *testing.T) {
+	got := templateParams([]string{"deploy", "{{ env }}", "--tag={{tag}}-{{suffix}}"})
+	want := []string{"env", "tag", "suffix"}
+	if !slices.Equal(got, want) {
+		t.Errorf("templateParams() = %q, want %q", got, want)
+	}
+}
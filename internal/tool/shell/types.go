@@ -2,8 +2,10 @@ package shell
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/tool"
 )
 
 // DockerConfig contains configuration for Docker readiness checks.
@@ -39,8 +41,43 @@ type ShellResponse struct {
 	Truncated  bool   `json:"truncated"`
 	WorkingDir string `json:"working_dir"`
 	Note       string `json:"note,omitempty"`
+	Error      string `json:"error,omitempty"` // Set if the command could not run to completion
+}
+
+// LLMContent returns the exit code and captured output.
+func (r *ShellResponse) LLMContent() string {
+	var sb strings.Builder
+	if r.Error != "" {
+		fmt.Fprintf(&sb, "Error: %s\n", r.Error)
+	}
+	workingDir := r.WorkingDir
+	if workingDir == "" {
+		workingDir = "."
+	}
+	fmt.Fprintf(&sb, "Exit code: %d\nWorking directory: %s", r.ExitCode, workingDir)
+	if r.Stdout != "" {
+		fmt.Fprintf(&sb, "\n\nStdout:\n%s", strings.TrimRight(r.Stdout, "\n"))
+	}
+	if r.Stderr != "" {
+		fmt.Fprintf(&sb, "\n\nStderr:\n%s", strings.TrimRight(r.Stderr, "\n"))
+	}
+	if r.Truncated {
+		sb.WriteString("\n\n(Output truncated)")
+	}
+	if r.Note != "" {
+		fmt.Fprintf(&sb, "\n\n%s", r.Note)
+	}
+	return sb.String()
+}
+
+// Display returns the UI representation
+func (r *ShellResponse) Display() tool.ToolDisplay {
+	if r.Error != "" {
+		return tool.StringDisplay(r.Error)
+	}
+	return tool.StringDisplay(fmt.Sprintf("Exit code %d", r.ExitCode))
 }
 
 func (r ShellResponse) Success() bool {
-	return r.ExitCode == 0
+	return r.Error == "" && r.ExitCode == 0
 }
@@ -18,12 +18,26 @@ const (
 
 // Schema represents a JSON Schema for tool parameters.
 type Schema struct {
-	Type        Type               `json:"type"`
+	Type        Type               `json:"type,omitempty"` // Empty accepts any JSON value
 	Description string             `json:"description,omitempty"`
 	Properties  map[string]*Schema `json:"properties,omitempty"`
 	Required    []string           `json:"required,omitempty"`
 	Items       *Schema            `json:"items,omitempty"`
 	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"` // RE2 syntax
+	Default     any                `json:"default,omitempty"`
+
+	// AdditionalProperties controls whether an object accepts properties not listed
+	// in Properties. Nil means allowed, matching JSON Schema semantics.
+	AdditionalProperties *bool `json:"additionalProperties,omitempty"`
+}
+
+// Ptr returns a pointer to v. It keeps schema literals with optional
+// bounds (Minimum, Maximum, AdditionalProperties) on one line.
+func Ptr[T any](v T) *T {
+	return &v
 }
 
 // Declaration declares a tool's function signature for the LLM.
@@ -46,13 +60,53 @@ func (StringDisplay) isToolDisplay() {}
 
 // DiffDisplay is for file edit operations with unified diff content.
 type DiffDisplay struct {
+	Path         string // Workspace-relative file the diff applies to; may be empty for single-file tools
 	Diff         string // Unified diff content
 	AddedLines   int
 	RemovedLines int
+
+	Language string     // Syntax highlighting hint, e.g. "Go" or "YAML"; empty if unknown
+	Hunks    []DiffHunk // Diff parsed into hunks, with the changed words of changed lines
 }
 
 func (DiffDisplay) isToolDisplay() {}
 
+// DiffHunk is one @@ section of a unified diff.
+type DiffHunk struct {
+	OldStart, OldLines int // 1-based, as in the @@ header
+	NewStart, NewLines int
+	Lines              []DiffLine
+}
+
+// DiffLineKind says which side of a diff a line belongs to.
+type DiffLineKind int
+
+const (
+	DiffContext DiffLineKind = iota
+	DiffRemoved
+	DiffAdded
+)
+
+// DiffLine is a line of a hunk without its +, - or space prefix.
+type DiffLine struct {
+	Kind DiffLineKind
+	Text string
+
+	// Changes are the byte ranges of Text that differ from the line it
+	// replaces or is replaced by; empty when the whole line is new or gone.
+	Changes []Span
+}
+
+// Span is the byte range [Start, End) of a string.
+type Span struct {
+	Start, End int
+}
+
+// MultiDiffDisplay is for operations that change several files, one diff per file.
+type MultiDiffDisplay []DiffDisplay
+
+func (MultiDiffDisplay) isToolDisplay() {}
+
 // ShellDisplay is for shell command execution with streaming output.
 type ShellDisplay struct {
 	Command    string
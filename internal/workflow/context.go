@@ -0,0 +1,16 @@
+package workflow
+
+import "context"
+
+type sessionIDKey struct{}
+
+// WithSessionID returns a context carrying the ID of the session that issued the work.
+func WithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, id)
+}
+
+// SessionID returns the session ID stored in ctx, or "" if there is none.
+func SessionID(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey{}).(string)
+	return id
+}
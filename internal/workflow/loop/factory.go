@@ -6,6 +6,7 @@ import "github.com/Cyclone1070/iav/internal/workflow"
 type LoopFactory struct {
 	provider      llmProvider
 	tools         toolManager
+	watcher       changeWatcher
 	events        chan<- workflow.Event
 	maxIterations int
 }
@@ -14,12 +15,14 @@ type LoopFactory struct {
 func NewLoopFactory(
 	provider llmProvider,
 	tools toolManager,
+	watcher changeWatcher,
 	events chan<- workflow.Event,
 	maxIterations int,
 ) *LoopFactory {
 	return &LoopFactory{
 		provider:      provider,
 		tools:         tools,
+		watcher:       watcher,
 		events:        events,
 		maxIterations: maxIterations,
 	}
@@ -27,5 +30,5 @@ func NewLoopFactory(
 
 // Create creates a new Loop instance with the given session.
 func (f *LoopFactory) Create(s session) *Loop {
-	return NewLoop(f.provider, f.tools, s, f.events, f.maxIterations)
+	return NewLoop(f.provider, f.tools, s, f.watcher, f.events, f.maxIterations)
 }
@@ -19,6 +19,9 @@ type toolManager interface {
 	// Declarations returns all tool schemas for the LLM.
 	Declarations() []tool.Declaration
 
+	// UseProfile restricts the tools to the named profile; "" enables all tools.
+	UseProfile(name string) error
+
 	// Execute runs a tool call and returns the result as a provider.Message.
 	// It emits ToolStartEvent, ToolEndEvent, and ToolStreamEvent to the events channel.
 	Execute(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error)
@@ -26,7 +29,16 @@ type toolManager interface {
 
 // session defines the contract for message history
 type session interface {
+	ID() string
+	Profile() string
 	Messages() []provider.Message
 	Add(msg provider.Message)
 	Save() error
 }
+
+// changeWatcher reports files changed outside the agent.
+type changeWatcher interface {
+	// Changes returns a note describing files changed since the agent last read
+	// them, or "" if there are none.
+	Changes() string
+}
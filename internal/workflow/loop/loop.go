@@ -12,14 +12,22 @@ type Loop struct {
 	provider      llmProvider
 	tools         toolManager
 	session       session
+	watcher       changeWatcher // Optional
 	events        chan<- workflow.Event
 	maxIterations int
+
+	// The session profile last applied to tools. It is applied again only when
+	// it changes, so tools enabled or disabled at runtime stay that way.
+	profile        string
+	profileApplied bool
 }
 
+// NewLoop creates a Loop. watcher and events may be nil.
 func NewLoop(
 	provider llmProvider,
 	tools toolManager,
 	session session,
+	watcher changeWatcher,
 	events chan<- workflow.Event,
 	maxIterations int,
 ) *Loop {
@@ -27,12 +35,21 @@ func NewLoop(
 		provider:      provider,
 		tools:         tools,
 		session:       session,
+		watcher:       watcher,
 		events:        events,
 		maxIterations: maxIterations,
 	}
 }
 
 func (l *Loop) Run(ctx context.Context, userInput string) error {
+	ctx = workflow.WithSessionID(ctx, l.session.ID())
+	if profile := l.session.Profile(); !l.profileApplied || profile != l.profile {
+		if err := l.tools.UseProfile(profile); err != nil {
+			return fmt.Errorf("tools.UseProfile: %w", err)
+		}
+		l.profile, l.profileApplied = profile, true
+	}
+
 	l.session.Add(provider.Message{
 		Role:    provider.RoleUser,
 		Content: userInput,
@@ -58,6 +75,16 @@ func (l *Loop) Run(ctx context.Context, userInput string) error {
 			l.events <- workflow.ThinkingEvent{}
 		}
 
+		// Tell the model about edits made behind its back before it plans the next step.
+		if l.watcher != nil {
+			if note := l.watcher.Changes(); note != "" {
+				l.session.Add(provider.Message{
+					Role:    provider.RoleUser,
+					Content: note,
+				})
+			}
+		}
+
 		resp, err := l.provider.Generate(ctx, l.session.Messages(), l.tools.Declarations())
 		if err != nil {
 			_ = l.session.Save() // Best effort
@@ -23,6 +23,17 @@ func (m *mockProvider) Generate(ctx context.Context, messages []provider.Message
 type mockToolManager struct {
 	declarations []tool.Declaration
 	executeFunc  func(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error)
+	profile      string
+	profileErr   error
+	profileCalls int
+	disabled     map[string]bool // Runtime Disable calls, cleared by UseProfile like the real manager
+}
+
+func (m *mockToolManager) UseProfile(name string) error {
+	m.profile = name
+	m.profileCalls++
+	m.disabled = nil
+	return m.profileErr
 }
 
 func (m *mockToolManager) Declarations() []tool.Declaration {
@@ -37,9 +48,19 @@ func (m *mockToolManager) Execute(ctx context.Context, tc provider.ToolCall, eve
 }
 
 type mockSession struct {
+	id       string
+	profile  string
 	messages []provider.Message
 }
 
+func (m *mockSession) ID() string {
+	return m.id
+}
+
+func (m *mockSession) Profile() string {
+	return m.profile
+}
+
 func (m *mockSession) Messages() []provider.Message {
 	return m.messages
 }
@@ -64,7 +85,7 @@ func TestRun_SingleTurn_TextOnly(t *testing.T) {
 	mtm := &mockToolManager{}
 	ms := &mockSession{}
 
-	l := NewLoop(mp, mtm, ms, events, 5)
+	l := NewLoop(mp, mtm, ms, nil, events, 5)
 	err := l.Run(ctx, "Hi")
 
 	assert.NoError(t, err)
@@ -106,7 +127,7 @@ func TestRun_SingleToolCall(t *testing.T) {
 	}
 	ms := &mockSession{}
 
-	l := NewLoop(mp, mtm, ms, events, 5)
+	l := NewLoop(mp, mtm, ms, nil, events, 5)
 	err := l.Run(ctx, "Weather?")
 
 	assert.NoError(t, err)
@@ -123,6 +144,126 @@ func TestRun_SingleToolCall(t *testing.T) {
 	assert.IsType(t, workflow.DoneEvent{}, <-events)
 }
 
+func TestRun_ToolCallsCarrySessionID(t *testing.T) {
+	callCount := 0
+	mp := &mockProvider{
+		generateFunc: func(ctx context.Context, messages []provider.Message, tools []tool.Declaration) (*provider.Message, error) {
+			callCount++
+			if callCount == 1 {
+				return &provider.Message{
+					Role:      provider.RoleAssistant,
+					ToolCalls: []provider.ToolCall{{Function: provider.FunctionCall{Name: "get_weather"}}},
+				}, nil
+			}
+			return &provider.Message{Role: provider.RoleAssistant, Content: "done"}, nil
+		},
+	}
+
+	var gotSessionID string
+	mtm := &mockToolManager{
+		executeFunc: func(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error) {
+			gotSessionID = workflow.SessionID(ctx)
+			return provider.Message{Role: provider.RoleTool, Content: "Sunny"}, nil
+		},
+	}
+
+	l := NewLoop(mp, mtm, &mockSession{id: "session-1"}, nil, nil, 5)
+	assert.NoError(t, l.Run(context.Background(), "Weather?"))
+	assert.Equal(t, "session-1", gotSessionID)
+}
+
+func TestRun_AppliesSessionProfile(t *testing.T) {
+	mp := &mockProvider{
+		generateFunc: func(ctx context.Context, messages []provider.Message, tools []tool.Declaration) (*provider.Message, error) {
+			return &provider.Message{Role: provider.RoleAssistant, Content: "ok"}, nil
+		},
+	}
+	mtm := &mockToolManager{}
+	l := NewLoop(mp, mtm, &mockSession{profile: "readonly"}, nil, nil, 5)
+
+	assert.NoError(t, l.Run(context.Background(), "hi"))
+	assert.Equal(t, "readonly", mtm.profile)
+}
+
+func TestRun_RuntimeDisable_SurvivesNextTurn(t *testing.T) {
+	mp := &mockProvider{
+		generateFunc: func(ctx context.Context, messages []provider.Message, tools []tool.Declaration) (*provider.Message, error) {
+			return &provider.Message{Role: provider.RoleAssistant, Content: "ok"}, nil
+		},
+	}
+	mtm := &mockToolManager{}
+	ms := &mockSession{profile: "readonly"}
+	l := NewLoop(mp, mtm, ms, nil, nil, 5)
+
+	assert.NoError(t, l.Run(context.Background(), "first"))
+	mtm.disabled = map[string]bool{"shell": true}
+
+	assert.NoError(t, l.Run(context.Background(), "second"))
+	assert.Equal(t, 1, mtm.profileCalls)
+	assert.True(t, mtm.disabled["shell"], "a runtime Disable must survive into the next turn")
+
+	// Switching profiles applies the new one.
+	ms.profile = "full"
+	assert.NoError(t, l.Run(context.Background(), "third"))
+	assert.Equal(t, 2, mtm.profileCalls)
+	assert.Equal(t, "full", mtm.profile)
+}
+
+func TestRun_UnknownProfile_ReturnsError(t *testing.T) {
+	mp := &mockProvider{
+		generateFunc: func(ctx context.Context, messages []provider.Message, tools []tool.Declaration) (*provider.Message, error) {
+			t.Fatal("Generate must not be called")
+			return nil, nil
+		},
+	}
+	mtm := &mockToolManager{profileErr: fmt.Errorf("unknown tool profile \"nope\"")}
+	ms := &mockSession{profile: "nope"}
+	l := NewLoop(mp, mtm, ms, nil, nil, 5)
+
+	err := l.Run(context.Background(), "hi")
+	assert.ErrorContains(t, err, "tools.UseProfile")
+	assert.Empty(t, ms.Messages())
+}
+
+type mockWatcher struct {
+	notes []string // Returned by successive Changes calls
+}
+
+func (m *mockWatcher) Changes() string {
+	if len(m.notes) == 0 {
+		return ""
+	}
+	note := m.notes[0]
+	m.notes = m.notes[1:]
+	return note
+}
+
+func TestRun_InjectsExternalChangesBeforeGenerate(t *testing.T) {
+	var seen [][]provider.Message
+	mp := &mockProvider{
+		generateFunc: func(ctx context.Context, messages []provider.Message, tools []tool.Declaration) (*provider.Message, error) {
+			seen = append(seen, append([]provider.Message{}, messages...))
+			if len(seen) == 1 {
+				return &provider.Message{
+					Role:      provider.RoleAssistant,
+					ToolCalls: []provider.ToolCall{{Function: provider.FunctionCall{Name: "read_file"}}},
+				}, nil
+			}
+			return &provider.Message{Role: provider.RoleAssistant, Content: "done"}, nil
+		},
+	}
+	mw := &mockWatcher{notes: []string{"", "[Files changed outside the agent since your last read.]\n- main.go (modified)"}}
+	l := NewLoop(mp, &mockToolManager{}, &mockSession{}, mw, nil, 5)
+
+	assert.NoError(t, l.Run(context.Background(), "hi"))
+
+	assert.Len(t, seen, 2)
+	assert.Len(t, seen[0], 1, "no note when nothing changed")
+	last := seen[1][len(seen[1])-1]
+	assert.Equal(t, provider.RoleUser, last.Role)
+	assert.Contains(t, last.Content, "main.go (modified)")
+}
+
 func TestRun_MaxIterationsExceeded_ReturnsError(t *testing.T) {
 	mp := &mockProvider{
 		generateFunc: func(ctx context.Context, messages []provider.Message, tools []tool.Declaration) (*provider.Message, error) {
@@ -135,7 +276,7 @@ func TestRun_MaxIterationsExceeded_ReturnsError(t *testing.T) {
 		},
 	}
 	ms := &mockSession{}
-	l := NewLoop(mp, &mockToolManager{}, ms, nil, 3)
+	l := NewLoop(mp, &mockToolManager{}, ms, nil, nil, 3)
 	err := l.Run(context.Background(), "go")
 
 	assert.Error(t, err)
@@ -150,7 +291,7 @@ func TestRun_ProviderError_ReturnsError(t *testing.T) {
 		},
 	}
 	ms := &mockSession{}
-	l := NewLoop(mp, &mockToolManager{}, ms, make(chan workflow.Event, 10), 5)
+	l := NewLoop(mp, &mockToolManager{}, ms, nil, make(chan workflow.Event, 10), 5)
 	err := l.Run(context.Background(), "hi")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "provider.Generate")
@@ -173,7 +314,7 @@ func TestRun_ToolError_ReturnsError(t *testing.T) {
 		},
 	}
 	ms := &mockSession{}
-	l := NewLoop(mp, mtm, ms, make(chan workflow.Event, 10), 5)
+	l := NewLoop(mp, mtm, ms, nil, make(chan workflow.Event, 10), 5)
 	err := l.Run(context.Background(), "hi")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "tools.Execute")
@@ -190,7 +331,7 @@ func TestRun_ContextCancelled_DuringThinking_ReturnsError(t *testing.T) {
 	cancel() // Cancel immediately
 
 	ms := &mockSession{}
-	l := NewLoop(mp, &mockToolManager{}, ms, nil, 5)
+	l := NewLoop(mp, &mockToolManager{}, ms, nil, nil, 5)
 	err := l.Run(ctx, "hi")
 
 	assert.ErrorIs(t, err, context.Canceled)
@@ -2,7 +2,9 @@ package toolmanager
 
 import (
 	"context"
+	"time"
 
+	"github.com/Cyclone1070/iav/internal/audit"
 	"github.com/Cyclone1070/iav/internal/tool"
 )
 
@@ -38,3 +40,25 @@ type Tool interface {
 	// Execute runs the tool with the request and returns a ToolResult.
 	Execute(ctx context.Context, req ToolRequest) (ToolResult, error)
 }
+
+// timeoutDeclarer is optionally implemented by tools whose deadline is part of
+// their definition, such as config-declared commands.
+type timeoutDeclarer interface {
+	Timeout() time.Duration
+}
+
+// readOnlyDeclarer is optionally implemented by tools that can say whether they
+// modify the workspace. A tool without it is taken to modify it.
+type readOnlyDeclarer interface {
+	ReadOnly() bool
+}
+
+// logger records diagnostics that must not be sent to the LLM, such as panic stack traces.
+type logger interface {
+	Error(msg string, args ...any)
+}
+
+// auditor durably records every tool call.
+type auditor interface {
+	Append(rec audit.Record, llmContent string) error
+}
@@ -0,0 +1,94 @@
+package toolmanager
+
+import (
+	"fmt"
+
+	"github.com/Cyclone1070/iav/internal/tool"
+)
+
+// UseProfile enables exactly the tools of the named profile from config and applies
+// its description overrides. A read-only profile leaves out the tools that do not
+// declare themselves read-only. The empty name enables every registered tool.
+func (m *ToolManager) UseProfile(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if name == "" {
+		m.profile = nil
+	} else {
+		p, ok := m.config.Profiles[name]
+		if !ok {
+			return fmt.Errorf("unknown tool profile %q", name)
+		}
+		m.profile = &p
+	}
+
+	for toolName, t := range m.registry {
+		m.disabled[toolName] = m.excluded(t)
+	}
+	return nil
+}
+
+// excluded reports whether the active profile leaves t out: it does not list t,
+// or it is read-only and t does not declare itself read-only. Callers must hold m.mu.
+func (m *ToolManager) excluded(t Tool) bool {
+	if m.profile == nil {
+		return false
+	}
+	if !m.profile.Includes(t.Name()) {
+		return true
+	}
+	if m.profile.ReadOnly {
+		ro, ok := t.(readOnlyDeclarer)
+		return !ok || !ro.ReadOnly()
+	}
+	return false
+}
+
+// Enable makes registered tools available to the LLM until the next UseProfile.
+func (m *ToolManager) Enable(names ...string) error {
+	return m.setDisabled(false, names)
+}
+
+// Disable hides registered tools from the LLM until the next UseProfile.
+func (m *ToolManager) Disable(names ...string) error {
+	return m.setDisabled(true, names)
+}
+
+func (m *ToolManager) setDisabled(disabled bool, names []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range names {
+		if _, ok := m.registry[name]; !ok {
+			return fmt.Errorf("tool %q is not registered", name)
+		}
+	}
+	for _, name := range names {
+		m.disabled[name] = disabled
+	}
+	return nil
+}
+
+// lookup returns the registered tool, its declaration and whether it is enabled.
+func (m *ToolManager) lookup(name string) (t Tool, decl tool.Declaration, enabled bool, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok = m.registry[name]
+	if !ok {
+		return nil, tool.Declaration{}, false, false
+	}
+	return t, m.declaration(t), !m.disabled[name], true
+}
+
+// declaration returns the tool's schema with the active profile's description override.
+// Callers must hold m.mu.
+func (m *ToolManager) declaration(t Tool) tool.Declaration {
+	decl := t.Declaration()
+	if m.profile != nil {
+		if desc, ok := m.profile.Descriptions[t.Name()]; ok {
+			decl.Description = desc
+		}
+	}
+	return decl
+}
@@ -0,0 +1,138 @@
+package toolmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/config"
+	"github.com/Cyclone1070/iav/internal/provider"
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProfileToolManager() *ToolManager {
+	cfg := config.DefaultConfig()
+	cfg.Profiles = map[string]config.ProfileConfig{
+		"readonly": {
+			Tools:        []string{"read_*", "search_content"},
+			Descriptions: map[string]string{"read_file": "Read a file. Editing is not available."},
+		},
+	}
+	tm := newTestToolManager(cfg)
+	for _, name := range []string{"read_file", "edit_file", "search_content"} {
+		tm.Register(&mockTool{name: name, declaration: tool.Declaration{Name: name, Description: "default"}})
+	}
+	return tm
+}
+
+func declNames(decls []tool.Declaration) []string {
+	names := make([]string, len(decls))
+	for i, d := range decls {
+		names[i] = d.Name
+	}
+	return names
+}
+
+func TestUseProfile_FiltersDeclarationsAndOverridesDescriptions(t *testing.T) {
+	tm := newProfileToolManager()
+
+	assert.NoError(t, tm.UseProfile("readonly"))
+
+	decls := tm.Declarations()
+	assert.Equal(t, []string{"read_file", "search_content"}, declNames(decls))
+	assert.Equal(t, "Read a file. Editing is not available.", decls[0].Description)
+	assert.Equal(t, "default", decls[1].Description)
+}
+
+func TestUseProfile_Empty_EnablesAllTools(t *testing.T) {
+	tm := newProfileToolManager()
+	assert.NoError(t, tm.UseProfile("readonly"))
+
+	assert.NoError(t, tm.UseProfile(""))
+
+	decls := tm.Declarations()
+	assert.Equal(t, []string{"edit_file", "read_file", "search_content"}, declNames(decls))
+	assert.Equal(t, "default", decls[1].Description)
+}
+
+func TestUseProfile_Unknown_ReturnsErrorAndKeepsCurrent(t *testing.T) {
+	tm := newProfileToolManager()
+	assert.NoError(t, tm.UseProfile("readonly"))
+
+	err := tm.UseProfile("nope")
+
+	assert.ErrorContains(t, err, `unknown tool profile "nope"`)
+	assert.Equal(t, []string{"read_file", "search_content"}, declNames(tm.Declarations()))
+}
+
+func TestRegister_AfterUseProfile_RespectsProfile(t *testing.T) {
+	tm := newProfileToolManager()
+	assert.NoError(t, tm.UseProfile("readonly"))
+
+	tm.Register(&mockTool{name: "read_many", declaration: tool.Declaration{Name: "read_many"}})
+	tm.Register(&mockTool{name: "shell", declaration: tool.Declaration{Name: "shell"}})
+
+	assert.Equal(t, []string{"read_file", "read_many", "search_content"}, declNames(tm.Declarations()))
+}
+
+type readOnlyMockTool struct {
+	mockTool
+	readOnly bool
+}
+
+func (t *readOnlyMockTool) ReadOnly() bool { return t.readOnly }
+
+func TestUseProfile_ReadOnly_KeepsOnlyReadOnlyTools(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Profiles = map[string]config.ProfileConfig{"review": {Tools: []string{"*"}, ReadOnly: true}}
+	tm := newTestToolManager(cfg)
+	tm.Register(&readOnlyMockTool{mockTool: mockTool{name: "lint", declaration: tool.Declaration{Name: "lint"}}, readOnly: true})
+	tm.Register(&readOnlyMockTool{mockTool: mockTool{name: "deploy", declaration: tool.Declaration{Name: "deploy"}}})
+	tm.Register(&mockTool{name: "edit_file", declaration: tool.Declaration{Name: "edit_file"}})
+
+	assert.NoError(t, tm.UseProfile("review"))
+
+	assert.Equal(t, []string{"lint"}, declNames(tm.Declarations()))
+}
+
+func TestEnableDisable_TogglesTools(t *testing.T) {
+	tm := newProfileToolManager()
+
+	assert.NoError(t, tm.Disable("edit_file", "search_content"))
+	assert.Equal(t, []string{"read_file"}, declNames(tm.Declarations()))
+
+	assert.NoError(t, tm.Enable("search_content"))
+	assert.Equal(t, []string{"read_file", "search_content"}, declNames(tm.Declarations()))
+}
+
+func TestEnableDisable_UnregisteredTool_ChangesNothing(t *testing.T) {
+	tm := newProfileToolManager()
+
+	err := tm.Disable("edit_file", "missing")
+
+	assert.ErrorContains(t, err, `tool "missing" is not registered`)
+	assert.Len(t, tm.Declarations(), 3)
+}
+
+func TestExecute_DisabledTool_ReturnsMessageToLLM(t *testing.T) {
+	tm := newProfileToolManager()
+	executed := false
+	tm.Register(&mockTool{
+		name:        "edit_file",
+		declaration: tool.Declaration{Name: "edit_file"},
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			executed = true
+			return &mockResult{llmContent: "ok", success: true}, nil
+		},
+	})
+	assert.NoError(t, tm.UseProfile("readonly"))
+
+	msg, err := tm.Execute(context.Background(), provider.ToolCall{
+		ID:       "call-1",
+		Function: provider.FunctionCall{Name: "edit_file", Arguments: []byte("{}")},
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.False(t, executed)
+	assert.Contains(t, msg.Content, `tool "edit_file" is not enabled in the current profile`)
+}
@@ -3,10 +3,16 @@ package toolmanager
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"runtime/debug"
 	"sort"
+	"sync"
+	"time"
 
+	"github.com/Cyclone1070/iav/internal/audit"
+	"github.com/Cyclone1070/iav/internal/config"
 	"github.com/Cyclone1070/iav/internal/provider"
 	"github.com/Cyclone1070/iav/internal/tool"
 	"github.com/Cyclone1070/iav/internal/workflow"
@@ -14,11 +20,31 @@ import (
 
 type ToolManager struct {
 	registry map[string]Tool
+	config   *config.Config
+	logger   logger
+	auditor  auditor
+
+	mu       sync.RWMutex
+	profile  *config.ProfileConfig // nil offers every registered tool
+	disabled map[string]bool       // Registered tools hidden from the LLM
 }
 
-func NewToolManager(tools ...Tool) *ToolManager {
+func NewToolManager(cfg *config.Config, logger logger, auditor auditor, tools ...Tool) *ToolManager {
+	if cfg == nil {
+		panic("cfg is required")
+	}
+	if logger == nil {
+		panic("logger is required")
+	}
+	if auditor == nil {
+		panic("auditor is required")
+	}
 	tm := &ToolManager{
 		registry: make(map[string]Tool),
+		config:   cfg,
+		logger:   logger,
+		auditor:  auditor,
+		disabled: make(map[string]bool),
 	}
 	for _, t := range tools {
 		tm.Register(t)
@@ -26,14 +52,25 @@ func NewToolManager(tools ...Tool) *ToolManager {
 	return tm
 }
 
+// Register adds a tool. It is enabled if the active profile includes it.
 func (m *ToolManager) Register(t Tool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.registry[t.Name()] = t
+	m.disabled[t.Name()] = m.excluded(t)
 }
 
+// Declarations returns the schemas of the enabled tools, with the active profile's
+// description overrides applied.
 func (m *ToolManager) Declarations() []tool.Declaration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	decls := make([]tool.Declaration, 0, len(m.registry))
-	for _, t := range m.registry {
-		decls = append(decls, t.Declaration())
+	for name, t := range m.registry {
+		if m.disabled[name] {
+			continue
+		}
+		decls = append(decls, m.declaration(t))
 	}
 	sort.Slice(decls, func(i, j int) bool {
 		return decls[i].Name < decls[j].Name
@@ -41,56 +78,84 @@ func (m *ToolManager) Declarations() []tool.Declaration {
 	return decls
 }
 
+// Execute runs a tool call and appends a record of it to the audit log.
 func (m *ToolManager) Execute(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, error) {
-	t, ok := m.registry[tc.Function.Name]
+	started := time.Now()
+	msg, success, err := m.execute(ctx, tc, events)
+	m.audit(ctx, tc, started, msg.Content, success, err)
+	return msg, err
+}
+
+// audit records a finished call. A failing audit log is reported but does not fail the call.
+func (m *ToolManager) audit(ctx context.Context, tc provider.ToolCall, started time.Time, content string, success bool, callErr error) {
+	rec := audit.Record{
+		SessionID:  workflow.SessionID(ctx),
+		ToolCallID: tc.ID,
+		Tool:       tc.Function.Name,
+		Arguments:  tc.Function.Arguments,
+		StartedAt:  started.UTC(),
+		EndedAt:    time.Now().UTC(),
+		Success:    success,
+	}
+	if callErr != nil {
+		rec.Error = callErr.Error()
+	}
+	if err := m.auditor.Append(rec, content); err != nil {
+		m.logger.Error("audit record failed", "tool", tc.Function.Name, "tool_call_id", tc.ID, "error", err)
+	}
+}
+
+// execute runs a tool call and reports whether the tool succeeded.
+func (m *ToolManager) execute(ctx context.Context, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, bool, error) {
+	t, decl, enabled, ok := m.lookup(tc.Function.Name)
+	if ok && !enabled {
+		errMsg := fmt.Sprintf("Error: tool %q is not enabled in the current profile. Use one of the available tools instead.", tc.Function.Name)
+		return m.invalidRequest(tc, errMsg, events), false, nil
+	}
 	if !ok {
 		decls := m.Declarations()
 		declsJSON, _ := json.MarshalIndent(decls, "", "  ")
 		errMsg := fmt.Sprintf("Error: tool %q does not exist.\n\nAvailable tools:\n%s", tc.Function.Name, declsJSON)
-
-		if events != nil {
-			events <- workflow.ToolStartEvent{
-				ToolName:       tc.Function.Name,
-				RequestDisplay: "",
-			}
-			events <- workflow.ToolEndEvent{
-				ToolName: tc.Function.Name,
-				Display:  tool.StringDisplay("Invalid tool request"),
-				Success:  false,
-			}
-		}
-
-		return provider.Message{
-			Role:       provider.RoleTool,
-			ToolCallID: tc.ID,
-			Content:    errMsg,
-		}, nil
+		return m.invalidRequest(tc, errMsg, events), false, nil
 	}
 
 	req := t.Request()
-	if err := json.Unmarshal(tc.Function.Arguments, req); err != nil {
-		declJSON, _ := json.MarshalIndent(t.Declaration(), "", "  ")
+	args, err := validateArguments(decl.Parameters, tc.Function.Arguments)
+	if err == nil {
+		err = json.Unmarshal(args, req)
+	}
+	if err != nil {
+		declJSON, _ := json.MarshalIndent(decl, "", "  ")
 		errMsg := fmt.Sprintf("Error: invalid arguments for tool %q: %v\n\nExpected schema:\n%s", tc.Function.Name, err, declJSON)
+		return m.invalidRequest(tc, errMsg, events), false, nil
+	}
 
-		if events != nil {
-			events <- workflow.ToolStartEvent{
-				ToolName:       tc.Function.Name,
-				RequestDisplay: "",
-			}
-			events <- workflow.ToolEndEvent{
-				ToolName: tc.Function.Name,
-				Display:  tool.StringDisplay("Invalid tool request"),
-				Success:  false,
-			}
+	return m.run(ctx, t, req, tc, events)
+}
+
+// invalidRequest reports a request that never reached the tool back to the LLM.
+func (m *ToolManager) invalidRequest(tc provider.ToolCall, errMsg string, events chan<- workflow.Event) provider.Message {
+	if events != nil {
+		events <- workflow.ToolStartEvent{
+			ToolName:       tc.Function.Name,
+			RequestDisplay: "",
+		}
+		events <- workflow.ToolEndEvent{
+			ToolName: tc.Function.Name,
+			Display:  tool.StringDisplay("Invalid tool request"),
+			Success:  false,
 		}
+	}
 
-		return provider.Message{
-			Role:       provider.RoleTool,
-			ToolCallID: tc.ID,
-			Content:    errMsg,
-		}, nil
+	return provider.Message{
+		Role:       provider.RoleTool,
+		ToolCallID: tc.ID,
+		Content:    errMsg,
 	}
+}
 
+// run executes a validated request and streams its events.
+func (m *ToolManager) run(ctx context.Context, t Tool, req ToolRequest, tc provider.ToolCall, events chan<- workflow.Event) (provider.Message, bool, error) {
 	if events != nil {
 		events <- workflow.ToolStartEvent{
 			ToolName:       tc.Function.Name,
@@ -98,7 +163,19 @@ func (m *ToolManager) Execute(ctx context.Context, tc provider.ToolCall, events
 		}
 	}
 
-	res, err := t.Execute(ctx, req)
+	timeout := m.timeoutFor(t)
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res, err := m.executeIsolated(toolCtx, t, req)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		// The tool's own deadline expired, not the caller's: the loop can continue.
+		res = &failureResult{
+			content: fmt.Sprintf("Error: tool %q timed out after %s. The operation may still be running in the background.", t.Name(), timeout),
+			display: "Timed out",
+		}
+		err = nil
+	}
 	if err != nil {
 		// Per contract, tools only return errors for infrastructure issues (context cancellation)
 		if events != nil {
@@ -108,7 +185,7 @@ func (m *ToolManager) Execute(ctx context.Context, tc provider.ToolCall, events
 				Success:  false,
 			}
 		}
-		return provider.Message{}, err
+		return provider.Message{}, false, err
 	}
 
 	display := res.Display()
@@ -126,7 +203,7 @@ func (m *ToolManager) Execute(ctx context.Context, tc provider.ToolCall, events
 						Success:  false,
 					}
 				}
-				return provider.Message{}, ctx.Err()
+				return provider.Message{}, false, ctx.Err()
 			default:
 			}
 
@@ -165,12 +242,56 @@ func (m *ToolManager) Execute(ctx context.Context, tc provider.ToolCall, events
 	}
 
 	if err := ctx.Err(); err != nil {
-		return provider.Message{}, err
+		return provider.Message{}, false, err
 	}
 
 	return provider.Message{
 		Role:       provider.RoleTool,
 		ToolCallID: tc.ID,
 		Content:    res.LLMContent(),
-	}, nil
+	}, res.Success(), nil
+}
+
+// executeIsolated runs the tool in its own goroutine so that a tool ignoring ctx
+// cannot block past its deadline, and a panicking tool cannot crash the process.
+// A timed-out tool's goroutine is abandoned; its result is discarded when it finishes.
+func (m *ToolManager) executeIsolated(ctx context.Context, t Tool, req ToolRequest) (ToolResult, error) {
+	type outcome struct {
+		res ToolResult
+		err error
+	}
+	done := make(chan outcome, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				m.logger.Error("tool panicked", "tool", t.Name(), "panic", r, "stack", string(debug.Stack()))
+				done <- outcome{res: &failureResult{
+					content: fmt.Sprintf("Error: tool %q crashed unexpectedly: %v", t.Name(), r),
+					display: "Crashed",
+				}}
+			}
+		}()
+		res, err := t.Execute(ctx, req)
+		done <- outcome{res: res, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.res, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// timeoutFor returns the configured deadline for a tool. A tool that declares its own
+// deadline uses it unless overridden in config; otherwise the default applies.
+func (m *ToolManager) timeoutFor(t Tool) time.Duration {
+	if seconds, ok := m.config.Tools.ToolTimeouts[t.Name()]; ok {
+		return time.Duration(seconds) * time.Second
+	}
+	if d, ok := t.(timeoutDeclarer); ok {
+		return d.Timeout()
+	}
+	return time.Duration(m.config.Tools.DefaultToolTimeout) * time.Second
 }
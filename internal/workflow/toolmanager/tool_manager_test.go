@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Cyclone1070/iav/internal/audit"
+	"github.com/Cyclone1070/iav/internal/config"
 	"github.com/Cyclone1070/iav/internal/provider"
 	"github.com/Cyclone1070/iav/internal/tool"
 	"github.com/Cyclone1070/iav/internal/workflow"
@@ -47,8 +49,43 @@ func (m *mockTool) Execute(ctx context.Context, req ToolRequest) (ToolResult, er
 	return &mockResult{llmContent: "ok", display: tool.StringDisplay("ok"), success: true}, nil
 }
 
+type mockLogger struct {
+	mu      sync.Mutex
+	entries []string
+	args    [][]any
+}
+
+func (m *mockLogger) Error(msg string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, msg)
+	m.args = append(m.args, args)
+}
+
+type auditEntry struct {
+	rec     audit.Record
+	content string
+}
+
+type mockAuditor struct {
+	mu        sync.Mutex
+	entries   []auditEntry
+	appendErr error
+}
+
+func (m *mockAuditor) Append(rec audit.Record, llmContent string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, auditEntry{rec: rec, content: llmContent})
+	return m.appendErr
+}
+
+func newTestToolManager(cfg *config.Config) *ToolManager {
+	return NewToolManager(cfg, &mockLogger{}, &mockAuditor{})
+}
+
 func TestRegister_AddsTool(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	mt := &mockTool{name: "test-tool", declaration: tool.Declaration{Name: "test-tool"}}
 	tm.Register(mt)
 
@@ -58,7 +95,7 @@ func TestRegister_AddsTool(t *testing.T) {
 }
 
 func TestRegister_DuplicateName(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	mt1 := &mockTool{name: "test-tool", declaration: tool.Declaration{Name: "test-tool", Description: "v1"}}
 	mt2 := &mockTool{name: "test-tool", declaration: tool.Declaration{Name: "test-tool", Description: "v2"}}
 
@@ -71,7 +108,7 @@ func TestRegister_DuplicateName(t *testing.T) {
 }
 
 func TestDeclarations_SortedByName(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	tm.Register(&mockTool{name: "z", declaration: tool.Declaration{Name: "z"}})
 	tm.Register(&mockTool{name: "a", declaration: tool.Declaration{Name: "a"}})
 	tm.Register(&mockTool{name: "m", declaration: tool.Declaration{Name: "m"}})
@@ -84,7 +121,7 @@ func TestDeclarations_SortedByName(t *testing.T) {
 }
 
 func TestExecute_UnknownTool_ReturnsMessageToLLM(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	res, err := tm.Execute(context.Background(), provider.ToolCall{
 		ID:       "tc-123",
 		Function: provider.FunctionCall{Name: "unknown"},
@@ -96,7 +133,7 @@ func TestExecute_UnknownTool_ReturnsMessageToLLM(t *testing.T) {
 }
 
 func TestExecute_ValidJSON_ParsesCorrectly(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	var capturedInput *mockInput
 	tm.Register(&mockTool{
 		name: "test",
@@ -121,7 +158,7 @@ func TestExecute_ValidJSON_ParsesCorrectly(t *testing.T) {
 }
 
 func TestExecute_MalformedJSON_ReturnsMessageToLLM(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	tm.Register(&mockTool{name: "test"})
 
 	res, err := tm.Execute(context.Background(), provider.ToolCall{
@@ -138,7 +175,7 @@ func TestExecute_MalformedJSON_ReturnsMessageToLLM(t *testing.T) {
 }
 
 func TestExecute_EmitsToolEvents(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	tm.Register(&mockTool{
 		name: "test",
 		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
@@ -171,7 +208,7 @@ func TestExecute_EmitsToolEvents(t *testing.T) {
 }
 
 func TestExecute_Shell_StreamsAndEnds(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	tm.Register(&mockTool{
 		name: "shell",
 		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
@@ -220,7 +257,7 @@ loop:
 }
 
 func TestExecute_ContextCancelled_StopsStreaming(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	tm.Register(&mockTool{
 		name: "shell",
 		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
@@ -255,7 +292,7 @@ func TestExecute_ContextCancelled_StopsStreaming(t *testing.T) {
 }
 
 func TestExecute_ConcurrentCalls_NoRace(t *testing.T) {
-	tm := NewToolManager()
+	tm := newTestToolManager(config.DefaultConfig())
 	tm.Register(&mockTool{name: "tool"})
 
 	var wg sync.WaitGroup
@@ -270,3 +307,254 @@ func TestExecute_ConcurrentCalls_NoRace(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestExecute_SchemaViolation_ReturnsMessageToLLM(t *testing.T) {
+	tm := newTestToolManager(config.DefaultConfig())
+	executed := false
+	tm.Register(&mockTool{
+		name: "test",
+		declaration: tool.Declaration{
+			Name: "test",
+			Parameters: &tool.Schema{
+				Type:       tool.TypeObject,
+				Properties: map[string]*tool.Schema{"value": {Type: tool.TypeString}},
+				Required:   []string{"value"},
+			},
+		},
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			executed = true
+			return &mockResult{llmContent: "ok", success: true}, nil
+		},
+	})
+
+	res, err := tm.Execute(context.Background(), provider.ToolCall{
+		ID:       "tc-1",
+		Function: provider.FunctionCall{Name: "test", Arguments: json.RawMessage(`{}`)},
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.False(t, executed)
+	assert.Contains(t, res.Content, "$.value: required property is missing")
+}
+
+func TestExecute_SchemaDefault_AppliedToRequest(t *testing.T) {
+	tm := newTestToolManager(config.DefaultConfig())
+	var captured *mockInput
+	tm.Register(&mockTool{
+		name: "test",
+		declaration: tool.Declaration{
+			Name: "test",
+			Parameters: &tool.Schema{
+				Type:       tool.TypeObject,
+				Properties: map[string]*tool.Schema{"value": {Type: tool.TypeString, Default: "fallback"}},
+			},
+		},
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			captured = req.(*mockInput)
+			return &mockResult{llmContent: "ok", success: true}, nil
+		},
+	})
+
+	_, err := tm.Execute(context.Background(), provider.ToolCall{
+		Function: provider.FunctionCall{Name: "test", Arguments: json.RawMessage(`{}`)},
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "fallback", captured.Value)
+}
+
+func TestExecute_ToolPanics_ReturnsFailureAndLogsStack(t *testing.T) {
+	log := &mockLogger{}
+	tm := NewToolManager(config.DefaultConfig(), log, &mockAuditor{})
+	tm.Register(&mockTool{
+		name: "boom",
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			panic("nil map write")
+		},
+	})
+
+	events := make(chan workflow.Event, 10)
+	res, err := tm.Execute(context.Background(), provider.ToolCall{
+		ID:       "tc-p",
+		Function: provider.FunctionCall{Name: "boom", Arguments: json.RawMessage(`{}`)},
+	}, events)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "tc-p", res.ToolCallID)
+	assert.Contains(t, res.Content, `tool "boom" crashed unexpectedly: nil map write`)
+
+	<-events // start
+	end := (<-events).(workflow.ToolEndEvent)
+	assert.False(t, end.Success)
+
+	assert.Equal(t, []string{"tool panicked"}, log.entries)
+	assert.Contains(t, log.args[0], "stack")
+}
+
+func TestExecute_ToolIgnoresContext_TimesOutAsFailure(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Tools.ToolTimeouts = map[string]int{"hang": 1}
+	tm := newTestToolManager(cfg)
+
+	release := make(chan struct{})
+	defer close(release)
+	tm.Register(&mockTool{
+		name: "hang",
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			<-release // ignores ctx entirely
+			return &mockResult{llmContent: "late", success: true}, nil
+		},
+	})
+
+	start := time.Now()
+	res, err := tm.Execute(context.Background(), provider.ToolCall{
+		Function: provider.FunctionCall{Name: "hang", Arguments: json.RawMessage(`{}`)},
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.Contains(t, res.Content, `tool "hang" timed out after 1s`)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestExecute_ToolReturnsDeadlineExceeded_TimesOutAsFailure(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Tools.DefaultToolTimeout = 1
+	tm := newTestToolManager(cfg)
+	tm.Register(&mockTool{
+		name: "slow",
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	res, err := tm.Execute(context.Background(), provider.ToolCall{
+		Function: provider.FunctionCall{Name: "slow", Arguments: json.RawMessage(`{}`)},
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.Contains(t, res.Content, "timed out")
+}
+
+// timedMockTool declares its own deadline.
+type timedMockTool struct {
+	mockTool
+	timeout time.Duration
+}
+
+func (m *timedMockTool) Timeout() time.Duration { return m.timeout }
+
+func TestExecute_ToolDeclaredTimeout_Applies(t *testing.T) {
+	tm := newTestToolManager(config.DefaultConfig())
+	tm.Register(&timedMockTool{
+		mockTool: mockTool{
+			name: "slow",
+			executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+		timeout: 50 * time.Millisecond,
+	})
+
+	res, err := tm.Execute(context.Background(), provider.ToolCall{
+		Function: provider.FunctionCall{Name: "slow", Arguments: json.RawMessage(`{}`)},
+	}, nil)
+
+	assert.NoError(t, err)
+	assert.Contains(t, res.Content, `timed out after 50ms`)
+}
+
+func TestTimeoutFor_ConfigOverridesToolDeclaredTimeout(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Tools.ToolTimeouts = map[string]int{"slow": 7}
+	tm := newTestToolManager(cfg)
+
+	assert.Equal(t, 7*time.Second, tm.timeoutFor(&timedMockTool{mockTool: mockTool{name: "slow"}, timeout: time.Minute}))
+}
+
+func TestExecute_CallerCancelled_ReturnsInfraError(t *testing.T) {
+	tm := newTestToolManager(config.DefaultConfig())
+	tm.Register(&mockTool{
+		name: "slow",
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := tm.Execute(ctx, provider.ToolCall{
+		Function: provider.FunctionCall{Name: "slow", Arguments: json.RawMessage(`{}`)},
+	}, nil)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestExecute_AppendsAuditRecord(t *testing.T) {
+	auditor := &mockAuditor{}
+	tm := NewToolManager(config.DefaultConfig(), &mockLogger{}, auditor)
+	tm.Register(&mockTool{
+		name: "read",
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			return &mockResult{llmContent: "contents", success: true}, nil
+		},
+	})
+
+	ctx := workflow.WithSessionID(context.Background(), "session-1")
+	_, err := tm.Execute(ctx, provider.ToolCall{
+		ID:       "call-1",
+		Function: provider.FunctionCall{Name: "read", Arguments: json.RawMessage(`{"value":"a"}`)},
+	}, nil)
+
+	assert.NoError(t, err)
+	if assert.Len(t, auditor.entries, 1) {
+		e := auditor.entries[0]
+		assert.Equal(t, "session-1", e.rec.SessionID)
+		assert.Equal(t, "call-1", e.rec.ToolCallID)
+		assert.Equal(t, "read", e.rec.Tool)
+		assert.JSONEq(t, `{"value":"a"}`, string(e.rec.Arguments))
+		assert.True(t, e.rec.Success)
+		assert.False(t, e.rec.EndedAt.Before(e.rec.StartedAt))
+		assert.Equal(t, "contents", e.content)
+	}
+}
+
+func TestExecute_InvalidAndCancelledCalls_AreAudited(t *testing.T) {
+	auditor := &mockAuditor{}
+	tm := NewToolManager(config.DefaultConfig(), &mockLogger{}, auditor)
+	tm.Register(&mockTool{
+		name: "slow",
+		executeFunc: func(ctx context.Context, req ToolRequest) (ToolResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+
+	_, _ = tm.Execute(context.Background(), provider.ToolCall{Function: provider.FunctionCall{Name: "missing"}}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := tm.Execute(ctx, provider.ToolCall{Function: provider.FunctionCall{Name: "slow", Arguments: json.RawMessage(`{}`)}}, nil)
+	assert.Error(t, err)
+
+	if assert.Len(t, auditor.entries, 2) {
+		assert.False(t, auditor.entries[0].rec.Success)
+		assert.Contains(t, auditor.entries[0].content, "does not exist")
+		assert.False(t, auditor.entries[1].rec.Success)
+		assert.Equal(t, context.Canceled.Error(), auditor.entries[1].rec.Error)
+	}
+}
+
+func TestExecute_AuditFailure_IsLoggedNotReturned(t *testing.T) {
+	log := &mockLogger{}
+	tm := NewToolManager(config.DefaultConfig(), log, &mockAuditor{appendErr: io.ErrShortWrite})
+	tm.Register(&mockTool{name: "ok"})
+
+	_, err := tm.Execute(context.Background(), provider.ToolCall{Function: provider.FunctionCall{Name: "ok", Arguments: json.RawMessage(`{}`)}}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"audit record failed"}, log.entries)
+}
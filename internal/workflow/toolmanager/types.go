@@ -0,0 +1,14 @@
+package toolmanager
+
+import "github.com/Cyclone1070/iav/internal/tool"
+
+// failureResult is produced by the manager itself when a tool cannot deliver
+// a result of its own (timeout or panic).
+type failureResult struct {
+	content string
+	display string
+}
+
+func (r *failureResult) LLMContent() string        { return r.content }
+func (r *failureResult) Display() tool.ToolDisplay { return tool.StringDisplay(r.display) }
+func (r *failureResult) Success() bool             { return false }
@@ -0,0 +1,237 @@
+package toolmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Cyclone1070/iav/internal/tool"
+)
+
+// validateArguments checks raw tool call arguments against the tool's parameter schema.
+// It returns the arguments with schema defaults filled in for missing properties,
+// ready to be unmarshalled into the tool's request struct.
+// All violations are reported together, each qualified with its JSON path (e.g. $.operations[0].before).
+func validateArguments(schema *tool.Schema, raw json.RawMessage) (json.RawMessage, error) {
+	if schema == nil {
+		return raw, nil
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		raw = json.RawMessage("{}")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+
+	v := &schemaValidator{}
+	value = v.validate(schema, value, "$")
+	if len(v.violations) > 0 {
+		return nil, fmt.Errorf("schema validation failed:\n- %s", strings.Join(v.violations, "\n- "))
+	}
+
+	withDefaults, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("encode arguments: %w", err)
+	}
+	return withDefaults, nil
+}
+
+// schemaValidator walks a decoded JSON value and collects violations.
+type schemaValidator struct {
+	violations []string
+}
+
+func (v *schemaValidator) addf(path, format string, args ...any) {
+	v.violations = append(v.violations, path+": "+fmt.Sprintf(format, args...))
+}
+
+// validate checks value against schema and returns the value with defaults applied.
+func (v *schemaValidator) validate(schema *tool.Schema, value any, path string) any {
+	switch schema.Type {
+	case tool.TypeObject:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			v.addf(path, "expected object, got %s", describe(value))
+			return value
+		}
+		return v.validateObject(schema, obj, path)
+
+	case tool.TypeArray:
+		arr, ok := value.([]any)
+		if !ok {
+			v.addf(path, "expected array, got %s", describe(value))
+			return value
+		}
+		if schema.Items != nil {
+			for i, item := range arr {
+				arr[i] = v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+		return arr
+
+	case tool.TypeString:
+		s, ok := value.(string)
+		if !ok {
+			v.addf(path, "expected string, got %s", describe(value))
+			return value
+		}
+		v.validateString(schema, s, path)
+		return s
+
+	case tool.TypeInteger:
+		n, ok := value.(json.Number)
+		if !ok {
+			v.addf(path, "expected integer, got %s", describe(value))
+			return value
+		}
+		if _, err := n.Int64(); err != nil {
+			v.addf(path, "expected integer, got number %s", n)
+			return value
+		}
+		v.validateRange(schema, n, path)
+		return n
+
+	case tool.TypeNumber:
+		n, ok := value.(json.Number)
+		if !ok {
+			v.addf(path, "expected number, got %s", describe(value))
+			return value
+		}
+		v.validateRange(schema, n, path)
+		return n
+
+	case tool.TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			v.addf(path, "expected boolean, got %s", describe(value))
+		}
+		return value
+	}
+
+	// Untyped schema accepts any value
+	return value
+}
+
+func (v *schemaValidator) validateObject(schema *tool.Schema, obj map[string]any, path string) map[string]any {
+	// A null property is treated as absent, models often send null for optional arguments.
+	for name, val := range obj {
+		if val == nil {
+			delete(obj, name)
+		}
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := obj[name]; !ok {
+			v.addf(path+"."+name, "required property is missing")
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propSchema, known := schema.Properties[name]
+		if !known {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				v.addf(path+"."+name, "unknown property (allowed: %s)", strings.Join(sortedKeys(schema.Properties), ", "))
+			}
+			continue
+		}
+		obj[name] = v.validate(propSchema, obj[name], path+"."+name)
+	}
+
+	for _, name := range sortedKeys(schema.Properties) {
+		if _, ok := obj[name]; !ok && schema.Properties[name].Default != nil {
+			obj[name] = schema.Properties[name].Default
+		}
+	}
+	return obj
+}
+
+func (v *schemaValidator) validateString(schema *tool.Schema, s string, path string) {
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, e := range schema.Enum {
+			if s == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.addf(path, "value %q is not one of [%s]", s, strings.Join(schema.Enum, ", "))
+		}
+	}
+	if schema.Pattern != "" {
+		re, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			v.addf(path, "schema pattern %q is invalid: %v", schema.Pattern, err)
+			return
+		}
+		if !re.MatchString(s) {
+			v.addf(path, "value %q does not match pattern %q", s, schema.Pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateRange(schema *tool.Schema, n json.Number, path string) {
+	f, err := n.Float64()
+	if err != nil {
+		v.addf(path, "invalid number %s", n)
+		return
+	}
+	if schema.Minimum != nil && f < *schema.Minimum {
+		v.addf(path, "value %s is less than minimum %s", n, formatFloat(*schema.Minimum))
+	}
+	if schema.Maximum != nil && f > *schema.Maximum {
+		v.addf(path, "value %s is greater than maximum %s", n, formatFloat(*schema.Maximum))
+	}
+}
+
+// describe names the JSON type of a decoded value for error messages.
+func describe(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number " + val.String()
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func formatFloat(f float64) string {
+	if f == math.Trunc(f) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]*tool.Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
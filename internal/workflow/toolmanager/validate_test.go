@@ -0,0 +1,117 @@
+package toolmanager
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Cyclone1070/iav/internal/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchema() *tool.Schema {
+	return &tool.Schema{
+		Type:                 tool.TypeObject,
+		AdditionalProperties: tool.Ptr(false),
+		Properties: map[string]*tool.Schema{
+			"path":   {Type: tool.TypeString, Pattern: `^[^\s]+$`},
+			"mode":   {Type: tool.TypeString, Enum: []string{"fast", "slow"}, Default: "fast"},
+			"offset": {Type: tool.TypeInteger, Minimum: tool.Ptr(0.0)},
+			"ratio":  {Type: tool.TypeNumber, Maximum: tool.Ptr(1.0)},
+			"force":  {Type: tool.TypeBoolean},
+			"operations": {
+				Type: tool.TypeArray,
+				Items: &tool.Schema{
+					Type: tool.TypeObject,
+					Properties: map[string]*tool.Schema{
+						"before": {Type: tool.TypeString},
+						"after":  {Type: tool.TypeString},
+					},
+					Required: []string{"before", "after"},
+				},
+			},
+		},
+		Required: []string{"path"},
+	}
+}
+
+func TestValidateArguments_Valid_AppliesDefaults(t *testing.T) {
+	args, err := validateArguments(testSchema(), json.RawMessage(`{"path": "a.txt", "offset": 3}`))
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(args, &got))
+	assert.Equal(t, "a.txt", got["path"])
+	assert.Equal(t, "fast", got["mode"])
+	assert.Equal(t, float64(3), got["offset"])
+}
+
+func TestValidateArguments_NilSchema_PassesThrough(t *testing.T) {
+	raw := json.RawMessage(`{"anything": 1}`)
+	args, err := validateArguments(nil, raw)
+	require.NoError(t, err)
+	assert.Equal(t, raw, args)
+}
+
+func TestValidateArguments_EmptyArguments_TreatedAsEmptyObject(t *testing.T) {
+	_, err := validateArguments(testSchema(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "$.path: required property is missing")
+}
+
+func TestValidateArguments_NullOptionalProperty_TreatedAsAbsent(t *testing.T) {
+	_, err := validateArguments(testSchema(), json.RawMessage(`{"path": "a", "offset": null}`))
+	assert.NoError(t, err)
+}
+
+func TestValidateArguments_Violations(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want string
+	}{
+		{"MissingRequired", `{}`, "$.path: required property is missing"},
+		{"WrongType", `{"path": 42}`, "$.path: expected string, got number 42"},
+		{"EnumMismatch", `{"path": "a", "mode": "medium"}`, `$.mode: value "medium" is not one of [fast, slow]`},
+		{"PatternMismatch", `{"path": "a b"}`, `$.path: value "a b" does not match pattern`},
+		{"BelowMinimum", `{"path": "a", "offset": -1}`, "$.offset: value -1 is less than minimum 0"},
+		{"AboveMaximum", `{"path": "a", "ratio": 1.5}`, "$.ratio: value 1.5 is greater than maximum 1"},
+		{"FractionalInteger", `{"path": "a", "offset": 1.5}`, "$.offset: expected integer, got number 1.5"},
+		{"WrongBoolean", `{"path": "a", "force": "yes"}`, "$.force: expected boolean, got string"},
+		{"UnknownProperty", `{"path": "a", "pth": "b"}`, "$.pth: unknown property"},
+		{"NestedRequired", `{"path": "a", "operations": [{"before": "x"}]}`, "$.operations[0].after: required property is missing"},
+		{"NestedWrongType", `{"path": "a", "operations": "x"}`, "$.operations: expected array, got string"},
+		{"NotAnObject", `["a"]`, "$: expected object, got array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validateArguments(testSchema(), json.RawMessage(tt.args))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestValidateArguments_ReportsAllViolations(t *testing.T) {
+	_, err := validateArguments(testSchema(), json.RawMessage(`{"offset": -5, "mode": "x"}`))
+	require.Error(t, err)
+	msg := err.Error()
+	assert.Contains(t, msg, "$.path")
+	assert.Contains(t, msg, "$.offset")
+	assert.Contains(t, msg, "$.mode")
+}
+
+func TestValidateArguments_AdditionalPropertiesUnset_Allowed(t *testing.T) {
+	schema := &tool.Schema{
+		Type:       tool.TypeObject,
+		Properties: map[string]*tool.Schema{"a": {Type: tool.TypeString}},
+	}
+	_, err := validateArguments(schema, json.RawMessage(`{"a": "x", "b": 1}`))
+	assert.NoError(t, err)
+}
+
+func TestValidateArguments_MalformedJSON_ReturnsError(t *testing.T) {
+	_, err := validateArguments(testSchema(), json.RawMessage(`{invalid}`))
+	assert.Error(t, err)
+}